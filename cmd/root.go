@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/urfave/cli/v2"
 	"github.com/urfave/cli/v2/altsrc"
+	"github.com/vanillaiice/itpg/db"
 	"github.com/vanillaiice/itpg/server"
 )
 
@@ -23,7 +26,7 @@ var rootCmd *cli.Command = &cli.Command{
 			&cli.StringFlag{
 				Name:    "db-backend",
 				Aliases: []string{"b"},
-				Usage:   "database backend, either sqlite or postgres",
+				Usage:   "database backend, either sqlite, postgres or mysql",
 				Value:   "sqlite",
 			},
 		),
@@ -35,6 +38,18 @@ var rootCmd *cli.Command = &cli.Command{
 				Value:   "itpg.db",
 			},
 		),
+		altsrc.NewStringFlag(
+			&cli.StringFlag{
+				Name:  "read-replica-db",
+				Usage: "read replica database connection `URL` (postgres and mysql only)",
+			},
+		),
+		altsrc.NewStringSliceFlag(
+			&cli.StringSliceFlag{
+				Name:  "tenants",
+				Usage: "serve each tenant from its own database, in the `TENANT=URL` form (enables multi-tenant mode)",
+			},
+		),
 		altsrc.NewPathFlag(
 			&cli.PathFlag{
 				Name:    "users-db",
@@ -59,6 +74,60 @@ var rootCmd *cli.Command = &cli.Command{
 				Value:   10,
 			},
 		),
+		altsrc.NewStringFlag(
+			&cli.StringFlag{
+				Name:  "dedup-scope",
+				Usage: "scope within which a user can only grade once, either course_professor, professor, or course",
+				Value: "course_professor",
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "min-grades-ranking",
+				Usage: "minimum number of grades for a professor to be ranked",
+				Value: 3,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "min-grades-verified",
+				Usage: "minimum number of grades for a score to be verified",
+				Value: 5,
+			},
+		),
+		altsrc.NewStringFlag(
+			&cli.StringFlag{
+				Name:  "hash-algorithm",
+				Usage: "algorithm used to hash grade deduplication inputs, either xxh3 or hmac-sha256",
+				Value: "xxh3",
+			},
+		),
+		altsrc.NewStringFlag(
+			&cli.StringFlag{
+				Name:  "hash-key",
+				Usage: "secret key used by keyed hash algorithms",
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "max-row-return",
+				Usage: "maximum number of rows returned by a query",
+				Value: 100,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "query-timeout",
+				Usage: "timeout in seconds of each database operation (0 to disable the timeout)",
+				Value: 30,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "slow-query-ms",
+				Usage: "duration in milliseconds above which a database operation is logged as slow (0 to disable the logging)",
+			},
+		),
 		altsrc.NewStringFlag(
 			&cli.StringFlag{
 				Name:    "log-level",
@@ -75,6 +144,47 @@ var rootCmd *cli.Command = &cli.Command{
 				Value:   30,
 			},
 		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "cookie-max-age",
+				Usage: "max age in minutes of the cookie sent to browsers (0 to use the cookie timeout)",
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "max-login-attempts",
+				Usage: "number of failed login attempts from an ip address after which a user is locked out (0 to disable lockouts)",
+				Value: 5,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "lockout",
+				Usage: "duration in minutes of the first lockout, doubled with each further failed login attempt",
+				Value: 15,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "idempotency-key-hours",
+				Usage: "duration in hours during which a grade submission can be retried with the same Idempotency-Key header (0 to ignore idempotency keys)",
+				Value: 24,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "public-cache-max-age",
+				Usage: "duration in seconds during which clients and proxies can cache the responses of the cacheable public reads (0 to disable caching)",
+				Value: 0,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "public-max-results",
+				Usage: "maximum number of results of the list and search endpoints returned to unauthenticated users (0 for no limit)",
+				Value: 0,
+			},
+		),
 		altsrc.NewPathFlag(
 			&cli.PathFlag{
 				Name:    "smtp-env",
@@ -87,7 +197,13 @@ var rootCmd *cli.Command = &cli.Command{
 			&cli.StringFlag{
 				Name:    "pass-reset-url",
 				Aliases: []string{"r"},
-				Usage:   "password reset web page `URL`",
+				Usage:   "absolute http(s) `URL` of the password reset web page",
+			},
+		),
+		altsrc.NewStringSliceFlag(
+			&cli.StringSliceFlag{
+				Name:  "pass-reset-urls",
+				Usage: "password reset web page of the frontend at an origin, in the `ORIGIN=URL` form (defaults to the pass-reset-url)",
 			},
 		),
 		altsrc.NewStringSliceFlag(
@@ -114,6 +230,39 @@ var rootCmd *cli.Command = &cli.Command{
 				Value:   false,
 			},
 		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "smtp-idle-timeout",
+				Usage: "time in seconds after which an idle connection to the SMTP server is closed (0 to disable connection reuse)",
+				Value: 30,
+			},
+		),
+		altsrc.NewStringFlag(
+			&cli.StringFlag{
+				Name:  "notifier",
+				Usage: "send confirmation codes with `KIND` (smtp or webhook)",
+				Value: "smtp",
+			},
+		),
+		altsrc.NewStringFlag(
+			&cli.StringFlag{
+				Name:  "notifier-webhook-url",
+				Usage: "absolute http(s) `URL` of the webhook receiving the confirmation codes with the webhook notifier",
+			},
+		),
+		altsrc.NewPathFlag(
+			&cli.PathFlag{
+				Name:  "mail-template-dir",
+				Usage: "load the confirmation and password reset mail templates from `DIR` (defaults to the embedded templates)",
+			},
+		),
+		altsrc.NewStringFlag(
+			&cli.StringFlag{
+				Name:  "site-name",
+				Usage: "`NAME` of the site used in the mails",
+				Value: "ITPG",
+			},
+		),
 		altsrc.NewBoolFlag(
 			&cli.BoolFlag{
 				Name:    "http",
@@ -122,6 +271,95 @@ var rootCmd *cli.Command = &cli.Command{
 				Value:   false,
 			},
 		),
+		altsrc.NewBoolFlag(
+			&cli.BoolFlag{
+				Name:  "no-content",
+				Usage: "return 204 No Content on successful mutations",
+				Value: false,
+			},
+		),
+		altsrc.NewBoolFlag(
+			&cli.BoolFlag{
+				Name:  "hide-server-header",
+				Usage: "remove the headers revealing the identity of the server from responses",
+				Value: false,
+			},
+		),
+		altsrc.NewBoolFlag(
+			&cli.BoolFlag{
+				Name:  "maintenance",
+				Usage: "run in read-only maintenance mode, rejecting state-changing requests",
+				Value: false,
+			},
+		),
+		altsrc.NewBoolFlag(
+			&cli.BoolFlag{
+				Name:  "require-origin",
+				Usage: "reject state-changing requests without an origin matching the allowed origins",
+				Value: false,
+			},
+		),
+		altsrc.NewFloat64Flag(
+			&cli.Float64Flag{
+				Name:  "verified-grade-weight",
+				Usage: "weight of the grades of verified users in the averages",
+				Value: 1,
+			},
+		),
+		altsrc.NewFloat64SliceFlag(
+			&cli.Float64SliceFlag{
+				Name:  "score-weights",
+				Usage: "weights of the teaching, coursework, and learning scores in the average score (equal weights compute the plain mean)",
+				Value: cli.NewFloat64Slice(1, 1, 1),
+			},
+		),
+		altsrc.NewStringSliceFlag(
+			&cli.StringSliceFlag{
+				Name:  "score-dimensions",
+				Usage: "names of the graded score dimensions, starting with teaching, coursework, and learning",
+				Value: cli.NewStringSlice(db.DefaultScoreDimensions...),
+			},
+		),
+		altsrc.NewBoolFlag(
+			&cli.BoolFlag{
+				Name:  "professor-name-dedup",
+				Usage: "reject professors whose name only differs from the name of an existing professor by case, punctuation, or spacing",
+				Value: false,
+			},
+		),
+		altsrc.NewBoolFlag(
+			&cli.BoolFlag{
+				Name:  "trust-proxy",
+				Usage: "read the ip address of clients from the X-Forwarded-For header set by a reverse proxy",
+				Value: false,
+			},
+		),
+		altsrc.NewStringSliceFlag(
+			&cli.StringSliceFlag{
+				Name:  "denied-ips",
+				Usage: "deny access to the server from the specified ip ranges or addresses",
+			},
+		),
+		altsrc.NewStringSliceFlag(
+			&cli.StringSliceFlag{
+				Name:  "admin-allowed-ips",
+				Usage: "only allow the specified ip ranges or addresses to access the admin routes",
+			},
+		),
+		altsrc.NewBoolFlag(
+			&cli.BoolFlag{
+				Name:  "metrics",
+				Usage: "expose metrics in the Prometheus text format at /metrics",
+				Value: false,
+			},
+		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "shutdown-timeout",
+				Usage: "time in seconds to wait for in-flight requests on shutdown",
+				Value: 10,
+			},
+		),
 		altsrc.NewPathFlag(
 			&cli.PathFlag{
 				Name:    "cert",
@@ -144,6 +382,13 @@ var rootCmd *cli.Command = &cli.Command{
 				Value:   180,
 			},
 		),
+		altsrc.NewIntFlag(
+			&cli.IntFlag{
+				Name:  "code-resend-cooldown",
+				Usage: "duration in seconds after sending a confirmation code or a password reset link to a user during which another one cannot be sent (0 to disable the cooldown)",
+				Value: 60,
+			},
+		),
 		altsrc.NewIntFlag(
 			&cli.IntFlag{
 				Name:    "code-length",
@@ -164,8 +409,7 @@ var rootCmd *cli.Command = &cli.Command{
 			&cli.PathFlag{
 				Name:    "handlers",
 				Aliases: []string{"H"},
-				Usage:   "load JSON handler config from `FILE`",
-				Value:   "handlers.json",
+				Usage:   "load JSON handler config from `FILE` (empty to use the embedded default)",
 			},
 		),
 		&cli.StringFlag{
@@ -175,28 +419,69 @@ var rootCmd *cli.Command = &cli.Command{
 		},
 	},
 	Action: func(ctx *cli.Context) error {
+		weights := ctx.Float64Slice("score-weights")
+		if len(weights) != 3 {
+			return fmt.Errorf("invalid score weights: %v (should be three weights)", weights)
+		}
+
 		return server.Run(
 			&server.RunCfg{
-				Port:               ctx.String("port"),
-				DbUrl:              ctx.String("db"),
-				DbBackend:          server.DatabaseBackend(ctx.String("db-backend")),
-				CacheDbUrl:         ctx.String("cache-db"),
-				CacheTtl:           ctx.Int("cache-ttl"),
-				UsersDbPath:        ctx.Path("users-db"),
-				AllowedOrigins:     ctx.StringSlice("allowed-origins"),
-				AllowedMailDomains: ctx.StringSlice("allowed-mail-domains"),
-				PasswordResetUrl:   ctx.String("pass-reset-url"),
-				SmtpEnvPath:        ctx.Path("smtp-env"),
-				UseSmtp:            ctx.Bool("smtp"),
-				UseHttp:            ctx.Bool("http"),
-				HandlersFilePath:   ctx.Path("handlers"),
-				CertFilePath:       ctx.Path("cert"),
-				KeyFilePath:        ctx.Path("key"),
-				CookieTimeout:      ctx.Int("cookie-timeout"),
-				CodeValidityMinute: ctx.Int("code-validity"),
-				CodeLength:         ctx.Int("code-length"),
-				MinPasswordScore:   ctx.Int("min-password-score"),
-				LogLevel:           server.LogLevel(ctx.String("log-level")),
+				Port:                   ctx.String("port"),
+				DbUrl:                  ctx.String("db"),
+				DbBackend:              server.DatabaseBackend(ctx.String("db-backend")),
+				ReadReplicaUrl:         ctx.String("read-replica-db"),
+				Tenants:                ctx.StringSlice("tenants"),
+				CacheDbUrl:             ctx.String("cache-db"),
+				CacheTtl:               ctx.Int("cache-ttl"),
+				DedupScope:             db.DedupScope(ctx.String("dedup-scope")),
+				MinGradesForRanking:    ctx.Int("min-grades-ranking"),
+				MinGradesForVerified:   ctx.Int("min-grades-verified"),
+				HashAlgorithm:          db.HashAlgorithm(ctx.String("hash-algorithm")),
+				HashKey:                ctx.String("hash-key"),
+				MaxRowReturn:           ctx.Int("max-row-return"),
+				QueryTimeout:           ctx.Int("query-timeout"),
+				SlowQueryMs:            ctx.Int("slow-query-ms"),
+				UsersDbPath:            ctx.Path("users-db"),
+				AllowedOrigins:         ctx.StringSlice("allowed-origins"),
+				AllowedMailDomains:     ctx.StringSlice("allowed-mail-domains"),
+				PasswordResetUrl:       ctx.String("pass-reset-url"),
+				PasswordResetUrls:      ctx.StringSlice("pass-reset-urls"),
+				SmtpEnvPath:            ctx.Path("smtp-env"),
+				UseSmtp:                ctx.Bool("smtp"),
+				SmtpIdleTimeout:        ctx.Int("smtp-idle-timeout"),
+				Notifier:               server.NotifierKind(ctx.String("notifier")),
+				NotifierWebhookUrl:     ctx.String("notifier-webhook-url"),
+				MailTemplateDir:        ctx.Path("mail-template-dir"),
+				SiteName:               ctx.String("site-name"),
+				UseHttp:                ctx.Bool("http"),
+				HandlersFilePath:       ctx.Path("handlers"),
+				CertFilePath:           ctx.Path("cert"),
+				KeyFilePath:            ctx.Path("key"),
+				CookieTimeout:          ctx.Int("cookie-timeout"),
+				CookieMaxAge:           ctx.Int("cookie-max-age"),
+				CodeValidityMinute:     ctx.Int("code-validity"),
+				CodeResendCooldown:     ctx.Int("code-resend-cooldown"),
+				CodeLength:             ctx.Int("code-length"),
+				MinPasswordScore:       ctx.Int("min-password-score"),
+				LogLevel:               server.LogLevel(ctx.String("log-level")),
+				NoContentOnSuccess:     ctx.Bool("no-content"),
+				HideServerHeader:       ctx.Bool("hide-server-header"),
+				RequireOrigin:          ctx.Bool("require-origin"),
+				Maintenance:            ctx.Bool("maintenance"),
+				VerifiedGradeWeight:    ctx.Float64("verified-grade-weight"),
+				ScoreWeights:           &[3]float32{float32(weights[0]), float32(weights[1]), float32(weights[2])},
+				ScoreDimensions:        ctx.StringSlice("score-dimensions"),
+				ProfessorNameDedup:     ctx.Bool("professor-name-dedup"),
+				ShutdownTimeoutSeconds: ctx.Int("shutdown-timeout"),
+				MaxLoginAttempts:       ctx.Int("max-login-attempts"),
+				LockoutMinutes:         ctx.Int("lockout"),
+				IdempotencyKeyHours:    ctx.Int("idempotency-key-hours"),
+				PublicCacheMaxAge:      ctx.Int("public-cache-max-age"),
+				PublicMaxResults:       ctx.Int("public-max-results"),
+				TrustProxy:             ctx.Bool("trust-proxy"),
+				DeniedIPs:              ctx.StringSlice("denied-ips"),
+				AdminAllowedIPs:        ctx.StringSlice("admin-allowed-ips"),
+				MetricsEnabled:         ctx.Bool("metrics"),
 			},
 		)
 	},
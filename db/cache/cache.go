@@ -5,12 +5,14 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/vanillaiice/itpg/metrics"
 )
 
 // Cache is a cache implementation.
 type Cache struct {
-	client *redis.Client
-	ctx    context.Context
+	client    *redis.Client
+	ctx       context.Context
+	namespace string // namespace is the prefix of the keys of the cache.
 }
 
 // ErrRedisNil is returned when a key is not found in redis.
@@ -35,17 +37,59 @@ func New(url string, ctx context.Context) (*Cache, error) {
 	}, nil
 }
 
+// SetNamespace prefixes the keys set, read, and deleted through the cache with the namespace,
+// so that several databases can share a cache without their keys colliding.
+func (c *Cache) SetNamespace(namespace string) {
+	c.namespace = namespace
+}
+
 // Close closes the cache.
 func (c *Cache) Close() error {
 	return c.client.Close()
 }
 
+// Ping checks that the connection to the cache is alive.
+func (c *Cache) Ping() error {
+	return c.client.Ping(c.ctx).Err()
+}
+
 // Set sets a value in the cache.
 func (c *Cache) Set(key string, value any, ttl time.Duration) error {
-	return c.client.Set(c.ctx, key, value, ttl).Err()
+	err := c.client.Set(c.ctx, c.namespace+key, value, ttl).Err()
+	if err != nil {
+		metrics.CacheSets.Inc("error")
+	} else {
+		metrics.CacheSets.Inc("ok")
+	}
+	return err
 }
 
 // Get gets a value from the cache.
 func (c *Cache) Get(key string) (string, error) {
-	return c.client.Get(c.ctx, key).Result()
+	value, err := c.client.Get(c.ctx, c.namespace+key).Result()
+	switch {
+	case err == nil:
+		metrics.CacheRequests.Inc("hit")
+	case err == ErrRedisNil:
+		metrics.CacheRequests.Inc("miss")
+	default:
+		metrics.CacheRequests.Inc("error")
+	}
+	return value, err
+}
+
+// DelPrefix deletes the keys starting with the specified prefix from the cache.
+func (c *Cache) DelPrefix(prefix string) error {
+	return c.DeletePattern(prefix + "*")
+}
+
+// DeletePattern deletes the keys matching the specified glob-style pattern from the cache.
+func (c *Cache) DeletePattern(pattern string) error {
+	iter := c.client.Scan(c.ctx, 0, c.namespace+pattern, 0).Iterator()
+	for iter.Next(c.ctx) {
+		if err := c.client.Del(c.ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
 }
@@ -96,6 +96,87 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestDelPrefix(t *testing.T) {
+	keys := map[string]bool{"GetLastCourses10_0": true, "GetLastCourses20_0": true, "GetLastProfessors10_0": false}
+	for k := range keys {
+		if err := DB.Set(k, "[]", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := DB.DelPrefix("GetLastCourses"); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, deleted := range keys {
+		_, err := DB.Get(k)
+		if deleted && err != ErrRedisNil {
+			t.Errorf("%s: got %v, want %v", k, err, ErrRedisNil)
+		} else if !deleted && err != nil {
+			t.Errorf("%s: got %v, want nil", k, err)
+		}
+	}
+}
+
+func TestDeletePattern(t *testing.T) {
+	keys := map[string]bool{"GetScoresByProfessorUUIDfoo": true, "GetScoresByProfessorUUIDfoo_bar": true, "GetScoresByProfessorUUIDbaz": false}
+	for k := range keys {
+		if err := DB.Set(k, "[]", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := DB.DeletePattern("GetScoresByProfessorUUIDfoo*"); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, deleted := range keys {
+		_, err := DB.Get(k)
+		if deleted && err != ErrRedisNil {
+			t.Errorf("%s: got %v, want %v", k, err, ErrRedisNil)
+		} else if !deleted && err != nil {
+			t.Errorf("%s: got %v, want nil", k, err)
+		}
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	namespaced, err := New(dbUrl, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer namespaced.Close()
+	namespaced.SetNamespace("tenant:foo:")
+
+	if err = DB.Set("GetLastCourses30_0", "[]", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err = namespaced.Set("GetLastCourses30_0", "[1]", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := DB.Get("tenant:foo:GetLastCourses30_0"); err != nil || v != "[1]" {
+		t.Errorf("got %s, %v, want %s, nil", v, err, "[1]")
+	}
+
+	if err = namespaced.DeletePattern("*"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = namespaced.Get("GetLastCourses30_0"); err != ErrRedisNil {
+		t.Errorf("got %v, want %v", err, ErrRedisNil)
+	}
+	if v, err := DB.Get("GetLastCourses30_0"); err != nil || v != "[]" {
+		t.Errorf("got %s, %v, want %s, nil", v, err, "[]")
+	}
+}
+
+func TestPing(t *testing.T) {
+	if err := DB.Ping(); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestClose(t *testing.T) {
 	err := DB.Close()
 	if err != nil {
@@ -0,0 +1,4083 @@
+package mysql
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/db/cache"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+// roundPrecision is the number decimals to use when rounding
+const roundPrecision = 2
+
+// defaultHash is the hash value used when adding course to a professor
+const defaultHash = ""
+
+// minGrade is the minimum value of a grade
+const minGrade = 0
+
+// maxGrade is the maximum value of a grade
+const maxGrade = 5
+
+// Error numbers of the mysql and mariadb errors mapped to database errors.
+const (
+	erBadNullError            = 1048 // erBadNullError is returned when a NOT NULL column is set to NULL.
+	erDupEntry                = 1062 // erDupEntry is returned when a unique key is duplicated.
+	erRowIsReferenced         = 1451 // erRowIsReferenced is returned when deleting a row referenced by a foreign key.
+	erNoReferencedRow         = 1452 // erNoReferencedRow is returned when a foreign key references no row.
+	erCheckConstraintViolated = 3819 // erCheckConstraintViolated is returned by mysql when a CHECK constraint fails.
+	erConstraintFailed        = 4025 // erConstraintFailed is returned by mariadb when a CHECK constraint fails.
+)
+
+// professorSortStmts maps the professor sort orders to the statements used to retrieve professors.
+var professorSortStmts = map[db.ProfessorSort]string{
+	db.ProfessorSortRecent: `
+		SELECT uuid, name
+		FROM Professors
+		WHERE deleted_at IS NULL
+		ORDER BY inserted_at
+		DESC
+		LIMIT ?
+		OFFSET ?
+	`,
+	db.ProfessorSortName: `
+		SELECT uuid, name
+		FROM Professors
+		WHERE deleted_at IS NULL
+		ORDER BY name
+		ASC
+		LIMIT ?
+		OFFSET ?
+	`,
+	db.ProfessorSortRating: `
+		SELECT Professors.uuid, Professors.name
+		FROM
+			Professors
+			LEFT JOIN (Scores JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL) ON Professors.uuid = Scores.professor_uuid
+		WHERE Professors.deleted_at IS NULL
+		GROUP BY Professors.uuid
+		ORDER BY IFNULL((? * SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0)) / ?, 0)
+		DESC, COUNT(Scores.score_teaching) DESC, Professors.inserted_at DESC, Professors.uuid
+		LIMIT ?
+		OFFSET ?
+	`,
+}
+
+// rankingScoreExprs maps the scores by which professors can be ranked to their expressions,
+// the expression of the average score taking the score weights as arguments.
+var rankingScoreExprs = map[db.RankingScore]string{
+	db.RankingScoreAverage:    "(? * SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0)) / ?",
+	db.RankingScoreTeaching:   "SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0)",
+	db.RankingScoreCoursework: "SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0)",
+	db.RankingScoreLearning:   "SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0)",
+}
+
+// courseCacheKeyPrefixes are the prefixes of the cache keys holding courses.
+var courseCacheKeyPrefixes = []string{
+	"GetLastCourses",
+	"GetCoursesByProfessorUUID",
+	"GetUngradedCoursesByProfessorUUID",
+	"GetLastScores",
+	"GetScoresBy",
+	"GetScoresBetween",
+	"GetScoreHistoryByCourseCode",
+	"GetDepartmentStats",
+	"GetProfessorDetail",
+	"GetProfessorRank",
+	"GetCourseDetail",
+	"GetProfessorsForCourses",
+	"GetMostRatedCourses",
+	"Search",
+}
+
+// scoreCacheKeyPrefixes are the prefixes of the cache keys holding data computed from the grades.
+var scoreCacheKeyPrefixes = []string{
+	"GetLastProfessors",
+	"GetUnratedProfessors",
+	"GetUngradedCoursesByProfessorUUID",
+	"GetLastScores",
+	"GetScoresBy",
+	"GetScoresBetween",
+	"GetScoreTrend",
+	"GetScoreHistoryByCourseCode",
+	"GetScoreDistributionByProfessorUUID",
+	"GetBottomRatedProfessors",
+	"GetTopProfessors",
+	"GetMostRatedCourses",
+	"GetDepartmentStats",
+	"GetComponentAverages",
+	"GetScoreDimensions",
+	"GetProfessorDetail",
+	"GetProfessorRank",
+	"GetCourseDetail",
+	"GetProfessorsForCourses",
+	"Search",
+}
+
+// professorCacheKeyPrefixes are the prefixes of the cache keys holding professors.
+var professorCacheKeyPrefixes = []string{
+	"GetLastProfessors",
+	"GetUnratedProfessors",
+	"GetProfessorsByCourseCode",
+	"GetProfessorUUIDByName",
+	"GetLastScores",
+	"GetScoresBy",
+	"GetScoresBetween",
+	"GetBottomRatedProfessors",
+	"GetTopProfessors",
+	"GetMostRatedCourses",
+	"GetScoreDistributionByProfessorUUID",
+	"GetProfessorDetail",
+	"GetProfessorRank",
+	"GetCourseDetail",
+	"GetProfessorsForCourses",
+	"Search",
+}
+
+// DB is a struct contaning a SQL database connection
+type DB struct {
+	conn     *sql.DB         // conn is the mysql database connection.
+	cache    *cache.Cache    // cache is the cache database connection.
+	cacheTtl time.Duration   // cacheTtl is the cache time-to-live.
+	ctx      context.Context // ctx is the context for database connections.
+	opts     *db.Options     // opts are the optional settings of the database.
+}
+
+// New initializes a new database connection and sets up the necessary tables if they don't exist, unless the database is read-only.
+func New(url, cacheUrl string, cacheTtl time.Duration, ctx context.Context, opts ...db.Option) (d *DB, err error) {
+	options, err := db.NewOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := mysql.ParseDSN(url)
+	if err != nil {
+		return nil, err
+	}
+	// report the matched rows instead of the changed rows, so that updates leaving a row unchanged still find it.
+	cfg.ClientFoundRows = true
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	// the queries group by the primary key and select its dependent columns, so ONLY_FULL_GROUP_BY is left out.
+	cfg.Params["sql_mode"] = "'STRICT_ALL_TABLES,NO_ENGINE_SUBSTITUTION'"
+	// the grade months are computed from unix timestamps, so they must not depend on the server time zone.
+	cfg.Params["time_zone"] = "'+00:00'"
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := sql.OpenDB(connector)
+
+	if err = conn.Ping(); err != nil {
+		return nil, err
+	}
+
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS Courses(
+			code VARCHAR(255) PRIMARY KEY NOT NULL
+			CHECK(code <> ''),
+			name VARCHAR(255) NOT NULL
+			CHECK(name <> ''),
+			department VARCHAR(255),
+			credits INT
+			CHECK(credits BETWEEN 0 AND 30),
+			inserted_at BIGINT NOT NULL
+			DEFAULT 0,
+			deleted_at BIGINT,
+			UNIQUE(code, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS Professors(
+			uuid VARCHAR(36) PRIMARY KEY NOT NULL,
+			name VARCHAR(255) NOT NULL
+			CHECK(name <> ''),
+			inserted_at BIGINT NOT NULL
+			DEFAULT 0,
+			deleted_at BIGINT,
+			UNIQUE(name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS Scores(
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			hash VARCHAR(255) NOT NULL,
+			graded_hash VARCHAR(255)
+			AS (NULLIF(hash, '')) STORED
+			UNIQUE,
+			professor_uuid VARCHAR(36) NOT NULL,
+			course_code VARCHAR(255) NOT NULL,
+			score_teaching DOUBLE
+			CHECK(score_teaching BETWEEN 0 AND 5),
+			score_coursework DOUBLE
+			CHECK(score_coursework BETWEEN 0 AND 5),
+			score_learning DOUBLE
+			CHECK(score_learning BETWEEN 0 AND 5),
+			weight DOUBLE NOT NULL
+			DEFAULT 1
+			CHECK(weight >= 0),
+			comment TEXT,
+			inserted_at BIGINT NOT NULL
+			DEFAULT 0,
+			INDEX(hash),
+			FOREIGN KEY(professor_uuid)
+			REFERENCES Professors(uuid),
+			FOREIGN KEY(course_code)
+			REFERENCES Courses(code)
+		)`,
+		`CREATE TABLE IF NOT EXISTS ScoreValues(
+			score_id BIGINT NOT NULL,
+			dimension VARCHAR(255) NOT NULL
+			CHECK(dimension <> ''),
+			value DOUBLE NOT NULL
+			CHECK(value BETWEEN 0 AND 5),
+			PRIMARY KEY(score_id, dimension),
+			FOREIGN KEY(score_id)
+			REFERENCES Scores(id)
+			ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS GradeAttempts(
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			course_code VARCHAR(255) NOT NULL,
+			outcome VARCHAR(255) NOT NULL,
+			inserted_at BIGINT NOT NULL
+			DEFAULT 0
+		)`,
+	}
+
+	if !options.ReadOnly {
+		for _, stmt := range stmts {
+			if err := execStmtContext(conn, ctx, stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	d = &DB{conn: conn, ctx: ctx, opts: options}
+
+	if cacheUrl != "" {
+		d.cache, err = cache.New(cacheUrl, ctx)
+		if err != nil {
+			return nil, err
+		}
+		d.cache.SetNamespace(options.CacheNamespace)
+		d.cacheTtl = cacheTtl
+	}
+
+	return
+}
+
+// Ping checks that the database connection is alive.
+func (d *DB) Ping() (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	return d.conn.PingContext(ctx)
+}
+
+// PingCache checks that the cache connection is alive.
+// It returns db.ErrNoCache if the database has no cache.
+func (d *DB) PingCache() error {
+	if d.cache == nil {
+		return db.ErrNoCache
+	}
+	return d.cache.Ping()
+}
+
+// FlushCache deletes all the keys from the cache.
+// It does nothing if the database has no cache.
+func (d *DB) FlushCache() error {
+	if d.cache == nil {
+		return nil
+	}
+	return d.cache.DeletePattern("*")
+}
+
+// Close closes the database connection.
+func (d *DB) Close() (err error) {
+	if err = d.conn.Close(); err != nil {
+		return
+	}
+
+	if d.cache != nil {
+		err = d.cache.Close()
+	}
+
+	return
+}
+
+// AddCourse adds a new course to the database.
+func (d *DB) AddCourse(course *db.Course) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := "INSERT INTO Courses(code, name, department, credits, inserted_at) VALUES(?, ?, NULLIF(?, ''), ?, ?)"
+	if err = execStmtContext(d.conn, ctx, stmt, course.Code, course.Name, course.Department, course.Credits, time.Now().UnixNano()); err != nil {
+		return
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+
+	return
+}
+
+// AddCourseMany adds new courses to the database in a single transaction.
+// If any course fails to be added, no course is added and db.ErrBatchFailed is returned,
+// with the error of each failing course at its index in errs.
+func (d *DB) AddCourseMany(courses []*db.Course) (errs []error, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO Courses(code, name, department, credits, inserted_at) VALUES(?, ?, NULLIF(?, ''), ?, ?)")
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	errs = make([]error, len(courses))
+	for i, c := range courses {
+		_, err := stmt.ExecContext(ctx, c.Code, c.Name, c.Department, c.Credits, time.Now().UnixNano())
+		errs[i] = mapError(err)
+	}
+
+	if err = db.BatchError(errs); err != nil {
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+
+	return
+}
+
+// AddProfessor adds a new professor to the database.
+func (d *DB) AddProfessor(name string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if d.opts.ProfessorNameDedup {
+		professors, err := getProfessorsByNormalizedName(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if professor, ok := professors[db.NormalizeName(name)]; ok {
+			return &db.LikelyDuplicateError{Professor: professor}
+		}
+	}
+
+	professorUUID, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	stmt := "INSERT INTO Professors(uuid, name, inserted_at) VALUES(?, ?, ?)"
+	if _, err = tx.ExecContext(ctx, stmt, professorUUID, name, time.Now().UnixNano()); err != nil {
+		return mapError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	d.invalidateCache(professorCacheKeyPrefixes...)
+
+	return
+}
+
+// AddProfessorMany adds new professors to the database in a single transaction.
+// If any professor fails to be added, no professor is added and db.ErrBatchFailed is returned,
+// with the error of each failing professor at its index in errs.
+func (d *DB) AddProfessorMany(names []string) (errs []error, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var professors map[string]*db.Professor
+	if d.opts.ProfessorNameDedup {
+		if professors, err = getProfessorsByNormalizedName(ctx, tx); err != nil {
+			return
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO Professors(uuid, name, inserted_at) VALUES(?, ?, ?)")
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	errs = make([]error, len(names))
+	for i, n := range names {
+		if professors != nil {
+			if professor, ok := professors[db.NormalizeName(n)]; ok {
+				errs[i] = &db.LikelyDuplicateError{Professor: professor}
+				continue
+			}
+		}
+
+		professorUUID, err := uuid.NewV4()
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		_, err = stmt.ExecContext(ctx, professorUUID, n, time.Now().UnixNano())
+		errs[i] = mapError(err)
+
+		if errs[i] == nil && professors != nil {
+			professors[db.NormalizeName(n)] = &db.Professor{UUID: professorUUID.String(), Name: n}
+		}
+	}
+
+	if err = db.BatchError(errs); err != nil {
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	d.invalidateCache(professorCacheKeyPrefixes...)
+
+	return
+}
+
+// AddCourseProfessor adds a course to a professor in the database.
+func (d *DB) AddCourseProfessor(professorUUID, courseCode string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := "INSERT INTO Scores(hash, professor_uuid, course_code, weight) VALUES(?, ?, ?, 0)"
+	if err = execStmtContext(d.conn, ctx, stmt, defaultHash, professorUUID, courseCode); err != nil {
+		return
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+	d.invalidateCache(professorCacheKeyPrefixes...)
+
+	return
+}
+
+// AddCourseProfessorMany adds courses to professors in the database in a single transaction.
+// If any course fails to be added to its professor, none are added.
+func (d *DB) AddCourseProfessorMany(professorUUIDS, courseCodes []string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if len(professorUUIDS) != len(courseCodes) {
+		return fmt.Errorf("unequal slice length")
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO Scores(hash, professor_uuid, course_code, weight) VALUES(?, ?, ?, 0)")
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	for i := 0; i < len(professorUUIDS); i++ {
+		if _, err = stmt.ExecContext(ctx, defaultHash, professorUUIDS[i], courseCodes[i]); err != nil {
+			return mapError(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+	d.invalidateCache(professorCacheKeyPrefixes...)
+
+	return
+}
+
+// UpdateCourseName renames a course in the database, keeping its code and scores.
+// If no course has the code, responses.ErrCourseNotFound is returned.
+func (d *DB) UpdateCourseName(code, newName string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := "UPDATE Courses SET name = ? WHERE code = ?"
+	res, err := d.conn.ExecContext(ctx, stmt, newName, code)
+	if err != nil {
+		return mapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return responses.ErrCourseNotFound
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+
+	return
+}
+
+// RenameProfessor renames a professor in the database, keeping its uuid and scores.
+// If no professor has the uuid, responses.ErrProfessorNotFound is returned,
+// and if another professor already has the new name, responses.ErrProfessorExists is returned.
+func (d *DB) RenameProfessor(professorUUID, newName string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	var exists bool
+
+	stmt := "SELECT EXISTS(SELECT 1 FROM Professors WHERE name = ? AND uuid <> ?)"
+	if err = d.conn.QueryRowContext(ctx, stmt, newName, professorUUID).Scan(&exists); err != nil {
+		return
+	}
+	if exists {
+		return responses.ErrProfessorExists
+	}
+
+	stmt = "UPDATE Professors SET name = ? WHERE uuid = ?"
+	res, err := d.conn.ExecContext(ctx, stmt, newName, professorUUID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return responses.ErrProfessorNotFound
+	}
+
+	d.invalidateCache(professorCacheKeyPrefixes...)
+
+	return
+}
+
+// UpdateProfessorName renames a professor in the database, like RenameProfessor.
+func (d *DB) UpdateProfessorName(professorUUID, newName string) error {
+	return d.RenameProfessor(professorUUID, newName)
+}
+
+// RemoveCourseProfessor removes a professor from a course in the database.
+// If the professor was graded for the course, db.ErrForeignKey is returned and the grades are kept.
+// If the professor does not teach the course, responses.ErrCourseProfessorNotFound is returned.
+func (d *DB) RemoveCourseProfessor(professorUUID, courseCode string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	var count int
+	if err = d.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM Scores WHERE professor_uuid = ? AND course_code = ? AND hash <> ?", professorUUID, courseCode, defaultHash).Scan(&count); err != nil {
+		return
+	}
+	if count > 0 {
+		return db.ErrForeignKey
+	}
+
+	stmt := "DELETE FROM Scores WHERE professor_uuid = ? AND course_code = ? AND hash = ? AND weight = 0"
+	res, err := d.conn.ExecContext(ctx, stmt, professorUUID, courseCode, defaultHash)
+	if err != nil {
+		return mapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return responses.ErrCourseProfessorNotFound
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+	d.invalidateCache(professorCacheKeyPrefixes...)
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// RemoveCourse removes a course from the database. If forceDelete is true, associated scores are also deleted.
+func (d *DB) RemoveCourse(code string, forceDelete bool) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := []struct {
+		s    string
+		args string
+		skip bool
+	}{
+		{s: "DELETE FROM Scores WHERE course_code = ?", args: code, skip: !forceDelete},
+		{s: "DELETE FROM Courses WHERE code = ?", args: code, skip: false},
+	}
+
+	for _, s := range stmt {
+		if s.skip {
+			continue
+		}
+
+		if err = execStmtContext(d.conn, ctx, s.s, s.args); err != nil {
+			return
+		}
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+	d.invalidateCache(professorCacheKeyPrefixes...)
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// RemoveProfessor removes a professor from the database. If forceDelete is true, associated scores are also deleted.
+func (d *DB) RemoveProfessor(professorUUID string, forceDelete bool) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := []struct {
+		s    string
+		args string
+		skip bool
+	}{
+		{s: "DELETE FROM Scores WHERE professor_uuid = ?", args: professorUUID, skip: !forceDelete},
+		{s: "DELETE FROM Professors WHERE uuid = ?", args: professorUUID, skip: false},
+	}
+
+	for _, s := range stmt {
+		if s.skip {
+			continue
+		}
+
+		if err = execStmtContext(d.conn, ctx, s.s, s.args); err != nil {
+			return
+		}
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+	d.invalidateCache(professorCacheKeyPrefixes...)
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// SoftRemoveCourse marks a course as deleted in the database, keeping the course and its scores so that it can be restored.
+// Soft-deleted courses are left out of the reads, and cannot be graded.
+// If no course has the code, or the course is already soft-deleted, responses.ErrCourseNotFound is returned.
+func (d *DB) SoftRemoveCourse(code string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := "UPDATE Courses SET deleted_at = ? WHERE code = ? AND deleted_at IS NULL"
+	return d.setDeletedAt(ctx, responses.ErrCourseNotFound, stmt, time.Now().UnixNano(), code)
+}
+
+// SoftRemoveProfessor marks a professor as deleted in the database, keeping the professor and their scores so that they can be restored.
+// Soft-deleted professors are left out of the reads, and cannot be graded.
+// If no professor has the uuid, or the professor is already soft-deleted, responses.ErrProfessorNotFound is returned.
+func (d *DB) SoftRemoveProfessor(professorUUID string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := "UPDATE Professors SET deleted_at = ? WHERE uuid = ? AND deleted_at IS NULL"
+	return d.setDeletedAt(ctx, responses.ErrProfessorNotFound, stmt, time.Now().UnixNano(), professorUUID)
+}
+
+// RestoreCourse restores a soft-deleted course in the database, along with its scores.
+// If no soft-deleted course has the code, responses.ErrCourseNotFound is returned.
+func (d *DB) RestoreCourse(code string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := "UPDATE Courses SET deleted_at = NULL WHERE code = ? AND deleted_at IS NOT NULL"
+	return d.setDeletedAt(ctx, responses.ErrCourseNotFound, stmt, code)
+}
+
+// RestoreProfessor restores a soft-deleted professor in the database, along with their scores.
+// If no soft-deleted professor has the uuid, responses.ErrProfessorNotFound is returned.
+func (d *DB) RestoreProfessor(professorUUID string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := "UPDATE Professors SET deleted_at = NULL WHERE uuid = ? AND deleted_at IS NOT NULL"
+	return d.setDeletedAt(ctx, responses.ErrProfessorNotFound, stmt, professorUUID)
+}
+
+// GetTrash retrieves the soft-deleted courses and professors from the database, the most recently removed first.
+// The result is not cached, so that it reflects the last removals and restorations.
+func (d *DB) GetTrash() (trash *db.Trash, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	trash = &db.Trash{Courses: []*db.DeletedCourse{}, Professors: []*db.DeletedProfessor{}}
+
+	courseRows, err := d.conn.QueryContext(ctx, "SELECT code, name, IFNULL(department, ''), credits, deleted_at FROM Courses WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, code")
+	if err != nil {
+		return nil, err
+	}
+	defer courseRows.Close()
+
+	for courseRows.Next() {
+		course := db.DeletedCourse{}
+		var deletedAt int64
+		if err = courseRows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits, &deletedAt); err != nil {
+			return nil, err
+		}
+		course.DeletedAt = time.Unix(0, deletedAt).UTC()
+		trash.Courses = append(trash.Courses, &course)
+	}
+
+	if err = courseRows.Err(); err != nil {
+		return nil, err
+	}
+
+	professorRows, err := d.conn.QueryContext(ctx, "SELECT uuid, name, deleted_at FROM Professors WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, uuid")
+	if err != nil {
+		return nil, err
+	}
+	defer professorRows.Close()
+
+	for professorRows.Next() {
+		professor := db.DeletedProfessor{}
+		var deletedAt int64
+		if err = professorRows.Scan(&professor.UUID, &professor.Name, &deletedAt); err != nil {
+			return nil, err
+		}
+		professor.DeletedAt = time.Unix(0, deletedAt).UTC()
+		trash.Professors = append(trash.Professors, &professor)
+	}
+
+	if err = professorRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// GetLastCourses retrieves the last courses from the database, paginated with the specified limit and offset.
+// The limit is clamped to the maximum number of rows returned.
+func (d *DB) GetLastCourses(limit, offset int) (courses []*db.Course, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	limit, offset = d.clampPage(limit, offset)
+
+	if d.cache != nil {
+		key := fmt.Sprintf("GetLastCourses%d_%d", limit, offset)
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(courses)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return courses, json.Unmarshal([]byte(cached), &courses)
+		}
+	}
+
+	stmt := `
+		SELECT code, name, IFNULL(department, ''), credits
+		FROM Courses
+		WHERE deleted_at IS NULL
+		ORDER BY inserted_at
+		DESC
+		LIMIT ?
+		OFFSET ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.Course{}
+		if err = rows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits); err != nil {
+			return
+		}
+		courses = append(courses, &course)
+	}
+
+	return
+}
+
+// GetLastProfessors retrieves the last professors from the database, sorted in the specified order,
+// and paginated with the specified limit and offset. The limit is clamped to the maximum number of rows returned.
+func (d *DB) GetLastProfessors(sort db.ProfessorSort, limit, offset int) (professors []*db.Professor, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt, ok := professorSortStmts[sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid professor sort: %s", sort)
+	}
+
+	limit, offset = d.clampPage(limit, offset)
+
+	if d.cache != nil {
+		key := fmt.Sprintf("GetLastProfessors%s%d_%d", sort, limit, offset)
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(professors)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return professors, json.Unmarshal([]byte(cached), &professors)
+		}
+	}
+
+	args := []any{limit, offset}
+	if sort == db.ProfessorSortRating {
+		args = append(d.scoreWeightArgs(), args...)
+	}
+
+	rows, err := d.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		professor := db.Professor{}
+		if err = rows.Scan(&professor.UUID, &professor.Name); err != nil {
+			return
+		}
+		professors = append(professors, &professor)
+	}
+
+	return
+}
+
+// GetCoursesBetween retrieves the courses added between the specified times (inclusive) from the database.
+func (d *DB) GetCoursesBetween(from, to time.Time) (courses []*db.Course, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := `
+		SELECT code, name, IFNULL(department, ''), credits
+		FROM Courses
+		WHERE inserted_at BETWEEN ? AND ? AND deleted_at IS NULL
+		ORDER BY inserted_at
+		DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, from.UnixNano(), to.UnixNano(), d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.Course{}
+		if err = rows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits); err != nil {
+			return
+		}
+		courses = append(courses, &course)
+	}
+
+	return
+}
+
+// GetRandomCourses retrieves a random selection of n courses from the database.
+// The result is not cached, so that it varies between calls.
+func (d *DB) GetRandomCourses(n int) (courses []*db.Course, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if n <= 0 || n > d.opts.MaxRowReturn {
+		n = d.opts.MaxRowReturn
+	}
+
+	stmt := `
+		SELECT code, name, IFNULL(department, ''), credits
+		FROM Courses
+		WHERE deleted_at IS NULL
+		ORDER BY RAND()
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, n)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.Course{}
+		if err = rows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits); err != nil {
+			return
+		}
+		courses = append(courses, &course)
+	}
+
+	return
+}
+
+// GetProfessorsBetween retrieves the professors added between the specified times (inclusive) from the database.
+func (d *DB) GetProfessorsBetween(from, to time.Time) (professors []*db.Professor, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := `
+		SELECT uuid, name
+		FROM Professors
+		WHERE inserted_at BETWEEN ? AND ? AND deleted_at IS NULL
+		ORDER BY inserted_at
+		DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, from.UnixNano(), to.UnixNano(), d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		professor := db.Professor{}
+		if err = rows.Scan(&professor.UUID, &professor.Name); err != nil {
+			return
+		}
+		professors = append(professors, &professor)
+	}
+
+	return
+}
+
+// GetUnratedProfessors retrieves the last professors without any grades from the database.
+// Professors only associated to courses, but never graded, are included.
+func (d *DB) GetUnratedProfessors() (professors []*db.Professor, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetUnratedProfessors"
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(professors)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return professors, json.Unmarshal([]byte(cached), &professors)
+		}
+	}
+
+	stmt := `
+		SELECT uuid, name
+		FROM Professors
+		WHERE deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1
+			FROM Scores
+			WHERE Scores.professor_uuid = Professors.uuid
+			AND Scores.hash <> ?
+		)
+		ORDER BY inserted_at
+		DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, defaultHash, d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		professor := db.Professor{}
+		if err = rows.Scan(&professor.UUID, &professor.Name); err != nil {
+			return
+		}
+		professors = append(professors, &professor)
+	}
+
+	return
+}
+
+// GetLastScores retrieves the last scores from the database, paginated with the specified limit and offset.
+// The limit is clamped to the maximum number of rows returned.
+func (d *DB) GetLastScores(limit, offset int) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	limit, offset = d.clampPage(limit, offset)
+
+	if d.cache != nil {
+		key := fmt.Sprintf("GetLastScores%d_%d", limit, offset)
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Scores.professor_uuid,
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+		LIMIT ?
+		OFFSET ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorUUID, &score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	return
+}
+
+// ExportCourses calls fn with each course of the database, in the order they were added.
+// The courses are read one at a time, so the whole table is never held in memory, and the query timeout does not apply.
+func (d *DB) ExportCourses(fn func(*db.Course) error) (err error) {
+	ctx, done := db.StreamContext(d.ctx)
+	defer done()
+
+	rows, err := d.conn.QueryContext(ctx, "SELECT code, name, IFNULL(department, ''), credits FROM Courses WHERE deleted_at IS NULL ORDER BY inserted_at, code")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.Course{}
+		if err = rows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits); err != nil {
+			return
+		}
+		if err = fn(&course); err != nil {
+			return
+		}
+	}
+
+	return rows.Err()
+}
+
+// ExportProfessors calls fn with each professor of the database, in the order they were added.
+// The professors are read one at a time, so the whole table is never held in memory, and the query timeout does not apply.
+func (d *DB) ExportProfessors(fn func(*db.Professor) error) (err error) {
+	ctx, done := db.StreamContext(d.ctx)
+	defer done()
+
+	rows, err := d.conn.QueryContext(ctx, "SELECT uuid, name FROM Professors WHERE deleted_at IS NULL ORDER BY inserted_at, uuid")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		professor := db.Professor{}
+		if err = rows.Scan(&professor.UUID, &professor.Name); err != nil {
+			return
+		}
+		if err = fn(&professor); err != nil {
+			return
+		}
+	}
+
+	return rows.Err()
+}
+
+// ExportScores calls fn with the average scores of each professor in each of their courses, ordered by course code and professor UUID.
+// The scores are read one at a time, so the whole table is never held in memory, and the query timeout does not apply.
+// The median, standard deviation, and comments are not set.
+func (d *DB) ExportScores(fn func(*db.Score) error) (err error) {
+	ctx, done := db.StreamContext(d.ctx)
+	defer done()
+
+	stmt := `
+		SELECT
+			Scores.professor_uuid,
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		GROUP BY Scores.course_code, Scores.professor_uuid, Professors.name, Courses.name
+		ORDER BY Scores.course_code, Scores.professor_uuid
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorUUID, &score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		if err = fn(&score); err != nil {
+			return
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetAllScoresStream calls fn with the average scores of each professor in each of their graded courses
+// whose last grade was inserted since the specified time (the zero time for all of them), ordered by the time of their last grade.
+// The scores are read one at a time, so the whole table is never held in memory, and the query timeout does not apply.
+func (d *DB) GetAllScoresStream(since time.Time, fn func(*db.StreamedScore) error) (err error) {
+	ctx, done := db.StreamContext(d.ctx)
+	defer done()
+
+	stmt := `
+		SELECT
+			Scores.professor_uuid,
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.id),
+			MAX(Scores.inserted_at)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.hash <> ?
+		GROUP BY Scores.course_code, Scores.professor_uuid, Professors.name, Courses.name
+		HAVING MAX(Scores.inserted_at) >= ?
+		ORDER BY MAX(Scores.inserted_at), Scores.course_code, Scores.professor_uuid
+	`
+
+	// the inserted_at column holds unix nanoseconds, and the zero time is out of their range.
+	var sinceNano int64
+	if !since.IsZero() {
+		sinceNano = since.UnixNano()
+	}
+
+	rows, err := d.conn.QueryContext(ctx, stmt, defaultHash, sinceNano)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.StreamedScore{}
+		var insertedAt int64
+		if err = rows.Scan(&score.ProfessorUUID, &score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count, &insertedAt); err != nil {
+			return
+		}
+		score.InsertedAt = time.Unix(0, insertedAt).UTC()
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		if err = fn(&score); err != nil {
+			return
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetCatalogSince retrieves the courses and professors inserted after the specified time (the zero time for all of them),
+// in the order they were added, with the time of the last one as the version of the catalog.
+// The version is the specified time if no course or professor was added since.
+func (d *DB) GetCatalogSince(since time.Time) (changes *db.CatalogChanges, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	// the inserted_at column holds unix nanoseconds, and the zero time is out of their range.
+	var sinceNano int64
+	if !since.IsZero() {
+		sinceNano = since.UnixNano()
+	}
+
+	changes = &db.CatalogChanges{Courses: []*db.Course{}, Professors: []*db.Professor{}, Version: since}
+	version := sinceNano
+
+	rows, err := d.conn.QueryContext(ctx, "SELECT code, name, IFNULL(department, ''), credits, inserted_at FROM Courses WHERE inserted_at > ? AND deleted_at IS NULL ORDER BY inserted_at, code", sinceNano)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.Course{}
+		var insertedAt int64
+		if err = rows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits, &insertedAt); err != nil {
+			return nil, err
+		}
+		changes.Courses = append(changes.Courses, &course)
+		if insertedAt > version {
+			version = insertedAt
+		}
+	}
+	// a connection runs one query at a time, so the courses are released before querying the professors.
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = d.conn.QueryContext(ctx, "SELECT uuid, name, inserted_at FROM Professors WHERE inserted_at > ? AND deleted_at IS NULL ORDER BY inserted_at, uuid", sinceNano)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		professor := db.Professor{}
+		var insertedAt int64
+		if err = rows.Scan(&professor.UUID, &professor.Name, &insertedAt); err != nil {
+			return nil, err
+		}
+		changes.Professors = append(changes.Professors, &professor)
+		if insertedAt > version {
+			version = insertedAt
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if version != sinceNano {
+		changes.Version = time.Unix(0, version).UTC()
+	}
+
+	return changes, nil
+}
+
+// GetCoursesByProfessor retrieves all courses associated with a professor from the database.
+func (d *DB) GetCoursesByProfessorUUID(UUID string) (courses []*db.Course, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetCoursesByProfessorUUID" + UUID
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(courses)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return courses, json.Unmarshal([]byte(cached), &courses)
+		}
+	}
+
+	stmt := `
+		SELECT code, name, IFNULL(department, ''), credits
+		FROM Courses
+		JOIN Scores ON Courses.code = Scores.course_code
+		WHERE Scores.professor_uuid = ? AND Courses.deleted_at IS NULL
+		ORDER BY Courses.inserted_at
+		DESC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, UUID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.Course{}
+		if err = rows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits); err != nil {
+			return
+		}
+		courses = append(courses, &course)
+	}
+
+	return
+}
+
+// GetUngradedCoursesByProfessorUUID retrieves the courses associated with a professor,
+// in which the professor has not been graded yet, from the database.
+func (d *DB) GetUngradedCoursesByProfessorUUID(UUID string) (courses []*db.Course, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetUngradedCoursesByProfessorUUID" + UUID
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(courses)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return courses, json.Unmarshal([]byte(cached), &courses)
+		}
+	}
+
+	stmt := `
+		SELECT code, name, IFNULL(department, ''), credits
+		FROM Courses
+		WHERE deleted_at IS NULL
+		AND EXISTS (
+			SELECT 1
+			FROM Scores
+			WHERE Scores.course_code = Courses.code
+			AND Scores.professor_uuid = ?
+			AND Scores.hash = ?
+		)
+		AND NOT EXISTS (
+			SELECT 1
+			FROM Scores
+			WHERE Scores.course_code = Courses.code
+			AND Scores.professor_uuid = ?
+			AND Scores.hash <> ?
+		)
+		ORDER BY inserted_at
+		DESC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, UUID, defaultHash, UUID, defaultHash)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.Course{}
+		if err = rows.Scan(&course.Code, &course.Name, &course.Department, &course.Credits); err != nil {
+			return
+		}
+		courses = append(courses, &course)
+	}
+
+	return
+}
+
+// GetProfessorsByCourse retrieves all professors associated with a course from the database.
+func (d *DB) GetProfessorsByCourseCode(code string) (professors []*db.Professor, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetProfessorsByCourseCode" + code
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(professors)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return professors, json.Unmarshal([]byte(cached), &professors)
+		}
+	}
+
+	stmt := `
+		SELECT uuid, name
+		FROM Professors
+		JOIN Scores ON Professors.uuid = Scores.professor_uuid
+		WHERE Scores.course_code = ? AND Professors.deleted_at IS NULL
+		ORDER BY Professors.inserted_at
+		DESC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, code)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		professor := db.Professor{}
+		if err = rows.Scan(&professor.UUID, &professor.Name); err != nil {
+			return
+		}
+		professors = append(professors, &professor)
+	}
+
+	return
+}
+
+// GetProfessorsForCourses retrieves the professors teaching any of the courses, with their scores in each course, from the database.
+// The professors are grouped by course code, and sorted by average score within each course.
+// Courses without professors are omitted.
+func (d *DB) GetProfessorsForCourses(courseCodes []string) (scores map[string][]*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	scores = map[string][]*db.Score{}
+	if len(courseCodes) == 0 {
+		return
+	}
+
+	if d.cache != nil {
+		key := "GetProfessorsForCourses" + strings.Join(courseCodes, ",")
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Scores.course_code,
+			Courses.name,
+			Scores.professor_uuid,
+			Professors.name,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.course_code IN (%s)
+		GROUP BY Scores.course_code, Courses.name, Scores.professor_uuid, Professors.name
+	`
+
+	args := make([]any, len(courseCodes))
+	for i, code := range courseCodes {
+		args[i] = code
+	}
+
+	rows, err := d.conn.QueryContext(ctx, fmt.Sprintf(stmt, strings.TrimSuffix(strings.Repeat("?, ", len(courseCodes)), ", ")), args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.CourseCode, &score.CourseName, &score.ProfessorUUID, &score.ProfessorName, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores[score.CourseCode] = append(scores[score.CourseCode], &score)
+	}
+
+	for _, courseScores := range scores {
+		slices.SortStableFunc(courseScores, func(a, b *db.Score) int {
+			return cmp.Compare(b.ScoreAverage, a.ScoreAverage)
+		})
+	}
+
+	return
+}
+
+// GetProfessorUUIDByName retrieves the UUID of the professor that matches the specified name.
+func (d *DB) GetProfessorUUIDByName(name string) (uuid string, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetProfessorUUIDByName" + name
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				if err = d.cache.Set(key, uuid, d.cacheTtl); err != nil {
+					log.Error().Err(err)
+				}
+			}()
+		} else if err == nil {
+			return cached, nil
+		}
+	}
+
+	stmt := `
+		SELECT uuid
+		FROM Professors
+		WHERE name = ? AND deleted_at IS NULL
+		LIMIT 1
+	`
+
+	row := d.conn.QueryRowContext(ctx, stmt, name)
+	if err = row.Scan(&uuid); err != nil {
+		return "", mapError(err)
+	}
+	return
+}
+
+// GetScoresByProfessorUUID retrieves all scores associated with a professor's UUID from the database.
+func (d *DB) GetScoresByProfessorUUID(UUID string) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresByProfessorUUID" + UUID
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE
+			Scores.professor_uuid = ?
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, UUID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ProfessorUUID = UUID
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	for _, score := range scores {
+		if score.Comments, err = d.getRecentComments(ctx, score.ProfessorUUID, score.CourseCode); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// GetScoresByProfessorName retrieves all scores associated with a professor's name from the database.
+func (d *DB) GetScoresByProfessorName(name string) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresByProfessorName" + name
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			Scores.professor_uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Professors.name = ?
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, name)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ProfessorUUID, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetScoresByProfessorNameLike retrieves the last scores for courses taught by professors whose names contain,
+// or start with if the match mode is prefix, the given search string.
+func (d *DB) GetScoresByProfessorNameLike(nameLike string, mode db.MatchMode) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresByProfessorNameLike" + string(mode) + nameLike
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			Scores.professor_uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.professor_uuid IN (SELECT uuid FROM Professors WHERE name LIKE ?)
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, mode.LikePattern(nameLike), d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ProfessorUUID, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetScoresByCourseName retrieves all scores associated with a course from the database.
+func (d *DB) GetScoresByCourseName(name string) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresByCourseName" + name
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			Scores.professor_uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Courses.name = ?
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, name)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ProfessorUUID, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetScoresByCourseNameLike retrieves the last scores associated with a course code from the database that matches the given search string,
+// as a substring or as a prefix depending on the match mode.
+func (d *DB) GetScoresByCourseNameLike(nameLike string, mode db.MatchMode) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresByCourseNameLike" + string(mode) + nameLike
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			Scores.professor_uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.course_code IN (SELECT code FROM Courses WHERE name LIKE ?)
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, mode.LikePattern(nameLike), d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ProfessorUUID, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetScoresByCourseCode retrieves all scores associated with a course from the database.
+func (d *DB) GetScoresByCourseCode(code string) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresByCourseCode" + code
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Professors.name,
+			Courses.name,
+			Scores.professor_uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.course_code = ?
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, code)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseName, &score.ProfessorUUID, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.CourseCode = code
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	for _, score := range scores {
+		if score.Comments, err = d.getRecentComments(ctx, score.ProfessorUUID, score.CourseCode); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// GetScoresByCourseCodeLike retrieves the last scores associated with a course code from the database that matches the given search string
+func (d *DB) GetScoresByCourseCodeLike(codeLike string) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresByCourseCodeLike" + codeLike
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT 
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			Scores.professor_uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.course_code IN (SELECT code FROM Courses WHERE code LIKE ?)
+		GROUP BY Scores.course_code, Scores.professor_uuid
+		ORDER BY Scores.inserted_at
+		DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, fmt.Sprintf("%%%s%%", codeLike), d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ProfessorUUID, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetScoresBetween retrieves the scores of each course and its professor computed only from the grades given between the specified times (inclusive),
+// from the database. An empty professor UUID or course code matches all the professors or courses.
+// The median and the standard deviation of the overall grades, which are computed over all the grades, are not set.
+func (d *DB) GetScoresBetween(professorUUID, courseCode string, from, to time.Time) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := fmt.Sprintf("GetScoresBetween%s_%s_%d_%d", professorUUID, courseCode, from.UnixNano(), to.UnixNano())
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Scores.professor_uuid,
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.inserted_at BETWEEN ? AND ?
+		AND (? = '' OR Scores.professor_uuid = ?)
+		AND (? = '' OR Scores.course_code = ?)
+		GROUP BY Scores.course_code, Scores.professor_uuid, Professors.name, Courses.name
+		ORDER BY MAX(Scores.inserted_at)
+		DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, from.UnixNano(), to.UnixNano(), professorUUID, professorUUID, courseCode, courseCode, d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorUUID, &score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	return
+}
+
+// GetScoresBySearch retrieves the last scores from the database whose professor name, course name, or course code matches the search query.
+// Each course and professor appears at most once.
+func (d *DB) GetScoresBySearch(query string) (scores []*db.Score, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoresBySearch" + query
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Professors.name,
+			Scores.course_code,
+			Courses.name,
+			Scores.professor_uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		WHERE Scores.professor_uuid IN (SELECT uuid FROM Professors WHERE name LIKE ?)
+		OR Scores.course_code IN (SELECT code FROM Courses WHERE name LIKE ? OR code LIKE ?)
+		GROUP BY Scores.course_code, Scores.professor_uuid, Professors.name, Courses.name
+		ORDER BY MAX(Scores.inserted_at)
+		DESC
+		LIMIT ?
+	`
+
+	queryLike := fmt.Sprintf("%%%s%%", query)
+
+	rows, err := d.conn.QueryContext(ctx, stmt, queryLike, queryLike, queryLike, d.opts.MaxRowReturn)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		score := db.Score{}
+		if err = rows.Scan(&score.ProfessorName, &score.CourseCode, &score.CourseName, &score.ProfessorUUID, &score.ScoreTeaching, &score.ScoreCourseWork, &score.ScoreLearning, &score.Count); err != nil {
+			return
+		}
+		score.ScoreAverage = averageScore(d.opts.ScoreWeights, score.ScoreTeaching, score.ScoreCourseWork, score.ScoreLearning)
+		score.Verified = score.Count >= d.opts.MinGradesForVerified
+		scores = append(scores, &score)
+	}
+
+	if err = d.setScoreDistributions(ctx, scores); err != nil {
+		return
+	}
+
+	return
+}
+
+// Search retrieves the professors and courses matching a search query from the database, with their average scores,
+// ordered by relevance, the first being the most relevant.
+// Each word of the query must be contained in the name of a professor, or in the code or name of a course.
+func (d *DB) Search(query string) (results []*db.SearchResult, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "Search" + query
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(results)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return results, json.Unmarshal([]byte(cached), &results)
+		}
+	}
+
+	words := db.SearchWords(query)
+	if len(words) == 0 {
+		return
+	}
+
+	professorMatches, courseMatches, args := searchLikeMatches(query, words)
+
+	stmt := fmt.Sprintf(`
+		SELECT kind, id, name, teaching, coursework, learning, grades
+		FROM (
+			SELECT
+				'professor' AS kind,
+				Professors.uuid AS id,
+				Professors.name AS name,
+				Matches.relevance AS relevance,
+				IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0) AS teaching,
+				IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0) AS coursework,
+				IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0) AS learning,
+				COUNT(Scores.score_teaching) AS grades
+			FROM
+				(%s) AS Matches
+				JOIN Professors ON Professors.uuid = Matches.id AND Professors.deleted_at IS NULL
+				LEFT JOIN (Scores JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL) ON Scores.professor_uuid = Professors.uuid
+			GROUP BY Professors.uuid, Professors.name, Matches.relevance
+			UNION ALL
+			SELECT
+				'course',
+				Courses.code,
+				Courses.name,
+				Matches.relevance,
+				IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+				IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+				IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+				COUNT(Scores.score_teaching)
+			FROM
+				(%s) AS Matches
+				JOIN Courses ON Courses.code = Matches.id AND Courses.deleted_at IS NULL
+				LEFT JOIN (Scores JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL) ON Scores.course_code = Courses.code
+			GROUP BY Courses.code, Courses.name, Matches.relevance
+		) AS Results
+		ORDER BY relevance, name
+		LIMIT ?
+	`, professorMatches, courseMatches)
+
+	rows, err := d.conn.QueryContext(ctx, stmt, append(args, d.opts.MaxRowReturn)...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := db.SearchResult{}
+		if err = rows.Scan(&result.Kind, &result.ID, &result.Name, &result.ScoreTeaching, &result.ScoreCourseWork, &result.ScoreLearning, &result.Count); err != nil {
+			return
+		}
+		result.ScoreAverage = averageScore(d.opts.ScoreWeights, result.ScoreTeaching, result.ScoreCourseWork, result.ScoreLearning)
+		results = append(results, &result)
+	}
+
+	return
+}
+
+// GetScoreTrend retrieves the average scores of a course and its professor over time,
+// grouping the grades in the specified bucket.
+func (d *DB) GetScoreTrend(professorUUID, courseCode string, bucket db.TrendBucket) (trend []*db.ScoreTrendPoint, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoreTrend" + professorUUID + courseCode + string(bucket)
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(trend)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return trend, json.Unmarshal([]byte(cached), &trend)
+		}
+	}
+
+	stmt := `
+		SELECT
+			score_teaching,
+			score_coursework,
+			score_learning,
+			weight,
+			inserted_at
+		FROM Scores
+		WHERE professor_uuid = ?
+		AND course_code = ?
+		AND hash <> ?
+		ORDER BY inserted_at
+		ASC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, professorUUID, courseCode, defaultHash)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var point *db.ScoreTrendPoint
+	var weights float32
+	for rows.Next() {
+		var grades [3]float32
+		var weight float32
+		var insertedAt int64
+		if err = rows.Scan(&grades[0], &grades[1], &grades[2], &weight, &insertedAt); err != nil {
+			return
+		}
+
+		start, err := bucket.Truncate(time.Unix(0, insertedAt))
+		if err != nil {
+			return nil, err
+		}
+
+		if point == nil || !point.Start.Equal(start) {
+			if point != nil {
+				trend = append(trend, d.averageTrendPoint(point, weights))
+			}
+			point, weights = &db.ScoreTrendPoint{Start: start}, 0
+		}
+
+		point.ScoreTeaching += grades[0] * weight
+		point.ScoreCourseWork += grades[1] * weight
+		point.ScoreLearning += grades[2] * weight
+		point.Count++
+		weights += weight
+	}
+
+	if point != nil {
+		trend = append(trend, d.averageTrendPoint(point, weights))
+	}
+
+	return
+}
+
+// GetScoreHistoryByCourseCode retrieves the average scores of a course over time, across all its professors,
+// grouping the grades by month. Months without grades are omitted.
+func (d *DB) GetScoreHistoryByCourseCode(courseCode string) (history []*db.ScoreTrendPoint, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoreHistoryByCourseCode" + courseCode
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(history)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return history, json.Unmarshal([]byte(cached), &history)
+		}
+	}
+
+	stmt := `
+		SELECT
+			DATE_FORMAT(FROM_UNIXTIME(inserted_at DIV 1000000000), '%Y-%m') AS month,
+			IFNULL(SUM(score_teaching * weight) / NULLIF(SUM(weight), 0), 0),
+			IFNULL(SUM(score_coursework * weight) / NULLIF(SUM(weight), 0), 0),
+			IFNULL(SUM(score_learning * weight) / NULLIF(SUM(weight), 0), 0),
+			COUNT(score_teaching)
+		FROM Scores
+		WHERE course_code = ?
+		AND hash <> ?
+		GROUP BY month
+		ORDER BY month
+		ASC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, courseCode, defaultHash)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var month string
+		point := db.ScoreTrendPoint{}
+		if err = rows.Scan(&month, &point.ScoreTeaching, &point.ScoreCourseWork, &point.ScoreLearning, &point.Count); err != nil {
+			return
+		}
+		if point.Start, err = time.Parse("2006-01", month); err != nil {
+			return
+		}
+		point.ScoreAverage = averageScore(d.opts.ScoreWeights, point.ScoreTeaching, point.ScoreCourseWork, point.ScoreLearning)
+		history = append(history, &point)
+	}
+
+	return
+}
+
+// GetScoreDistributionByProfessorUUID retrieves the number of grades of a professor in the ranges 0–1, 1–2, 2–3, 3–4, and 4–5,
+// for each of the teaching, coursework, and learning scores, from the database.
+// A professor without grades has no grades in any range.
+func (d *DB) GetScoreDistributionByProfessorUUID(professorUUID string) (distribution *db.ScoreDistribution, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoreDistributionByProfessorUUID" + professorUUID
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(distribution)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return distribution, json.Unmarshal([]byte(cached), &distribution)
+		}
+	}
+
+	stmt := `
+		SELECT 0, LEAST(FLOOR(score_teaching), 4) AS bucket, COUNT(*)
+		FROM Scores
+		WHERE professor_uuid = ?
+		AND hash <> ?
+		GROUP BY bucket
+		UNION ALL
+		SELECT 1, LEAST(FLOOR(score_coursework), 4) AS bucket, COUNT(*)
+		FROM Scores
+		WHERE professor_uuid = ?
+		AND hash <> ?
+		GROUP BY bucket
+		UNION ALL
+		SELECT 2, LEAST(FLOOR(score_learning), 4) AS bucket, COUNT(*)
+		FROM Scores
+		WHERE professor_uuid = ?
+		AND hash <> ?
+		GROUP BY bucket
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, professorUUID, defaultHash, professorUUID, defaultHash, professorUUID, defaultHash)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	distribution = &db.ScoreDistribution{}
+	counts := []*[5]int{&distribution.Teaching, &distribution.CourseWork, &distribution.Learning}
+	for rows.Next() {
+		var score, bucket, count int
+		if err = rows.Scan(&score, &bucket, &count); err != nil {
+			return nil, err
+		}
+		counts[score][bucket] = count
+	}
+
+	return
+}
+
+// GetBottomRatedProfessors retrieves the lowest rated professors from the database,
+// excluding professors with fewer grades than the ranking threshold.
+// Professors with the same average score are ordered by their number of grades, then by their most recent grade.
+func (d *DB) GetBottomRatedProfessors(limit int) (ratings []*db.ProfessorRating, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if limit <= 0 || limit > d.opts.MaxRowReturn {
+		limit = d.opts.MaxRowReturn
+	}
+
+	if d.cache != nil {
+		key := fmt.Sprintf("GetBottomRatedProfessors%d", limit)
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(ratings)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return ratings, json.Unmarshal([]byte(cached), &ratings)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Professors.uuid,
+			Professors.name,
+			SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0),
+			SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0),
+			SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0),
+			COUNT(Scores.id)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+		WHERE Scores.hash <> ?
+		GROUP BY Professors.uuid
+		HAVING COUNT(Scores.id) >= ?
+		ORDER BY (? * SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0)) / ?
+		ASC, COUNT(Scores.id) DESC, MAX(Scores.inserted_at) DESC
+		LIMIT ?
+	`
+
+	args := append([]any{defaultHash, d.opts.MinGradesForRanking}, d.scoreWeightArgs()...)
+	rows, err := d.conn.QueryContext(ctx, stmt, append(args, limit)...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rating := db.ProfessorRating{}
+		if err = rows.Scan(&rating.ProfessorUUID, &rating.ProfessorName, &rating.ScoreTeaching, &rating.ScoreCourseWork, &rating.ScoreLearning, &rating.Count); err != nil {
+			return
+		}
+		rating.ScoreAverage = averageScore(d.opts.ScoreWeights, rating.ScoreTeaching, rating.ScoreCourseWork, rating.ScoreLearning)
+		ratings = append(ratings, &rating)
+	}
+
+	return
+}
+
+// GetTopProfessors retrieves the highest rated professors by the specified score from the database,
+// excluding professors with fewer grades than the ranking threshold.
+// Professors with the same score are ordered by their number of grades, then by their most recent grade.
+func (d *DB) GetTopProfessors(limit int, by db.RankingScore) (ratings []*db.ProfessorRating, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	expr, ok := rankingScoreExprs[by]
+	if !ok {
+		return nil, fmt.Errorf("invalid ranking score: %s", by)
+	}
+
+	if limit <= 0 || limit > d.opts.MaxRowReturn {
+		limit = d.opts.MaxRowReturn
+	}
+
+	if d.cache != nil {
+		key := fmt.Sprintf("GetTopProfessors%s%d", by, limit)
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(ratings)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return ratings, json.Unmarshal([]byte(cached), &ratings)
+		}
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT
+			Professors.uuid,
+			Professors.name,
+			SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0),
+			SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0),
+			SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0),
+			COUNT(Scores.id)
+		FROM
+			Scores
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+		WHERE Scores.hash <> ?
+		GROUP BY Professors.uuid
+		HAVING COUNT(Scores.id) >= ?
+		ORDER BY %s
+		DESC, COUNT(Scores.id) DESC, MAX(Scores.inserted_at) DESC
+		LIMIT ?
+	`, expr)
+
+	args := []any{defaultHash, d.opts.MinGradesForRanking}
+	if by == db.RankingScoreAverage {
+		args = append(args, d.scoreWeightArgs()...)
+	}
+
+	rows, err := d.conn.QueryContext(ctx, stmt, append(args, limit)...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rating := db.ProfessorRating{}
+		if err = rows.Scan(&rating.ProfessorUUID, &rating.ProfessorName, &rating.ScoreTeaching, &rating.ScoreCourseWork, &rating.ScoreLearning, &rating.Count); err != nil {
+			return
+		}
+		rating.ScoreAverage = averageScore(d.opts.ScoreWeights, rating.ScoreTeaching, rating.ScoreCourseWork, rating.ScoreLearning)
+		ratings = append(ratings, &rating)
+	}
+
+	return
+}
+
+// GetMostRatedCourses retrieves the courses with the most grades across all their professors from the database,
+// excluding the rows adding courses to professors, and the grades of soft-deleted professors.
+// Courses with the same number of grades are ordered by code.
+func (d *DB) GetMostRatedCourses(limit int) (courses []*db.PopularCourse, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if limit <= 0 || limit > d.opts.MaxRowReturn {
+		limit = d.opts.MaxRowReturn
+	}
+
+	if d.cache != nil {
+		key := fmt.Sprintf("GetMostRatedCourses%d", limit)
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(courses)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return courses, json.Unmarshal([]byte(cached), &courses)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Courses.code,
+			Courses.name,
+			COUNT(Scores.id)
+		FROM
+			Scores
+			JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+			JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+		WHERE Scores.hash <> ?
+		GROUP BY Courses.code, Courses.name
+		ORDER BY COUNT(Scores.id) DESC, Courses.code
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, defaultHash, limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		course := db.PopularCourse{}
+		if err = rows.Scan(&course.CourseCode, &course.CourseName, &course.Count); err != nil {
+			return
+		}
+		courses = append(courses, &course)
+	}
+
+	return
+}
+
+// GetDepartmentStats retrieves the number of courses and professors of each department, and its average score, from the database.
+// Courses without a department are not counted.
+func (d *DB) GetDepartmentStats() (stats []*db.DepartmentStats, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetDepartmentStats"
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(stats)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return stats, json.Unmarshal([]byte(cached), &stats)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Courses.department,
+			COUNT(DISTINCT Courses.code),
+			COUNT(DISTINCT Scores.professor_uuid),
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0)
+		FROM
+			Courses
+			LEFT JOIN (Scores JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL) ON Courses.code = Scores.course_code
+		WHERE Courses.department IS NOT NULL AND Courses.deleted_at IS NULL
+		GROUP BY Courses.department
+		ORDER BY Courses.department
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var teaching, coursework, learning float32
+		stat := db.DepartmentStats{}
+		if err = rows.Scan(&stat.Department, &stat.Courses, &stat.Professors, &teaching, &coursework, &learning); err != nil {
+			return
+		}
+		stat.ScoreAverage = averageScore(d.opts.ScoreWeights, teaching, coursework, learning)
+		stats = append(stats, &stat)
+	}
+
+	return
+}
+
+// GetComponentAverages retrieves the average of the teaching, coursework, and learning scores across all the grades from the database.
+// The rows added with a professor to a course, which hold no grade, are not counted.
+func (d *DB) GetComponentAverages() (averages *db.ComponentAverages, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetComponentAverages"
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(averages)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return averages, json.Unmarshal([]byte(cached), &averages)
+		}
+	}
+
+	stmt := `
+		SELECT
+			IFNULL(SUM(score_teaching * weight) / NULLIF(SUM(weight), 0), 0),
+			IFNULL(SUM(score_coursework * weight) / NULLIF(SUM(weight), 0), 0),
+			IFNULL(SUM(score_learning * weight) / NULLIF(SUM(weight), 0), 0),
+			COUNT(id)
+		FROM Scores
+		WHERE hash <> ?
+	`
+
+	averages = &db.ComponentAverages{}
+	if err = d.conn.QueryRowContext(ctx, stmt, defaultHash).Scan(&averages.ScoreTeaching, &averages.ScoreCourseWork, &averages.ScoreLearning, &averages.Count); err != nil {
+		return nil, err
+	}
+
+	if extra := d.opts.ExtraScoreDimensions(); len(extra) > 0 {
+		scores, err := d.getExtraDimensionScores(ctx, "", "")
+		if err != nil {
+			return nil, err
+		}
+
+		averages.Dimensions = make(map[string]float32, len(extra))
+		for _, dimension := range extra {
+			averages.Dimensions[dimension] = scores[dimension].Score
+		}
+	}
+
+	return
+}
+
+// Stats retrieves the number of courses, professors, and grades from the database.
+// The rows added with a professor to a course, which hold no grade, are not counted.
+func (d *DB) Stats() (stats *db.Stats, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := `
+		SELECT
+			(SELECT COUNT(*) FROM Courses WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM Professors WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM Scores WHERE hash <> ?)
+	`
+
+	stats = &db.Stats{}
+	if err = d.conn.QueryRowContext(ctx, stmt, defaultHash).Scan(&stats.Courses, &stats.Professors, &stats.Grades); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// GetScoreDimensions retrieves the average score of a professor in a course for each score dimension from the database,
+// in the order of the score dimensions.
+func (d *DB) GetScoreDimensions(courseCode, professorUUID string) (scores []*db.DimensionScore, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetScoreDimensions" + courseCode + professorUUID
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				data, err := json.Marshal(scores)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return scores, json.Unmarshal([]byte(cached), &scores)
+		}
+	}
+
+	stmt := `
+		SELECT
+			IFNULL(SUM(score_teaching * weight) / NULLIF(SUM(weight), 0), 0),
+			IFNULL(SUM(score_coursework * weight) / NULLIF(SUM(weight), 0), 0),
+			IFNULL(SUM(score_learning * weight) / NULLIF(SUM(weight), 0), 0),
+			COUNT(id)
+		FROM Scores
+		WHERE hash <> ?
+		AND course_code = ?
+		AND professor_uuid = ?
+	`
+
+	var grades [3]float32
+	var count int
+	if err = d.conn.QueryRowContext(ctx, stmt, defaultHash, courseCode, professorUUID).Scan(&grades[0], &grades[1], &grades[2], &count); err != nil {
+		return nil, err
+	}
+
+	for i, dimension := range db.DefaultScoreDimensions {
+		scores = append(scores, &db.DimensionScore{Dimension: dimension, Score: grades[i], Count: count})
+	}
+
+	if extra := d.opts.ExtraScoreDimensions(); len(extra) > 0 {
+		extraScores, err := d.getExtraDimensionScores(ctx, courseCode, professorUUID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dimension := range extra {
+			scores = append(scores, extraScores[dimension])
+		}
+	}
+
+	return
+}
+
+// getExtraDimensionScores retrieves the average score of each extra score dimension from the database,
+// of the grades of a professor in a course, or of all the grades if the course code is empty.
+// The dimensions without grades have a score of 0.
+func (d *DB) getExtraDimensionScores(ctx context.Context, courseCode, professorUUID string) (scores map[string]*db.DimensionScore, err error) {
+	stmt := `
+		SELECT
+			ScoreValues.dimension,
+			IFNULL(SUM(ScoreValues.value * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.id)
+		FROM ScoreValues
+		JOIN Scores ON ScoreValues.score_id = Scores.id
+		WHERE Scores.hash <> ?
+	`
+	args := []any{defaultHash}
+
+	if courseCode != "" {
+		stmt += "AND Scores.course_code = ? AND Scores.professor_uuid = ?"
+		args = append(args, courseCode, professorUUID)
+	}
+
+	rows, err := d.conn.QueryContext(ctx, stmt+" GROUP BY ScoreValues.dimension", args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	scores = map[string]*db.DimensionScore{}
+	for rows.Next() {
+		score := db.DimensionScore{}
+		if err = rows.Scan(&score.Dimension, &score.Score, &score.Count); err != nil {
+			return
+		}
+		scores[score.Dimension] = &score
+	}
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, dimension := range d.opts.ExtraScoreDimensions() {
+		if _, ok := scores[dimension]; !ok {
+			scores[dimension] = &db.DimensionScore{Dimension: dimension}
+		}
+	}
+
+	return
+}
+
+// GetProfessorDetail retrieves a professor with the courses they teach, their average scores in each course,
+// and their average scores across all courses from the database, in a single query.
+// If no professor has the uuid, responses.ErrProfessorNotFound is returned.
+func (d *DB) GetProfessorDetail(professorUUID string) (detail *db.ProfessorDetail, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetProfessorDetail" + professorUUID
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				if detail == nil {
+					return
+				}
+				data, err := json.Marshal(detail)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return detail, json.Unmarshal([]byte(cached), &detail)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Professors.name,
+			IFNULL(Courses.code, ''),
+			IFNULL(Courses.name, ''),
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching),
+			IFNULL(SUM(SUM(Scores.score_teaching * Scores.weight)) OVER () / NULLIF(SUM(SUM(Scores.weight)) OVER (), 0), 0),
+			IFNULL(SUM(SUM(Scores.score_coursework * Scores.weight)) OVER () / NULLIF(SUM(SUM(Scores.weight)) OVER (), 0), 0),
+			IFNULL(SUM(SUM(Scores.score_learning * Scores.weight)) OVER () / NULLIF(SUM(SUM(Scores.weight)) OVER (), 0), 0),
+			SUM(COUNT(Scores.score_teaching)) OVER ()
+		FROM
+			Professors
+			LEFT JOIN (Scores JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL) ON Scores.professor_uuid = Professors.uuid
+		WHERE Professors.uuid = ? AND Professors.deleted_at IS NULL
+		GROUP BY Professors.name, Courses.code, Courses.name
+		ORDER BY Courses.code
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, professorUUID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if detail == nil {
+			detail = &db.ProfessorDetail{ProfessorUUID: professorUUID, Courses: []*db.CourseScore{}}
+		}
+		course := db.CourseScore{}
+		if err = rows.Scan(&detail.ProfessorName, &course.CourseCode, &course.CourseName, &course.ScoreTeaching, &course.ScoreCourseWork, &course.ScoreLearning, &course.Count, &detail.ScoreTeaching, &detail.ScoreCourseWork, &detail.ScoreLearning, &detail.Count); err != nil {
+			return nil, err
+		}
+		if course.CourseCode == "" {
+			continue
+		}
+		course.ScoreAverage = averageScore(d.opts.ScoreWeights, course.ScoreTeaching, course.ScoreCourseWork, course.ScoreLearning)
+		detail.Courses = append(detail.Courses, &course)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if detail == nil {
+		return nil, responses.ErrProfessorNotFound
+	}
+
+	detail.ScoreAverage = averageScore(d.opts.ScoreWeights, detail.ScoreTeaching, detail.ScoreCourseWork, detail.ScoreLearning)
+
+	return
+}
+
+// GetCourseDetail retrieves a course with the professors teaching it, the average scores of each professor in the course,
+// and the average scores of the course across all professors from the database, in a single query.
+// If no course has the code, responses.ErrCourseNotFound is returned.
+func (d *DB) GetCourseDetail(courseCode string) (detail *db.CourseDetail, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetCourseDetail" + courseCode
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				if detail == nil {
+					return
+				}
+				data, err := json.Marshal(detail)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return detail, json.Unmarshal([]byte(cached), &detail)
+		}
+	}
+
+	stmt := `
+		SELECT
+			Courses.name,
+			IFNULL(Courses.department, ''),
+			IFNULL(Professors.uuid, ''),
+			IFNULL(Professors.name, ''),
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			COUNT(Scores.score_teaching),
+			IFNULL(SUM(SUM(Scores.score_teaching * Scores.weight)) OVER () / NULLIF(SUM(SUM(Scores.weight)) OVER (), 0), 0),
+			IFNULL(SUM(SUM(Scores.score_coursework * Scores.weight)) OVER () / NULLIF(SUM(SUM(Scores.weight)) OVER (), 0), 0),
+			IFNULL(SUM(SUM(Scores.score_learning * Scores.weight)) OVER () / NULLIF(SUM(SUM(Scores.weight)) OVER (), 0), 0),
+			SUM(COUNT(Scores.score_teaching)) OVER ()
+		FROM
+			Courses
+			LEFT JOIN (Scores JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL) ON Scores.course_code = Courses.code
+		WHERE Courses.code = ? AND Courses.deleted_at IS NULL
+		GROUP BY Courses.name, Courses.department, Professors.uuid, Professors.name
+		ORDER BY Professors.name
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, courseCode)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if detail == nil {
+			detail = &db.CourseDetail{CourseCode: courseCode, Professors: []*db.ProfessorScore{}}
+		}
+		professor := db.ProfessorScore{}
+		if err = rows.Scan(&detail.CourseName, &detail.Department, &professor.ProfessorUUID, &professor.ProfessorName, &professor.ScoreTeaching, &professor.ScoreCourseWork, &professor.ScoreLearning, &professor.Count, &detail.ScoreTeaching, &detail.ScoreCourseWork, &detail.ScoreLearning, &detail.Count); err != nil {
+			return nil, err
+		}
+		if professor.ProfessorUUID == "" {
+			continue
+		}
+		professor.ScoreAverage = averageScore(d.opts.ScoreWeights, professor.ScoreTeaching, professor.ScoreCourseWork, professor.ScoreLearning)
+		detail.Professors = append(detail.Professors, &professor)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if detail == nil {
+		return nil, responses.ErrCourseNotFound
+	}
+
+	detail.ScoreAverage = averageScore(d.opts.ScoreWeights, detail.ScoreTeaching, detail.ScoreCourseWork, detail.ScoreLearning)
+
+	return
+}
+
+// GetProfessorRank retrieves the rank of a professor by average score among the professors teaching courses of their department from the database.
+// The department of a professor is the one of which they teach the most courses, the first by name in case of a tie.
+// Professors with the same average score are ordered by their number of grades, then by the most recently added.
+// If no professor has the UUID, responses.ErrProfessorNotFound is returned,
+// and if the professor teaches no course with a department, responses.ErrProfessorNoDepartment is returned.
+func (d *DB) GetProfessorRank(professorUUID string) (rank *db.ProfessorRank, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if d.cache != nil {
+		key := "GetProfessorRank" + professorUUID
+		cached, err := d.cache.Get(key)
+		if err == cache.ErrRedisNil {
+			defer func() {
+				if rank == nil {
+					return
+				}
+				data, err := json.Marshal(rank)
+				if err == nil {
+					if err = d.cache.Set(key, data, d.cacheTtl); err != nil {
+						log.Error().Err(err)
+					}
+				}
+			}()
+		} else if err == nil {
+			return rank, json.Unmarshal([]byte(cached), &rank)
+		}
+	}
+
+	stmt := `
+		SELECT Professors.name, IFNULL(Courses.department, '')
+		FROM
+			Professors
+			LEFT JOIN (Scores JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL AND Courses.department IS NOT NULL) ON Scores.professor_uuid = Professors.uuid
+		WHERE Professors.uuid = ? AND Professors.deleted_at IS NULL
+		GROUP BY Professors.name, Courses.department
+		ORDER BY COUNT(DISTINCT Courses.code) DESC, Courses.department
+		LIMIT 1
+	`
+
+	rank = &db.ProfessorRank{ProfessorUUID: professorUUID}
+	if err = d.conn.QueryRowContext(ctx, stmt, professorUUID).Scan(&rank.ProfessorName, &rank.Department); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, responses.ErrProfessorNotFound
+		}
+		return nil, err
+	}
+
+	if rank.Department == "" {
+		return nil, responses.ErrProfessorNoDepartment
+	}
+
+	// the rank is computed from the ordered professors of the department, the same way as ROW_NUMBER.
+	stmt = `
+		SELECT
+			Professors.uuid,
+			IFNULL(SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0),
+			IFNULL(SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0), 0)
+		FROM
+			Professors
+			LEFT JOIN (Scores JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL) ON Professors.uuid = Scores.professor_uuid
+		WHERE Professors.deleted_at IS NULL AND Professors.uuid IN (
+			SELECT Scores.professor_uuid
+			FROM
+				Scores
+				JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+			WHERE Courses.department = ?
+		)
+		GROUP BY Professors.uuid
+		ORDER BY IFNULL((? * SUM(Scores.score_teaching * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_coursework * Scores.weight) / NULLIF(SUM(Scores.weight), 0) + ? * SUM(Scores.score_learning * Scores.weight) / NULLIF(SUM(Scores.weight), 0)) / ?, 0)
+		DESC, COUNT(Scores.score_teaching) DESC, Professors.inserted_at DESC, Professors.uuid
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, append([]any{rank.Department}, d.scoreWeightArgs()...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uuid string
+		var teaching, coursework, learning float32
+		if err = rows.Scan(&uuid, &teaching, &coursework, &learning); err != nil {
+			return nil, err
+		}
+		rank.Total++
+		if uuid == professorUUID {
+			rank.Rank = rank.Total
+			rank.ScoreAverage = averageScore(d.opts.ScoreWeights, teaching, coursework, learning)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// GradeCourseProfessor updates the scores of a professor for a specific course in the database.
+func (d *DB) GradeCourseProfessor(professorUUID, courseCode, username string, grades [3]float32) (err error) {
+	return d.GradeCourseProfessorWithDetails(professorUUID, courseCode, username, grades, &db.GradeDetails{Weight: db.DefaultGradeWeight})
+}
+
+// GradeCourseProfessorWithDetails updates the scores of a professor for a specific course in the database,
+// with the grades counting with the weight of the details in the averages, and the comment of the details, if any.
+// The details should have grades for all the extra score dimensions, and only for them.
+// If the professor or the course is soft-deleted, db.ErrNotFound is returned.
+func (d *DB) GradeCourseProfessorWithDetails(professorUUID, courseCode, username string, grades [3]float32, details *db.GradeDetails) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if details.Weight <= 0 {
+		return fmt.Errorf("invalid grade weight: %v (should be greater than 0)", details.Weight)
+	}
+
+	if err = d.opts.CheckExtraGrades(details.Grades); err != nil {
+		return
+	}
+
+	hash := d.opts.GradeHash(username, courseCode, professorUUID)
+
+	if !validGrades(grades) || !validExtraGrades(details.Grades) {
+		d.addGradeAttempt(ctx, courseCode, db.GradeAttemptOutOfRange)
+		return responses.ErrGradeOutOfRange
+	}
+
+	graded, err := d.addScore(ctx, hash, professorUUID, courseCode, grades, details)
+	if err != nil {
+		return
+	}
+
+	if graded {
+		d.addGradeAttempt(ctx, courseCode, db.GradeAttemptGraded)
+		return responses.ErrCourseGraded
+	}
+
+	d.addGradeAttempt(ctx, courseCode, db.GradeAttemptAccepted)
+
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// addScore adds the score of a grade and the grades of its extra score dimensions in a transaction,
+// unless the user already graded within the dedup scope, in which case graded is true.
+// Grades are only counted once by the unique index on the hashes of the scores, even for concurrent requests.
+func (d *DB) addScore(ctx context.Context, hash, professorUUID, courseCode string, grades [3]float32, details *db.GradeDetails) (graded bool, err error) {
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err = checkNotDeleted(ctx, tx, professorUUID, courseCode); err != nil {
+		return
+	}
+
+	stmt := `
+		INSERT INTO Scores (
+			hash,
+			professor_uuid,
+			course_code,
+			score_teaching,
+			score_coursework,
+			score_learning,
+			weight,
+			comment,
+			inserted_at
+		) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+	`
+
+	res, err := tx.ExecContext(ctx, stmt, hash, professorUUID, courseCode, grades[0], grades[1], grades[2], details.Weight, details.Comment, time.Now().UnixNano())
+	if err != nil {
+		if err = mapError(err); errors.Is(err, db.ErrDuplicate) {
+			return true, nil
+		}
+		return
+	}
+
+	if err = insertScoreValues(ctx, tx, res, details.Grades); err != nil {
+		return
+	}
+
+	return false, tx.Commit()
+}
+
+// GradeCourseProfessorMany grades professors teaching courses in the database in a single transaction.
+// Unlike the other batch methods, the valid grades are added even if other grades of the batch fail,
+// with the error of each failing grade at its index in errs.
+// A grade fails with responses.ErrCourseGraded if the user already graded within the dedup scope,
+// including earlier in the batch, with responses.ErrGradeOutOfRange if a grade is out of range,
+// and with db.ErrNotFound if the professor or the course is soft-deleted.
+func (d *DB) GradeCourseProfessorMany(username string, grades []*db.Grade) (errs []error, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO Scores (
+			hash,
+			professor_uuid,
+			course_code,
+			score_teaching,
+			score_coursework,
+			score_learning,
+			weight,
+			comment,
+			inserted_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+	`)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	errs = make([]error, len(grades))
+	outcomes := make([]db.GradeAttemptOutcome, len(grades))
+	for i, g := range grades {
+		if g.Details.Weight <= 0 {
+			errs[i] = fmt.Errorf("invalid grade weight: %v (should be greater than 0)", g.Details.Weight)
+			continue
+		}
+
+		if errs[i] = d.opts.CheckExtraGrades(g.Details.Grades); errs[i] != nil {
+			continue
+		}
+
+		if !validGrades(g.Grades) || !validExtraGrades(g.Details.Grades) {
+			errs[i], outcomes[i] = responses.ErrGradeOutOfRange, db.GradeAttemptOutOfRange
+			continue
+		}
+
+		if errs[i] = checkNotDeleted(ctx, tx, g.ProfessorUUID, g.CourseCode); errs[i] != nil {
+			continue
+		}
+
+		hash := d.opts.GradeHash(username, g.CourseCode, g.ProfessorUUID)
+
+		res, err := stmt.ExecContext(ctx, hash, g.ProfessorUUID, g.CourseCode, g.Grades[0], g.Grades[1], g.Grades[2], g.Details.Weight, g.Details.Comment, time.Now().UnixNano())
+		if err != nil {
+			if errs[i] = mapError(err); errors.Is(errs[i], db.ErrDuplicate) {
+				errs[i], outcomes[i] = responses.ErrCourseGraded, db.GradeAttemptGraded
+			}
+			continue
+		}
+
+		if err = insertScoreValues(ctx, tx, res, g.Details.Grades); err != nil {
+			return nil, err
+		}
+		outcomes[i] = db.GradeAttemptAccepted
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	for i, outcome := range outcomes {
+		if outcome != "" {
+			d.addGradeAttempt(ctx, grades[i].CourseCode, outcome)
+		}
+	}
+
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// UpdateGrade replaces the scores and the grades of the extra score dimensions of the grade given by a user
+// to a professor for a specific course in the database, keeping its weight and comment.
+// If the user did not grade them, responses.ErrNotGraded is returned.
+func (d *DB) UpdateGrade(professorUUID, courseCode, username string, grades [3]float32, extraGrades map[string]float32) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	if err = d.opts.CheckExtraGrades(extraGrades); err != nil {
+		return
+	}
+
+	if !validGrades(grades) || !validExtraGrades(extraGrades) {
+		return responses.ErrGradeOutOfRange
+	}
+
+	hash := d.opts.GradeHash(username, courseCode, professorUUID)
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt := `
+		SELECT id
+		FROM Scores
+		WHERE hash = ?
+		AND professor_uuid = ?
+		AND course_code = ?
+		FOR UPDATE
+	`
+	var scoreID int64
+	if err = tx.QueryRowContext(ctx, stmt, hash, professorUUID, courseCode).Scan(&scoreID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return responses.ErrNotGraded
+		}
+		return mapError(err)
+	}
+
+	stmt = "UPDATE Scores SET score_teaching = ?, score_coursework = ?, score_learning = ? WHERE id = ?"
+	if _, err = tx.ExecContext(ctx, stmt, grades[0], grades[1], grades[2], scoreID); err != nil {
+		return mapError(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM ScoreValues WHERE score_id = ?", scoreID); err != nil {
+		return mapError(err)
+	}
+
+	if err = addScoreValues(ctx, tx, scoreID, extraGrades); err != nil {
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// DeleteGrade deletes the grade given by a user to a professor for a specific course from the database,
+// so that the user can grade them again. If the user did not grade them, responses.ErrNotGraded is returned.
+func (d *DB) DeleteGrade(professorUUID, courseCode, username string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	hash := d.opts.GradeHash(username, courseCode, professorUUID)
+
+	stmt := "DELETE FROM Scores WHERE hash = ? AND professor_uuid = ? AND course_code = ?"
+	res, err := d.conn.ExecContext(ctx, stmt, hash, professorUUID, courseCode)
+	if err != nil {
+		return mapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return responses.ErrNotGraded
+	}
+
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// CheckGradedMany checks if a user graded each of the specified courses and their professors.
+// The returned slice is parallel to the specified pairs.
+func (d *DB) CheckGradedMany(username string, pairs []*db.CourseProfessor) (graded []bool, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	hashes := make([]string, len(pairs))
+	for i, pair := range pairs {
+		hashes[i] = d.opts.GradeHash(username, pair.CourseCode, pair.ProfessorUUID)
+	}
+	gradedHashes, err := d.getGradedHashes(ctx, hashes)
+	if err != nil {
+		return
+	}
+
+	graded = make([]bool, len(pairs))
+	for i, hash := range hashes {
+		graded[i] = gradedHashes[hash]
+	}
+
+	return
+}
+
+// GetEligibleGrades retrieves the courses and their professors that a user can still grade, up to limit pairs,
+// ordered by course code and professor name. The pairs graded by the user within the dedup scope are skipped.
+func (d *DB) GetEligibleGrades(username string, limit int) (eligible []*db.EligibleGrade, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	limit, _ = d.clampPage(limit, 0)
+
+	pairs, err := d.getCourseProfessors(ctx)
+	if err != nil {
+		return
+	}
+
+	hashes := make([]string, len(pairs))
+	for i, pair := range pairs {
+		hashes[i] = d.opts.GradeHash(username, pair.CourseCode, pair.ProfessorUUID)
+	}
+	graded, err := d.getGradedHashes(ctx, hashes)
+	if err != nil {
+		return
+	}
+
+	for i, pair := range pairs {
+		if graded[hashes[i]] {
+			continue
+		}
+
+		eligible = append(eligible, pair)
+		if len(eligible) == limit {
+			break
+		}
+	}
+
+	return
+}
+
+// MigrateGrades moves the grades of a user to a new username, so that the user still cannot grade again
+// within the dedup scope after changing username. The grades of the old username within a scope the new username
+// already graded are left as they are.
+func (d *DB) MigrateGrades(oldUsername, newUsername string) (err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	pairs, err := d.getGradedPairs(ctx)
+	if err != nil {
+		return
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, pair := range pairs {
+		newHash := d.opts.GradeHash(newUsername, pair.CourseCode, pair.ProfessorUUID)
+
+		var count int
+		if err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM Scores WHERE hash = ?", newHash).Scan(&count); err != nil {
+			return
+		}
+		if count > 0 {
+			continue
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE Scores SET hash = ? WHERE hash = ?", newHash, d.opts.GradeHash(oldUsername, pair.CourseCode, pair.ProfessorUUID)); err != nil {
+			return mapError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// getGradedPairs retrieves the courses and their professors with at least one grade, including the soft-deleted ones.
+func (d *DB) getGradedPairs(ctx context.Context) (pairs []*db.CourseProfessor, err error) {
+	rows, err := d.conn.QueryContext(ctx, "SELECT DISTINCT professor_uuid, course_code FROM Scores WHERE hash <> ''")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		pair := db.CourseProfessor{}
+		if err = rows.Scan(&pair.ProfessorUUID, &pair.CourseCode); err != nil {
+			return
+		}
+		pairs = append(pairs, &pair)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// getCourseProfessors retrieves the courses and their professors, ordered by course code and professor name,
+// excluding the soft-deleted courses and professors.
+func (d *DB) getCourseProfessors(ctx context.Context) (pairs []*db.EligibleGrade, err error) {
+	stmt := `
+		SELECT DISTINCT Scores.professor_uuid, Professors.name, Scores.course_code, Courses.name
+		FROM Scores
+		JOIN Professors ON Scores.professor_uuid = Professors.uuid AND Professors.deleted_at IS NULL
+		JOIN Courses ON Scores.course_code = Courses.code AND Courses.deleted_at IS NULL
+		ORDER BY Scores.course_code, Professors.name
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		pair := db.EligibleGrade{}
+		if err = rows.Scan(&pair.ProfessorUUID, &pair.ProfessorName, &pair.CourseCode, &pair.CourseName); err != nil {
+			return
+		}
+		pairs = append(pairs, &pair)
+	}
+
+	return
+}
+
+// GetRawGrades retrieves the individual grades of a course and its professor from the database,
+// excluding the row adding the course to the professor.
+func (d *DB) GetRawGrades(professorUUID, courseCode string) (grades []*db.RawGrade, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	stmt := `
+		SELECT
+			id,
+			hash,
+			score_teaching,
+			score_coursework,
+			score_learning,
+			weight,
+			IFNULL(comment, ''),
+			inserted_at
+		FROM Scores
+		WHERE professor_uuid = ?
+		AND course_code = ?
+		AND hash <> ?
+		ORDER BY inserted_at
+		ASC
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, professorUUID, courseCode, defaultHash)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var insertedAt int64
+		grade := db.RawGrade{}
+		if err = rows.Scan(&grade.ID, &grade.Hash, &grade.ScoreTeaching, &grade.ScoreCourseWork, &grade.ScoreLearning, &grade.Weight, &grade.Comment, &insertedAt); err != nil {
+			return
+		}
+		grade.InsertedAt = time.Unix(0, insertedAt)
+		grades = append(grades, &grade)
+	}
+
+	return
+}
+
+// GetGradeAttemptsByCourseCode retrieves the number of grade submissions for a course since the specified time, by outcome.
+// If since is the zero time, all submissions are counted.
+func (d *DB) GetGradeAttemptsByCourseCode(code string, since time.Time) (attempts *db.GradeAttempts, err error) {
+	ctx, done := db.QueryContext(d.ctx, d.opts, &err)
+	defer done()
+
+	var sinceNano int64
+	if !since.IsZero() {
+		sinceNano = since.UnixNano()
+	}
+
+	stmt := `
+		SELECT
+			COUNT(CASE WHEN outcome = ? THEN 1 END),
+			COUNT(CASE WHEN outcome = ? THEN 1 END),
+			COUNT(CASE WHEN outcome = ? THEN 1 END)
+		FROM GradeAttempts
+		WHERE course_code = ?
+		AND inserted_at >= ?
+	`
+
+	attempts = &db.GradeAttempts{CourseCode: code}
+
+	row := d.conn.QueryRowContext(ctx, stmt, db.GradeAttemptAccepted, db.GradeAttemptGraded, db.GradeAttemptOutOfRange, code, sinceNano)
+	if err = row.Scan(&attempts.Accepted, &attempts.AlreadyGraded, &attempts.OutOfRange); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// addGradeAttempt records the outcome of a grade submission for a course.
+// Errors are only logged, since they should not make the grade submission fail.
+func (d *DB) addGradeAttempt(ctx context.Context, courseCode string, outcome db.GradeAttemptOutcome) {
+	stmt := "INSERT INTO GradeAttempts(course_code, outcome, inserted_at) VALUES(?, ?, ?)"
+	if err := execStmtContext(d.conn, ctx, stmt, courseCode, outcome, time.Now().UnixNano()); err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+// CheckGraded checks if a user graded a course.
+// The hash parameter is obtained by hashing
+// the concatenation of the username, course code,
+// and professor uuid (depending on the dedup scope) using the configured hash algorithm.
+func (d *DB) checkGraded(ctx context.Context, hash string) (graded bool, err error) {
+	var count int
+
+	stmt := "SELECT COUNT(*) FROM Scores WHERE hash = ?"
+	if err = d.conn.QueryRowContext(ctx, stmt, hash).Scan(&count); err != nil {
+		return
+	}
+
+	if count > 0 {
+		return !graded, nil
+	} else {
+		return graded, nil
+	}
+}
+
+// getGradedHashes checks in one query which of the specified hashes a grade was given with.
+// As in checkGraded, the hashes are compared to the default hash, so that the partial unique index on the hashes is used.
+func (d *DB) getGradedHashes(ctx context.Context, hashes []string) (graded map[string]bool, err error) {
+	graded = map[string]bool{}
+	if len(hashes) == 0 {
+		return
+	}
+
+	conds, args := make([]string, len(hashes)), make([]any, len(hashes))
+	for i, hash := range hashes {
+		conds[i], args[i] = "?", hash
+	}
+
+	rows, err := d.conn.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT hash FROM Scores WHERE hash IN (%s) AND hash <> ''", strings.Join(conds, ", ")), args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash string
+		if err = rows.Scan(&hash); err != nil {
+			return
+		}
+		graded[hash] = true
+	}
+
+	return graded, rows.Err()
+}
+
+// setDeletedAt executes the statement soft-deleting or restoring a course or a professor,
+// and returns notFound if no row was updated.
+func (d *DB) setDeletedAt(ctx context.Context, notFound error, stmt string, args ...any) (err error) {
+	res, err := d.conn.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return mapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return notFound
+	}
+
+	d.invalidateCache(courseCacheKeyPrefixes...)
+	d.invalidateCache(professorCacheKeyPrefixes...)
+	d.invalidateCache(scoreCacheKeyPrefixes...)
+
+	return
+}
+
+// checkNotDeleted returns db.ErrNotFound if the professor or the course is soft-deleted, as they cannot be graded.
+func checkNotDeleted(ctx context.Context, tx *sql.Tx, professorUUID, courseCode string) (err error) {
+	var deleted bool
+
+	stmt := "SELECT EXISTS(SELECT 1 FROM Professors WHERE uuid = ? AND deleted_at IS NOT NULL) OR EXISTS(SELECT 1 FROM Courses WHERE code = ? AND deleted_at IS NOT NULL)"
+	if err = tx.QueryRowContext(ctx, stmt, professorUUID, courseCode).Scan(&deleted); err != nil {
+		return
+	}
+	if deleted {
+		return db.ErrNotFound
+	}
+
+	return
+}
+
+// getRecentComments retrieves the most recent comments of the grades of a course and its professor.
+func (d *DB) getRecentComments(ctx context.Context, professorUUID, courseCode string) (comments []string, err error) {
+	stmt := `
+		SELECT comment
+		FROM Scores
+		WHERE professor_uuid = ?
+		AND course_code = ?
+		AND comment IS NOT NULL
+		ORDER BY inserted_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := d.conn.QueryContext(ctx, stmt, professorUUID, courseCode, db.MaxRecentComments)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment string
+		if err = rows.Scan(&comment); err != nil {
+			return
+		}
+		comments = append(comments, comment)
+	}
+
+	return
+}
+
+// getProfessorsByNormalizedName returns the professors of the database by their normalized name,
+// to find the likely duplicates of the professors being added in a transaction.
+// The professors are locked until the end of the transaction, so that no professor can be added concurrently.
+func getProfessorsByNormalizedName(ctx context.Context, tx *sql.Tx) (professors map[string]*db.Professor, err error) {
+	rows, err := tx.QueryContext(ctx, "SELECT uuid, name FROM Professors FOR UPDATE")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	professors = map[string]*db.Professor{}
+	for rows.Next() {
+		professor := db.Professor{}
+		if err = rows.Scan(&professor.UUID, &professor.Name); err != nil {
+			return
+		}
+		professors[db.NormalizeName(professor.Name)] = &professor
+	}
+
+	return professors, rows.Err()
+}
+
+// setScoreDistributions sets the median and the standard deviation of the overall grades of each score,
+// the overall grade of a student being the mean of their teaching, coursework, and learning grades.
+// The grades count with their weight, as in the averages, and are read for all the scores at once.
+func (d *DB) setScoreDistributions(ctx context.Context, scores []*db.Score) (err error) {
+	if len(scores) == 0 {
+		return
+	}
+
+	courseConds, professorConds := make([]string, len(scores)), make([]string, len(scores))
+	courseArgs, professorArgs := make([]any, len(scores)), make([]any, len(scores))
+	for i, score := range scores {
+		courseConds[i], professorConds[i] = "?", "?"
+		courseArgs[i], professorArgs[i] = score.CourseCode, score.ProfessorUUID
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT course_code, professor_uuid, (? * score_teaching + ? * score_coursework + ? * score_learning) / ?, weight
+		FROM Scores
+		WHERE course_code IN (%s)
+		AND professor_uuid IN (%s)
+		AND score_teaching IS NOT NULL
+	`, strings.Join(courseConds, ", "), strings.Join(professorConds, ", "))
+
+	args := append(append(d.scoreWeightArgs(), courseArgs...), professorArgs...)
+	rows, err := d.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	grades := map[gradeKey][]weightedGrade{}
+	for rows.Next() {
+		var key gradeKey
+		var grade weightedGrade
+		if err = rows.Scan(&key.courseCode, &key.professorUUID, &grade.grade, &grade.weight); err != nil {
+			return
+		}
+		grades[key] = append(grades[key], grade)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, score := range scores {
+		scoreGrades := grades[gradeKey{courseCode: score.CourseCode, professorUUID: score.ProfessorUUID}]
+		score.ScoreMedian, score.ScoreStdDev = medianScore(scoreGrades), stdDevScore(scoreGrades)
+	}
+
+	return
+}
+
+// searchLikeMatches returns the statements selecting the professors and the courses of which the name, or the code for courses,
+// contains all the words of a search query, with their relevance, and the arguments of the statements.
+// The professors and courses starting with the search query are the most relevant.
+func searchLikeMatches(query string, words []string) (professorMatches, courseMatches string, args []any) {
+	professorConds, courseConds := make([]string, len(words)), make([]string, len(words))
+	professorArgs, courseArgs := []any{query + "%"}, []any{query + "%", query + "%"}
+	for i, word := range words {
+		professorConds[i], courseConds[i] = "name LIKE ?", "(code LIKE ? OR name LIKE ?)"
+		professorArgs = append(professorArgs, "%"+word+"%")
+		courseArgs = append(courseArgs, "%"+word+"%", "%"+word+"%")
+	}
+
+	professorMatches = "SELECT uuid AS id, CASE WHEN name LIKE ? THEN 0 ELSE 1 END AS relevance FROM Professors WHERE " + strings.Join(professorConds, " AND ")
+	courseMatches = "SELECT code AS id, CASE WHEN code LIKE ? OR name LIKE ? THEN 0 ELSE 1 END AS relevance FROM Courses WHERE " + strings.Join(courseConds, " AND ")
+
+	return professorMatches, courseMatches, append(professorArgs, courseArgs...)
+}
+
+// validGrades checks if all grades are between minGrade and maxGrade.
+func validGrades(grades [3]float32) bool {
+	for _, g := range grades {
+		if g < minGrade || g > maxGrade {
+			return false
+		}
+	}
+	return true
+}
+
+// insertScoreValues inserts the grades of the extra score dimensions of the score inserted with the result.
+func insertScoreValues(ctx context.Context, tx *sql.Tx, res sql.Result, grades map[string]float32) (err error) {
+	if len(grades) == 0 {
+		return
+	}
+
+	scoreID, err := res.LastInsertId()
+	if err != nil {
+		return
+	}
+
+	return addScoreValues(ctx, tx, scoreID, grades)
+}
+
+// addScoreValues inserts the grades of the extra score dimensions of a score in a transaction.
+func addScoreValues(ctx context.Context, tx *sql.Tx, scoreID int64, grades map[string]float32) (err error) {
+	for dimension, value := range grades {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO ScoreValues(score_id, dimension, value) VALUES(?, ?, ?)", scoreID, dimension, value); err != nil {
+			return mapError(err)
+		}
+	}
+
+	return
+}
+
+// validExtraGrades checks if all the grades of the extra score dimensions are between minGrade and maxGrade.
+func validExtraGrades(grades map[string]float32) bool {
+	for _, g := range grades {
+		if g < minGrade || g > maxGrade {
+			return false
+		}
+	}
+	return true
+}
+
+// invalidateCache deletes the cache keys starting with the specified prefixes.
+// Errors are logged, since the cache expires anyway.
+func (d *DB) invalidateCache(prefixes ...string) {
+	if d.cache == nil {
+		return
+	}
+
+	for _, prefix := range prefixes {
+		if err := d.cache.DelPrefix(prefix); err != nil {
+			log.Error().Err(err).Msg("invalidating cache")
+		}
+	}
+}
+
+// clampPage clamps the limit of a page between 1 and the maximum number of rows returned,
+// and the offset to a non-negative value. A non-positive limit defaults to the maximum number of rows returned.
+func (d *DB) clampPage(limit, offset int) (int, int) {
+	if limit <= 0 || limit > d.opts.MaxRowReturn {
+		limit = d.opts.MaxRowReturn
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// averageScore calculates the average of the teaching, coursework, and learning scores, weighted by the specified weights.
+func averageScore(weights [3]float32, teaching, coursework, learning float32) float32 {
+	avg := (weights[0]*teaching + weights[1]*coursework + weights[2]*learning) / (weights[0] + weights[1] + weights[2])
+
+	return float32(decimal.NewFromFloat32(avg).Round(roundPrecision).InexactFloat64())
+}
+
+// scoreWeightArgs returns the weights of the teaching, coursework, and learning scores, and their sum,
+// used as the arguments of the statements computing the average score.
+func (d *DB) scoreWeightArgs() []any {
+	w := d.opts.ScoreWeights
+	return []any{w[0], w[1], w[2], w[0] + w[1] + w[2]}
+}
+
+// gradeKey identifies the grades given to a professor in a course.
+type gradeKey struct {
+	courseCode    string
+	professorUUID string
+}
+
+// weightedGrade is the overall grade of a student, with the weight it counts with.
+type weightedGrade struct {
+	grade  float64
+	weight float64
+}
+
+// medianScore calculates the weighted median of the grades, or 0 if they have no weight.
+// With a weight of half of the total at a grade, the median is the mean of this grade and the next one.
+func medianScore(grades []weightedGrade) float32 {
+	sorted := slices.DeleteFunc(slices.Clone(grades), func(g weightedGrade) bool { return g.weight <= 0 })
+	if len(sorted) == 0 {
+		return 0
+	}
+	slices.SortFunc(sorted, func(a, b weightedGrade) int { return cmp.Compare(a.grade, b.grade) })
+
+	var total float64
+	for _, g := range sorted {
+		total += g.weight
+	}
+
+	var median, cumulative float64
+	for i, g := range sorted {
+		cumulative += g.weight
+		if cumulative > total/2 {
+			median = g.grade
+			break
+		}
+		if cumulative == total/2 {
+			median = (g.grade + sorted[i+1].grade) / 2
+			break
+		}
+	}
+
+	return float32(decimal.NewFromFloat(median).Round(roundPrecision).InexactFloat64())
+}
+
+// stdDevScore calculates the weighted population standard deviation of the grades, or 0 if they have no weight.
+func stdDevScore(grades []weightedGrade) float32 {
+	var total, mean float64
+	for _, g := range grades {
+		total += g.weight
+		mean += g.weight * g.grade
+	}
+	if total <= 0 {
+		return 0
+	}
+	mean /= total
+
+	var variance float64
+	for _, g := range grades {
+		variance += g.weight * (g.grade - mean) * (g.grade - mean)
+	}
+	variance /= total
+
+	return float32(decimal.NewFromFloat(math.Sqrt(variance)).Round(roundPrecision).InexactFloat64())
+}
+
+// averageTrendPoint turns the weighted sums of the scores of a trend point into weighted averages.
+func (d *DB) averageTrendPoint(point *db.ScoreTrendPoint, weights float32) *db.ScoreTrendPoint {
+	point.ScoreTeaching /= weights
+	point.ScoreCourseWork /= weights
+	point.ScoreLearning /= weights
+	point.ScoreAverage = averageScore(d.opts.ScoreWeights, point.ScoreTeaching, point.ScoreCourseWork, point.ScoreLearning)
+	return point
+}
+
+// execStmtContext executes a SQL statement.
+func execStmtContext(conn *sql.DB, ctx context.Context, stmt string, args ...any) (err error) {
+	_, err = conn.ExecContext(ctx, stmt, args...)
+	return mapError(err)
+}
+
+// mapError wraps the mysql errors matching a database error with that database error.
+func mapError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", db.ErrNotFound, err)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	switch mysqlErr.Number {
+	case erDupEntry:
+		return fmt.Errorf("%w: %w", db.ErrDuplicate, err)
+	case erRowIsReferenced, erNoReferencedRow:
+		return fmt.Errorf("%w: %w", db.ErrForeignKey, err)
+	case erCheckConstraintViolated, erConstraintFailed, erBadNullError:
+		return fmt.Errorf("%w: %w", db.ErrInvalid, err)
+	default:
+		return err
+	}
+}
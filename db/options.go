@@ -0,0 +1,267 @@
+package db
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/zeebo/xxh3"
+)
+
+// DedupScope is the scope within which a user can only grade once.
+type DedupScope string
+
+// Enum for grade deduplication scopes
+const (
+	DedupScopeCourseProfessor DedupScope = "course_professor" // DedupScopeCourseProfessor allows one grade per user, course, and professor.
+	DedupScopeProfessor       DedupScope = "professor"        // DedupScopeProfessor allows one grade per user and professor, across courses.
+	DedupScopeCourse          DedupScope = "course"           // DedupScopeCourse allows one grade per user and course, across professors.
+)
+
+// HashInput returns the string hashed to check if a user already graded within the scope.
+func (s DedupScope) HashInput(username, courseCode, professorUUID string) string {
+	switch s {
+	case DedupScopeProfessor:
+		return username + professorUUID
+	case DedupScopeCourse:
+		return username + courseCode
+	default:
+		return username + courseCode + professorUUID
+	}
+}
+
+// HashAlgorithm is the algorithm used to hash grade deduplication inputs.
+type HashAlgorithm string
+
+// Enum for hash algorithms
+const (
+	HashAlgorithmXxh3       HashAlgorithm = "xxh3"        // HashAlgorithmXxh3 is the fast, non-cryptographic xxh3 hash.
+	HashAlgorithmHmacSha256 HashAlgorithm = "hmac-sha256" // HashAlgorithmHmacSha256 is the keyed, cryptographic HMAC-SHA256 hash.
+)
+
+// DefaultScoreDimensions are the names of the teaching, coursework, and learning score dimensions,
+// graded by default and stored with the scores.
+var DefaultScoreDimensions = []string{"teaching", "coursework", "learning"}
+
+// Options represents the optional settings of a database.
+type Options struct {
+	DedupScope           DedupScope    // DedupScope is the scope within which a user can only grade once.
+	MinGradesForRanking  int           // MinGradesForRanking is the minimum number of grades for a professor to be ranked.
+	MinGradesForVerified int           // MinGradesForVerified is the minimum number of grades for a score to be verified.
+	HashAlgorithm        HashAlgorithm // HashAlgorithm is the algorithm used to hash grade deduplication inputs.
+	HashKey              string        // HashKey is the secret key used by keyed hash algorithms.
+	MaxRowReturn         int           // MaxRowReturn is the maximum number of rows returned by a query.
+	ScoreWeights         [3]float32    // ScoreWeights are the weights of the teaching, coursework, and learning scores in the average score.
+	QueryTimeout         time.Duration // QueryTimeout is the maximum duration of a database operation (0 to disable the timeout).
+	SlowQueryThreshold   time.Duration // SlowQueryThreshold is the duration above which a database operation is logged as slow (0 to disable the logging).
+	ScoreDimensions      []string      // ScoreDimensions are the names of the graded score dimensions, starting with the default ones.
+	ProfessorNameDedup   bool          // ProfessorNameDedup rejects the professors whose normalized name matches the name of an existing professor.
+	CacheNamespace       string        // CacheNamespace is the prefix of the keys of the database in the cache.
+	ReadOnly             bool          // ReadOnly skips the creation and migration of the schema, for read-only replicas.
+}
+
+// Option sets an optional setting of a database.
+type Option func(*Options)
+
+// WithDedupScope sets the scope within which a user can only grade once.
+func WithDedupScope(scope DedupScope) Option {
+	return func(o *Options) {
+		o.DedupScope = scope
+	}
+}
+
+// WithMinGradesForRanking sets the minimum number of grades for a professor to be ranked.
+func WithMinGradesForRanking(n int) Option {
+	return func(o *Options) {
+		o.MinGradesForRanking = n
+	}
+}
+
+// WithMinGradesForVerified sets the minimum number of grades for a score to be verified.
+func WithMinGradesForVerified(n int) Option {
+	return func(o *Options) {
+		o.MinGradesForVerified = n
+	}
+}
+
+// WithHashAlgorithm sets the algorithm used to hash grade deduplication inputs.
+// The key is only used by keyed algorithms, such as hmac-sha256.
+func WithHashAlgorithm(algorithm HashAlgorithm, key string) Option {
+	return func(o *Options) {
+		o.HashAlgorithm = algorithm
+		o.HashKey = key
+	}
+}
+
+// WithMaxRowReturn sets the maximum number of rows returned by a query.
+func WithMaxRowReturn(n int) Option {
+	return func(o *Options) {
+		o.MaxRowReturn = n
+	}
+}
+
+// WithScoreWeights sets the weights of the teaching, coursework, and learning scores in the average score.
+// Equal weights compute the plain mean of the three scores.
+func WithScoreWeights(weights [3]float32) Option {
+	return func(o *Options) {
+		o.ScoreWeights = weights
+	}
+}
+
+// WithQueryTimeout sets the maximum duration of a database operation.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.QueryTimeout = timeout
+	}
+}
+
+// WithSlowQueryThreshold sets the duration above which a database operation is logged as slow.
+func WithSlowQueryThreshold(threshold time.Duration) Option {
+	return func(o *Options) {
+		o.SlowQueryThreshold = threshold
+	}
+}
+
+// WithScoreDimensions sets the names of the graded score dimensions.
+// The dimensions should start with the default ones, the following ones being graded in addition to them.
+func WithScoreDimensions(dimensions ...string) Option {
+	return func(o *Options) {
+		o.ScoreDimensions = dimensions
+	}
+}
+
+// WithProfessorNameDedup sets whether adding a professor whose normalized name matches the name of an existing professor,
+// such as "prof oak" and "Prof. Oak", is rejected with a LikelyDuplicateError.
+func WithProfessorNameDedup(dedup bool) Option {
+	return func(o *Options) {
+		o.ProfessorNameDedup = dedup
+	}
+}
+
+// WithCacheNamespace sets the prefix of the keys of the database in the cache,
+// so that several databases can share a cache.
+func WithCacheNamespace(namespace string) Option {
+	return func(o *Options) {
+		o.CacheNamespace = namespace
+	}
+}
+
+// WithReadOnly sets the database as read-only, so that the schema set up by the primary database is used as is.
+// Read-only replicas, such as hot standbys, reject the statements creating and migrating the schema.
+// It is supported by the postgres and mysql backends.
+func WithReadOnly() Option {
+	return func(o *Options) {
+		o.ReadOnly = true
+	}
+}
+
+// NewOptions returns the default database options, overridden by the specified options.
+func NewOptions(opts ...Option) (*Options, error) {
+	o := &Options{DedupScope: DedupScopeCourseProfessor, MinGradesForRanking: 3, MinGradesForVerified: 5, HashAlgorithm: HashAlgorithmXxh3, MaxRowReturn: 100, ScoreWeights: [3]float32{1, 1, 1}, ScoreDimensions: DefaultScoreDimensions}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	switch o.DedupScope {
+	case DedupScopeCourseProfessor, DedupScopeProfessor, DedupScopeCourse:
+	default:
+		return nil, fmt.Errorf("invalid dedup scope: %s", o.DedupScope)
+	}
+
+	if o.MinGradesForRanking < 0 {
+		return nil, fmt.Errorf("invalid min grades for ranking: %d (should be greater than or equal to 0)", o.MinGradesForRanking)
+	}
+
+	if o.MinGradesForVerified < 0 {
+		return nil, fmt.Errorf("invalid min grades for verified: %d (should be greater than or equal to 0)", o.MinGradesForVerified)
+	}
+
+	if o.QueryTimeout < 0 {
+		return nil, fmt.Errorf("invalid query timeout: %s (should be greater than or equal to 0)", o.QueryTimeout)
+	}
+
+	if o.SlowQueryThreshold < 0 {
+		return nil, fmt.Errorf("invalid slow query threshold: %s (should be greater than or equal to 0)", o.SlowQueryThreshold)
+	}
+
+	if o.MaxRowReturn <= 0 {
+		return nil, fmt.Errorf("invalid max row return: %d (should be greater than 0)", o.MaxRowReturn)
+	}
+
+	for _, w := range o.ScoreWeights {
+		if w < 0 {
+			return nil, fmt.Errorf("invalid score weights: %v (should be greater than or equal to 0)", o.ScoreWeights)
+		}
+	}
+	if o.ScoreWeights == [3]float32{} {
+		return nil, fmt.Errorf("invalid score weights: %v (should not all be 0)", o.ScoreWeights)
+	}
+
+	if len(o.ScoreDimensions) < len(DefaultScoreDimensions) || !slices.Equal(o.ScoreDimensions[:len(DefaultScoreDimensions)], DefaultScoreDimensions) {
+		return nil, fmt.Errorf("invalid score dimensions: %v (should start with %v)", o.ScoreDimensions, DefaultScoreDimensions)
+	}
+	for i, dimension := range o.ScoreDimensions {
+		if dimension == "" || slices.Index(o.ScoreDimensions, dimension) != i {
+			return nil, fmt.Errorf("invalid score dimensions: %v (should be unique and not empty)", o.ScoreDimensions)
+		}
+	}
+
+	switch o.HashAlgorithm {
+	case HashAlgorithmXxh3:
+	case HashAlgorithmHmacSha256:
+		if o.HashKey == "" {
+			return nil, fmt.Errorf("hash algorithm %s requires a key", o.HashAlgorithm)
+		}
+	default:
+		return nil, fmt.Errorf("invalid hash algorithm: %s", o.HashAlgorithm)
+	}
+
+	return o, nil
+}
+
+// GradeHash returns the hash used to check if a user already graded a course and its professor,
+// computed from the dedup scope hash input with the hash algorithm.
+func (o *Options) GradeHash(username, courseCode, professorUUID string) string {
+	input := o.DedupScope.HashInput(username, courseCode, professorUUID)
+
+	switch o.HashAlgorithm {
+	case HashAlgorithmHmacSha256:
+		mac := hmac.New(sha256.New, []byte(o.HashKey))
+		mac.Write([]byte(input))
+		return hex.EncodeToString(mac.Sum(nil))
+	default:
+		return fmt.Sprintf("%d", xxh3.HashString(input))
+	}
+}
+
+// ExtraScoreDimensions returns the score dimensions graded in addition to the default ones,
+// whose grades are stored separately from the scores.
+func (o *Options) ExtraScoreDimensions() []string {
+	return o.ScoreDimensions[len(DefaultScoreDimensions):]
+}
+
+// CheckExtraGrades checks that grades are given for all the extra score dimensions, and only for them.
+// Otherwise, an error wrapping ErrInvalid is returned.
+func (o *Options) CheckExtraGrades(grades map[string]float32) error {
+	extra := o.ExtraScoreDimensions()
+
+	for _, dimension := range extra {
+		if _, ok := grades[dimension]; !ok {
+			return fmt.Errorf("%w: missing grade of score dimension %s", ErrInvalid, dimension)
+		}
+	}
+
+	if len(grades) != len(extra) {
+		for dimension := range grades {
+			if !slices.Contains(extra, dimension) {
+				return fmt.Errorf("%w: unknown score dimension %s", ErrInvalid, dimension)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/vanillaiice/itpg/metrics"
+	"github.com/zeebo/xxh3"
+)
+
+func TestGradeHash(t *testing.T) {
+	xxh3Opts, err := NewOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := xxh3Opts.GradeHash("joe", "S209", "uuid")
+	expected := fmt.Sprintf("%d", xxh3.HashString("joe"+"S209"+"uuid"))
+	if hash != expected {
+		t.Errorf("got %s, want %s", hash, expected)
+	}
+
+	hmacOpts, err := NewOptions(WithHashAlgorithm(HashAlgorithmHmacSha256, "pepper"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hmacHash := hmacOpts.GradeHash("joe", "S209", "uuid")
+	if hmacHash == hash {
+		t.Errorf("got same hash %s for %s and %s", hash, HashAlgorithmXxh3, HashAlgorithmHmacSha256)
+	}
+
+	if hmacHash != hmacOpts.GradeHash("joe", "S209", "uuid") {
+		t.Error("got different hashes for the same input")
+	}
+
+	otherKeyOpts, err := NewOptions(WithHashAlgorithm(HashAlgorithmHmacSha256, "salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hmacHash == otherKeyOpts.GradeHash("joe", "S209", "uuid") {
+		t.Error("got same hash for different keys")
+	}
+
+	if _, err = NewOptions(WithHashAlgorithm(HashAlgorithmHmacSha256, "")); err == nil {
+		t.Error("expected failure")
+	}
+
+	if _, err = NewOptions(WithHashAlgorithm("md5", "")); err == nil {
+		t.Error("expected failure")
+	}
+}
+
+func TestMaxRowReturn(t *testing.T) {
+	opts, err := NewOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.MaxRowReturn != 100 {
+		t.Errorf("got %d, want %d", opts.MaxRowReturn, 100)
+	}
+
+	if opts, err = NewOptions(WithMaxRowReturn(500)); err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.MaxRowReturn != 500 {
+		t.Errorf("got %d, want %d", opts.MaxRowReturn, 500)
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err = NewOptions(WithMaxRowReturn(n)); err == nil {
+			t.Errorf("%d: expected failure", n)
+		}
+	}
+}
+
+func TestScoreWeights(t *testing.T) {
+	opts, err := NewOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := [3]float32{1, 1, 1}; opts.ScoreWeights != expected {
+		t.Errorf("got %v, want %v", opts.ScoreWeights, expected)
+	}
+
+	if opts, err = NewOptions(WithScoreWeights([3]float32{2, 0, 1})); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := [3]float32{2, 0, 1}; opts.ScoreWeights != expected {
+		t.Errorf("got %v, want %v", opts.ScoreWeights, expected)
+	}
+
+	for _, weights := range [][3]float32{{0, 0, 0}, {-1, 1, 1}} {
+		if _, err = NewOptions(WithScoreWeights(weights)); err == nil {
+			t.Errorf("%v: expected failure", weights)
+		}
+	}
+}
+
+func TestQueryTimeoutOption(t *testing.T) {
+	opts, err := NewOptions(WithQueryTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.QueryTimeout != time.Second {
+		t.Errorf("got %s, want %s", opts.QueryTimeout, time.Second)
+	}
+
+	if _, err = NewOptions(WithQueryTimeout(-time.Second)); err == nil {
+		t.Error("expected failure")
+	}
+}
+
+func TestSlowQueryThresholdOption(t *testing.T) {
+	opts, err := NewOptions(WithSlowQueryThreshold(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.SlowQueryThreshold != time.Second {
+		t.Errorf("got %s, want %s", opts.SlowQueryThreshold, time.Second)
+	}
+
+	if _, err = NewOptions(WithSlowQueryThreshold(-time.Second)); err == nil {
+		t.Error("expected failure")
+	}
+}
+
+// metricsTestDB is a database whose operations are recorded in the metrics.
+type metricsTestDB struct{}
+
+// SlowOperation is a database operation taking a millisecond.
+func (d *metricsTestDB) SlowOperation(opts *Options) (err error) {
+	_, done := QueryContext(context.Background(), opts, &err)
+	defer done()
+
+	time.Sleep(time.Millisecond)
+
+	return
+}
+
+func TestQueryContextMetrics(t *testing.T) {
+	d := &metricsTestDB{}
+
+	if err := d.SlowOperation(&Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics.Enable(true)
+	defer metrics.Enable(false)
+
+	for _, timeout := range []time.Duration{0, time.Second} {
+		if err := d.SlowOperation(&Options{QueryTimeout: timeout}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if want := `itpg_db_query_duration_seconds_count{method="SlowOperation"} 2`; !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("expected metrics to contain %q, got:\n%s", want, rr.Body.String())
+	}
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = defaultLogger }()
+
+	d := &metricsTestDB{}
+
+	if err := d.SlowOperation(&Options{SlowQueryThreshold: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("got unexpected log %s", buf.String())
+	}
+
+	if err := d.SlowOperation(&Options{SlowQueryThreshold: time.Microsecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	var entry struct {
+		Level    string  `json:"level"`
+		Method   string  `json:"method"`
+		Duration float64 `json:"duration"`
+		Message  string  `json:"message"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("got invalid log %q: %v", buf.String(), err)
+	}
+
+	if entry.Level != zerolog.LevelWarnValue || entry.Method != "SlowOperation" || entry.Message != "slow query" {
+		t.Errorf("got unexpected log %s", buf.String())
+	}
+	if entry.Duration < 1 {
+		t.Errorf("got duration %vms, want at least 1ms", entry.Duration)
+	}
+}
+
+func TestScoreDimensions(t *testing.T) {
+	opts, err := NewOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(opts.ExtraScoreDimensions()) != 0 {
+		t.Errorf("got %v, want no extra score dimensions", opts.ExtraScoreDimensions())
+	}
+
+	if err = opts.CheckExtraGrades(nil); err != nil {
+		t.Error(err)
+	}
+
+	if opts, err = NewOptions(WithScoreDimensions("teaching", "coursework", "learning", "availability")); err != nil {
+		t.Fatal(err)
+	}
+
+	if extra := opts.ExtraScoreDimensions(); len(extra) != 1 || extra[0] != "availability" {
+		t.Errorf("got %v, want %v", extra, []string{"availability"})
+	}
+
+	if err = opts.CheckExtraGrades(map[string]float32{"availability": 4}); err != nil {
+		t.Error(err)
+	}
+
+	for _, grades := range []map[string]float32{nil, {"humor": 4}, {"availability": 4, "humor": 4}} {
+		if err = opts.CheckExtraGrades(grades); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%v: got %v, want %v", grades, err, ErrInvalid)
+		}
+	}
+
+	for _, dimensions := range [][]string{{"teaching", "learning"}, {"availability", "teaching", "coursework", "learning"}, {"teaching", "coursework", "learning", ""}, {"teaching", "coursework", "learning", "teaching"}} {
+		if _, err = NewOptions(WithScoreDimensions(dimensions...)); err == nil {
+			t.Errorf("%v: expected failure", dimensions)
+		}
+	}
+}
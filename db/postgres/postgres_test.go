@@ -2,15 +2,20 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofrs/uuid"
 	itpgDB "github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/responses"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ory/dockertest/v3"
@@ -88,7 +93,7 @@ func TestMain(m *testing.M) {
 }
 
 func initDB() (err error) {
-	err = execStmt(TestDB.ctx, TestDB.conn, "DROP TABLE IF EXISTS Courses, Professors, Scores")
+	err = execStmt(TestDB.ctx, TestDB.conn, "DROP TABLE IF EXISTS Courses, Professors, Scores, ScoreValues, GradeAttempts")
 	if err != nil {
 		return
 	}
@@ -117,7 +122,7 @@ func initDB() (err error) {
 		}
 	}
 
-	professors, err = TestDB.GetLastProfessors()
+	professors, err = TestDB.GetLastProfessors(itpgDB.ProfessorSortRecent, 0, 0)
 	if err != nil {
 		return
 	}
@@ -130,7 +135,7 @@ func initDB() (err error) {
 		}
 	}
 
-	scores, err = TestDB.GetLastScores()
+	scores, err = TestDB.GetLastScores(0, 0)
 	if err != nil {
 		return
 	}
@@ -146,6 +151,166 @@ func TestNew(t *testing.T) {
 	db.Close()
 }
 
+func TestQueryTimeout(t *testing.T) {
+	db, err := New(TestDBUrl, "", 0, context.Background(), itpgDB.WithQueryTimeout(time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err = db.GetLastCourses(0, 0); !errors.Is(err, itpgDB.ErrTimeout) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrTimeout)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "redis",
+		Tag:        "7.2.5-alpine",
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Purge(resource) //nolint:errcheck
+
+	cacheUrl := fmt.Sprintf("redis://%s", resource.GetHostPort("6379/tcp"))
+
+	var cachedDB *DB
+	if err = pool.Retry(func() error {
+		cachedDB, err = New(TestDBUrl, cacheUrl, time.Minute, context.Background())
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer cachedDB.Close()
+
+	before, err := cachedDB.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writes without a cache do not invalidate it, so the cached courses are returned.
+	if err = TestDB.AddCourse(&itpgDB.Course{Code: "GC8F", Name: "How to drive in the snow"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := cachedDB.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != len(before) {
+		t.Errorf("got %d courses, want %d cached courses", len(cached), len(before))
+	}
+
+	if err = cachedDB.AddCourse(&itpgDB.Course{Code: "JZA80", Name: "How to tune a 2JZ"}); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := cachedDB.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before)+2 {
+		t.Errorf("got %d courses, want %d", len(after), len(before)+2)
+	}
+
+	scoresBefore, err := cachedDB.GetScoresByCourseCode(courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cachedDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	scoresAfter, err := cachedDB.GetScoresByCourseCode(courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scoresBefore) == 0 || len(scoresAfter) == 0 {
+		t.Fatalf("got %v and %v, want scores", scoresBefore, scoresAfter)
+	}
+	if scoresAfter[0].Count != scoresBefore[0].Count+1 {
+		t.Errorf("got count %d, want %d", scoresAfter[0].Count, scoresBefore[0].Count+1)
+	}
+
+	// removing a course removes it from its professors, so the professors of the course are invalidated.
+	if err = cachedDB.AddCourseProfessor(professors[0].UUID, "JZA80"); err != nil {
+		t.Fatal(err)
+	}
+
+	if professorsBefore, err := cachedDB.GetProfessorsByCourseCode("JZA80"); err != nil || len(professorsBefore) != 1 {
+		t.Fatalf("got %v, %v, want 1 professor", professorsBefore, err)
+	}
+
+	if err = cachedDB.RemoveCourse("JZA80", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if professorsAfter, err := cachedDB.GetProfessorsByCourseCode("JZA80"); err != nil || len(professorsAfter) != 0 {
+		t.Errorf("got %v, %v, want no professors", professorsAfter, err)
+	}
+
+	// removing a professor removes them from their courses, so the courses of the professor are invalidated.
+	if err = cachedDB.AddProfessor("Master Roshi"); err != nil {
+		t.Fatal(err)
+	}
+
+	roshiUUID, err := cachedDB.GetProfessorUUIDByName("Master Roshi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cachedDB.AddCourseProfessor(roshiUUID, courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	if coursesBefore, err := cachedDB.GetCoursesByProfessorUUID(roshiUUID); err != nil || len(coursesBefore) != 1 {
+		t.Fatalf("got %v, %v, want 1 course", coursesBefore, err)
+	}
+
+	if err = cachedDB.RemoveProfessor(roshiUUID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if coursesAfter, err := cachedDB.GetCoursesByProfessorUUID(roshiUUID); err != nil || len(coursesAfter) != 0 {
+		t.Errorf("got %v, %v, want no courses", coursesAfter, err)
+	}
+
+	// flushing the cache returns the writes made without the cache.
+	if before, err = cachedDB.GetLastCourses(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourse(&itpgDB.Course{Code: "AE86", Name: "How to deliver tofu"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cachedDB.FlushCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	if after, err = cachedDB.GetLastCourses(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before)+1 {
+		t.Errorf("got %d courses, want %d", len(after), len(before)+1)
+	}
+}
+
 func TestAddCourse(t *testing.T) {
 	err := initDB()
 	if err != nil {
@@ -173,6 +338,49 @@ func TestAddCourse(t *testing.T) {
 	}
 }
 
+func TestCourseCredits(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credits, zero := 3, 0
+	creditCourses := []*itpgDB.Course{
+		{Code: "GC8", Name: "Rally driving", Credits: &credits},
+		{Code: "BNR32", Name: "Godzilla maintenance", Credits: &zero},
+	}
+
+	if err = TestDB.AddCourse(creditCourses[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = TestDB.AddCourseMany(creditCourses[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	allCourses, err := TestDB.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, course := range append(creditCourses, courses[0]) {
+		i := slices.IndexFunc(allCourses, func(c *itpgDB.Course) bool { return c.Code == course.Code })
+		if i == -1 {
+			t.Fatalf("course %s not found", course.Code)
+		}
+		if !cmp.Equal(allCourses[i], course) {
+			t.Errorf("got %+v, want %+v", allCourses[i], course)
+		}
+	}
+
+	for _, c := range []int{-1, 31} {
+		err = TestDB.AddCourse(&itpgDB.Course{Code: "EVO9", Name: "Launch control", Credits: &c})
+		if !errors.Is(err, itpgDB.ErrInvalid) {
+			t.Errorf("got %v, want %v", err, itpgDB.ErrInvalid)
+		}
+	}
+}
+
 func TestAddCourseMany(t *testing.T) {
 	err := initDB()
 	if err != nil {
@@ -185,10 +393,40 @@ func TestAddCourseMany(t *testing.T) {
 		{Code: "EK9", Name: "Art of VTEC"},
 	}
 
-	err = TestDB.AddCourseMany(cs)
+	errs, err := TestDB.AddCourseMany(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) != len(cs) {
+		t.Fatalf("got %d, want %d", len(errs), len(cs))
+	}
+
+	failing := []*itpgDB.Course{
+		{Code: "GC8", Name: "Rally Driving"},
+		courses[0],
+		{Code: "FD2", Name: "Drifting 101"},
+	}
+
+	errs, err = TestDB.AddCourseMany(failing)
+	if !errors.Is(err, itpgDB.ErrBatchFailed) {
+		t.Fatalf("got %v, want %v", err, itpgDB.ErrBatchFailed)
+	}
+
+	for i, e := range errs {
+		if failed := i == 1; (e != nil) != failed {
+			t.Errorf("%s: got error %v, want failure %t", failing[i].Code, e, failed)
+		}
+	}
+
+	allCourses, err := TestDB.GetLastCourses(0, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	if len(allCourses) != len(courses)+len(cs) {
+		t.Errorf("got %d, want %d", len(allCourses), len(courses)+len(cs))
+	}
 }
 
 func TestAddProfessor(t *testing.T) {
@@ -220,10 +458,80 @@ func TestAddProfessorMany(t *testing.T) {
 		"baz",
 	}
 
-	err = TestDB.AddProfessorMany(ps)
+	errs, err := TestDB.AddProfessorMany(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) != len(ps) {
+		t.Fatalf("got %d, want %d", len(errs), len(ps))
+	}
+
+	failing := []string{"Master Roshi", "", "Yamcha"}
+
+	errs, err = TestDB.AddProfessorMany(failing)
+	if !errors.Is(err, itpgDB.ErrBatchFailed) {
+		t.Fatalf("got %v, want %v", err, itpgDB.ErrBatchFailed)
+	}
+
+	for i, e := range errs {
+		if failed := i == 1; (e != nil) != failed {
+			t.Errorf("%q: got error %v, want failure %t", failing[i], e, failed)
+		}
+	}
+
+	allProfessors, err := TestDB.GetLastProfessors(itpgDB.ProfessorSortRecent, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allProfessors) != len(professorNames)+len(ps) {
+		t.Errorf("got %d, want %d", len(allProfessors), len(professorNames)+len(ps))
+	}
+}
+
+func TestProfessorNameDedup(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if TestDB.opts, err = itpgDB.NewOptions(itpgDB.WithProfessorNameDedup(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddProfessor("Prof. Oak"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = TestDB.AddProfessor("prof  oak")
+	var likelyDuplicate *itpgDB.LikelyDuplicateError
+	if !errors.As(err, &likelyDuplicate) {
+		t.Fatalf("got %v, want a likely duplicate error", err)
+	}
+
+	if !errors.Is(err, itpgDB.ErrDuplicate) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrDuplicate)
+	}
+
+	professorUUID, err := TestDB.GetProfessorUUIDByName("Prof. Oak")
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	if likelyDuplicate.Professor.UUID != professorUUID || likelyDuplicate.Professor.Name != "Prof. Oak" {
+		t.Errorf("got %+v, want the professor %s", *likelyDuplicate.Professor, professorUUID)
+	}
+
+	// the likely duplicates within a batch are rejected too.
+	errs, err := TestDB.AddProfessorMany([]string{"Ms. Kitty", "ms kitty"})
+	if !errors.Is(err, itpgDB.ErrBatchFailed) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrBatchFailed)
+	}
+
+	if errs[0] != nil || !errors.As(errs[1], &likelyDuplicate) {
+		t.Errorf("got %v, want a likely duplicate error for the second professor only", errs)
+	}
 }
 
 func TestAddCourseProfessor(t *testing.T) {
@@ -277,392 +585,3252 @@ func TestAddCourseProfessorMany(t *testing.T) {
 	if err == nil {
 		t.Error("expected failure")
 	}
-}
 
-func TestRemoveCourse(t *testing.T) {
-	err := initDB()
-	if err != nil {
+	var countBefore, countAfter int
+	if err = TestDB.conn.QueryRow(TestDB.ctx, "SELECT COUNT(*) FROM Scores").Scan(&countBefore); err != nil {
 		t.Fatal(err)
 	}
 
-	err = TestDB.RemoveCourse("CN9A", false)
-	if err == nil {
+	uuids = []string{professors[1].UUID, professors[0].UUID, professors[2].UUID}
+	codes = []string{courses[0].Code, "GC8F", courses[0].Code}
+	if err = TestDB.AddCourseProfessorMany(uuids, codes); err == nil {
 		t.Error("expected failure")
 	}
 
-	err = TestDB.RemoveCourse("CN9A", true)
-	if err != nil {
-		t.Error(err)
+	if err = TestDB.conn.QueryRow(TestDB.ctx, "SELECT COUNT(*) FROM Scores").Scan(&countAfter); err != nil {
+		t.Fatal(err)
 	}
 
-	err = TestDB.RemoveCourse("GC8F", false)
-	if err != nil {
-		t.Error(err)
+	if countAfter != countBefore {
+		t.Errorf("got %d, want %d", countAfter, countBefore)
 	}
 }
 
-func TestRemoveProfessor(t *testing.T) {
+func TestUpdateCourseName(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = TestDB.RemoveProfessor(professors[0].UUID, false)
-	if err == nil {
-		t.Error("expected failure")
+	newName := "How to replace head gaskets, again"
+	if err = TestDB.UpdateCourseName(courses[0].Code, newName); err != nil {
+		t.Fatal(err)
 	}
 
-	err = TestDB.RemoveProfessor(professors[0].UUID, true)
+	allCourses, err := TestDB.GetLastCourses(0, 0)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-}
 
-func TestGetLastCourses(t *testing.T) {
-	err := initDB()
-	if err != nil {
-		t.Fatal(err)
+	if !slices.ContainsFunc(allCourses, func(c *itpgDB.Course) bool { return c.Code == courses[0].Code && c.Name == newName }) {
+		t.Errorf("got %v, want %s renamed to %s", allCourses, courses[0].Code, newName)
 	}
 
-	allCourses, err := TestDB.GetLastCourses()
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[0].Code)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if len(allCourses) == 0 {
-		t.Fatal("got 0 courses")
+	if len(courseScores) == 0 {
+		t.Fatal("got 0 scores")
 	}
 
-	if len(allCourses) != len(courses) {
-		t.Fatal("slices len unequal")
+	if courseScores[0].CourseName != newName {
+		t.Errorf("got %s, want %s", courseScores[0].CourseName, newName)
 	}
 
-	if !cmp.Equal(allCourses, courses) {
-		t.Errorf("got %v, want %v", allCourses, courses)
+	if err = TestDB.UpdateCourseName("GC8F", newName); !errors.Is(err, responses.ErrCourseNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrCourseNotFound)
 	}
 }
 
-func TestGetLastProfessors(t *testing.T) {
+func TestRenameProfessor(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allProfessors, err := TestDB.GetLastProfessors()
-	if err != nil {
-		t.Error(err)
-	}
-
-	if len(allProfessors) == 0 {
-		t.Fatal("got 0 professors")
+	newName := "Takumi Fujiwara"
+	if err = TestDB.RenameProfessor(professors[0].UUID, newName); err != nil {
+		t.Fatal(err)
 	}
 
-	if len(allProfessors) != len(professors) {
-		t.Fatal("slices len unequal")
+	professorUUID, err := TestDB.GetProfessorUUIDByName(newName)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if !cmp.Equal(allProfessors, professors) {
-		t.Errorf("got %v, want %v", allProfessors, professors)
+	if professorUUID != professors[0].UUID {
+		t.Errorf("got %s, want %s", professorUUID, professors[0].UUID)
 	}
-}
 
-func TestGetLastScores(t *testing.T) {
-	err := initDB()
+	professorScores, err := TestDB.GetScoresByProfessorUUID(professors[0].UUID)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allScores, err := TestDB.GetLastScores()
-	if err != nil {
-		t.Error(err)
+	if len(professorScores) == 0 {
+		t.Fatal("got 0 scores")
 	}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
+	if professorScores[0].ProfessorName != newName {
+		t.Errorf("got %s, want %s", professorScores[0].ProfessorName, newName)
 	}
 
-	if len(allScores) != len(scores) {
-		t.Fatal("slices len unequal")
+	if err = TestDB.UpdateProfessorName(professors[0].UUID, newName); err != nil {
+		t.Error(err)
 	}
 
-	if !cmp.Equal(allScores, scores) {
-		t.Errorf("got %v, want %v", allScores, scores)
+	if err = TestDB.RenameProfessor(professors[1].UUID, newName); !errors.Is(err, responses.ErrProfessorExists) {
+		t.Errorf("got %v, want %v", err, responses.ErrProfessorExists)
+	}
+
+	if err = TestDB.RenameProfessor("deadbeef", "Bunta Fujiwara"); !errors.Is(err, responses.ErrProfessorNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrProfessorNotFound)
 	}
 }
 
-func TestGetCoursesByProfessorUUID(t *testing.T) {
+func TestMapError(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allCourses, err := TestDB.GetCoursesByProfessorUUID(professors[0].UUID)
-	if err != nil {
-		t.Error(err)
+	if err = TestDB.AddCourse(courses[0]); !errors.Is(err, itpgDB.ErrDuplicate) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrDuplicate)
 	}
 
-	if len(allCourses) == 0 {
-		t.Fatal("got 0 courses")
+	if err = TestDB.AddCourse(&itpgDB.Course{Code: "GC8F", Name: ""}); !errors.Is(err, itpgDB.ErrInvalid) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrInvalid)
 	}
 
-	if !cmp.Equal(allCourses[0], courses[len(courses)-1]) {
-		t.Errorf("got %v, want %v", allCourses[0], courses[len(courses)-1])
+	if err = TestDB.RemoveCourse(courses[0].Code, false); !errors.Is(err, itpgDB.ErrForeignKey) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrForeignKey)
+	}
+
+	if err = TestDB.GradeCourseProfessor("deadbeef", courses[0].Code, "joe", [3]float32{1, 2, 3}); !errors.Is(err, itpgDB.ErrForeignKey) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrForeignKey)
+	}
+
+	if _, err = TestDB.GetProfessorUUIDByName("Bunta Fujiwara"); !errors.Is(err, itpgDB.ErrNotFound) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrNotFound)
 	}
 }
 
-func TestGetProfessorsByCourseCode(t *testing.T) {
+func TestRemoveCourseProfessor(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allProfessors, err := TestDB.GetProfessorsByCourseCode("S209")
+	if err = TestDB.RemoveCourseProfessor(professors[0].UUID, courses[0].Code); !errors.Is(err, itpgDB.ErrForeignKey) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrForeignKey)
+	}
+
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[0].Code)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(courseScores, func(s *itpgDB.Score) bool { return s.ProfessorUUID == professors[0].UUID && s.Count == 1 }) {
+		t.Errorf("got %v, want the grade of %s kept", courseScores, professors[0].UUID)
 	}
 
-	if len(allProfessors) == 0 {
-		t.Fatal("got 0 professors")
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
 	}
 
-	if !cmp.Equal(allProfessors[0], professors[len(professors)-1]) {
-		t.Errorf("got %v, want %v", allProfessors[0], professors[len(professors)-1])
+	if err = TestDB.RemoveCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	courseProfessors, err := TestDB.GetProfessorsByCourseCode(courses[1].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(courseProfessors, func(p *itpgDB.Professor) bool { return p.UUID == professors[0].UUID }) {
+		t.Errorf("got %v, want %s removed", courseProfessors, professors[0].UUID)
+	}
+
+	if err = TestDB.RemoveCourseProfessor(professors[0].UUID, courses[1].Code); !errors.Is(err, responses.ErrCourseProfessorNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrCourseProfessorNotFound)
 	}
 }
 
-func TestGetProfessorUUIDByName(t *testing.T) {
+func TestRemoveCourse(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	uuid, err := TestDB.GetProfessorUUIDByName(professors[0].Name)
+	err = TestDB.RemoveCourse("CN9A", false)
+	if err == nil {
+		t.Error("expected failure")
+	}
+
+	err = TestDB.RemoveCourse("CN9A", true)
 	if err != nil {
-		t.Fatal(err)
+		t.Error(err)
 	}
 
-	if uuid != professors[0].UUID {
-		t.Errorf("got %s, want %s", uuid, professors[0].UUID)
+	err = TestDB.RemoveCourse("GC8F", false)
+	if err != nil {
+		t.Error(err)
 	}
 }
 
-func TestGetScoresByProfessorUUID(t *testing.T) {
+func TestRemoveProfessor(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allScores, err := TestDB.GetScoresByProfessorUUID(professors[0].UUID)
+	err = TestDB.RemoveProfessor(professors[0].UUID, false)
+	if err == nil {
+		t.Error("expected failure")
+	}
+
+	err = TestDB.RemoveProfessor(professors[0].UUID, true)
 	if err != nil {
 		t.Error(err)
 	}
+}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
+func TestSoftRemove(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if !cmp.Equal(allScores[0], scores[0]) {
-		t.Errorf("got %v, want %v", allScores[0], scores[0])
+	if err = TestDB.SoftRemoveCourse(courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+	if err = TestDB.SoftRemoveCourse(courses[0].Code); !errors.Is(err, responses.ErrCourseNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrCourseNotFound)
+	}
+	if err = TestDB.SoftRemoveProfessor(professors[1].UUID); err != nil {
+		t.Fatal(err)
 	}
-}
 
-func TestGetScoresByProfessorName(t *testing.T) {
-	err := initDB()
+	allCourses, err := TestDB.GetLastCourses(0, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(allCourses) != len(courses)-1 {
+		t.Errorf("got %d courses, want %d", len(allCourses), len(courses)-1)
+	}
 
-	allScores, err := TestDB.GetScoresByProfessorName(professors[0].Name)
+	allProfessors, err := TestDB.GetLastProfessors(itpgDB.ProfessorSortRecent, 0, 0)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if len(allProfessors) != len(professors)-1 {
+		t.Errorf("got %d professors, want %d", len(allProfessors), len(professors)-1)
 	}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(courseScores) != 0 {
+		t.Errorf("got %d scores, want 0", len(courseScores))
 	}
 
-	if !cmp.Equal(allScores[0], scores[0]) {
-		t.Errorf("got %v, want %v", allScores[0], scores[0])
+	if _, err = TestDB.GetProfessorDetail(professors[1].UUID); !errors.Is(err, responses.ErrProfessorNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrProfessorNotFound)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", [3]float32{1, 2, 3}); !errors.Is(err, itpgDB.ErrNotFound) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrNotFound)
+	}
+
+	trash, err := TestDB.GetTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash.Courses) != 1 || trash.Courses[0].Code != courses[0].Code || trash.Courses[0].DeletedAt.IsZero() {
+		t.Errorf("got unexpected deleted courses %v", trash.Courses)
+	}
+	if len(trash.Professors) != 1 || trash.Professors[0].UUID != professors[1].UUID || trash.Professors[0].DeletedAt.IsZero() {
+		t.Errorf("got unexpected deleted professors %v", trash.Professors)
+	}
+
+	if err = TestDB.RestoreCourse(courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+	if err = TestDB.RestoreCourse(courses[0].Code); !errors.Is(err, responses.ErrCourseNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrCourseNotFound)
+	}
+	if err = TestDB.RestoreProfessor(professors[1].UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	courseScores, err = TestDB.GetScoresByCourseCode(courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(courseScores) != 1 {
+		t.Errorf("got %d scores, want 1", len(courseScores))
+	}
+
+	if trash, err = TestDB.GetTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trash.Courses) != 0 || len(trash.Professors) != 0 {
+		t.Errorf("got %d deleted courses and %d deleted professors, want none", len(trash.Courses), len(trash.Professors))
 	}
 }
 
-func TestGetScoresByProfessorNameLike(t *testing.T) {
+func TestGetLastCourses(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allScores, err := TestDB.GetScoresByProfessorNameLike(professors[0].Name[:5])
+	allCourses, err := TestDB.GetLastCourses(0, 0)
 	if err != nil {
 		t.Error(err)
 	}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
+	if len(allCourses) == 0 {
+		t.Fatal("got 0 courses")
 	}
 
-	if !cmp.Equal(allScores[0], scores[0]) {
-		t.Errorf("got %v, want %v", allScores[0], scores[0])
+	if len(allCourses) != len(courses) {
+		t.Fatal("slices len unequal")
+	}
+
+	if !cmp.Equal(allCourses, courses) {
+		t.Errorf("got %v, want %v", allCourses, courses)
 	}
 }
 
-func TestGetScoresByCourseName(t *testing.T) {
+func TestGetLastCoursesPagination(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allScores, err := TestDB.GetScoresByCourseName("How to replace head gaskets")
-	if err != nil {
-		t.Error(err)
+	tests := []struct {
+		limit, offset int
+		expected      []*itpgDB.Course
+	}{
+		{2, 0, []*itpgDB.Course{courses[0], courses[1]}},
+		{2, 2, []*itpgDB.Course{courses[2], courses[3]}},
+		{1, 1, []*itpgDB.Course{courses[1]}},
+		{2, len(courses), nil},
+		{-1, -1, courses},
 	}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
-	}
+	for _, test := range tests {
+		page, err := TestDB.GetLastCourses(test.limit, test.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
-		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+		if !cmp.Equal(page, test.expected) {
+			t.Errorf("limit %d, offset %d: got %v, want %v", test.limit, test.offset, page, test.expected)
+		}
 	}
 }
 
-func TestGetScoresByCourseNameLike(t *testing.T) {
+func TestGetLastProfessors(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allScores, err := TestDB.GetScoresByCourseNameLike("How to rep")
+	allProfessors, err := TestDB.GetLastProfessors(itpgDB.ProfessorSortRecent, 0, 0)
 	if err != nil {
 		t.Error(err)
 	}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
+	if len(allProfessors) == 0 {
+		t.Fatal("got 0 professors")
 	}
 
-	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
-		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+	if len(allProfessors) != len(professors) {
+		t.Fatal("slices len unequal")
+	}
+
+	if !cmp.Equal(allProfessors, professors) {
+		t.Errorf("got %v, want %v", allProfessors, professors)
 	}
 }
 
-func TestGetScoresByCourseCode(t *testing.T) {
+func TestGetLastProfessorsSortName(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allScores, err := TestDB.GetScoresByCourseCode("S209")
+	allProfessors, err := TestDB.GetLastProfessors(itpgDB.ProfessorSortName, 0, 0)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
+	if len(allProfessors) != len(professorNames) {
+		t.Fatal("slices len unequal")
 	}
 
-	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
-		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+	names := []string{}
+	for _, p := range allProfessors {
+		names = append(names, p.Name)
+	}
+
+	expected := slices.Clone(professorNames)
+	slices.Sort(expected)
+
+	if !cmp.Equal(names, expected) {
+		t.Errorf("got %v, want %v", names, expected)
 	}
 }
 
-func TestGetScoresByCourseCodeLike(t *testing.T) {
+func TestGetLastProfessorsSortRating(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	allScores, err := TestDB.GetScoresByCourseCodeLike("S2")
+	if _, err = TestDB.AddProfessorMany([]string{"Master Roshi", "Yamcha"}); err != nil {
+		t.Fatal(err)
+	}
+
+	roshiUUID, err := TestDB.GetProfessorUUIDByName("Master Roshi")
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if len(allScores) == 0 {
-		t.Fatal("got 0 scores")
+	if err = TestDB.GradeCourseProfessor(roshiUUID, courses[0].Code, "joe", [3]float32{5, 5, 5}); err != nil {
+		t.Fatal(err)
 	}
 
-	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
-		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+	allProfessors, err := TestDB.GetLastProfessors(itpgDB.ProfessorSortRating, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allProfessors) != len(professorNames)+2 {
+		t.Fatal("slices len unequal")
+	}
+
+	if allProfessors[0].Name != "Master Roshi" {
+		t.Errorf("got %s, want %s", allProfessors[0].Name, "Master Roshi")
+	}
+
+	if allProfessors[len(allProfessors)-1].Name != "Yamcha" {
+		t.Errorf("got %s, want %s", allProfessors[len(allProfessors)-1].Name, "Yamcha")
+	}
+
+	if _, err = TestDB.GetLastProfessors("foo", 0, 0); err == nil {
+		t.Error("expected failure")
 	}
 }
 
-func TestGradeCourseProfessor(t *testing.T) {
+func TestGetRandomCourses(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	profScores := [3]float32{5.00, 4.00, 3.00}
-	err = TestDB.GradeCourseProfessor(professors[1].UUID, "CN9A", "joe", profScores)
+	randomCourses, err := TestDB.GetRandomCourses(2)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	err = TestDB.GradeCourseProfessor(professors[1].UUID, "CN9A", "joe", profScores)
-	if err == nil {
-		t.Error("expected failure")
+	if len(randomCourses) != 2 {
+		t.Fatalf("got %d, want %d", len(randomCourses), 2)
 	}
 
-	err = TestDB.GradeCourseProfessor("1", "GC8F", "joe", profScores)
-	if err == nil {
-		t.Error("expected failure")
+	for _, c := range randomCourses {
+		if !slices.ContainsFunc(courses, func(course *itpgDB.Course) bool { return cmp.Equal(course, c) }) {
+			t.Errorf("got %v, want one of %v", c, courses)
+		}
+	}
+
+	randomCourses, err = TestDB.GetRandomCourses(len(courses) + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(randomCourses) != len(courses) {
+		t.Errorf("got %d, want %d", len(randomCourses), len(courses))
 	}
 }
 
-func TestCheckGraded(t *testing.T) {
+func TestGetCoursesBetween(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	hasher := xxh3.New()
-	if _, err := hasher.WriteString("joe" + courses[0].Code + professors[0].UUID); err != nil {
+	time.Sleep(10 * time.Millisecond)
+	mid := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	newCourse := &itpgDB.Course{Code: "GC8", Name: "Rally Driving"}
+	if err = TestDB.AddCourse(newCourse); err != nil {
 		t.Fatal(err)
 	}
-	hash := hasher.Sum64()
 
-	graded, err := TestDB.checkGraded(hash)
+	newCourses, err := TestDB.GetCoursesBetween(mid, time.Now().Add(time.Second))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if graded {
-		t.Errorf("got %v, want %v", graded, false)
+	if len(newCourses) != 1 || !cmp.Equal(newCourses[0], newCourse) {
+		t.Errorf("got %v, want %v", newCourses, []*itpgDB.Course{newCourse})
 	}
 
-	grades := [3]float32{5.00, 4.00, 3.00}
-	err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", grades)
+	oldCourses, err := TestDB.GetCoursesBetween(mid.Add(-time.Hour), mid)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	graded, err = TestDB.checkGraded(hash)
+	if len(oldCourses) != len(courses) {
+		t.Errorf("got %d, want %d", len(oldCourses), len(courses))
+	}
+
+	noCourses, err := TestDB.GetCoursesBetween(mid.Add(time.Hour), mid.Add(2*time.Hour))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if !graded {
-		t.Errorf("got %v, want %v", graded, true)
+	if len(noCourses) != 0 {
+		t.Errorf("got %d, want %d", len(noCourses), 0)
 	}
 }
 
-func TestAverageScore(t *testing.T) {
-	scores := []float32{5, 4, 3}
-	avgScore := averageScore(scores...)
-	expected := float32((5 + 4 + 3) / 3)
-	if avgScore != float32(expected) {
-		t.Errorf("got %f, want %f", avgScore, expected)
+func TestGetProfessorsBetween(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	mid := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	if err = TestDB.AddProfessor("Master Roshi"); err != nil {
+		t.Fatal(err)
+	}
+
+	newProfessors, err := TestDB.GetProfessorsBetween(mid, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(newProfessors) != 1 || newProfessors[0].Name != "Master Roshi" {
+		t.Errorf("got %v, want %s", newProfessors, "Master Roshi")
+	}
+
+	oldProfessors, err := TestDB.GetProfessorsBetween(mid.Add(-time.Hour), mid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(oldProfessors) != len(professorNames) {
+		t.Errorf("got %d, want %d", len(oldProfessors), len(professorNames))
 	}
 }
 
-func TestExecStmt(t *testing.T) {
+func TestGetLastScores(t *testing.T) {
 	err := initDB()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = execStmt(TestDB.ctx, TestDB.conn, "SELECT * FROM Courses")
+	allScores, err := TestDB.GetLastScores(0, 0)
 	if err != nil {
 		t.Error(err)
 	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if len(allScores) != len(scores) {
+		t.Fatal("slices len unequal")
+	}
+
+	if !cmp.Equal(allScores, scores) {
+		t.Errorf("got %v, want %v", allScores, scores)
+	}
+}
+
+func TestExport(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exportedCourses := []*itpgDB.Course{}
+	if err = TestDB.ExportCourses(func(c *itpgDB.Course) error {
+		exportedCourses = append(exportedCourses, c)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sortedCourses := slices.Clone(courses)
+	byCode := func(a, b *itpgDB.Course) int { return strings.Compare(a.Code, b.Code) }
+	slices.SortFunc(sortedCourses, byCode)
+	slices.SortFunc(exportedCourses, byCode)
+	if !cmp.Equal(exportedCourses, sortedCourses) {
+		t.Errorf("got %v, want %v", exportedCourses, sortedCourses)
+	}
+
+	exportedProfessors := []*itpgDB.Professor{}
+	if err = TestDB.ExportProfessors(func(p *itpgDB.Professor) error {
+		exportedProfessors = append(exportedProfessors, p)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exportedProfessors) != len(professors) {
+		t.Errorf("got %d professors, want %d", len(exportedProfessors), len(professors))
+	}
+
+	exportedScores := []*itpgDB.Score{}
+	if err = TestDB.ExportScores(func(s *itpgDB.Score) error {
+		exportedScores = append(exportedScores, s)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exportedScores) != len(scores) {
+		t.Fatalf("got %d scores, want %d", len(exportedScores), len(scores))
+	}
+
+	for _, s := range exportedScores {
+		i := slices.IndexFunc(scores, func(score *itpgDB.Score) bool {
+			return score.CourseCode == s.CourseCode && score.ProfessorUUID == s.ProfessorUUID
+		})
+		if i == -1 || scores[i].ScoreAverage != s.ScoreAverage || scores[i].Count != s.Count {
+			t.Errorf("got unexpected score %+v", *s)
+		}
+	}
+
+	// an error returned by the callback stops the export.
+	errStop := errors.New("stop")
+	calls := 0
+	if err = TestDB.ExportCourses(func(*itpgDB.Course) error {
+		calls++
+		return errStop
+	}); !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("got %v after %d calls, want %v after 1 call", err, calls, errStop)
+	}
+
+	// the query timeout does not cut off an export whose rows are consumed slowly.
+	TestDB.opts.QueryTimeout = time.Millisecond
+	defer func() { TestDB.opts.QueryTimeout = 0 }()
+	calls = 0
+	if err = TestDB.ExportCourses(func(*itpgDB.Course) error {
+		calls++
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}); err != nil || calls != len(courses) {
+		t.Errorf("got %v after %d calls, want nil after %d calls", err, calls, len(courses))
+	}
+}
+
+func TestGetAllScoresStream(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamed := []*itpgDB.StreamedScore{}
+	if err = TestDB.GetAllScoresStream(time.Time{}, func(s *itpgDB.StreamedScore) error {
+		streamed = append(streamed, s)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streamed) != len(scores) {
+		t.Fatalf("got %d scores, want %d", len(streamed), len(scores))
+	}
+
+	for i, s := range streamed {
+		j := slices.IndexFunc(scores, func(score *itpgDB.Score) bool {
+			return score.CourseCode == s.CourseCode && score.ProfessorUUID == s.ProfessorUUID
+		})
+		if j == -1 || scores[j].ScoreAverage != s.ScoreAverage || scores[j].Count != s.Count || scores[j].ProfessorName != s.ProfessorName || scores[j].CourseName != s.CourseName {
+			t.Errorf("got unexpected score %+v", *s)
+		}
+		if s.InsertedAt.IsZero() {
+			t.Errorf("got zero insertion time for score %+v", *s)
+		}
+		if i > 0 && s.InsertedAt.Before(streamed[i-1].InsertedAt) {
+			t.Errorf("got scores not ordered by insertion time")
+		}
+	}
+
+	since := streamed[len(streamed)-1].InsertedAt
+	recent := []*itpgDB.StreamedScore{}
+	if err = TestDB.GetAllScoresStream(since, func(s *itpgDB.StreamedScore) error {
+		recent = append(recent, s)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recent) == 0 || len(recent) > len(streamed) {
+		t.Errorf("got %d scores graded since %v, want between 1 and %d", len(recent), since, len(streamed))
+	}
+
+	calls := 0
+	if err = TestDB.GetAllScoresStream(since.Add(time.Hour), func(*itpgDB.StreamedScore) error {
+		calls++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 0 {
+		t.Errorf("got %d scores graded in the future, want 0", calls)
+	}
+
+	// the query timeout does not cut off a stream whose rows are consumed slowly.
+	TestDB.opts.QueryTimeout = time.Millisecond
+	defer func() { TestDB.opts.QueryTimeout = 0 }()
+	calls = 0
+	if err = TestDB.GetAllScoresStream(time.Time{}, func(*itpgDB.StreamedScore) error {
+		calls++
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}); err != nil || calls != len(scores) {
+		t.Errorf("got %v after %d calls, want nil after %d calls", err, calls, len(scores))
+	}
+}
+
+func TestGetCatalogSince(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := TestDB.GetCatalogSince(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(catalog.Courses) != len(courses) || len(catalog.Professors) != len(professors) {
+		t.Errorf("got %d courses and %d professors, want %d and %d", len(catalog.Courses), len(catalog.Professors), len(courses), len(professors))
+	}
+	if catalog.Version.IsZero() {
+		t.Error("got zero version, want the time of the last addition")
+	}
+
+	unchanged, err := TestDB.GetCatalogSince(catalog.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unchanged.Courses) != 0 || len(unchanged.Professors) != 0 || !unchanged.Version.Equal(catalog.Version) {
+		t.Errorf("got %+v, want no changes at version %v", *unchanged, catalog.Version)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	newCourse := &itpgDB.Course{Code: "GC8", Name: "Rally Driving"}
+	if err = TestDB.AddCourse(newCourse); err != nil {
+		t.Fatal(err)
+	}
+	if err = TestDB.AddProfessor("Master Roshi"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := TestDB.GetCatalogSince(catalog.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes.Courses) != 1 || !cmp.Equal(changes.Courses[0], newCourse) {
+		t.Errorf("got %v, want %v", changes.Courses, []*itpgDB.Course{newCourse})
+	}
+	if len(changes.Professors) != 1 || changes.Professors[0].Name != "Master Roshi" {
+		t.Errorf("got %v, want Master Roshi", changes.Professors)
+	}
+	if !changes.Version.After(catalog.Version) {
+		t.Errorf("got version %v, want after %v", changes.Version, catalog.Version)
+	}
+}
+
+func TestGetCoursesByProfessorUUID(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allCourses, err := TestDB.GetCoursesByProfessorUUID(professors[0].UUID)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allCourses) == 0 {
+		t.Fatal("got 0 courses")
+	}
+
+	if !cmp.Equal(allCourses[0], courses[len(courses)-1]) {
+		t.Errorf("got %v, want %v", allCourses[0], courses[len(courses)-1])
+	}
+}
+
+func TestGetUngradedCoursesByProfessorUUID(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, course := range courses[1:3] {
+		if err = TestDB.AddCourseProfessor(professors[0].UUID, course.Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[2].Code, "joe", [3]float32{4, 4, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	ungradedCourses, err := TestDB.GetUngradedCoursesByProfessorUUID(professors[0].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ungradedCourses) != 1 {
+		t.Fatalf("got %d courses, want %d", len(ungradedCourses), 1)
+	}
+
+	if !cmp.Equal(ungradedCourses[0], courses[1]) {
+		t.Errorf("got %v, want %v", ungradedCourses[0], courses[1])
+	}
+
+	ungradedCourses, err = TestDB.GetUngradedCoursesByProfessorUUID(professors[1].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ungradedCourses) != 0 {
+		t.Errorf("got %d courses, want %d", len(ungradedCourses), 0)
+	}
+}
+
+func TestGetProfessorsByCourseCode(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allProfessors, err := TestDB.GetProfessorsByCourseCode("S209")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allProfessors) == 0 {
+		t.Fatal("got 0 professors")
+	}
+
+	if !cmp.Equal(allProfessors[0], professors[len(professors)-1]) {
+		t.Errorf("got %v, want %v", allProfessors[0], professors[len(professors)-1])
+	}
+}
+
+func TestGetProfessorUUIDByName(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uuid, err := TestDB.GetProfessorUUIDByName(professors[0].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uuid != professors[0].UUID {
+		t.Errorf("got %s, want %s", uuid, professors[0].UUID)
+	}
+}
+
+func TestGetScoresByProfessorUUID(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allScores, err := TestDB.GetScoresByProfessorUUID(professors[0].UUID)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if !cmp.Equal(allScores[0], scores[0]) {
+		t.Errorf("got %v, want %v", allScores[0], scores[0])
+	}
+}
+
+func TestScoreVerified(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if TestDB.opts, err = itpgDB.NewOptions(itpgDB.WithMinGradesForVerified(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	verified := func() bool {
+		professorScores, err := TestDB.GetScoresByProfessorUUID(professors[0].UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(professorScores) != 1 {
+			t.Fatalf("got %d scores, want 1", len(professorScores))
+		}
+		return professorScores[0].Verified
+	}
+
+	if verified() {
+		t.Error("expected score with 1 grade to not be verified")
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", [3]float32{4, 4, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !verified() {
+		t.Error("expected score with 2 grades to be verified")
+	}
+}
+
+func TestGetScoresByProfessorName(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allScores, err := TestDB.GetScoresByProfessorName(professors[0].Name)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if !cmp.Equal(allScores[0], scores[0]) {
+		t.Errorf("got %v, want %v", allScores[0], scores[0])
+	}
+}
+
+func TestGetScoresByProfessorNameLike(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allScores, err := TestDB.GetScoresByProfessorNameLike(professors[0].Name[:5], itpgDB.MatchSubstring)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if !cmp.Equal(allScores[0], scores[0]) {
+		t.Errorf("got %v, want %v", allScores[0], scores[0])
+	}
+}
+
+func TestGetScoresByProfessorNameLikeMode(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		search string
+		mode   itpgDB.MatchMode
+		match  bool
+	}{
+		{"Prof", itpgDB.MatchPrefix, true},
+		{"essor", itpgDB.MatchPrefix, false},
+		{"essor", itpgDB.MatchSubstring, true},
+	}
+
+	for _, test := range tests {
+		scores, err := TestDB.GetScoresByProfessorNameLike(test.search, test.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		match := slices.ContainsFunc(scores, func(score *itpgDB.Score) bool { return score.ProfessorName == "Professor Oak" })
+		if match != test.match {
+			t.Errorf("%s %s: got match %v, want %v", test.mode, test.search, match, test.match)
+		}
+	}
+}
+
+func TestGetScoresByCourseName(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allScores, err := TestDB.GetScoresByCourseName("How to replace head gaskets")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
+		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+	}
+}
+
+func TestGetScoresByCourseNameLike(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allScores, err := TestDB.GetScoresByCourseNameLike("How to rep", itpgDB.MatchSubstring)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
+		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+	}
+}
+
+func TestGetScoresByCourseCode(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allScores, err := TestDB.GetScoresByCourseCode("S209")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
+		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+	}
+}
+
+func TestGetScoresByCourseCodeLike(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allScores, err := TestDB.GetScoresByCourseCodeLike("S2")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(allScores) == 0 {
+		t.Fatal("got 0 scores")
+	}
+
+	if !cmp.Equal(allScores[0], scores[len(scores)-1]) {
+		t.Errorf("got %v, want %v", allScores[0], scores[len(scores)-1])
+	}
+}
+
+func TestGetScoresBetween(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+	from := time.Now()
+	time.Sleep(time.Millisecond)
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "bob", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+	to := time.Now()
+
+	// only the grade given within the time range is counted.
+	windowScores, err := TestDB.GetScoresBetween(professors[0].UUID, "", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windowScores) != 1 {
+		t.Fatalf("got %d scores, want 1", len(windowScores))
+	}
+
+	s := windowScores[0]
+	if s.CourseCode != courses[0].Code || s.ScoreTeaching != 1 || s.ScoreCourseWork != 2 || s.ScoreLearning != 3 || s.Count != 1 {
+		t.Errorf("got %+v, want the scores of the grade given within the time range", *s)
+	}
+
+	// the grades given after the time range are not counted.
+	windowScores, err = TestDB.GetScoresBetween("", courses[0].Code, time.Unix(0, 0), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windowScores) != 1 {
+		t.Fatalf("got %d scores, want 1", len(windowScores))
+	}
+
+	s = windowScores[0]
+	if s.ScoreTeaching != scores[0].ScoreTeaching || s.ScoreAverage != scores[0].ScoreAverage || s.Count != 1 {
+		t.Errorf("got %+v, want %+v", *s, *scores[0])
+	}
+
+	windowScores, err = TestDB.GetScoresBetween("", "", time.Unix(0, 0), to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windowScores) != len(scores) {
+		t.Errorf("got %d scores, want %d", len(windowScores), len(scores))
+	}
+
+	for _, s := range windowScores {
+		if s.CourseCode == courses[0].Code && s.Count != 2 {
+			t.Errorf("got %d grades, want 2", s.Count)
+		}
+	}
+}
+
+func TestGetScoresBySearch(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"Oak", 1},
+		{courses[0].Code, 1},
+		{"head gaskets", 1},
+		{"How to", 3},
+		{"o", 4},
+		{"Master Roshi", 0},
+	}
+
+	for _, test := range tests {
+		allScores, err := TestDB.GetScoresBySearch(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(allScores) != test.expected {
+			t.Errorf("%s: got %d, want %d", test.query, len(allScores), test.expected)
+		}
+
+		seen := map[string]bool{}
+		for _, score := range allScores {
+			key := score.ProfessorUUID + score.CourseCode
+			if seen[key] {
+				t.Errorf("%s: got duplicate score for %s and %s", test.query, score.ProfessorUUID, score.CourseCode)
+			}
+			seen[key] = true
+		}
+	}
+
+	allScores, err := TestDB.GetScoresBySearch("Oak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allScores) == 1 && allScores[0].ProfessorName != professorNames[2] {
+		t.Errorf("got %s, want %s", allScores[0].ProfessorName, professorNames[2])
+	}
+}
+
+func TestSearch(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := TestDB.Search("onizuka GREAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &itpgDB.SearchResult{
+		Kind:            itpgDB.SearchKindProfessor,
+		ID:              professors[0].UUID,
+		Name:            professors[0].Name,
+		ScoreTeaching:   scores[0].ScoreTeaching,
+		ScoreCourseWork: scores[0].ScoreCourseWork,
+		ScoreLearning:   scores[0].ScoreLearning,
+		ScoreAverage:    scores[0].ScoreAverage,
+		Count:           1,
+	}
+	if len(results) != 1 || !cmp.Equal(results[0], expected) {
+		t.Fatalf("got %v, want %v", results, expected)
+	}
+
+	if err = TestDB.UpdateProfessorName(professors[0].UUID, "Eikichi Onizuka"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.RemoveCourse(courses[2].Code, true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"eikichi", []string{professors[0].UUID}},
+		{"great teacher", []string{}},
+		{"how to", []string{courses[0].Code, courses[3].Code}},
+		{courses[1].Code, []string{courses[1].Code}},
+		{courses[2].Code, []string{}},
+		{"zzz", []string{}},
+		{"!?", []string{}},
+	}
+
+	for _, test := range tests {
+		results, err := TestDB.Search(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+		slices.Sort(ids)
+		slices.Sort(test.expected)
+		if !slices.Equal(ids, test.expected) {
+			t.Errorf("%q: got %v, want %v", test.query, ids, test.expected)
+		}
+	}
+}
+
+func TestGetScoreTrend(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddProfessor("Master Roshi"); err != nil {
+		t.Fatal(err)
+	}
+
+	professorUUID, err := TestDB.GetProfessorUUIDByName("Master Roshi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourseProfessor(professorUUID, courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	grades := []struct {
+		grades     [3]float32
+		insertedAt time.Time
+	}{
+		{[3]float32{1, 2, 3}, time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)},
+		{[3]float32{3, 4, 5}, time.Date(2024, time.January, 20, 12, 0, 0, 0, time.UTC)},
+		{[3]float32{5, 5, 5}, time.Date(2024, time.March, 5, 12, 0, 0, 0, time.UTC)},
+	}
+
+	stmt := `
+		INSERT INTO Scores (
+			hash,
+			professor_uuid,
+			course_code,
+			score_teaching,
+			score_coursework,
+			score_learning,
+			inserted_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	for i, g := range grades {
+		if err = execStmt(TestDB.ctx, TestDB.conn, stmt, fmt.Sprintf("%d", i), professorUUID, courses[0].Code, g.grades[0], g.grades[1], g.grades[2], g.insertedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trend, err := TestDB.GetScoreTrend(professorUUID, courses[0].Code, itpgDB.TrendBucketMonth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*itpgDB.ScoreTrendPoint{
+		{Start: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), ScoreTeaching: 2, ScoreCourseWork: 3, ScoreLearning: 4, ScoreAverage: 3, Count: 2},
+		{Start: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), ScoreTeaching: 5, ScoreCourseWork: 5, ScoreLearning: 5, ScoreAverage: 5, Count: 1},
+	}
+
+	if !cmp.Equal(trend, expected) {
+		t.Errorf("got %v, want %v", trend, expected)
+	}
+
+	trend, err = TestDB.GetScoreTrend(professorUUID, courses[0].Code, itpgDB.TrendBucketYear)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trend) != 1 || trend[0].Count != len(grades) {
+		t.Errorf("got %v, want 1 bucket with %d grades", trend, len(grades))
+	}
+
+	if _, err = TestDB.GetScoreTrend(professorUUID, courses[0].Code, "decade"); err == nil {
+		t.Error("expected failure")
+	}
+}
+
+func TestGetScoreHistoryByCourseCode(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	course := &itpgDB.Course{Code: "MA101", Name: "Calculus"}
+	if err = TestDB.AddCourse(course); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, professor := range professors[:2] {
+		if err = TestDB.AddCourseProfessor(professor.UUID, course.Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	grades := []struct {
+		professor  int
+		grades     [3]float32
+		insertedAt time.Time
+	}{
+		{0, [3]float32{1, 2, 3}, time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)},
+		{1, [3]float32{3, 4, 5}, time.Date(2024, time.January, 20, 12, 0, 0, 0, time.UTC)},
+		{0, [3]float32{5, 5, 5}, time.Date(2024, time.March, 5, 12, 0, 0, 0, time.UTC)},
+	}
+
+	stmt := `
+		INSERT INTO Scores (
+			hash,
+			professor_uuid,
+			course_code,
+			score_teaching,
+			score_coursework,
+			score_learning,
+			inserted_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	for i, g := range grades {
+		if err = execStmt(TestDB.ctx, TestDB.conn, stmt, fmt.Sprintf("%d", i), professors[g.professor].UUID, course.Code, g.grades[0], g.grades[1], g.grades[2], g.insertedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := TestDB.GetScoreHistoryByCourseCode(course.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*itpgDB.ScoreTrendPoint{
+		{Start: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), ScoreTeaching: 2, ScoreCourseWork: 3, ScoreLearning: 4, ScoreAverage: 3, Count: 2},
+		{Start: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), ScoreTeaching: 5, ScoreCourseWork: 5, ScoreLearning: 5, ScoreAverage: 5, Count: 1},
+	}
+
+	if !cmp.Equal(history, expected) {
+		t.Errorf("got %v, want %v", history, expected)
+	}
+
+	history, err = TestDB.GetScoreHistoryByCourseCode("XX999")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 0 {
+		t.Errorf("got %v, want no history", history)
+	}
+}
+
+func TestGetScoreDistributionByProfessorUUID(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddProfessor("Master Roshi"); err != nil {
+		t.Fatal(err)
+	}
+
+	professorUUID, err := TestDB.GetProfessorUUIDByName("Master Roshi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourseProfessor(professorUUID, courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	distribution, err := TestDB.GetScoreDistributionByProfessorUUID(professorUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *distribution != (itpgDB.ScoreDistribution{}) {
+		t.Errorf("got %+v, want no grades", *distribution)
+	}
+
+	grades := map[string][3]float32{
+		"joe": {0, 1, 5},
+		"bob": {0.5, 4.99, 5},
+		"ann": {2.5, 3, 4},
+	}
+	for username, g := range grades {
+		if err = TestDB.GradeCourseProfessor(professorUUID, courses[0].Code, username, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	distribution, err = TestDB.GetScoreDistributionByProfessorUUID(professorUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := itpgDB.ScoreDistribution{
+		Teaching:   [5]int{2, 0, 1, 0, 0},
+		CourseWork: [5]int{0, 1, 0, 1, 1},
+		Learning:   [5]int{0, 0, 0, 0, 3},
+	}
+	if *distribution != expected {
+		t.Errorf("got %+v, want %+v", *distribution, expected)
+	}
+}
+
+func TestGetProfessorsForCourses(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddProfessor("Master Roshi"); err != nil {
+		t.Fatal(err)
+	}
+
+	roshiUUID, err := TestDB.GetProfessorUUIDByName("Master Roshi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, course := range courses[:2] {
+		if err = TestDB.AddCourseProfessor(roshiUUID, course.Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = TestDB.GradeCourseProfessor(roshiUUID, courses[0].Code, "joe", [3]float32{5, 5, 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	scores, err := TestDB.GetProfessorsForCourses([]string{courses[0].Code, courses[1].Code, "XX999"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scores) != 2 {
+		t.Fatalf("got %d courses, want %d", len(scores), 2)
+	}
+
+	for _, course := range courses[:2] {
+		if len(scores[course.Code]) != 2 {
+			t.Fatalf("got %d professors for %s, want %d", len(scores[course.Code]), course.Code, 2)
+		}
+		for _, score := range scores[course.Code] {
+			if score.CourseCode != course.Code || score.CourseName != course.Name {
+				t.Errorf("got %s (%s), want %s (%s)", score.CourseCode, score.CourseName, course.Code, course.Name)
+			}
+		}
+	}
+
+	if first := scores[courses[0].Code][0]; first.ProfessorUUID != roshiUUID || first.ScoreAverage != 5 || first.Count != 1 {
+		t.Errorf("got %+v, want %s first with 1 grade", *first, "Master Roshi")
+	}
+
+	if last := scores[courses[1].Code][1]; last.ProfessorUUID != roshiUUID || last.Count != 0 {
+		t.Errorf("got %+v, want %s last without grades", *last, "Master Roshi")
+	}
+
+	if scores, err = TestDB.GetProfessorsForCourses([]string{}); err != nil || len(scores) != 0 {
+		t.Errorf("got %v, %v, want no courses", scores, err)
+	}
+}
+
+func TestRatingTiebreak(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuids := []string{}
+	for _, name := range []string{"Master Roshi", "Yamcha"} {
+		if err = TestDB.AddProfessor(name); err != nil {
+			t.Fatal(err)
+		}
+		uuid, err := TestDB.GetProfessorUUIDByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	// the older professor has more grades, with the same average
+	for i, usernames := range [][]string{{"joe", "bob"}, {"joe"}} {
+		for _, username := range usernames {
+			if err = TestDB.GradeCourseProfessor(uuids[i], courses[0].Code, username, [3]float32{5, 5, 5}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for page := 0; page < 2; page++ {
+		sorted, err := TestDB.GetLastProfessors(itpgDB.ProfessorSortRating, 1, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sorted) != 1 || sorted[0].UUID != uuids[page] {
+			t.Errorf("got %v at page %d, want %s", sorted, page, uuids[page])
+		}
+	}
+
+	// the newer professor now has more grades, with the same average
+	for _, username := range []string{"ann", "kim", "lee"} {
+		if err = TestDB.GradeCourseProfessor(uuids[1], courses[0].Code, username, [3]float32{5, 5, 5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = TestDB.GradeCourseProfessor(uuids[0], courses[0].Code, "ann", [3]float32{5, 5, 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	ratings, err := TestDB.GetTopProfessors(2, itpgDB.RankingScoreAverage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ratings) != 2 || ratings[0].ProfessorUUID != uuids[1] || ratings[1].ProfessorUUID != uuids[0] {
+		t.Errorf("got %v, want %s, %s", ratings, "Yamcha", "Master Roshi")
+	}
+}
+
+func TestGetBottomRatedProfessors(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, username := range []string{"joe", "bob"} {
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, username, [3]float32{0, 0, 0}); err != nil {
+			t.Fatal(err)
+		}
+		if err = TestDB.GradeCourseProfessor(professors[1].UUID, courses[1].Code, username, [3]float32{5, 5, 5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = TestDB.AddCourseProfessor(professors[2].UUID, courses[2].Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ratings, err := TestDB.GetBottomRatedProfessors(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ratings) != 2 {
+		t.Fatalf("got %d, want %d", len(ratings), 2)
+	}
+
+	if ratings[0].ProfessorUUID != professors[0].UUID || ratings[1].ProfessorUUID != professors[1].UUID {
+		t.Errorf("got %s, %s, want %s, %s", ratings[0].ProfessorName, ratings[1].ProfessorName, professors[0].Name, professors[1].Name)
+	}
+
+	if ratings[0].Count != 3 {
+		t.Errorf("got %d, want %d", ratings[0].Count, 3)
+	}
+
+	ratings, err = TestDB.GetBottomRatedProfessors(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ratings) != 1 || ratings[0].ProfessorUUID != professors[0].UUID {
+		t.Errorf("got %v, want %s only", ratings, professors[0].Name)
+	}
+}
+
+func TestGetTopProfessors(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = TestDB.AddProfessorMany([]string{"Master Roshi", "Yamcha"}); err != nil {
+		t.Fatal(err)
+	}
+
+	uuids := []string{}
+	for _, name := range []string{"Master Roshi", "Yamcha"} {
+		uuid, err := TestDB.GetProfessorUUIDByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	for _, uuid := range uuids {
+		for _, username := range []string{"joe", "bob", "ann"} {
+			if err = TestDB.GradeCourseProfessor(uuid, courses[0].Code, username, [3]float32{5, 5, 5}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, username := range []string{"joe", "bob"} {
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, username, [3]float32{0, 0, 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ratings, err := TestDB.GetTopProfessors(10, itpgDB.RankingScoreAverage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ratings) != 3 {
+		t.Fatalf("got %d, want %d", len(ratings), 3)
+	}
+
+	expected := []string{uuids[1], uuids[0], professors[0].UUID}
+	for i, rating := range ratings {
+		if rating.ProfessorUUID != expected[i] {
+			t.Errorf("got %s at %d, want %s", rating.ProfessorUUID, i, expected[i])
+		}
+	}
+
+	if ratings[0].ScoreAverage != 5 || ratings[0].Count != 3 {
+		t.Errorf("got %v, %d, want %v, %d", ratings[0].ScoreAverage, ratings[0].Count, 5, 3)
+	}
+
+	ratings, err = TestDB.GetTopProfessors(1, itpgDB.RankingScoreAverage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ratings) != 1 || ratings[0].ProfessorUUID != uuids[1] {
+		t.Errorf("got %v, want %s only", ratings, "Yamcha")
+	}
+}
+
+func TestGetTopProfessorsBy(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = TestDB.AddProfessorMany([]string{"Master Roshi", "Yamcha"}); err != nil {
+		t.Fatal(err)
+	}
+
+	uuids := []string{}
+	for _, name := range []string{"Master Roshi", "Yamcha"} {
+		uuid, err := TestDB.GetProfessorUUIDByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	// Master Roshi is the better teacher, and Yamcha is better overall.
+	for i, grades := range [][3]float32{{5, 1, 1}, {1, 5, 5}} {
+		for _, username := range []string{"joe", "bob", "ann"} {
+			if err = TestDB.GradeCourseProfessor(uuids[i], courses[0].Code, username, grades); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	expected := map[itpgDB.RankingScore][]string{
+		itpgDB.RankingScoreAverage:    {uuids[1], uuids[0]},
+		itpgDB.RankingScoreTeaching:   {uuids[0], uuids[1]},
+		itpgDB.RankingScoreCoursework: {uuids[1], uuids[0]},
+		itpgDB.RankingScoreLearning:   {uuids[1], uuids[0]},
+	}
+	for by, want := range expected {
+		ratings, err := TestDB.GetTopProfessors(10, by)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ratings) != len(want) {
+			t.Fatalf("%s: got %d, want %d", by, len(ratings), len(want))
+		}
+		for i, rating := range ratings {
+			if rating.ProfessorUUID != want[i] || rating.Count != 3 {
+				t.Errorf("%s: got %s with %d grades at %d, want %s with 3 grades", by, rating.ProfessorUUID, rating.Count, i, want[i])
+			}
+		}
+	}
+
+	if _, err = TestDB.GetTopProfessors(10, "popularity"); err == nil {
+		t.Error("got nil, want an error for the invalid ranking score")
+	}
+}
+
+func TestGetMostRatedCourses(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// jim graded each course once when initializing the database.
+	for _, username := range []string{"joe", "bob", "ann"} {
+		if err = TestDB.GradeCourseProfessor(professors[2].UUID, courses[2].Code, username, [3]float32{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = TestDB.GradeCourseProfessor(professors[3].UUID, courses[3].Code, "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the row adding the course to the professor is not a grade.
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	popular, err := TestDB.GetMostRatedCourses(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*itpgDB.PopularCourse{
+		{CourseCode: courses[2].Code, CourseName: courses[2].Name, Count: 4},
+		{CourseCode: courses[3].Code, CourseName: courses[3].Name, Count: 2},
+		{CourseCode: courses[1].Code, CourseName: courses[1].Name, Count: 1},
+		{CourseCode: courses[0].Code, CourseName: courses[0].Name, Count: 1},
+	}
+	if len(popular) != len(expected) {
+		t.Fatalf("got %d courses, want %d", len(popular), len(expected))
+	}
+	for i, course := range popular {
+		if *course != *expected[i] {
+			t.Errorf("got %v at %d, want %v", *course, i, *expected[i])
+		}
+	}
+
+	if popular, err = TestDB.GetMostRatedCourses(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(popular) != 1 || popular[0].CourseCode != courses[2].Code {
+		t.Errorf("got %v, want %s only", popular, courses[2].Code)
+	}
+}
+
+func TestGetUnratedProfessors(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unratedName := "Yeji Kim"
+	if err = TestDB.AddProfessor(unratedName); err != nil {
+		t.Fatal(err)
+	}
+
+	unratedUUID, err := TestDB.GetProfessorUUIDByName(unratedName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourseProfessor(unratedUUID, courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	unrated, err := TestDB.GetUnratedProfessors()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unrated) != 1 {
+		t.Fatalf("got %d, want %d", len(unrated), 1)
+	}
+
+	if unrated[0].UUID != unratedUUID || unrated[0].Name != unratedName {
+		t.Errorf("got %v, want %v", *unrated[0], itpgDB.Professor{UUID: unratedUUID, Name: unratedName})
+	}
+}
+
+func TestGetScoresNames(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastScores, err := TestDB.GetLastScores(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := lastScores[0]
+
+	tests := map[string]func() ([]*itpgDB.Score, error){
+		"GetLastScores":            func() ([]*itpgDB.Score, error) { return TestDB.GetLastScores(0, 0) },
+		"GetScoresByProfessorUUID": func() ([]*itpgDB.Score, error) { return TestDB.GetScoresByProfessorUUID(s.ProfessorUUID) },
+		"GetScoresByProfessorName": func() ([]*itpgDB.Score, error) { return TestDB.GetScoresByProfessorName(s.ProfessorName) },
+		"GetScoresByProfessorNameLike": func() ([]*itpgDB.Score, error) {
+			return TestDB.GetScoresByProfessorNameLike(s.ProfessorName[:3], itpgDB.MatchSubstring)
+		},
+		"GetScoresByCourseName": func() ([]*itpgDB.Score, error) { return TestDB.GetScoresByCourseName(s.CourseName) },
+		"GetScoresByCourseNameLike": func() ([]*itpgDB.Score, error) {
+			return TestDB.GetScoresByCourseNameLike(s.CourseName[:3], itpgDB.MatchSubstring)
+		},
+		"GetScoresByCourseCode":     func() ([]*itpgDB.Score, error) { return TestDB.GetScoresByCourseCode(s.CourseCode) },
+		"GetScoresByCourseCodeLike": func() ([]*itpgDB.Score, error) { return TestDB.GetScoresByCourseCodeLike(s.CourseCode[:2]) },
+	}
+
+	for name, getScores := range tests {
+		scores, err := getScores()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(scores) == 0 {
+			t.Errorf("%s: got len = 0, want > 0", name)
+		}
+
+		for _, score := range scores {
+			if score.ProfessorName == "" || score.CourseName == "" {
+				t.Errorf("%s: got professor name %q and course name %q, want non-empty", name, score.ProfessorName, score.CourseName)
+			}
+		}
+	}
+}
+
+func TestGradeCourseProfessor(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profScores := [3]float32{5.00, 4.00, 3.00}
+	err = TestDB.GradeCourseProfessor(professors[1].UUID, "CN9A", "joe", profScores)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = TestDB.GradeCourseProfessor(professors[1].UUID, "CN9A", "joe", profScores)
+	if err == nil {
+		t.Error("expected failure")
+	}
+
+	err = TestDB.GradeCourseProfessor("1", "GC8F", "joe", profScores)
+	if err == nil {
+		t.Error("expected failure")
+	}
+}
+
+func TestGetDepartmentStats(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	departmentCourses := []*itpgDB.Course{
+		{Code: "MA101", Name: "Calculus", Department: "Math"},
+		{Code: "MA102", Name: "Linear algebra", Department: "Math"},
+		{Code: "PH101", Name: "Mechanics", Department: "Physics"},
+	}
+	if _, err = TestDB.AddCourseMany(departmentCourses); err != nil {
+		t.Fatal(err)
+	}
+
+	// professor index, course index
+	pairs := [][2]int{{0, 0}, {0, 1}, {1, 1}, {2, 2}}
+	for _, pair := range pairs {
+		if err = TestDB.AddCourseProfessor(professors[pair[0]].UUID, departmentCourses[pair[1]].Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, "MA101", "joe", [3]float32{4, 4, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[2].UUID, "PH101", "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := TestDB.GetDepartmentStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []itpgDB.DepartmentStats{
+		{Department: "Math", Courses: 2, Professors: 2, ScoreAverage: 4},
+		{Department: "Physics", Courses: 1, Professors: 1, ScoreAverage: 2},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("got %d departments, want %d", len(stats), len(expected))
+	}
+	for i, stat := range stats {
+		if *stat != expected[i] {
+			t.Errorf("got %+v, want %+v", *stat, expected[i])
+		}
+	}
+
+	allCourses, err := TestDB.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.ContainsFunc(allCourses, func(c *itpgDB.Course) bool { return *c == *departmentCourses[0] }) {
+		t.Errorf("got %v, want %v", allCourses, departmentCourses[0])
+	}
+}
+
+func TestGetComponentAverages(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	grades := [][3]float32{{1, 3, 5}, {2, 4, 0}}
+	for i, username := range []string{"joe", "bob"} {
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[1].Code, username, grades[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var teaching, coursework, learning float64
+	for _, score := range scores {
+		teaching += float64(score.ScoreTeaching)
+		coursework += float64(score.ScoreCourseWork)
+		learning += float64(score.ScoreLearning)
+	}
+	for _, grade := range grades {
+		teaching += float64(grade[0])
+		coursework += float64(grade[1])
+		learning += float64(grade[2])
+	}
+	count := len(scores) + len(grades)
+
+	averages, err := TestDB.GetComponentAverages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if averages.Count != count {
+		t.Errorf("got %d, want %d", averages.Count, count)
+	}
+
+	expected := []float64{teaching / float64(count), coursework / float64(count), learning / float64(count)}
+	for i, got := range []float32{averages.ScoreTeaching, averages.ScoreCourseWork, averages.ScoreLearning} {
+		if math.Abs(float64(got)-expected[i]) > 1e-4 {
+			t.Errorf("got %v for component %d, want %v", got, i, expected[i])
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := TestDB.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &itpgDB.Stats{Courses: len(courses), Professors: len(professors), Grades: len(scores)}
+	if !cmp.Equal(stats, expected) {
+		t.Errorf("got %+v, want %+v", *stats, *expected)
+	}
+
+	// adding a professor to a course adds no grade.
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = TestDB.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Grades != len(scores) {
+		t.Errorf("got %d grades, want %d", stats.Grades, len(scores))
+	}
+}
+
+func TestGetProfessorDetail(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = TestDB.AddProfessorMany([]string{"Master Roshi", "Yamcha"}); err != nil {
+		t.Fatal(err)
+	}
+
+	uuids := []string{}
+	for _, name := range []string{"Master Roshi", "Yamcha"} {
+		uuid, err := TestDB.GetProfessorUUIDByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	for _, course := range courses {
+		if err = TestDB.AddCourseProfessor(uuids[0], course.Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	grades := []struct {
+		course   string
+		username string
+		grades   [3]float32
+	}{
+		{courses[0].Code, "joe", [3]float32{5, 5, 5}},
+		{courses[0].Code, "bob", [3]float32{3, 3, 3}},
+		{courses[1].Code, "joe", [3]float32{4, 1, 1}},
+	}
+	for _, grade := range grades {
+		if err = TestDB.GradeCourseProfessor(uuids[0], grade.course, grade.username, grade.grades); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	detail, err := TestDB.GetProfessorDetail(uuids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if detail.ProfessorName != "Master Roshi" || len(detail.Courses) != len(courses) {
+		t.Fatalf("got %s with %d courses, want %s with %d courses", detail.ProfessorName, len(detail.Courses), "Master Roshi", len(courses))
+	}
+
+	expected := map[string]itpgDB.CourseScore{
+		courses[0].Code: {CourseCode: courses[0].Code, CourseName: courses[0].Name, ScoreTeaching: 4, ScoreCourseWork: 4, ScoreLearning: 4, ScoreAverage: 4, Count: 2},
+		courses[1].Code: {CourseCode: courses[1].Code, CourseName: courses[1].Name, ScoreTeaching: 4, ScoreCourseWork: 1, ScoreLearning: 1, ScoreAverage: 2, Count: 1},
+		courses[2].Code: {CourseCode: courses[2].Code, CourseName: courses[2].Name},
+		courses[3].Code: {CourseCode: courses[3].Code, CourseName: courses[3].Name},
+	}
+	for _, course := range detail.Courses {
+		if *course != expected[course.CourseCode] {
+			t.Errorf("got %+v, want %+v", *course, expected[course.CourseCode])
+		}
+	}
+
+	if detail.ScoreTeaching != 4 || detail.ScoreCourseWork != 3 || detail.ScoreLearning != 3 || detail.Count != 3 {
+		t.Errorf("got %v, %v, %v, %d, want %v, %v, %v, %d", detail.ScoreTeaching, detail.ScoreCourseWork, detail.ScoreLearning, detail.Count, 4, 3, 3, 3)
+	}
+
+	detail, err = TestDB.GetProfessorDetail(uuids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if detail.ProfessorName != "Yamcha" || len(detail.Courses) != 0 || detail.Count != 0 {
+		t.Errorf("got %+v, want %s without courses", *detail, "Yamcha")
+	}
+
+	if _, err = TestDB.GetProfessorDetail("deadbeef"); !errors.Is(err, responses.ErrProfessorNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrProfessorNotFound)
+	}
+}
+
+func TestGetProfessorRank(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = TestDB.AddProfessorMany([]string{"Master Roshi", "Yamcha", "Krillin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	uuids := []string{}
+	for _, name := range []string{"Master Roshi", "Yamcha", "Krillin"} {
+		uuid, err := TestDB.GetProfessorUUIDByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	departmentCourses := []*itpgDB.Course{
+		{Code: "MA101", Name: "Calculus", Department: "Math"},
+		{Code: "MA102", Name: "Linear algebra", Department: "Math"},
+		{Code: "PH101", Name: "Mechanics", Department: "Physics"},
+	}
+	if _, err = TestDB.AddCourseMany(departmentCourses); err != nil {
+		t.Fatal(err)
+	}
+
+	// professor index, course index, grades
+	grades := []struct {
+		professor int
+		course    int
+		grades    [3]float32
+	}{
+		{0, 0, [3]float32{3, 3, 3}},
+		{0, 1, [3]float32{3, 3, 3}},
+		{1, 1, [3]float32{5, 5, 5}},
+		{2, 2, [3]float32{1, 1, 1}},
+	}
+	for _, g := range grades {
+		if err = TestDB.AddCourseProfessor(uuids[g.professor], departmentCourses[g.course].Code); err != nil {
+			t.Fatal(err)
+		}
+		if err = TestDB.GradeCourseProfessor(uuids[g.professor], departmentCourses[g.course].Code, "joe", g.grades); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Krillin teaches as many courses in both departments, so their department is the first by name.
+	if err = TestDB.AddCourseProfessor(uuids[2], departmentCourses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []itpgDB.ProfessorRank{
+		{ProfessorUUID: uuids[0], ProfessorName: "Master Roshi", Department: "Math", Rank: 2, Total: 3, ScoreAverage: 3},
+		{ProfessorUUID: uuids[1], ProfessorName: "Yamcha", Department: "Math", Rank: 1, Total: 3, ScoreAverage: 5},
+		{ProfessorUUID: uuids[2], ProfessorName: "Krillin", Department: "Math", Rank: 3, Total: 3, ScoreAverage: 1},
+	}
+	for i, uuid := range uuids {
+		rank, err := TestDB.GetProfessorRank(uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *rank != expected[i] {
+			t.Errorf("got %+v, want %+v", *rank, expected[i])
+		}
+	}
+
+	if _, err = TestDB.GetProfessorRank(professors[0].UUID); !errors.Is(err, responses.ErrProfessorNoDepartment) {
+		t.Errorf("got %v, want %v", err, responses.ErrProfessorNoDepartment)
+	}
+
+	if _, err = TestDB.GetProfessorRank("deadbeef"); !errors.Is(err, responses.ErrProfessorNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrProfessorNotFound)
+	}
+}
+
+func TestGetCourseDetail(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	course := &itpgDB.Course{Code: "MA101", Name: "Calculus", Department: "Math"}
+	if err = TestDB.AddCourse(course); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, professor := range professors {
+		if err = TestDB.AddCourseProfessor(professor.UUID, course.Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	grades := []struct {
+		professor int
+		username  string
+		grades    [3]float32
+	}{
+		{0, "joe", [3]float32{4, 4, 4}},
+		{0, "bob", [3]float32{2, 2, 2}},
+		{1, "joe", [3]float32{0, 3, 3}},
+	}
+	for _, grade := range grades {
+		if err = TestDB.GradeCourseProfessor(professors[grade.professor].UUID, course.Code, grade.username, grade.grades); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	detail, err := TestDB.GetCourseDetail(course.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if detail.CourseName != course.Name || detail.Department != course.Department || len(detail.Professors) != len(professors) {
+		t.Fatalf("got %s (%s) with %d professors, want %s (%s) with %d professors", detail.CourseName, detail.Department, len(detail.Professors), course.Name, course.Department, len(professors))
+	}
+
+	expected := map[string]itpgDB.ProfessorScore{
+		professors[0].UUID: {ProfessorUUID: professors[0].UUID, ProfessorName: professors[0].Name, ScoreTeaching: 3, ScoreCourseWork: 3, ScoreLearning: 3, ScoreAverage: 3, Count: 2},
+		professors[1].UUID: {ProfessorUUID: professors[1].UUID, ProfessorName: professors[1].Name, ScoreTeaching: 0, ScoreCourseWork: 3, ScoreLearning: 3, ScoreAverage: 2, Count: 1},
+		professors[2].UUID: {ProfessorUUID: professors[2].UUID, ProfessorName: professors[2].Name},
+		professors[3].UUID: {ProfessorUUID: professors[3].UUID, ProfessorName: professors[3].Name},
+	}
+	for _, professor := range detail.Professors {
+		if *professor != expected[professor.ProfessorUUID] {
+			t.Errorf("got %+v, want %+v", *professor, expected[professor.ProfessorUUID])
+		}
+	}
+
+	if detail.ScoreTeaching != 2 || detail.ScoreCourseWork != 3 || detail.ScoreLearning != 3 || detail.Count != 3 {
+		t.Errorf("got %v, %v, %v, %d, want %v, %v, %v, %d", detail.ScoreTeaching, detail.ScoreCourseWork, detail.ScoreLearning, detail.Count, 2, 3, 3, 3)
+	}
+
+	if _, err = TestDB.GetCourseDetail("XX999"); !errors.Is(err, responses.ErrCourseNotFound) {
+		t.Errorf("got %v, want %v", err, responses.ErrCourseNotFound)
+	}
+}
+
+func TestGradeCourseProfessorWithDetails(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessorWithDetails(professors[0].UUID, courses[1].Code, "joe", [3]float32{5, 4, 3}, &itpgDB.GradeDetails{Weight: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessorWithDetails(professors[0].UUID, courses[1].Code, "bob", [3]float32{1, 0, 3}, &itpgDB.GradeDetails{Weight: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessorWithDetails(professors[0].UUID, courses[1].Code, "jim", [3]float32{1, 1, 1}, &itpgDB.GradeDetails{Weight: 0}); err == nil {
+		t.Error("expected failure")
+	}
+
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[1].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(courseScores, func(s *itpgDB.Score) bool { return s.ProfessorUUID == professors[0].UUID })
+	if i == -1 {
+		t.Fatalf("got %v, want score of %s", courseScores, professors[0].UUID)
+	}
+
+	expected := [3]float32{4, 3, 3}
+	if got := [3]float32{courseScores[i].ScoreTeaching, courseScores[i].ScoreCourseWork, courseScores[i].ScoreLearning}; got != expected {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestGradeCourseProfessorMany(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	grades := []*itpgDB.Grade{
+		{ProfessorUUID: professors[0].UUID, CourseCode: courses[0].Code, Grades: [3]float32{5, 4, 3}, Details: itpgDB.GradeDetails{Weight: 1}},
+		{ProfessorUUID: professors[1].UUID, CourseCode: courses[1].Code, Grades: [3]float32{5, 4, 3}, Details: itpgDB.GradeDetails{Weight: 1, Comment: "great"}},
+		{ProfessorUUID: professors[1].UUID, CourseCode: courses[1].Code, Grades: [3]float32{1, 1, 1}, Details: itpgDB.GradeDetails{Weight: 1}},
+		{ProfessorUUID: professors[2].UUID, CourseCode: courses[2].Code, Grades: [3]float32{6, 4, 3}, Details: itpgDB.GradeDetails{Weight: 1}},
+		{ProfessorUUID: professors[2].UUID, CourseCode: "GC8F", Grades: [3]float32{5, 4, 3}, Details: itpgDB.GradeDetails{Weight: 1}},
+	}
+
+	errs, err := TestDB.GradeCourseProfessorMany("joe", grades)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(errs[0], responses.ErrCourseGraded) {
+		t.Errorf("got %v, want %v", errs[0], responses.ErrCourseGraded)
+	}
+	if errs[1] != nil {
+		t.Errorf("got %v, want nil", errs[1])
+	}
+	if !errors.Is(errs[2], responses.ErrCourseGraded) {
+		t.Errorf("got %v, want %v", errs[2], responses.ErrCourseGraded)
+	}
+	if !errors.Is(errs[3], responses.ErrGradeOutOfRange) {
+		t.Errorf("got %v, want %v", errs[3], responses.ErrGradeOutOfRange)
+	}
+	if !errors.Is(errs[4], itpgDB.ErrForeignKey) {
+		t.Errorf("got %v, want %v", errs[4], itpgDB.ErrForeignKey)
+	}
+
+	graded, err := TestDB.CheckGradedMany("joe", []*itpgDB.CourseProfessor{{ProfessorUUID: professors[1].UUID, CourseCode: courses[1].Code}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !graded[0] {
+		t.Error("expected grade to be added")
+	}
+
+	rawGrades, err := TestDB.GetRawGrades(professors[1].UUID, courses[1].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(rawGrades, func(g *itpgDB.RawGrade) bool { return g.Comment == "great" }) {
+		t.Errorf("got %v, want grade with comment %s", rawGrades, "great")
+	}
+}
+
+func TestScoreDistribution(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for username, grades := range map[string][3]float32{"joe": {5, 5, 5}, "bob": {1, 1, 1}, "al": {3, 2, 4}} {
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[1].Code, username, grades); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[1].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(courseScores, func(s *itpgDB.Score) bool { return strings.Contains(s.ProfessorUUID, professors[0].UUID) })
+	if i == -1 {
+		t.Fatalf("got %v, want score of %s", courseScores, professors[0].UUID)
+	}
+
+	if courseScores[i].ScoreMedian != 3 {
+		t.Errorf("got median %v, want %v", courseScores[i].ScoreMedian, 3)
+	}
+	if courseScores[i].ScoreStdDev != 1.63 {
+		t.Errorf("got standard deviation %v, want %v", courseScores[i].ScoreStdDev, 1.63)
+	}
+}
+
+func TestScoreCount(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	professorScores, err := TestDB.GetScoresByProfessorUUID(professors[0].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(professorScores) != 1 {
+		t.Fatalf("got %d scores, want %d", len(professorScores), 1)
+	}
+
+	if professorScores[0].Count != 2 {
+		t.Errorf("got %d, want %d", professorScores[0].Count, 2)
+	}
+
+	lastScores, err := TestDB.GetLastScores(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, score := range lastScores {
+		expected := 1
+		if score.ProfessorUUID == professors[0].UUID {
+			expected = 2
+		}
+		if score.Count != expected {
+			t.Errorf("%s: got %d, want %d", score.ProfessorName, score.Count, expected)
+		}
+	}
+}
+
+func TestUpdateGrade(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[1].UUID, courses[1].Code, "joe", [3]float32{1, 1, 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.UpdateGrade(professors[1].UUID, courses[1].Code, "joe", [3]float32{5, 4, 3}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.UpdateGrade(professors[1].UUID, courses[1].Code, "joe", [3]float32{6, 4, 3}, nil); !errors.Is(err, responses.ErrGradeOutOfRange) {
+		t.Errorf("got %v, want %v", err, responses.ErrGradeOutOfRange)
+	}
+
+	if err = TestDB.UpdateGrade(professors[1].UUID, courses[1].Code, "bob", [3]float32{5, 4, 3}, nil); !errors.Is(err, responses.ErrNotGraded) {
+		t.Errorf("got %v, want %v", err, responses.ErrNotGraded)
+	}
+
+	rawGrades, err := TestDB.GetRawGrades(professors[1].UUID, courses[1].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rawGrades) != 2 {
+		t.Fatalf("got %d grades, want %d", len(rawGrades), 2)
+	}
+
+	expected := [3]float32{5, 4, 3}
+	if got := [3]float32{rawGrades[1].ScoreTeaching, rawGrades[1].ScoreCourseWork, rawGrades[1].ScoreLearning}; got != expected {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestUpdateGradeAverage(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[1].Code, "joe", [3]float32{1, 1, 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[1].Code, "bob", [3]float32{5, 5, 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.UpdateGrade(professors[0].UUID, courses[1].Code, "joe", [3]float32{4, 4, 4}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[1].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(courseScores, func(s *itpgDB.Score) bool { return strings.Contains(s.ProfessorUUID, professors[0].UUID) })
+	if i == -1 {
+		t.Fatalf("got %v, want score of %s", courseScores, professors[0].UUID)
+	}
+
+	if courseScores[i].Count != 2 {
+		t.Errorf("got count %d, want %d", courseScores[i].Count, 2)
+	}
+
+	if courseScores[i].ScoreAverage != 4.5 {
+		t.Errorf("got average %v, want %v", courseScores[i].ScoreAverage, 4.5)
+	}
+}
+
+func TestDeleteGrade(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := []*itpgDB.CourseProfessor{{ProfessorUUID: professors[0].UUID, CourseCode: courses[0].Code}}
+
+	if err = TestDB.DeleteGrade(professors[0].UUID, courses[0].Code, "jim"); err != nil {
+		t.Fatal(err)
+	}
+
+	graded, err := TestDB.CheckGradedMany("jim", pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graded[0] {
+		t.Error("got graded, want not graded")
+	}
+
+	if err = TestDB.DeleteGrade(professors[0].UUID, courses[0].Code, "jim"); !errors.Is(err, responses.ErrNotGraded) {
+		t.Errorf("got %v, want %v", err, responses.ErrNotGraded)
+	}
+
+	if err = TestDB.DeleteGrade(professors[1].UUID, courses[0].Code, "jim"); !errors.Is(err, responses.ErrNotGraded) {
+		t.Errorf("got %v, want %v", err, responses.ErrNotGraded)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "jim", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckGradedMany(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := []*itpgDB.CourseProfessor{
+		{ProfessorUUID: professors[0].UUID, CourseCode: courses[0].Code},
+		{ProfessorUUID: professors[1].UUID, CourseCode: courses[1].Code},
+		{ProfessorUUID: professors[0].UUID, CourseCode: courses[1].Code},
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[1].UUID, courses[1].Code, "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	graded, err := TestDB.CheckGradedMany("joe", pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []bool{false, true, false}
+	if !slices.Equal(graded, expected) {
+		t.Errorf("got %v, want %v", graded, expected)
+	}
+}
+
+func TestGetEligibleGrades(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	// jim graded professors[1] in courses[1] when initializing the database, but not professors[0].
+	eligible, err := TestDB.GetEligibleGrades("jim", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(eligible) != 1 {
+		t.Fatalf("got %d eligible grades, want 1", len(eligible))
+	}
+	expected := itpgDB.EligibleGrade{ProfessorUUID: professors[0].UUID, ProfessorName: professors[0].Name, CourseCode: courses[1].Code, CourseName: courses[1].Name}
+	if *eligible[0] != expected {
+		t.Errorf("got %v, want %v", *eligible[0], expected)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[1].Code, "jim", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if eligible, err = TestDB.GetEligibleGrades("jim", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(eligible) != 0 {
+		t.Errorf("got %d eligible grades, want 0", len(eligible))
+	}
+
+	if eligible, err = TestDB.GetEligibleGrades("joe", 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(eligible) != 2 {
+		t.Errorf("got %d eligible grades, want 2", len(eligible))
+	}
+}
+
+func TestMigrateGrades(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// joe already graded professors[0] in courses[0], which jim also graded when initializing the database.
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.MigrateGrades("jim", "joe"); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := make([]*itpgDB.CourseProfessor, len(professors))
+	for i := range professors {
+		pairs[i] = &itpgDB.CourseProfessor{ProfessorUUID: professors[i].UUID, CourseCode: courses[i].Code}
+	}
+
+	graded, err := TestDB.CheckGradedMany("joe", pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(graded, false) {
+		t.Errorf("got %v, want joe to have graded all the pairs", graded)
+	}
+
+	if graded, err = TestDB.CheckGradedMany("jim", pairs); err != nil {
+		t.Fatal(err)
+	}
+	if !graded[0] || slices.Contains(graded[1:], true) {
+		t.Errorf("got %v, want jim to have only kept the grade joe already had", graded)
+	}
+}
+
+func TestGradeComments(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	comments := []string{"great", "", "too much homework", "fair", "boring", "fun", "hard"}
+	for i, comment := range comments {
+		username := fmt.Sprintf("user%d", i)
+		if err = TestDB.GradeCourseProfessorWithDetails(professors[0].UUID, courses[0].Code, username, [3]float32{1, 2, 3}, &itpgDB.GradeDetails{Weight: 1, Comment: comment}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"hard", "fun", "boring", "fair", "too much homework"}
+
+	profScores, err := TestDB.GetScoresByProfessorUUID(professors[0].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(profScores, func(s *itpgDB.Score) bool { return s.CourseCode == courses[0].Code })
+	if i == -1 {
+		t.Fatalf("got %v, want score of %s", profScores, courses[0].Code)
+	}
+	if !slices.Equal(profScores[i].Comments, expected) {
+		t.Errorf("got %v, want %v", profScores[i].Comments, expected)
+	}
+
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i = slices.IndexFunc(courseScores, func(s *itpgDB.Score) bool { return s.ProfessorUUID == professors[0].UUID })
+	if i == -1 {
+		t.Fatalf("got %v, want score of %s", courseScores, professors[0].UUID)
+	}
+	if !slices.Equal(courseScores[i].Comments, expected) {
+		t.Errorf("got %v, want %v", courseScores[i].Comments, expected)
+	}
+
+	rawGrades, err := TestDB.GetRawGrades(professors[0].UUID, courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rawGrades) < len(comments) {
+		t.Fatalf("got %d grades, want at least %d", len(rawGrades), len(comments))
+	}
+	for i, grade := range rawGrades[len(rawGrades)-len(comments):] {
+		if grade.Comment != comments[i] {
+			t.Errorf("got comment %q, want %q", grade.Comment, comments[i])
+		}
+	}
+}
+
+func TestGetRawGrades(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.AddCourseProfessor(professors[0].UUID, courses[0].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	grades := [][3]float32{{1, 2, 3}, {4, 5, 0}}
+	for i, username := range []string{"joe", "bob"} {
+		if err = TestDB.GradeCourseProfessorWithDetails(professors[0].UUID, courses[0].Code, username, grades[i], &itpgDB.GradeDetails{Weight: float32(i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rawGrades, err := TestDB.GetRawGrades(professors[0].UUID, courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rawGrades) != len(grades)+1 {
+		t.Fatalf("got %d grades, want %d", len(rawGrades), len(grades)+1)
+	}
+
+	for i, grade := range rawGrades[1:] {
+		if got := [3]float32{grade.ScoreTeaching, grade.ScoreCourseWork, grade.ScoreLearning}; got != grades[i] {
+			t.Errorf("got %v, want %v", got, grades[i])
+		}
+		if grade.Weight != float32(i+1) {
+			t.Errorf("got weight %v, want %v", grade.Weight, float32(i+1))
+		}
+		if grade.Hash == "" || grade.InsertedAt.IsZero() {
+			t.Errorf("got %+v, want hash and insertion time", grade)
+		}
+	}
+}
+
+func TestGetGradeAttemptsByCourseCode(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profScores := [3]float32{5.00, 4.00, 3.00}
+	if err = TestDB.GradeCourseProfessor(professors[len(professors)-1].UUID, courses[0].Code, "jim", profScores); err == nil {
+		t.Error("expected failure")
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[len(professors)-1].UUID, courses[0].Code, "joe", [3]float32{6.00, 4.00, 3.00}); err == nil {
+		t.Error("expected failure")
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[len(professors)-1].UUID, courses[0].Code, "joe", profScores); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts, err := TestDB.GetGradeAttemptsByCourseCode(courses[0].Code, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &itpgDB.GradeAttempts{CourseCode: courses[0].Code, Accepted: 2, AlreadyGraded: 1, OutOfRange: 1}
+	if !cmp.Equal(attempts, expected) {
+		t.Errorf("got %v, want %v", attempts, expected)
+	}
+
+	attempts, err = TestDB.GetGradeAttemptsByCourseCode(courses[0].Code, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = &itpgDB.GradeAttempts{CourseCode: courses[0].Code}
+	if !cmp.Equal(attempts, expected) {
+		t.Errorf("got %v, want %v", attempts, expected)
+	}
+}
+
+func TestGradeCourseProfessorDedupScope(t *testing.T) {
+	tests := []struct {
+		scope                itpgDB.DedupScope
+		otherProfessorGraded bool
+		otherCourseGraded    bool
+	}{
+		{itpgDB.DedupScopeCourseProfessor, true, true},
+		{itpgDB.DedupScopeProfessor, true, false},
+		{itpgDB.DedupScopeCourse, false, true},
+	}
+
+	for _, test := range tests {
+		err := initDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		TestDB.opts, err = itpgDB.NewOptions(itpgDB.WithDedupScope(test.scope))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		grades := [3]float32{1, 2, 3}
+
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", grades); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", grades); err != responses.ErrCourseGraded {
+			t.Errorf("%s: got %v, want %v", test.scope, err, responses.ErrCourseGraded)
+		}
+
+		err = TestDB.GradeCourseProfessor(professors[1].UUID, courses[0].Code, "joe", grades)
+		if test.otherProfessorGraded && err != nil {
+			t.Errorf("%s: got %v, want nil for another professor", test.scope, err)
+		} else if !test.otherProfessorGraded && err != responses.ErrCourseGraded {
+			t.Errorf("%s: got %v, want %v for another professor", test.scope, err, responses.ErrCourseGraded)
+		}
+
+		err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[1].Code, "joe", grades)
+		if test.otherCourseGraded && err != nil {
+			t.Errorf("%s: got %v, want nil for another course", test.scope, err)
+		} else if !test.otherCourseGraded && err != responses.ErrCourseGraded {
+			t.Errorf("%s: got %v, want %v for another course", test.scope, err, responses.ErrCourseGraded)
+		}
+	}
+
+	if _, err := New(TestDBUrl, "", 0, context.Background(), itpgDB.WithDedupScope("foo")); err == nil {
+		t.Error("expected failure")
+	}
+}
+
+func TestMaxRowReturn(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if TestDB.opts, err = itpgDB.NewOptions(itpgDB.WithMaxRowReturn(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	allCourses, err := TestDB.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allCourses) != 2 {
+		t.Errorf("got %d, want %d", len(allCourses), 2)
+	}
+
+	scores, err := TestDB.GetScoresByCourseCodeLike("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scores) != 2 {
+		t.Errorf("got %d, want %d", len(scores), 2)
+	}
+}
+
+func TestGradeCourseProfessorHashAlgorithm(t *testing.T) {
+	algorithms := []itpgDB.Option{
+		itpgDB.WithHashAlgorithm(itpgDB.HashAlgorithmXxh3, ""),
+		itpgDB.WithHashAlgorithm(itpgDB.HashAlgorithmHmacSha256, "pepper"),
+	}
+
+	hashes := []string{}
+
+	for _, algorithm := range algorithms {
+		err := initDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		TestDB.opts, err = itpgDB.NewOptions(algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		grades := [3]float32{1, 2, 3}
+
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", grades); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", grades); err != responses.ErrCourseGraded {
+			t.Errorf("%s: got %v, want %v", TestDB.opts.HashAlgorithm, err, responses.ErrCourseGraded)
+		}
+
+		hash := TestDB.opts.GradeHash("joe", courses[0].Code, professors[0].UUID)
+		graded, err := TestDB.checkGraded(context.Background(), hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !graded {
+			t.Errorf("%s: got %v, want %v", TestDB.opts.HashAlgorithm, graded, true)
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	if hashes[0] == hashes[1] {
+		t.Errorf("got same hash %s for all algorithms", hashes[0])
+	}
+}
+
+func TestCheckGraded(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasher := xxh3.New()
+	if _, err := hasher.WriteString("joe" + courses[0].Code + professors[0].UUID); err != nil {
+		t.Fatal(err)
+	}
+	hash := hasher.Sum64()
+
+	graded, err := TestDB.checkGraded(context.Background(), fmt.Sprintf("%d", hash))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if graded {
+		t.Errorf("got %v, want %v", graded, false)
+	}
+
+	grades := [3]float32{5.00, 4.00, 3.00}
+	err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", grades)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graded, err = TestDB.checkGraded(context.Background(), fmt.Sprintf("%d", hash))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !graded {
+		t.Errorf("got %v, want %v", graded, true)
+	}
+}
+
+func TestAverageScore(t *testing.T) {
+	tests := []struct {
+		weights  [3]float32
+		expected float32
+	}{
+		{[3]float32{1, 1, 1}, 4},
+		{[3]float32{2, 2, 2}, 4},
+		{[3]float32{2, 1, 1}, 4.25},
+		{[3]float32{0, 0, 1}, 3},
+	}
+
+	for _, test := range tests {
+		if avgScore := averageScore(test.weights, 5, 4, 3); avgScore != test.expected {
+			t.Errorf("%v: got %f, want %f", test.weights, avgScore, test.expected)
+		}
+	}
+}
+
+func TestScoreWeights(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if TestDB.opts, err = itpgDB.NewOptions(itpgDB.WithScoreWeights([3]float32{2, 1, 1})); err != nil {
+		t.Fatal(err)
+	}
+
+	for username, grades := range map[string][3]float32{"joe": {5, 1, 1}, "bob": {3, 3, 3}} {
+		if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[1].Code, username, grades); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	courseScores, err := TestDB.GetScoresByCourseCode(courses[1].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(courseScores, func(s *itpgDB.Score) bool { return strings.Contains(s.ProfessorUUID, professors[0].UUID) })
+	if i == -1 {
+		t.Fatalf("got %v, want score of %s", courseScores, professors[0].UUID)
+	}
+
+	if courseScores[i].ScoreAverage != 3 {
+		t.Errorf("got average %v, want %v", courseScores[i].ScoreAverage, 3)
+	}
+
+	if courseScores[i].ScoreMedian != 3 || courseScores[i].ScoreStdDev != 0 {
+		t.Errorf("got median %v and standard deviation %v, want %v and %v", courseScores[i].ScoreMedian, courseScores[i].ScoreStdDev, 3, 0)
+	}
+}
+
+func TestScoreDimensions(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if TestDB.opts, err = itpgDB.NewOptions(itpgDB.WithScoreDimensions("teaching", "coursework", "learning", "availability")); err != nil {
+		t.Fatal(err)
+	}
+
+	details := &itpgDB.GradeDetails{Weight: 1, Grades: map[string]float32{"availability": 4}}
+	if err = TestDB.GradeCourseProfessorWithDetails(professors[0].UUID, courses[1].Code, "joe", [3]float32{5, 4, 3}, details); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := []*itpgDB.Grade{
+		{ProfessorUUID: professors[0].UUID, CourseCode: courses[1].Code, Grades: [3]float32{1, 2, 3}, Details: itpgDB.GradeDetails{Weight: 1, Grades: map[string]float32{"availability": 2}}},
+		{ProfessorUUID: professors[1].UUID, CourseCode: courses[1].Code, Grades: [3]float32{1, 2, 3}, Details: itpgDB.GradeDetails{Weight: 1}},
+	}
+	errs, err := TestDB.GradeCourseProfessorMany("bob", batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs[0] != nil {
+		t.Error(errs[0])
+	}
+	if !errors.Is(errs[1], itpgDB.ErrInvalid) {
+		t.Errorf("got %v, want %v", errs[1], itpgDB.ErrInvalid)
+	}
+
+	tests := []struct {
+		grades   map[string]float32
+		expected error
+	}{
+		{nil, itpgDB.ErrInvalid},
+		{map[string]float32{"availability": 4, "humor": 5}, itpgDB.ErrInvalid},
+		{map[string]float32{"availability": 6}, responses.ErrGradeOutOfRange},
+	}
+
+	for _, test := range tests {
+		details := &itpgDB.GradeDetails{Weight: 1, Grades: test.grades}
+		if err = TestDB.GradeCourseProfessorWithDetails(professors[2].UUID, courses[1].Code, "jim", [3]float32{1, 1, 1}, details); !errors.Is(err, test.expected) {
+			t.Errorf("%v: got %v, want %v", test.grades, err, test.expected)
+		}
+	}
+
+	scores, err := TestDB.GetScoreDimensions(courses[1].Code, professors[0].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []itpgDB.DimensionScore{
+		{Dimension: "teaching", Score: 3, Count: 2},
+		{Dimension: "coursework", Score: 3, Count: 2},
+		{Dimension: "learning", Score: 3, Count: 2},
+		{Dimension: "availability", Score: 3, Count: 2},
+	}
+	if len(scores) != len(expected) {
+		t.Fatalf("got %d dimensions, want %d", len(scores), len(expected))
+	}
+	for i, score := range scores {
+		if *score != expected[i] {
+			t.Errorf("got %+v, want %+v", *score, expected[i])
+		}
+	}
+
+	averages, err := TestDB.GetComponentAverages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if averages.Dimensions["availability"] != 3 {
+		t.Errorf("got %v, want %v", averages.Dimensions["availability"], 3)
+	}
+}
+
+func TestUpdateGradeScoreDimensions(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := TestDB.opts
+	defer func() { TestDB.opts = opts }()
+
+	if TestDB.opts, err = itpgDB.NewOptions(itpgDB.WithScoreDimensions("teaching", "coursework", "learning", "availability")); err != nil {
+		t.Fatal(err)
+	}
+
+	details := &itpgDB.GradeDetails{Weight: 1, Grades: map[string]float32{"availability": 4}}
+	if err = TestDB.GradeCourseProfessorWithDetails(professors[0].UUID, courses[1].Code, "joe", [3]float32{1, 1, 1}, details); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = TestDB.UpdateGrade(professors[0].UUID, courses[1].Code, "joe", [3]float32{5, 4, 3}, map[string]float32{"availability": 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		grades   map[string]float32
+		expected error
+	}{
+		{nil, itpgDB.ErrInvalid},
+		{map[string]float32{"availability": 4, "humor": 5}, itpgDB.ErrInvalid},
+		{map[string]float32{"availability": 6}, responses.ErrGradeOutOfRange},
+	}
+
+	for _, test := range tests {
+		if err = TestDB.UpdateGrade(professors[0].UUID, courses[1].Code, "joe", [3]float32{1, 1, 1}, test.grades); !errors.Is(err, test.expected) {
+			t.Errorf("%v: got %v, want %v", test.grades, err, test.expected)
+		}
+	}
+
+	scores, err := TestDB.GetScoreDimensions(courses[1].Code, professors[0].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []itpgDB.DimensionScore{
+		{Dimension: "teaching", Score: 5, Count: 1},
+		{Dimension: "coursework", Score: 4, Count: 1},
+		{Dimension: "learning", Score: 3, Count: 1},
+		{Dimension: "availability", Score: 2, Count: 1},
+	}
+	if len(scores) != len(expected) {
+		t.Fatalf("got %d dimensions, want %d", len(scores), len(expected))
+	}
+	for i, score := range scores {
+		if *score != expected[i] {
+			t.Errorf("got %+v, want %+v", *score, expected[i])
+		}
+	}
+}
+
+func TestScoresHashUnique(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt := "INSERT INTO Scores(hash, professor_uuid, course_code, weight) VALUES($1, $2, $3, 0)"
+
+	// the rows adding courses to professors all have the default hash.
+	for i := 0; i < 2; i++ {
+		if err = execStmt(TestDB.ctx, TestDB.conn, stmt, defaultHash, professors[1].UUID, courses[2].Code); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash := TestDB.opts.GradeHash("jim", courses[0].Code, professors[0].UUID)
+	if err = execStmt(TestDB.ctx, TestDB.conn, stmt, hash, professors[0].UUID, courses[0].Code); !errors.Is(err, itpgDB.ErrDuplicate) {
+		t.Errorf("got %v, want %v", err, itpgDB.ErrDuplicate)
+	}
+
+	if err = TestDB.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "jim", [3]float32{1, 2, 3}); !errors.Is(err, responses.ErrCourseGraded) {
+		t.Errorf("got %v, want %v", err, responses.ErrCourseGraded)
+	}
+}
+
+func TestMedianScore(t *testing.T) {
+	tests := []struct {
+		grades   []weightedGrade
+		expected float32
+	}{
+		{nil, 0},
+		{[]weightedGrade{{4, 0}}, 0},
+		{[]weightedGrade{{4, 1}}, 4},
+		{[]weightedGrade{{5, 1}, {1, 1}, {3, 1}}, 3},
+		{[]weightedGrade{{5, 1}, {1, 1}, {2, 1}, {4, 1}}, 3},
+		{[]weightedGrade{{5, 3}, {1, 1}, {3, 1}}, 5},
+		{[]weightedGrade{{5, 2}, {1, 1}, {3, 1}}, 4},
+		{[]weightedGrade{{5, 1}, {1, 1}, {3, 0}}, 3},
+	}
+
+	for _, test := range tests {
+		if median := medianScore(test.grades); median != test.expected {
+			t.Errorf("%v: got %f, want %f", test.grades, median, test.expected)
+		}
+	}
+}
+
+func TestStdDevScore(t *testing.T) {
+	tests := []struct {
+		grades   []weightedGrade
+		expected float32
+	}{
+		{nil, 0},
+		{[]weightedGrade{{4, 0}}, 0},
+		{[]weightedGrade{{4, 1}}, 0},
+		{[]weightedGrade{{2, 1}, {4, 1}, {4, 1}, {4, 1}, {5, 1}, {5, 1}, {7, 1}, {9, 1}}, 2},
+		{[]weightedGrade{{2, 1}, {4, 3}, {5, 2}, {7, 1}, {9, 1}}, 2},
+		{[]weightedGrade{{5, 1}, {1, 1}, {3, 1}}, 1.63},
+		{[]weightedGrade{{5, 1}, {1, 1}, {3, 0}}, 2},
+	}
+
+	for _, test := range tests {
+		if stdDev := stdDevScore(test.grades); stdDev != test.expected {
+			t.Errorf("%v: got %f, want %f", test.grades, stdDev, test.expected)
+		}
+	}
+}
+
+func TestExecStmt(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = execStmt(TestDB.ctx, TestDB.conn, "SELECT * FROM Courses")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadOnlyReplica(t *testing.T) {
+	err := initDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the replica is a separate database, read by a role whose transactions are read-only like on a hot standby,
+	// so that the reads served by the replica can be told apart from the ones served by the primary.
+	stmts := []string{
+		"DROP DATABASE IF EXISTS replica",
+		"CREATE DATABASE replica",
+		"DO $$ BEGIN IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'reader') THEN CREATE ROLE reader LOGIN PASSWORD 'reader'; END IF; END $$",
+		"ALTER ROLE reader SET default_transaction_read_only = on",
+	}
+	for _, stmt := range stmts {
+		if err = execStmt(TestDB.ctx, TestDB.conn, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replicaUrl := strings.Replace(TestDBUrl, "/db?", "/replica?", 1)
+	setup, err := New(replicaUrl, "", 0, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicaCourse := &itpgDB.Course{Code: "R1", Name: "Replicated course"}
+	if err = setup.AddCourse(replicaCourse); err != nil {
+		t.Fatal(err)
+	}
+	if err = execStmt(setup.ctx, setup.conn, "GRANT SELECT ON ALL TABLES IN SCHEMA public TO reader"); err != nil {
+		t.Fatal(err)
+	}
+	if err = setup.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	readerUrl := strings.Replace(replicaUrl, "uzer:pazzword@", "reader:reader@", 1)
+	if d, err := New(readerUrl, "", 0, context.Background()); err == nil {
+		d.Close()
+		t.Fatal("got nil, want an error setting up the schema in a read-only transaction")
+	}
+
+	replica, err := New(readerUrl, "", 0, context.Background(), itpgDB.WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+
+	d := itpgDB.NewReplicaDB(TestDB, replica)
+
+	replicaCourses, err := d.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replicaCourses) != 1 || !cmp.Equal(replicaCourses[0], replicaCourse) {
+		t.Errorf("got %v, want the courses of the replica", replicaCourses)
+	}
+
+	newCourse := &itpgDB.Course{Code: "GC8", Name: "Rally Driving"}
+	if err = d.AddCourse(newCourse); err != nil {
+		t.Fatal(err)
+	}
+
+	primaryCourses, err := TestDB.GetLastCourses(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(primaryCourses, func(c *itpgDB.Course) bool { return c.Code == newCourse.Code }) {
+		t.Errorf("got %v, want the added course in the primary", primaryCourses)
+	}
+
+	if err = replica.AddCourse(newCourse); err == nil {
+		t.Error("got nil, want an error adding a course to the read-only replica")
+	}
 }
@@ -0,0 +1,375 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// ReplicaDB is a database that routes read operations to a read replica,
+// and write operations to the primary database.
+type ReplicaDB struct {
+	primary DB // primary is the database used for writes.
+	replica DB // replica is the database used for reads.
+}
+
+// NewReplicaDB returns a new ReplicaDB routing writes to primary and reads to replica.
+func NewReplicaDB(primary, replica DB) *ReplicaDB {
+	return &ReplicaDB{primary: primary, replica: replica}
+}
+
+// Close closes the connections to the primary and replica databases.
+func (r *ReplicaDB) Close() error {
+	return errors.Join(r.primary.Close(), r.replica.Close())
+}
+
+// Ping checks that the connections to the primary and replica databases are alive.
+func (r *ReplicaDB) Ping() error {
+	return errors.Join(r.primary.Ping(), r.replica.Ping())
+}
+
+// PingCache checks that the connection to the cache of the primary database is alive.
+func (r *ReplicaDB) PingCache() error {
+	return r.primary.PingCache()
+}
+
+// FlushCache deletes all the keys from the caches of the primary and replica databases.
+func (r *ReplicaDB) FlushCache() error {
+	return errors.Join(r.primary.FlushCache(), r.replica.FlushCache())
+}
+
+// AddCourse adds a new course to the primary database.
+func (r *ReplicaDB) AddCourse(course *Course) error {
+	return r.primary.AddCourse(course)
+}
+
+// AddCourseMany adds new courses to the primary database.
+func (r *ReplicaDB) AddCourseMany(courses []*Course) ([]error, error) {
+	return r.primary.AddCourseMany(courses)
+}
+
+// UpdateCourseName renames a course in the primary database.
+func (r *ReplicaDB) UpdateCourseName(code, newName string) error {
+	return r.primary.UpdateCourseName(code, newName)
+}
+
+// AddProfessor adds a new professor to the primary database.
+func (r *ReplicaDB) AddProfessor(name string) error {
+	return r.primary.AddProfessor(name)
+}
+
+// AddProfessorMany adds new professors to the primary database.
+func (r *ReplicaDB) AddProfessorMany(names []string) ([]error, error) {
+	return r.primary.AddProfessorMany(names)
+}
+
+// RenameProfessor renames a professor in the primary database.
+func (r *ReplicaDB) RenameProfessor(professorUUID, newName string) error {
+	return r.primary.RenameProfessor(professorUUID, newName)
+}
+
+// UpdateProfessorName renames a professor in the primary database.
+func (r *ReplicaDB) UpdateProfessorName(professorUUID, newName string) error {
+	return r.primary.UpdateProfessorName(professorUUID, newName)
+}
+
+// AddCourseProfessor adds a professor to a course in the primary database.
+func (r *ReplicaDB) AddCourseProfessor(professorUUID, courseCode string) error {
+	return r.primary.AddCourseProfessor(professorUUID, courseCode)
+}
+
+// AddCourseProfessorMany adds professors to courses in the primary database.
+func (r *ReplicaDB) AddCourseProfessorMany(professorUUIDS, courseCodes []string) error {
+	return r.primary.AddCourseProfessorMany(professorUUIDS, courseCodes)
+}
+
+// RemoveCourseProfessor removes a professor from a course in the primary database.
+func (r *ReplicaDB) RemoveCourseProfessor(professorUUID, courseCode string) error {
+	return r.primary.RemoveCourseProfessor(professorUUID, courseCode)
+}
+
+// RemoveCourse removes a course from the primary database.
+func (r *ReplicaDB) RemoveCourse(courseCode string, forceDelete bool) error {
+	return r.primary.RemoveCourse(courseCode, forceDelete)
+}
+
+// RemoveProfessor removes a professor from the primary database.
+func (r *ReplicaDB) RemoveProfessor(professorUUID string, forceDelete bool) error {
+	return r.primary.RemoveProfessor(professorUUID, forceDelete)
+}
+
+// SoftRemoveCourse soft-deletes a course in the primary database.
+func (r *ReplicaDB) SoftRemoveCourse(courseCode string) error {
+	return r.primary.SoftRemoveCourse(courseCode)
+}
+
+// SoftRemoveProfessor soft-deletes a professor in the primary database.
+func (r *ReplicaDB) SoftRemoveProfessor(professorUUID string) error {
+	return r.primary.SoftRemoveProfessor(professorUUID)
+}
+
+// RestoreCourse restores a soft-deleted course in the primary database.
+func (r *ReplicaDB) RestoreCourse(courseCode string) error {
+	return r.primary.RestoreCourse(courseCode)
+}
+
+// RestoreProfessor restores a soft-deleted professor in the primary database.
+func (r *ReplicaDB) RestoreProfessor(professorUUID string) error {
+	return r.primary.RestoreProfessor(professorUUID)
+}
+
+// GetTrash retrieves the soft-deleted courses and professors from the replica database.
+func (r *ReplicaDB) GetTrash() (*Trash, error) {
+	return r.replica.GetTrash()
+}
+
+// GetLastCourses retrieves the last courses from the replica database.
+func (r *ReplicaDB) GetLastCourses(limit, offset int) ([]*Course, error) {
+	return r.replica.GetLastCourses(limit, offset)
+}
+
+// GetLastProfessors retrieves the last professors from the replica database.
+func (r *ReplicaDB) GetLastProfessors(sort ProfessorSort, limit, offset int) ([]*Professor, error) {
+	return r.replica.GetLastProfessors(sort, limit, offset)
+}
+
+// GetLastScores retrieves the last scores from the replica database.
+func (r *ReplicaDB) GetLastScores(limit, offset int) ([]*Score, error) {
+	return r.replica.GetLastScores(limit, offset)
+}
+
+// ExportCourses calls fn with each course of the replica database.
+func (r *ReplicaDB) ExportCourses(fn func(*Course) error) error {
+	return r.replica.ExportCourses(fn)
+}
+
+// ExportProfessors calls fn with each professor of the replica database.
+func (r *ReplicaDB) ExportProfessors(fn func(*Professor) error) error {
+	return r.replica.ExportProfessors(fn)
+}
+
+// ExportScores calls fn with the average scores of each professor in each of their courses in the replica database.
+func (r *ReplicaDB) ExportScores(fn func(*Score) error) error {
+	return r.replica.ExportScores(fn)
+}
+
+// GetCoursesBetween retrieves the courses added between the specified times from the replica database.
+func (r *ReplicaDB) GetCoursesBetween(from, to time.Time) ([]*Course, error) {
+	return r.replica.GetCoursesBetween(from, to)
+}
+
+// GetRandomCourses retrieves a random selection of courses from the replica database.
+func (r *ReplicaDB) GetRandomCourses(n int) ([]*Course, error) {
+	return r.replica.GetRandomCourses(n)
+}
+
+// GetProfessorsBetween retrieves the professors added between the specified times from the replica database.
+func (r *ReplicaDB) GetProfessorsBetween(from, to time.Time) ([]*Professor, error) {
+	return r.replica.GetProfessorsBetween(from, to)
+}
+
+// GetUnratedProfessors retrieves the professors without any grades from the replica database.
+func (r *ReplicaDB) GetUnratedProfessors() ([]*Professor, error) {
+	return r.replica.GetUnratedProfessors()
+}
+
+// GetAllScoresStream calls fn with the average scores of each professor in each of their graded courses in the replica database,
+// whose last grade was inserted since the specified time.
+func (r *ReplicaDB) GetAllScoresStream(since time.Time, fn func(*StreamedScore) error) error {
+	return r.replica.GetAllScoresStream(since, fn)
+}
+
+// GetCatalogSince retrieves the courses and professors added after the specified time from the replica database.
+func (r *ReplicaDB) GetCatalogSince(since time.Time) (*CatalogChanges, error) {
+	return r.replica.GetCatalogSince(since)
+}
+
+// GetCoursesByProfessorUUID retrieves the courses taught by a professor from the replica database.
+func (r *ReplicaDB) GetCoursesByProfessorUUID(professorUUID string) ([]*Course, error) {
+	return r.replica.GetCoursesByProfessorUUID(professorUUID)
+}
+
+// GetUngradedCoursesByProfessorUUID retrieves the courses in which a professor has not been graded from the replica database.
+func (r *ReplicaDB) GetUngradedCoursesByProfessorUUID(professorUUID string) ([]*Course, error) {
+	return r.replica.GetUngradedCoursesByProfessorUUID(professorUUID)
+}
+
+// GetProfessorsByCourseCode retrieves the professors teaching a course from the replica database.
+func (r *ReplicaDB) GetProfessorsByCourseCode(courseCode string) ([]*Professor, error) {
+	return r.replica.GetProfessorsByCourseCode(courseCode)
+}
+
+// GetProfessorsForCourses retrieves the professors teaching any of the courses, with their scores, from the replica database.
+func (r *ReplicaDB) GetProfessorsForCourses(courseCodes []string) (map[string][]*Score, error) {
+	return r.replica.GetProfessorsForCourses(courseCodes)
+}
+
+// GetProfessorUUIDByName retrieves the uuid of a professor from the replica database.
+func (r *ReplicaDB) GetProfessorUUIDByName(professorName string) (string, error) {
+	return r.replica.GetProfessorUUIDByName(professorName)
+}
+
+// GetScoresByProfessorUUID retrieves the scores of a professor from the replica database.
+func (r *ReplicaDB) GetScoresByProfessorUUID(professorUUID string) ([]*Score, error) {
+	return r.replica.GetScoresByProfessorUUID(professorUUID)
+}
+
+// GetScoresByProfessorName retrieves the scores of a professor from the replica database.
+func (r *ReplicaDB) GetScoresByProfessorName(professorName string) ([]*Score, error) {
+	return r.replica.GetScoresByProfessorName(professorName)
+}
+
+// GetScoresByProfessorNameLike retrieves the scores of professors with a similar name from the replica database.
+func (r *ReplicaDB) GetScoresByProfessorNameLike(professorName string, mode MatchMode) ([]*Score, error) {
+	return r.replica.GetScoresByProfessorNameLike(professorName, mode)
+}
+
+// GetScoresByCourseName retrieves the scores of a course from the replica database.
+func (r *ReplicaDB) GetScoresByCourseName(courseName string) ([]*Score, error) {
+	return r.replica.GetScoresByCourseName(courseName)
+}
+
+// GetScoresByCourseNameLike retrieves the scores of courses with a similar name from the replica database.
+func (r *ReplicaDB) GetScoresByCourseNameLike(courseName string, mode MatchMode) ([]*Score, error) {
+	return r.replica.GetScoresByCourseNameLike(courseName, mode)
+}
+
+// GetScoresByCourseCode retrieves the scores of a course from the replica database.
+func (r *ReplicaDB) GetScoresByCourseCode(courseCode string) ([]*Score, error) {
+	return r.replica.GetScoresByCourseCode(courseCode)
+}
+
+// GetScoresByCourseCodeLike retrieves the scores of courses with a similar code from the replica database.
+func (r *ReplicaDB) GetScoresByCourseCodeLike(courseCode string) ([]*Score, error) {
+	return r.replica.GetScoresByCourseCodeLike(courseCode)
+}
+
+// GetScoresBetween retrieves the scores computed from the grades given within a time range from the replica database,
+// optionally restricted to a professor and a course.
+func (r *ReplicaDB) GetScoresBetween(professorUUID, courseCode string, from, to time.Time) ([]*Score, error) {
+	return r.replica.GetScoresBetween(professorUUID, courseCode, from, to)
+}
+
+// GetScoresBySearch retrieves the scores of professors or courses matching a search query from the replica database.
+func (r *ReplicaDB) GetScoresBySearch(query string) ([]*Score, error) {
+	return r.replica.GetScoresBySearch(query)
+}
+
+// GradeCourseProfessor grades a professor teaching a course in the primary database.
+func (r *ReplicaDB) GradeCourseProfessor(professorUUID, courseCode, username string, grades [3]float32) error {
+	return r.primary.GradeCourseProfessor(professorUUID, courseCode, username, grades)
+}
+
+// GradeCourseProfessorWithDetails grades a professor teaching a course with optional details in the primary database.
+func (r *ReplicaDB) GradeCourseProfessorWithDetails(professorUUID, courseCode, username string, grades [3]float32, details *GradeDetails) error {
+	return r.primary.GradeCourseProfessorWithDetails(professorUUID, courseCode, username, grades, details)
+}
+
+// GradeCourseProfessorMany grades professors teaching courses in the primary database.
+func (r *ReplicaDB) GradeCourseProfessorMany(username string, grades []*Grade) ([]error, error) {
+	return r.primary.GradeCourseProfessorMany(username, grades)
+}
+
+// UpdateGrade updates the grade of a user for a professor teaching a course in the primary database.
+func (r *ReplicaDB) UpdateGrade(professorUUID, courseCode, username string, grades [3]float32, extraGrades map[string]float32) error {
+	return r.primary.UpdateGrade(professorUUID, courseCode, username, grades, extraGrades)
+}
+
+// DeleteGrade deletes the grade of a user for a professor teaching a course from the primary database.
+func (r *ReplicaDB) DeleteGrade(professorUUID, courseCode, username string) error {
+	return r.primary.DeleteGrade(professorUUID, courseCode, username)
+}
+
+// CheckGradedMany checks if a user graded courses and their professors in the replica database.
+func (r *ReplicaDB) CheckGradedMany(username string, pairs []*CourseProfessor) ([]bool, error) {
+	return r.replica.CheckGradedMany(username, pairs)
+}
+
+// GetEligibleGrades retrieves the courses and their professors that a user can still grade from the replica database.
+func (r *ReplicaDB) GetEligibleGrades(username string, limit int) ([]*EligibleGrade, error) {
+	return r.replica.GetEligibleGrades(username, limit)
+}
+
+// MigrateGrades moves the grades of a user to a new username in the primary database.
+func (r *ReplicaDB) MigrateGrades(oldUsername, newUsername string) error {
+	return r.primary.MigrateGrades(oldUsername, newUsername)
+}
+
+// GetRawGrades retrieves the individual grades of a course and its professor from the replica database.
+func (r *ReplicaDB) GetRawGrades(professorUUID, courseCode string) ([]*RawGrade, error) {
+	return r.replica.GetRawGrades(professorUUID, courseCode)
+}
+
+// GetGradeAttemptsByCourseCode retrieves the grade submission counts of a course from the replica database.
+func (r *ReplicaDB) GetGradeAttemptsByCourseCode(courseCode string, since time.Time) (*GradeAttempts, error) {
+	return r.replica.GetGradeAttemptsByCourseCode(courseCode, since)
+}
+
+// Search retrieves the professors and courses matching a search query from the replica database.
+func (r *ReplicaDB) Search(query string) ([]*SearchResult, error) {
+	return r.replica.Search(query)
+}
+
+// GetScoreTrend retrieves the average scores of a course and its professor over time from the replica database.
+func (r *ReplicaDB) GetScoreTrend(professorUUID, courseCode string, bucket TrendBucket) ([]*ScoreTrendPoint, error) {
+	return r.replica.GetScoreTrend(professorUUID, courseCode, bucket)
+}
+
+// GetScoreHistoryByCourseCode retrieves the monthly average scores of a course from the replica database.
+func (r *ReplicaDB) GetScoreHistoryByCourseCode(courseCode string) ([]*ScoreTrendPoint, error) {
+	return r.replica.GetScoreHistoryByCourseCode(courseCode)
+}
+
+// GetScoreDistributionByProfessorUUID retrieves the number of grades of a professor in each score range from the replica database.
+func (r *ReplicaDB) GetScoreDistributionByProfessorUUID(professorUUID string) (*ScoreDistribution, error) {
+	return r.replica.GetScoreDistributionByProfessorUUID(professorUUID)
+}
+
+// GetBottomRatedProfessors retrieves the lowest rated professors from the replica database.
+func (r *ReplicaDB) GetBottomRatedProfessors(limit int) ([]*ProfessorRating, error) {
+	return r.replica.GetBottomRatedProfessors(limit)
+}
+
+// GetTopProfessors retrieves the highest rated professors by the specified score from the replica database.
+func (r *ReplicaDB) GetTopProfessors(limit int, by RankingScore) ([]*ProfessorRating, error) {
+	return r.replica.GetTopProfessors(limit, by)
+}
+
+// GetMostRatedCourses retrieves the courses with the most grades from the replica database.
+func (r *ReplicaDB) GetMostRatedCourses(limit int) ([]*PopularCourse, error) {
+	return r.replica.GetMostRatedCourses(limit)
+}
+
+// GetDepartmentStats retrieves the number of courses and professors, and the average score of each department from the replica database.
+func (r *ReplicaDB) GetDepartmentStats() ([]*DepartmentStats, error) {
+	return r.replica.GetDepartmentStats()
+}
+
+// GetComponentAverages retrieves the average of each score component across all the grades from the replica database.
+func (r *ReplicaDB) GetComponentAverages() (*ComponentAverages, error) {
+	return r.replica.GetComponentAverages()
+}
+
+// Stats retrieves the number of courses, professors, and grades from the replica database.
+func (r *ReplicaDB) Stats() (*Stats, error) {
+	return r.replica.Stats()
+}
+
+// GetScoreDimensions retrieves the average score of a professor in a course for each score dimension from the replica database.
+func (r *ReplicaDB) GetScoreDimensions(courseCode, professorUUID string) ([]*DimensionScore, error) {
+	return r.replica.GetScoreDimensions(courseCode, professorUUID)
+}
+
+// GetProfessorDetail retrieves a professor with the courses they teach and their scores from the replica database.
+func (r *ReplicaDB) GetProfessorDetail(professorUUID string) (*ProfessorDetail, error) {
+	return r.replica.GetProfessorDetail(professorUUID)
+}
+
+// GetProfessorRank retrieves the rank of a professor within their department from the replica database.
+func (r *ReplicaDB) GetProfessorRank(professorUUID string) (*ProfessorRank, error) {
+	return r.replica.GetProfessorRank(professorUUID)
+}
+
+// GetCourseDetail retrieves a course with the professors teaching it and their scores from the replica database.
+func (r *ReplicaDB) GetCourseDetail(courseCode string) (*CourseDetail, error) {
+	return r.replica.GetCourseDetail(courseCode)
+}
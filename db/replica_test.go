@@ -0,0 +1,72 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+// recordDB is a database recording the operations it receives.
+type recordDB struct {
+	DB
+	ops []string
+}
+
+func (r *recordDB) Close() error {
+	r.ops = append(r.ops, "Close")
+	return nil
+}
+
+func (r *recordDB) AddCourse(*Course) error {
+	r.ops = append(r.ops, "AddCourse")
+	return nil
+}
+
+func (r *recordDB) GradeCourseProfessor(string, string, string, [3]float32) error {
+	r.ops = append(r.ops, "GradeCourseProfessor")
+	return nil
+}
+
+func (r *recordDB) GetLastCourses(int, int) ([]*Course, error) {
+	r.ops = append(r.ops, "GetLastCourses")
+	return nil, nil
+}
+
+func (r *recordDB) GetScoresByCourseCode(string) ([]*Score, error) {
+	r.ops = append(r.ops, "GetScoresByCourseCode")
+	return nil, nil
+}
+
+func TestReplicaDB(t *testing.T) {
+	primary, replica := &recordDB{}, &recordDB{}
+	db := NewReplicaDB(primary, replica)
+
+	if err := db.AddCourse(&Course{Code: "S209", Name: "How to replicate"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.GradeCourseProfessor("uuid", "S209", "jim", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.GetLastCourses(10, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.GetScoresByCourseCode("S209"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"AddCourse", "GradeCourseProfessor", "Close"}
+	if !slices.Equal(primary.ops, expected) {
+		t.Errorf("got %v, want %v", primary.ops, expected)
+	}
+
+	expected = []string{"GetLastCourses", "GetScoresByCourseCode", "Close"}
+	if !slices.Equal(replica.ops, expected) {
+		t.Errorf("got %v, want %v", replica.ops, expected)
+	}
+}
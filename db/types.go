@@ -1,36 +1,219 @@
 package db
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"github.com/vanillaiice/itpg/metrics"
+)
+
+// Database errors, wrapping the errors returned by the database drivers.
+var (
+	// ErrNotFound indicates that the requested row does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate indicates that the row already exists.
+	ErrDuplicate = errors.New("duplicate")
+	// ErrForeignKey indicates that a row references a missing row, or is still referenced by other rows.
+	ErrForeignKey = errors.New("foreign key violation")
+	// ErrInvalid indicates that a value is not accepted by the table constraints.
+	ErrInvalid = errors.New("invalid value")
+	// ErrTimeout indicates that the operation did not complete within the query timeout.
+	ErrTimeout = errors.New("query timed out")
+)
+
+// ErrBatchFailed is returned when items of a batch fail to be added, and no item of the batch is added.
+var ErrBatchFailed = errors.New("batch failed, no items were added")
+
+// ErrNoCache is returned when pinging the cache of a database without a cache.
+var ErrNoCache = errors.New("cache not configured")
+
+// LikelyDuplicateError is returned when adding a professor whose normalized name matches the name of an existing professor.
+// It wraps ErrDuplicate.
+type LikelyDuplicateError struct {
+	Professor *Professor // Existing professor whose name matches
+}
+
+// Error returns the error message, suggesting the existing professor.
+func (e *LikelyDuplicateError) Error() string {
+	return fmt.Sprintf("%s: likely duplicate of professor %s (%s)", ErrDuplicate, e.Professor.Name, e.Professor.UUID)
+}
+
+// Unwrap returns ErrDuplicate.
+func (e *LikelyDuplicateError) Unwrap() error {
+	return ErrDuplicate
+}
+
+// NormalizeName returns a name in lowercase, without punctuation, and with its words separated by a single space,
+// so that names only differing by case, punctuation, or spacing are equal.
+func NormalizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// DefaultGradeWeight is the weight of a grade in the averages, unless specified otherwise.
+const DefaultGradeWeight float32 = 1
+
+// MaxRecentComments is the maximum number of recent comments returned with a score.
+const MaxRecentComments = 5
+
+// BatchError returns ErrBatchFailed if any of the errors of the items of a batch is not nil.
+func BatchError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return ErrBatchFailed
+		}
+	}
+	return nil
+}
+
+// QueryContext returns a context derived from ctx for a database operation, canceled after the query timeout of opts if it is positive,
+// and a function canceling it, which wraps err with ErrTimeout if the timeout was exceeded.
+// The function also records the duration of the operation, if metrics are enabled,
+// and logs it if it exceeded the slow query threshold of opts.
+func QueryContext(ctx context.Context, opts *Options, err *error) (context.Context, func()) {
+	observe := observeQuery(opts.SlowQueryThreshold)
+
+	if opts.QueryTimeout <= 0 {
+		return ctx, observe
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.QueryTimeout)
+	return ctx, func() {
+		if *err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			*err = fmt.Errorf("%w: %w", ErrTimeout, *err)
+		}
+		cancel()
+		observe()
+	}
+}
+
+// StreamContext returns ctx for a database operation streaming its rows to a callback, and a function ending it,
+// which records the duration of the operation if metrics are enabled.
+// The query timeout and the slow query threshold are not applied, since the operation lasts as long as the callback
+// takes to consume the rows, such as while a large export is downloaded.
+func StreamContext(ctx context.Context) (context.Context, func()) {
+	return ctx, observeQuery(0)
+}
+
+// observeQuery returns a function recording the duration of the database operation calling QueryContext,
+// labeled with the name of the method of the operation, and logging it as a warning if it exceeded slowThreshold (if positive).
+func observeQuery(slowThreshold time.Duration) func() {
+	if !metrics.Enabled() && slowThreshold <= 0 {
+		return func() {}
+	}
+
+	method := "unknown"
+	if pc, _, _, ok := runtime.Caller(2); ok {
+		if f := runtime.FuncForPC(pc); f != nil {
+			method = f.Name()[strings.LastIndex(f.Name(), ".")+1:]
+		}
+	}
+
+	start := time.Now()
+	return func() {
+		duration := time.Since(start)
+		if metrics.Enabled() {
+			metrics.DbQueryDuration.Observe(duration.Seconds(), method)
+		}
+		if slowThreshold > 0 && duration > slowThreshold {
+			log.Warn().Str("method", method).Dur("duration", duration).Msg("slow query")
+		}
+	}
+}
+
 // DB is the database interface.
 type DB interface {
 	Close() error
+	Ping() error
+	PingCache() error
+	FlushCache() error
 	AddCourse(course *Course) error
-	AddCourseMany([]*Course) error
+	AddCourseMany([]*Course) ([]error, error)
+	UpdateCourseName(code, newName string) error
 	AddProfessor(string) error
-	AddProfessorMany(names []string) error
+	AddProfessorMany(names []string) ([]error, error)
+	RenameProfessor(professorUUID, newName string) error
+	UpdateProfessorName(professorUUID, newName string) error
 	AddCourseProfessor(professorUUID, courseCode string) error
 	AddCourseProfessorMany(professorUUIDS, courseCodes []string) error
+	RemoveCourseProfessor(professorUUID, courseCode string) error
 	RemoveCourse(string, bool) error
 	RemoveProfessor(string, bool) error
-	GetLastCourses() ([]*Course, error)
-	GetLastProfessors() ([]*Professor, error)
-	GetLastScores() ([]*Score, error)
+	SoftRemoveCourse(string) error
+	SoftRemoveProfessor(string) error
+	RestoreCourse(string) error
+	RestoreProfessor(string) error
+	GetTrash() (*Trash, error)
+	GetLastCourses(int, int) ([]*Course, error)
+	GetLastProfessors(ProfessorSort, int, int) ([]*Professor, error)
+	GetLastScores(int, int) ([]*Score, error)
+	ExportCourses(func(*Course) error) error
+	ExportProfessors(func(*Professor) error) error
+	ExportScores(func(*Score) error) error
+	GetAllScoresStream(time.Time, func(*StreamedScore) error) error
+	GetCatalogSince(time.Time) (*CatalogChanges, error)
+	GetCoursesBetween(time.Time, time.Time) ([]*Course, error)
+	GetRandomCourses(int) ([]*Course, error)
+	GetProfessorsBetween(time.Time, time.Time) ([]*Professor, error)
+	GetUnratedProfessors() ([]*Professor, error)
 	GetCoursesByProfessorUUID(string) ([]*Course, error)
+	GetUngradedCoursesByProfessorUUID(string) ([]*Course, error)
 	GetProfessorsByCourseCode(string) ([]*Professor, error)
+	GetProfessorsForCourses([]string) (map[string][]*Score, error)
 	GetProfessorUUIDByName(string) (string, error)
 	GetScoresByProfessorUUID(string) ([]*Score, error)
 	GetScoresByProfessorName(string) ([]*Score, error)
-	GetScoresByProfessorNameLike(string) ([]*Score, error)
+	GetScoresByProfessorNameLike(string, MatchMode) ([]*Score, error)
 	GetScoresByCourseName(string) ([]*Score, error)
-	GetScoresByCourseNameLike(string) ([]*Score, error)
+	GetScoresByCourseNameLike(string, MatchMode) ([]*Score, error)
 	GetScoresByCourseCode(string) ([]*Score, error)
 	GetScoresByCourseCodeLike(string) ([]*Score, error)
+	GetScoresBySearch(string) ([]*Score, error)
+	GetScoresBetween(string, string, time.Time, time.Time) ([]*Score, error)
+	Search(string) ([]*SearchResult, error)
 	GradeCourseProfessor(string, string, string, [3]float32) error
+	GradeCourseProfessorWithDetails(string, string, string, [3]float32, *GradeDetails) error
+	GradeCourseProfessorMany(string, []*Grade) ([]error, error)
+	UpdateGrade(string, string, string, [3]float32, map[string]float32) error
+	DeleteGrade(string, string, string) error
+	CheckGradedMany(string, []*CourseProfessor) ([]bool, error)
+	GetEligibleGrades(string, int) ([]*EligibleGrade, error)
+	MigrateGrades(string, string) error
+	GetRawGrades(string, string) ([]*RawGrade, error)
+	GetGradeAttemptsByCourseCode(string, time.Time) (*GradeAttempts, error)
+	GetScoreTrend(string, string, TrendBucket) ([]*ScoreTrendPoint, error)
+	GetScoreHistoryByCourseCode(string) ([]*ScoreTrendPoint, error)
+	GetScoreDistributionByProfessorUUID(string) (*ScoreDistribution, error)
+	GetBottomRatedProfessors(int) ([]*ProfessorRating, error)
+	GetTopProfessors(int, RankingScore) ([]*ProfessorRating, error)
+	GetMostRatedCourses(int) ([]*PopularCourse, error)
+	GetDepartmentStats() ([]*DepartmentStats, error)
+	GetComponentAverages() (*ComponentAverages, error)
+	Stats() (*Stats, error)
+	GetScoreDimensions(string, string) ([]*DimensionScore, error)
+	GetProfessorDetail(string) (*ProfessorDetail, error)
+	GetProfessorRank(string) (*ProfessorRank, error)
+	GetCourseDetail(string) (*CourseDetail, error)
 }
 
 // Course represents a course with its code and name.
 type Course struct {
-	Code string `json:"code"` // Code of the course
-	Name string `json:"name"` // Name of the course
+	Code       string `json:"code"`                 // Code of the course
+	Name       string `json:"name"`                 // Name of the course
+	Department string `json:"department,omitempty"` // Department of the course, if any
+	Credits    *int   `json:"credits,omitempty"`    // Credit hours of the course, if any
 }
 
 // Professor represents a professor with surname, middle name, and name.
@@ -39,15 +222,337 @@ type Professor struct {
 	Name string `json:"name"` // Name of the professor
 }
 
-// Score represents a score for a course and its professor
+// DeletedCourse represents a soft-deleted course with the time it was removed.
+type DeletedCourse struct {
+	Course
+	DeletedAt time.Time `json:"deletedAt"` // Time the course was soft-deleted
+}
+
+// DeletedProfessor represents a soft-deleted professor with the time they were removed.
+type DeletedProfessor struct {
+	Professor
+	DeletedAt time.Time `json:"deletedAt"` // Time the professor was soft-deleted
+}
+
+// Trash represents the soft-deleted courses and professors, which can be restored.
+type Trash struct {
+	Courses    []*DeletedCourse    `json:"courses"`    // Soft-deleted courses, the most recently removed first
+	Professors []*DeletedProfessor `json:"professors"` // Soft-deleted professors, the most recently removed first
+}
+
+// CourseProfessor represents a course and a professor teaching it.
+type CourseProfessor struct {
+	ProfessorUUID string `json:"uuid"` // UUID of the professor
+	CourseCode    string `json:"code"` // Code of the course
+}
+
+// EligibleGrade represents a course and its professor that a user can still grade.
+type EligibleGrade struct {
+	ProfessorUUID string `json:"profUUID"`   // UUID of the professor
+	ProfessorName string `json:"profName"`   // Name of the professor
+	CourseCode    string `json:"courseCode"` // Code of the course
+	CourseName    string `json:"courseName"` // Name of the course
+}
+
+// Score represents a score for a course and its professor.
+// The names of the professor and the course are always populated.
 type Score struct {
-	ProfessorUUID   string  `json:"profUUID"`        // UUID of the professor
-	ProfessorName   string  `json:"profName"`        // Name of the professor
+	ProfessorUUID   string   `json:"profUUID"`           // UUID of the professor
+	ProfessorName   string   `json:"profName"`           // Name of the professor
+	CourseCode      string   `json:"courseCode"`         // Code of the course
+	CourseName      string   `json:"courseName"`         // Name of the course
+	ScoreTeaching   float32  `json:"scoreTeaching"`      // Score related to the Teaching style/method of the professor
+	ScoreCourseWork float32  `json:"scoreCoursework"`    // Score related to the homeworks, quizzes, and exams given by the professor
+	ScoreLearning   float32  `json:"scoreLearning"`      // Score related to the learning outcomes of the course
+	ScoreAverage    float32  `json:"scoreAverage"`       // Average score of the teaching, coursework, and learning scores
+	ScoreMedian     float32  `json:"scoreMedian"`        // Weighted median of the overall grades, the overall grade of a student being the mean of their teaching, coursework, and learning grades
+	ScoreStdDev     float32  `json:"scoreStdDev"`        // Weighted standard deviation of the overall grades
+	Count           int      `json:"count"`              // Numbero of students who graded this course
+	Verified        bool     `json:"verified"`           // Whether enough students graded this course for the score to be trusted
+	Comments        []string `json:"comments,omitempty"` // Most recent comments of the grades, only populated when getting scores by professor uuid or course code
+}
+
+// GradeDetails represents the optional details given with a grade.
+type GradeDetails struct {
+	Weight  float32            // Weight of the grade in the averages
+	Comment string             // Written review given with the grade, if any
+	Grades  map[string]float32 // Grades of the extra score dimensions, by dimension
+}
+
+// Grade represents a grade given to a professor teaching a course, as part of a batch of grades.
+type Grade struct {
+	ProfessorUUID string       // UUID of the professor
+	CourseCode    string       // Code of the course
+	Grades        [3]float32   // Teaching, coursework, and learning grades
+	Details       GradeDetails // Weight and comment of the grade
+}
+
+// DepartmentStats represents the number of courses and professors of a department, and its average score.
+type DepartmentStats struct {
+	Department   string  `json:"department"`   // Name of the department
+	Courses      int     `json:"courses"`      // Number of courses of the department
+	Professors   int     `json:"professors"`   // Number of professors teaching courses of the department
+	ScoreAverage float32 `json:"scoreAverage"` // Average score of the grades of the courses of the department
+}
+
+// Stats represents the totals of an instance.
+type Stats struct {
+	Courses    int `json:"courses"`    // Number of courses
+	Professors int `json:"professors"` // Number of professors
+	Grades     int `json:"grades"`     // Number of grades submitted
+	Users      int `json:"users"`      // Number of registered users, which are not stored in the database
+}
+
+// ComponentAverages represents the average of each score component across all the grades.
+type ComponentAverages struct {
+	ScoreTeaching   float32            `json:"scoreTeaching"`        // Average teaching score of all the grades
+	ScoreCourseWork float32            `json:"scoreCoursework"`      // Average coursework score of all the grades
+	ScoreLearning   float32            `json:"scoreLearning"`        // Average learning score of all the grades
+	Dimensions      map[string]float32 `json:"dimensions,omitempty"` // Average score of each extra score dimension of all the grades
+	Count           int                `json:"count"`                // Number of grades
+}
+
+// StreamedScore represents the average scores of a professor in a course, with the time of their last grade.
+type StreamedScore struct {
+	ProfessorUUID   string    `json:"profUUID"`        // UUID of the professor
+	ProfessorName   string    `json:"profName"`        // Name of the professor
+	CourseCode      string    `json:"courseCode"`      // Code of the course
+	CourseName      string    `json:"courseName"`      // Name of the course
+	ScoreTeaching   float32   `json:"scoreTeaching"`   // Average teaching score of the professor in the course
+	ScoreCourseWork float32   `json:"scoreCoursework"` // Average coursework score of the professor in the course
+	ScoreLearning   float32   `json:"scoreLearning"`   // Average learning score of the professor in the course
+	ScoreAverage    float32   `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int       `json:"count"`           // Number of grades of the professor in the course
+	InsertedAt      time.Time `json:"insertedAt"`      // Time at which the last grade of the professor in the course was inserted
+}
+
+// CatalogChanges represents the courses and professors added to the catalog since a version.
+type CatalogChanges struct {
+	Courses    []*Course    // Courses added since the version, in the order they were added
+	Professors []*Professor // Professors added since the version, in the order they were added
+	Version    time.Time    // Time at which the last course or professor of the catalog was inserted
+}
+
+// DimensionScore represents the average score of a professor in a course for one score dimension.
+type DimensionScore struct {
+	Dimension string  `json:"dimension"` // Name of the score dimension
+	Score     float32 `json:"score"`     // Average score of the professor in the course for the dimension
+	Count     int     `json:"count"`     // Number of grades of the dimension
+}
+
+// CourseScore represents the average scores of a professor in one of their courses.
+type CourseScore struct {
 	CourseCode      string  `json:"courseCode"`      // Code of the course
 	CourseName      string  `json:"courseName"`      // Name of the course
-	ScoreTeaching   float32 `json:"scoreTeaching"`   // Score related to the Teaching style/method of the professor
-	ScoreCourseWork float32 `json:"scoreCoursework"` // Score related to the homeworks, quizzes, and exams given by the professor
-	ScoreLearning   float32 `json:"scoreLearning"`   // Score related to the learning outcomes of the course
-	ScoreAverage    float32 `json:"scoreAverage"`    // Average score of the teaching, coursework, and learning scores
-	Count           int     `json:"count"`           // Numbero of students who graded this course
+	ScoreTeaching   float32 `json:"scoreTeaching"`   // Average teaching score of the professor in the course
+	ScoreCourseWork float32 `json:"scoreCoursework"` // Average coursework score of the professor in the course
+	ScoreLearning   float32 `json:"scoreLearning"`   // Average learning score of the professor in the course
+	ScoreAverage    float32 `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int     `json:"count"`           // Number of grades of the professor in the course
+}
+
+// ProfessorDetail represents a professor with the courses they teach, and their scores in each course and across all courses.
+type ProfessorDetail struct {
+	ProfessorUUID   string         `json:"profUUID"`        // UUID of the professor
+	ProfessorName   string         `json:"profName"`        // Name of the professor
+	Courses         []*CourseScore `json:"courses"`         // Courses taught by the professor, with their scores
+	ScoreTeaching   float32        `json:"scoreTeaching"`   // Average teaching score of the professor across all courses
+	ScoreCourseWork float32        `json:"scoreCoursework"` // Average coursework score of the professor across all courses
+	ScoreLearning   float32        `json:"scoreLearning"`   // Average learning score of the professor across all courses
+	ScoreAverage    float32        `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int            `json:"count"`           // Number of grades of the professor
+}
+
+// ProfessorRank represents the rank of a professor by average score among the professors teaching courses of their department.
+type ProfessorRank struct {
+	ProfessorUUID string  `json:"profUUID"`     // UUID of the professor
+	ProfessorName string  `json:"profName"`     // Name of the professor
+	Department    string  `json:"department"`   // Department of which the professor teaches the most courses
+	Rank          int     `json:"rank"`         // Rank of the professor in the department, starting at 1
+	Total         int     `json:"total"`        // Number of professors teaching courses of the department
+	ScoreAverage  float32 `json:"scoreAverage"` // Average score of the professor across all their courses
+}
+
+// ProfessorScore represents the average scores of one of the professors teaching a course.
+type ProfessorScore struct {
+	ProfessorUUID   string  `json:"profUUID"`        // UUID of the professor
+	ProfessorName   string  `json:"profName"`        // Name of the professor
+	ScoreTeaching   float32 `json:"scoreTeaching"`   // Average teaching score of the professor in the course
+	ScoreCourseWork float32 `json:"scoreCoursework"` // Average coursework score of the professor in the course
+	ScoreLearning   float32 `json:"scoreLearning"`   // Average learning score of the professor in the course
+	ScoreAverage    float32 `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int     `json:"count"`           // Number of grades of the professor in the course
+}
+
+// CourseDetail represents a course with the professors teaching it, and the scores of each professor and across all professors.
+type CourseDetail struct {
+	CourseCode      string            `json:"courseCode"`           // Code of the course
+	CourseName      string            `json:"courseName"`           // Name of the course
+	Department      string            `json:"department,omitempty"` // Department of the course, if any
+	Professors      []*ProfessorScore `json:"professors"`           // Professors teaching the course, with their scores
+	ScoreTeaching   float32           `json:"scoreTeaching"`        // Average teaching score of the course across all professors
+	ScoreCourseWork float32           `json:"scoreCoursework"`      // Average coursework score of the course across all professors
+	ScoreLearning   float32           `json:"scoreLearning"`        // Average learning score of the course across all professors
+	ScoreAverage    float32           `json:"scoreAverage"`         // Average of the teaching, coursework, and learning scores
+	Count           int               `json:"count"`                // Number of grades of the course
+}
+
+// ProfessorRating represents the scores of a professor across all their courses.
+type ProfessorRating struct {
+	ProfessorUUID   string  `json:"profUUID"`        // UUID of the professor
+	ProfessorName   string  `json:"profName"`        // Name of the professor
+	ScoreTeaching   float32 `json:"scoreTeaching"`   // Average teaching score of the professor
+	ScoreCourseWork float32 `json:"scoreCoursework"` // Average coursework score of the professor
+	ScoreLearning   float32 `json:"scoreLearning"`   // Average learning score of the professor
+	ScoreAverage    float32 `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int     `json:"count"`           // Number of grades of the professor
+}
+
+// PopularCourse represents a course with its number of grades across all its professors.
+type PopularCourse struct {
+	CourseCode string `json:"courseCode"` // Code of the course
+	CourseName string `json:"courseName"` // Name of the course
+	Count      int    `json:"count"`      // Number of grades of the course
+}
+
+// RawGrade represents a single grade given to a professor teaching a course.
+type RawGrade struct {
+	ID              int       `json:"id"`                // ID of the grade
+	Hash            string    `json:"hash"`              // Deduplication hash of the grade
+	ScoreTeaching   float32   `json:"scoreTeaching"`     // Teaching score of the grade
+	ScoreCourseWork float32   `json:"scoreCoursework"`   // Coursework score of the grade
+	ScoreLearning   float32   `json:"scoreLearning"`     // Learning score of the grade
+	Weight          float32   `json:"weight"`            // Weight of the grade in the averages
+	Comment         string    `json:"comment,omitempty"` // Written review given with the grade, if any
+	InsertedAt      time.Time `json:"insertedAt"`        // Time at which the grade was given
+}
+
+// ProfessorSort is the order in which professors are sorted.
+type ProfessorSort string
+
+// Enum for professor sort orders
+const (
+	ProfessorSortRecent ProfessorSort = "recent" // ProfessorSortRecent sorts professors by most recently added.
+	ProfessorSortName   ProfessorSort = "name"   // ProfessorSortName sorts professors by name.
+	ProfessorSortRating ProfessorSort = "rating" // ProfessorSortRating sorts professors by overall average score, then by number of grades and recency.
+)
+
+// RankingScore is the score by which professors are ranked.
+type RankingScore string
+
+// Enum for ranking scores
+const (
+	RankingScoreAverage    RankingScore = "average"    // RankingScoreAverage ranks professors by the weighted average of their teaching, coursework, and learning scores.
+	RankingScoreTeaching   RankingScore = "teaching"   // RankingScoreTeaching ranks professors by their teaching score.
+	RankingScoreCoursework RankingScore = "coursework" // RankingScoreCoursework ranks professors by their coursework score.
+	RankingScoreLearning   RankingScore = "learning"   // RankingScoreLearning ranks professors by their learning score.
+)
+
+// GradeAttemptOutcome is the outcome of a grade submission.
+type GradeAttemptOutcome string
+
+// Enum for grade attempt outcomes
+const (
+	GradeAttemptAccepted   GradeAttemptOutcome = "accepted"     // GradeAttemptAccepted is a grade that was stored.
+	GradeAttemptGraded     GradeAttemptOutcome = "graded"       // GradeAttemptGraded is a grade rejected because the course was already graded.
+	GradeAttemptOutOfRange GradeAttemptOutcome = "out_of_range" // GradeAttemptOutOfRange is a grade rejected because a score was out of range.
+)
+
+// GradeAttempts represents the number of grade submissions for a course, by outcome.
+type GradeAttempts struct {
+	CourseCode    string `json:"courseCode"`    // Code of the course
+	Accepted      int    `json:"accepted"`      // Number of accepted grades
+	AlreadyGraded int    `json:"alreadyGraded"` // Number of grades rejected because the course was already graded
+	OutOfRange    int    `json:"outOfRange"`    // Number of grades rejected because a score was out of range
+}
+
+// ScoreDistribution represents the number of grades of a professor in the ranges 0–1, 1–2, 2–3, 3–4, and 4–5,
+// for each of the teaching, coursework, and learning scores. A grade on a range boundary counts in the upper range, except 5.
+type ScoreDistribution struct {
+	Teaching   [5]int `json:"teaching"`   // Number of teaching grades in each range
+	CourseWork [5]int `json:"coursework"` // Number of coursework grades in each range
+	Learning   [5]int `json:"learning"`   // Number of learning grades in each range
+}
+
+// MatchMode is how names are matched against a search string.
+type MatchMode string
+
+// Enum for match modes
+const (
+	MatchSubstring MatchMode = "substring" // MatchSubstring matches names containing the search string.
+	MatchPrefix    MatchMode = "prefix"    // MatchPrefix matches names starting with the search string.
+)
+
+// LikePattern returns the LIKE pattern matching the search string with the match mode.
+func (m MatchMode) LikePattern(search string) string {
+	if m == MatchPrefix {
+		return search + "%"
+	}
+	return "%" + search + "%"
+}
+
+// SearchKind is the kind of a search result.
+type SearchKind string
+
+// Enum for search result kinds
+const (
+	SearchKindProfessor SearchKind = "professor" // SearchKindProfessor is a professor matching the search query.
+	SearchKindCourse    SearchKind = "course"    // SearchKindCourse is a course matching the search query.
+)
+
+// SearchResult represents a professor or a course matching a search query, with its average scores.
+type SearchResult struct {
+	Kind            SearchKind `json:"kind"`            // Kind of the result
+	ID              string     `json:"id"`              // UUID of the professor, or code of the course
+	Name            string     `json:"name"`            // Name of the professor or of the course
+	ScoreTeaching   float32    `json:"scoreTeaching"`   // Average teaching score of the professor or of the course
+	ScoreCourseWork float32    `json:"scoreCoursework"` // Average coursework score of the professor or of the course
+	ScoreLearning   float32    `json:"scoreLearning"`   // Average learning score of the professor or of the course
+	ScoreAverage    float32    `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int        `json:"count"`           // Number of grades of the professor or of the course
+}
+
+// SearchWords splits a search query into its words, separated by any character that is not a letter or a number.
+func SearchWords(query string) []string {
+	return strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// TrendBucket is the time interval used to group grades in a score trend.
+type TrendBucket string
+
+// Enum for trend buckets
+const (
+	TrendBucketDay   TrendBucket = "day"   // TrendBucketDay groups grades by day.
+	TrendBucketWeek  TrendBucket = "week"  // TrendBucketWeek groups grades by week, starting on monday.
+	TrendBucketMonth TrendBucket = "month" // TrendBucketMonth groups grades by month.
+	TrendBucketYear  TrendBucket = "year"  // TrendBucketYear groups grades by year.
+)
+
+// Truncate returns the start of the bucket containing t, in UTC.
+func (b TrendBucket) Truncate(t time.Time) (time.Time, error) {
+	t = t.UTC()
+	switch b {
+	case TrendBucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	case TrendBucketWeek:
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC), nil
+	case TrendBucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case TrendBucketYear:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return t, fmt.Errorf("invalid trend bucket: %s", b)
+	}
+}
+
+// ScoreTrendPoint represents the average scores of a course, or of a course and its professor, within a trend bucket.
+type ScoreTrendPoint struct {
+	Start           time.Time `json:"start"`           // Start of the bucket
+	ScoreTeaching   float32   `json:"scoreTeaching"`   // Average teaching score in the bucket
+	ScoreCourseWork float32   `json:"scoreCoursework"` // Average coursework score in the bucket
+	ScoreLearning   float32   `json:"scoreLearning"`   // Average learning score in the bucket
+	ScoreAverage    float32   `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int       `json:"count"`           // Number of grades in the bucket
 }
@@ -1,14 +1,38 @@
 package mail
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/smtp"
+	"net/textproto"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Notifier sends the confirmation codes of users registering with the service.
+type Notifier interface {
+	// SendConfirmation sends the confirmation code to the specified recipient.
+	SendConfirmation(to, code string) error
+}
+
+// Default connection pool settings of the smtp clients.
+const (
+	defaultIdleTimeout = 30 * time.Second // defaultIdleTimeout is the duration after which an idle connection is closed.
+	maxIdleConns       = 4                // maxIdleConns is the maximum number of idle connections kept for reuse.
+)
+
+// idleConn is a connection to the smtp server kept for reuse.
+type idleConn struct {
+	client    *smtp.Client
+	idleSince time.Time
+}
+
 type SmtpClient struct {
 	host     string
 	url      string
@@ -16,12 +40,19 @@ type SmtpClient struct {
 	username string
 	password string
 	secure   bool
+
+	templates *Templates
+
+	idleTimeout time.Duration
+	mu          sync.Mutex
+	idle        []*idleConn // idle are the connections kept for reuse, the most recently used last.
+	closed      bool
 }
 
 func NewClient(envPath string, secure bool) (*SmtpClient, error) {
 	godotenv.Load(envPath) //nolint:errcheck
 
-	var client SmtpClient
+	client := SmtpClient{idleTimeout: defaultIdleTimeout}
 
 	keys := []string{"MAIL_FROM", "SMTP_HOST", "SMTP_PORT"}
 
@@ -52,21 +83,160 @@ func NewClient(envPath string, secure bool) (*SmtpClient, error) {
 	client.username = keysMap["USERNAME"]
 	client.password = keysMap["PASSWORD"]
 
+	templates, err := LoadTemplates("", DefaultSiteName)
+	if err != nil {
+		return nil, err
+	}
+	client.templates = templates
+
 	return &client, nil
 }
 
+// SetIdleTimeout sets the duration after which an idle connection to the smtp server is closed.
+// A zero duration disables the reuse of connections.
+func (c *SmtpClient) SetIdleTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	c.idleTimeout = timeout
+	c.mu.Unlock()
+}
+
+// SendMail sends the message to the specified address, reusing an idle connection if there is one.
+// A transient failure is retried once on a new connection.
 func (c *SmtpClient) SendMail(mailToAddress string, message []byte) error {
+	err := c.sendMail(mailToAddress, message, true)
+	if err != nil && isTransient(err) {
+		err = c.sendMail(mailToAddress, message, false)
+	}
+	return err
+}
+
+// Close closes the idle connections to the smtp server.
+// Messages can still be sent afterwards, but their connections are not reused.
+func (c *SmtpClient) Close() {
+	c.mu.Lock()
+	c.closed = true
+	idle := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+
+	for _, conn := range idle {
+		conn.client.Quit() //nolint:errcheck
+	}
+}
+
+// sendMail sends the message on an idle connection if reuse is true and there is one, and on a new connection otherwise.
+// The connection is kept for reuse if the message is sent.
+func (c *SmtpClient) sendMail(mailToAddress string, message []byte, reuse bool) error {
+	var client *smtp.Client
+	if reuse {
+		client = c.getIdle()
+	}
+
+	if client == nil {
+		var err error
+		if client, err = c.dial(); err != nil {
+			return err
+		}
+	}
+
+	if err := writeMail(client, c.mailFrom, mailToAddress, message); err != nil {
+		client.Close()
+		return err
+	}
+
+	c.putIdle(client)
+
+	return nil
+}
+
+// dial connects to the smtp server, authenticating over TLS if the client is secure.
+func (c *SmtpClient) dial() (*smtp.Client, error) {
+	client, err := smtp.Dial(c.url)
+	if err != nil {
+		return nil, err
+	}
+
 	if c.secure {
-		return sendMailSmtps(c.username, c.password, c.host, c.url, c.mailFrom, mailToAddress, message)
-	} else {
-		return sendMailSmtp(c.url, c.mailFrom, mailToAddress, message)
+		// as smtp.SendMail does, the connection is upgraded when the server supports it.
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(&tls.Config{ServerName: c.host}); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+
+		if err = client.Auth(smtp.PlainAuth("", c.username, c.password, c.host)); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// getIdle returns the most recently used idle connection, or nil if there is none.
+func (c *SmtpClient) getIdle() *smtp.Client {
+	c.closeExpired()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle) == 0 {
+		return nil
+	}
+
+	conn := c.idle[len(c.idle)-1]
+	c.idle = c.idle[:len(c.idle)-1]
+
+	return conn.client
+}
+
+// putIdle keeps the connection for reuse, or closes it if the pool is full, disabled, or closed.
+func (c *SmtpClient) putIdle(client *smtp.Client) {
+	c.mu.Lock()
+	if c.closed || c.idleTimeout <= 0 || len(c.idle) >= maxIdleConns {
+		c.mu.Unlock()
+		client.Quit() //nolint:errcheck
+		return
 	}
+	c.idle = append(c.idle, &idleConn{client: client, idleSince: time.Now()})
+	timeout := c.idleTimeout
+	c.mu.Unlock()
+
+	time.AfterFunc(timeout, c.closeExpired)
 }
 
-// sendMailSmtps sends an email using smtp over TLS, with smtp authentication.
-func sendMailSmtps(username, password, host, smtpUrl, mailFromAddress, mailToAddress string, message []byte) error {
-	auth := smtp.PlainAuth("", username, password, host)
-	return smtp.SendMail(smtpUrl, auth, mailFromAddress, []string{mailToAddress}, message)
+// closeExpired closes the connections idle for longer than the idle timeout.
+func (c *SmtpClient) closeExpired() {
+	c.mu.Lock()
+	var expired []*idleConn
+	now := time.Now()
+	kept := c.idle[:0]
+	for _, conn := range c.idle {
+		if now.Sub(conn.idleSince) >= c.idleTimeout {
+			expired = append(expired, conn)
+		} else {
+			kept = append(kept, conn)
+		}
+	}
+	c.idle = kept
+	c.mu.Unlock()
+
+	for _, conn := range expired {
+		conn.client.Quit() //nolint:errcheck
+	}
+}
+
+// isTransient returns whether the error is worth retrying on a new connection:
+// a network error, a connection closed by the server, or a 4xx reply of the server.
+func isTransient(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 400 && protoErr.Code < 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
 }
 
 // sendMailSmtp sends an email using smtp without authentication.
@@ -79,11 +249,20 @@ func sendMailSmtp(url, mailFromAddress, mailToAddress string, message []byte) er
 	}
 	defer c.Close()
 
-	if err = c.Mail(mailFromAddress); err != nil {
+	if err = writeMail(c, mailFromAddress, mailToAddress, message); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// writeMail sends the message over the connection, leaving it open for another message.
+func writeMail(c *smtp.Client, mailFromAddress, mailToAddress string, message []byte) error {
+	if err := c.Mail(mailFromAddress); err != nil {
 		return err
 	}
 
-	if err = c.Rcpt(mailToAddress); err != nil {
+	if err := c.Rcpt(mailToAddress); err != nil {
 		return err
 	}
 
@@ -96,23 +275,29 @@ func sendMailSmtp(url, mailFromAddress, mailToAddress string, message []byte) er
 		return err
 	}
 
-	if err = w.Close(); err != nil {
-		return err
-	}
+	return w.Close()
+}
+
+// SetTemplates sets the templates used to render the confirmation and password reset mails.
+func (c *SmtpClient) SetTemplates(templates *Templates) {
+	c.templates = templates
+}
 
-	if err = c.Quit(); err != nil {
+// SendConfirmation sends the confirmation code email to the specified address.
+func (c *SmtpClient) SendConfirmation(to, code string) error {
+	message, err := c.MakeConfCodeMessage(to, code)
+	if err != nil {
 		return err
 	}
-
-	return nil
+	return c.SendMail(to, message)
 }
 
 // MakeConfCodeMessage creates the confirmation code email to be sent.
-func (c *SmtpClient) MakeConfCodeMessage(mailToAddress, confirmationCode string) []byte {
-	return []byte(fmt.Sprintf("To: %s\r\nFrom: %s\r\nDate: %s\r\nSubject: ITPG Account Confirmation Code\r\n\r\nHello %s,\r\n\nYour confirmation code: %s\r\n\nUse this code to complete your registration on itpg.cc.\r\n\nThanks,\r\nITPG Team\r\n\r\nThis is an auto-generated email. Please do not reply to it.\r\n", mailToAddress, c.mailFrom, time.Now().Format(time.RFC1123Z), mailToAddress, confirmationCode))
+func (c *SmtpClient) MakeConfCodeMessage(mailToAddress, confirmationCode string) ([]byte, error) {
+	return makeMessage(c.templates, confirmationTemplate, c.mailFrom, &TemplateData{To: mailToAddress, Code: confirmationCode})
 }
 
 // MakeResetCodeMessage creates the reset password email to be sent.
-func (c *SmtpClient) MakeResetCodeMessage(mailToAddress, resetLink string) []byte {
-	return []byte(fmt.Sprintf("To: %s\r\nFrom: %s\r\nDate: %s\r\nSubject: ITPG Account Password Reset Code\r\n\r\nHello %s,\r\n\nYour password reset link: %s\r\n\nUse this code to reset your password on itpg.cc.\r\n\nThanks,\r\nITPG Team\r\n\r\nThis is an auto-generated email. Please do not reply to it.\r\n", mailToAddress, c.mailFrom, time.Now().Format(time.RFC1123Z), mailToAddress, resetLink))
+func (c *SmtpClient) MakeResetCodeMessage(mailToAddress, resetLink string) ([]byte, error) {
+	return makeMessage(c.templates, resetTemplate, c.mailFrom, &TemplateData{To: mailToAddress, ResetUrl: resetLink})
 }
@@ -1,8 +1,13 @@
 package mail
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/textproto"
 	"testing"
+	"time"
 
 	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 )
@@ -63,3 +68,78 @@ func TestSendMailSmtps(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSendMailPool(t *testing.T) {
+	server := smtpmock.New(smtpmock.ConfigurationAttr{MultipleMessageReceiving: true})
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &SmtpClient{url: fmt.Sprintf("127.0.0.1:%d", server.PortNumber()), mailFrom: "testing@test.com", idleTimeout: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if err := c.SendMail("takumi@fuji.ae", []byte("iamsuperduperfastondownhills")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(c.idle) != 1 {
+		t.Errorf("got %d idle connections, want 1", len(c.idle))
+	}
+
+	c.Close()
+
+	if len(c.idle) != 0 {
+		t.Errorf("got %d idle connections, want none after closing", len(c.idle))
+	}
+
+	if err := server.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendMailRetry(t *testing.T) {
+	server, err := initTestSmtpServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &SmtpClient{url: fmt.Sprintf("127.0.0.1:%d", server.PortNumber()), mailFrom: "testing@test.com", idleTimeout: time.Minute}
+
+	// the idle connection is closed by the server, so the first attempt fails and is retried on a new connection.
+	client, err := c.dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	c.putIdle(client)
+
+	if err = c.SendMail("takumi@fuji.ae", []byte("iamsuperduperfastondownhills")); err != nil {
+		t.Error(err)
+	}
+
+	c.Close()
+
+	if err = server.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&textproto.Error{Code: 421, Msg: "service not available"}, true},
+		{&textproto.Error{Code: 550, Msg: "mailbox unavailable"}, false},
+		{io.EOF, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{errors.New("smtp: server doesn't support AUTH"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v): got %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package mail
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Default delivery settings of the queues.
+const (
+	queueSize         = 100              // queueSize is the capacity of the channel of messages waiting for delivery, and the number of failed messages kept.
+	queueMaxAttempts  = 5                // queueMaxAttempts is the number of delivery attempts after which a message is marked as failed.
+	queueBackoff      = 5 * time.Second  // queueBackoff is the delay before retrying a failed delivery, doubled after each further failure.
+	queueMaxBackoff   = 10 * time.Minute // queueMaxBackoff is the maximum delay before retrying a failed delivery.
+	queuePollInterval = time.Second      // queuePollInterval is the interval at which the messages due for a retry are looked for.
+)
+
+// queueStoreKey is the key of the queued messages in the store.
+const queueStoreKey = "messages"
+
+// Mailer sends mails.
+type Mailer interface {
+	// SendMail sends the message to the specified address.
+	SendMail(to string, message []byte) error
+}
+
+// QueueStore persists the messages of a queue, so that they survive restarts.
+// The key-value stores of the permissionbolt user states satisfy it.
+type QueueStore interface {
+	Set(key, value string) error
+	Get(key string) (string, error)
+}
+
+// MessageKind is the kind of a queued message.
+type MessageKind string
+
+// Enum for message kinds
+const (
+	MessageKindMail         MessageKind = "mail"         // MessageKindMail is a mail sent with the mailer.
+	MessageKindConfirmation MessageKind = "confirmation" // MessageKindConfirmation is a confirmation code sent with the notifier.
+)
+
+// QueuedMessage is a message waiting for delivery, or whose delivery failed.
+type QueuedMessage struct {
+	ID          string      `json:"id"`                  // ID of the message
+	Kind        MessageKind `json:"kind"`                // Kind of the message
+	To          string      `json:"to"`                  // Recipient of the message
+	Attempts    int         `json:"attempts"`            // Number of failed delivery attempts
+	LastError   string      `json:"lastError,omitempty"` // Error of the last failed delivery attempt, if any
+	Failed      bool        `json:"failed"`              // Whether the delivery failed for good
+	QueuedAt    time.Time   `json:"queuedAt"`            // Time at which the message was queued
+	NextAttempt time.Time   `json:"nextAttempt"`         // Time after which the delivery is attempted again
+}
+
+// QueueStatus holds the messages of a queue.
+type QueueStatus struct {
+	Pending []*QueuedMessage `json:"pending"` // Messages waiting for delivery, the oldest first
+	Failed  []*QueuedMessage `json:"failed"`  // Messages whose delivery failed for good, the oldest first
+}
+
+// queuedMessage is a queued message along with its content, as persisted in the store.
+type queuedMessage struct {
+	QueuedMessage
+	Body []byte `json:"body,omitempty"` // Mail, for mail messages
+	Code string `json:"code,omitempty"` // Confirmation code, for confirmation messages
+}
+
+// Queue delivers mails and confirmation codes in the background, so that requests do not wait for them.
+// Failed deliveries are retried with an exponential backoff, and the messages are persisted in a store until delivered.
+type Queue struct {
+	mailer   Mailer
+	notifier Notifier
+	store    QueueStore
+
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+
+	mu       sync.Mutex
+	messages []*queuedMessage // messages are the pending and failed messages, the oldest first.
+
+	ch   chan *queuedMessage
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewQueue returns a queue delivering mails with the mailer and confirmation codes with the notifier,
+// persisting the messages in the store. The messages persisted by a previous queue are delivered again.
+func NewQueue(mailer Mailer, notifier Notifier, store QueueStore) (*Queue, error) {
+	q := &Queue{
+		mailer:       mailer,
+		notifier:     notifier,
+		store:        store,
+		maxAttempts:  queueMaxAttempts,
+		backoff:      queueBackoff,
+		maxBackoff:   queueMaxBackoff,
+		pollInterval: queuePollInterval,
+	}
+
+	if err := q.start(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// start loads the persisted messages, and starts delivering messages in the background.
+func (q *Queue) start() error {
+	// the store has no messages until the first one is queued.
+	if value, err := q.store.Get(queueStoreKey); err == nil && value != "" {
+		if err = json.Unmarshal([]byte(value), &q.messages); err != nil {
+			return err
+		}
+	}
+
+	q.ch = make(chan *queuedMessage, queueSize)
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+
+	go q.run()
+
+	return nil
+}
+
+// Close stops delivering messages, waiting for the delivery in progress if any.
+// The messages not yet delivered stay in the store.
+func (q *Queue) Close() {
+	close(q.stop)
+	<-q.done
+}
+
+// SendAsync queues the mail for delivery to the specified address.
+func (q *Queue) SendAsync(to string, message []byte) error {
+	return q.enqueue(&queuedMessage{QueuedMessage: QueuedMessage{Kind: MessageKindMail, To: to}, Body: message})
+}
+
+// SendConfirmationAsync queues the confirmation code for delivery to the specified recipient.
+func (q *Queue) SendConfirmationAsync(to, code string) error {
+	return q.enqueue(&queuedMessage{QueuedMessage: QueuedMessage{Kind: MessageKindConfirmation, To: to}, Code: code})
+}
+
+// Status returns the pending and failed messages of the queue.
+func (q *Queue) Status() *QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	status := &QueueStatus{Pending: []*QueuedMessage{}, Failed: []*QueuedMessage{}}
+	for _, m := range q.messages {
+		message := m.QueuedMessage
+		if m.Failed {
+			status.Failed = append(status.Failed, &message)
+		} else {
+			status.Pending = append(status.Pending, &message)
+		}
+	}
+
+	return status
+}
+
+// enqueue persists the message, and hands it to the worker.
+func (q *Queue) enqueue(m *queuedMessage) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	m.ID = id.String()
+	m.QueuedAt = time.Now()
+	m.NextAttempt = m.QueuedAt
+
+	q.mu.Lock()
+	q.messages = append(q.messages, m)
+	if err = q.persist(); err != nil {
+		q.messages = q.messages[:len(q.messages)-1]
+		q.mu.Unlock()
+		return err
+	}
+	q.mu.Unlock()
+
+	// if the channel is full, the message is delivered when the worker next looks for due messages.
+	select {
+	case q.ch <- m:
+	default:
+	}
+
+	return nil
+}
+
+// run delivers the messages handed to the worker, and the messages due for a retry, until the queue is closed.
+func (q *Queue) run() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for _, m := range q.due() {
+		q.deliver(m)
+	}
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case m := <-q.ch:
+			q.deliver(m)
+		case <-ticker.C:
+			for _, m := range q.due() {
+				q.deliver(m)
+			}
+		}
+	}
+}
+
+// due returns the messages due for delivery.
+func (q *Queue) due() (due []*queuedMessage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for _, m := range q.messages {
+		if !m.Failed && !now.Before(m.NextAttempt) {
+			due = append(due, m)
+		}
+	}
+
+	return
+}
+
+// deliver sends the message if it is still due, removing it from the queue if it is delivered,
+// and scheduling its next attempt otherwise.
+func (q *Queue) deliver(m *queuedMessage) {
+	q.mu.Lock()
+	if !slices.Contains(q.messages, m) || m.Failed || time.Now().Before(m.NextAttempt) {
+		q.mu.Unlock()
+		return
+	}
+	q.mu.Unlock()
+
+	var err error
+	switch m.Kind {
+	case MessageKindConfirmation:
+		err = q.notifier.SendConfirmation(m.To, m.Code)
+	default:
+		err = q.mailer.SendMail(m.To, m.Body)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err == nil {
+		q.messages = slices.DeleteFunc(q.messages, func(other *queuedMessage) bool { return other == m })
+	} else {
+		m.Attempts++
+		m.LastError = err.Error()
+		if m.Attempts >= q.maxAttempts {
+			m.Failed = true
+			q.dropOldFailed()
+			log.Error().Err(err).Str("id", m.ID).Str("kind", string(m.Kind)).Int("attempts", m.Attempts).Msg("message delivery failed")
+		} else {
+			m.NextAttempt = time.Now().Add(q.backoffAfter(m.Attempts))
+			log.Warn().Err(err).Str("id", m.ID).Str("kind", string(m.Kind)).Int("attempts", m.Attempts).Msg("message delivery failed, retrying")
+		}
+	}
+
+	if err = q.persist(); err != nil {
+		log.Error().Err(err).Msg("persisting message queue failed")
+	}
+}
+
+// backoffAfter returns the delay before retrying a delivery which failed the specified number of times.
+func (q *Queue) backoffAfter(attempts int) time.Duration {
+	backoff := q.backoff
+	for i := 1; i < attempts && backoff < q.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, q.maxBackoff)
+}
+
+// dropOldFailed removes the oldest failed messages beyond the number of failed messages kept.
+func (q *Queue) dropOldFailed() {
+	var failed int
+	for _, m := range q.messages {
+		if m.Failed {
+			failed++
+		}
+	}
+
+	q.messages = slices.DeleteFunc(q.messages, func(m *queuedMessage) bool {
+		if m.Failed && failed > queueSize {
+			failed--
+			return true
+		}
+		return false
+	})
+}
+
+// persist writes the messages to the store.
+// It should be called with the queue locked.
+func (q *Queue) persist() error {
+	data, err := json.Marshal(q.messages)
+	if err != nil {
+		return err
+	}
+	return q.store.Set(queueStoreKey, string(data))
+}
@@ -0,0 +1,186 @@
+package mail
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is a queue store keeping the messages in memory.
+type memoryStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memoryStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memoryStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return value, nil
+}
+
+// flakySender records the messages it sends, failing the first failures attempts.
+type flakySender struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []string
+}
+
+func (s *flakySender) send(to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("connection refused")
+	}
+	s.sent = append(s.sent, to)
+	return nil
+}
+
+func (s *flakySender) SendMail(to string, message []byte) error {
+	return s.send(to + ":" + string(message))
+}
+
+func (s *flakySender) SendConfirmation(to, code string) error {
+	return s.send(to + ":" + code)
+}
+
+func (s *flakySender) Sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.sent...)
+}
+
+func newTestQueue(t *testing.T, sender *flakySender, store *memoryStore) *Queue {
+	q := &Queue{
+		mailer:       sender,
+		notifier:     sender,
+		store:        store,
+		maxAttempts:  3,
+		backoff:      time.Millisecond,
+		maxBackoff:   5 * time.Millisecond,
+		pollInterval: time.Millisecond,
+	}
+	if err := q.start(); err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// waitFor waits for the condition to be true, failing the test after a second.
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueSendAsync(t *testing.T) {
+	sender := &flakySender{}
+	q := newTestQueue(t, sender, &memoryStore{values: map[string]string{}})
+	defer q.Close()
+
+	if err := q.SendAsync("foo@bar.com", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SendConfirmationAsync("+15550100", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(sender.Sent()) == 2 })
+
+	sent := sender.Sent()
+	if sent[0] != "foo@bar.com:hello" || sent[1] != "+15550100:123456" {
+		t.Errorf("got %v, want the mail then the confirmation code", sent)
+	}
+
+	waitFor(t, func() bool { return len(q.Status().Pending) == 0 })
+}
+
+func TestQueueRetry(t *testing.T) {
+	sender := &flakySender{failures: 2}
+	q := newTestQueue(t, sender, &memoryStore{values: map[string]string{}})
+	defer q.Close()
+
+	if err := q.SendAsync("foo@bar.com", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(sender.Sent()) == 1 })
+
+	status := q.Status()
+	if len(status.Pending) != 0 || len(status.Failed) != 0 {
+		t.Errorf("got %d pending and %d failed messages, want none", len(status.Pending), len(status.Failed))
+	}
+}
+
+func TestQueueFailed(t *testing.T) {
+	sender := &flakySender{failures: 10}
+	q := newTestQueue(t, sender, &memoryStore{values: map[string]string{}})
+	defer q.Close()
+
+	if err := q.SendConfirmationAsync("foo@bar.com", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(q.Status().Failed) == 1 })
+
+	failed := q.Status().Failed[0]
+	if failed.Attempts != 3 || failed.LastError != "connection refused" || failed.Kind != MessageKindConfirmation || failed.To != "foo@bar.com" {
+		t.Errorf("got unexpected failed message %+v", failed)
+	}
+	if len(sender.Sent()) != 0 {
+		t.Errorf("got %v, want no sent messages", sender.Sent())
+	}
+}
+
+func TestQueuePersist(t *testing.T) {
+	store := &memoryStore{values: map[string]string{}}
+
+	// the first queue only gets to attempt the delivery once.
+	q := &Queue{mailer: &flakySender{failures: 10}, store: store, maxAttempts: 3, backoff: time.Millisecond, maxBackoff: time.Millisecond, pollInterval: time.Hour}
+	if err := q.start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SendAsync("foo@bar.com", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return q.Status().Pending[0].Attempts == 1 })
+	q.Close()
+
+	sender := &flakySender{}
+	q = newTestQueue(t, sender, store)
+	defer q.Close()
+
+	waitFor(t, func() bool { return len(sender.Sent()) == 1 })
+
+	if sent := sender.Sent(); sent[0] != "foo@bar.com:hello" {
+		t.Errorf("got %v, want the persisted message", sent)
+	}
+}
+
+func TestQueueBackoff(t *testing.T) {
+	q := &Queue{backoff: time.Second, maxBackoff: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := q.backoffAfter(i + 1); got != want {
+			t.Errorf("attempt %d: got %s, want %s", i+1, got, want)
+		}
+	}
+}
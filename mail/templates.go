@@ -0,0 +1,196 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// DefaultSiteName is the site name used in the mails when none is configured.
+const DefaultSiteName = "ITPG"
+
+// Names of the mail templates.
+const (
+	confirmationTemplate = "confirmation" // confirmationTemplate is the template of the confirmation code mails.
+	resetTemplate        = "reset"        // resetTemplate is the template of the password reset mails.
+)
+
+// defaultTemplates are the templates used when no template directory is configured,
+// or when it lacks some of the template files.
+//
+//go:embed templates
+var defaultTemplates embed.FS
+
+// TemplateData holds the variables passed to the mail templates.
+type TemplateData struct {
+	SiteName string // Name of the site sending the mail
+	To       string // Address of the recipient
+	Code     string // Confirmation code, in confirmation mails
+	ResetUrl string // Password reset link, in reset mails
+}
+
+// messageTemplate is the template of a mail, with a text/template for the subject and the plain text part,
+// and a html/template for the HTML part.
+type messageTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates render the confirmation and password reset mails.
+type Templates struct {
+	siteName  string
+	templates map[string]*messageTemplate
+}
+
+// LoadTemplates loads the mail templates from dir, falling back to the embedded defaults
+// for the template files missing from dir, or for all of them if dir is empty.
+// A template is made of a NAME.txt file, which defines the subject in a "subject" template and the plain text body,
+// and of a NAME.html file with the HTML body, where NAME is confirmation or reset.
+func LoadTemplates(dir, siteName string) (*Templates, error) {
+	if siteName == "" {
+		siteName = DefaultSiteName
+	}
+
+	t := &Templates{siteName: siteName, templates: map[string]*messageTemplate{}}
+
+	for _, name := range []string{confirmationTemplate, resetTemplate} {
+		text, err := readTemplate(dir, name+".txt")
+		if err != nil {
+			return nil, err
+		}
+
+		html, err := readTemplate(dir, name+".html")
+		if err != nil {
+			return nil, err
+		}
+
+		m := &messageTemplate{}
+		if m.text, err = texttemplate.New(name).Option("missingkey=error").Parse(text); err != nil {
+			return nil, err
+		}
+		if m.text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("missing subject in %s.txt", name)
+		}
+		if m.html, err = htmltemplate.New(name).Option("missingkey=error").Parse(html); err != nil {
+			return nil, err
+		}
+
+		t.templates[name] = m
+
+		// rendering the template now surfaces the errors at startup, instead of when sending a mail.
+		if _, _, _, err = t.render(name, &TemplateData{To: "user@example.com", Code: "123456", ResetUrl: "https://example.com/reset"}); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// readTemplate reads the template file from dir if it has one, and from the embedded defaults otherwise.
+func readTemplate(dir, file string) (string, error) {
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	data, err := defaultTemplates.ReadFile("templates/" + file)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// render returns the subject, plain text body, and HTML body of the mail rendered from the named template.
+func (t *Templates) render(name string, data *TemplateData) (subject, text, html string, err error) {
+	m, ok := t.templates[name]
+	if !ok {
+		return "", "", "", fmt.Errorf("unknown template: %s", name)
+	}
+
+	data.SiteName = t.siteName
+
+	var b strings.Builder
+	if err = m.text.ExecuteTemplate(&b, "subject", data); err != nil {
+		return
+	}
+	subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+	if err = m.text.Execute(&b, data); err != nil {
+		return
+	}
+	text = b.String()
+
+	b.Reset()
+	if err = m.html.Execute(&b, data); err != nil {
+		return
+	}
+	html = b.String()
+
+	return
+}
+
+// makeMessage renders the named template as a multipart/alternative mail with a plain text and a HTML part.
+func makeMessage(t *Templates, name, mailFrom string, data *TemplateData) ([]byte, error) {
+	subject, text, html, err := t.render(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if _, err = qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = mw.Close(); err != nil {
+		return nil, err
+	}
+
+	header := fmt.Sprintf(
+		"To: %s\r\nFrom: %s\r\nDate: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s\r\n\r\n",
+		data.To,
+		mailFrom,
+		time.Now().Format(time.RFC1123Z),
+		mime.QEncoding.Encode("UTF-8", subject),
+		mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}),
+	)
+
+	return append([]byte(header), body.Bytes()...), nil
+}
@@ -0,0 +1,169 @@
+package mail
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readMessage parses the multipart/alternative message, returning its subject and the content of its parts by content type.
+func readMessage(t *testing.T, message []byte) (string, map[string]string) {
+	m, err := mail.ReadMessage(strings.NewReader(string(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("got %s, want multipart/alternative", mediaType)
+	}
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the multipart reader decodes the quoted-printable parts.
+		content, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contentType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[contentType] = string(content)
+	}
+
+	return subject, parts
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	templates, err := LoadTemplates("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &SmtpClient{mailFrom: "mailer@example.com", templates: templates}
+
+	t.Run("confirmation", func(t *testing.T) {
+		message, err := c.MakeConfCodeMessage("foo@bar.com", "123456")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subject, parts := readMessage(t, message)
+		if subject != "ITPG Account Confirmation Code" {
+			t.Errorf("got %s, want %s", subject, "ITPG Account Confirmation Code")
+		}
+		for _, contentType := range []string{"text/plain", "text/html"} {
+			if !strings.Contains(parts[contentType], "123456") || !strings.Contains(parts[contentType], "foo@bar.com") {
+				t.Errorf("got %s part %q, want the code and the recipient", contentType, parts[contentType])
+			}
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		message, err := c.MakeResetCodeMessage("foo@bar.com", "https://itpg.cc/resetpass?code=abc&x=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subject, parts := readMessage(t, message)
+		if subject != "ITPG Account Password Reset Code" {
+			t.Errorf("got %s, want %s", subject, "ITPG Account Password Reset Code")
+		}
+		if !strings.Contains(parts["text/plain"], "https://itpg.cc/resetpass?code=abc&x=1") {
+			t.Errorf("got %q, want the reset link", parts["text/plain"])
+		}
+		if !strings.Contains(parts["text/html"], `href="https://itpg.cc/resetpass?code=abc&amp;x=1"`) {
+			t.Errorf("got %q, want the escaped reset link", parts["text/html"])
+		}
+	})
+}
+
+func TestCustomTemplates(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"confirmation.txt":  `{{define "subject"}}Welcome to {{.SiteName}}{{end}}Code for {{.SiteName}}: {{.Code}}`,
+		"confirmation.html": `<p>Code for {{.SiteName}}: <b>{{.Code}}</b></p>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	templates, err := LoadTemplates(dir, "Rate My Prof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &SmtpClient{mailFrom: "mailer@example.com", templates: templates}
+
+	t.Run("override", func(t *testing.T) {
+		message, err := c.MakeConfCodeMessage("foo@bar.com", "123456")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subject, parts := readMessage(t, message)
+		if subject != "Welcome to Rate My Prof" {
+			t.Errorf("got %s, want %s", subject, "Welcome to Rate My Prof")
+		}
+		if parts["text/plain"] != "Code for Rate My Prof: 123456" {
+			t.Errorf("got %q, want %q", parts["text/plain"], "Code for Rate My Prof: 123456")
+		}
+		if parts["text/html"] != "<p>Code for Rate My Prof: <b>123456</b></p>" {
+			t.Errorf("got %q, want %q", parts["text/html"], "<p>Code for Rate My Prof: <b>123456</b></p>")
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		message, err := c.MakeResetCodeMessage("foo@bar.com", "https://itpg.cc/resetpass")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subject, _ := readMessage(t, message)
+		if subject != "Rate My Prof Account Password Reset Code" {
+			t.Errorf("got %s, want %s", subject, "Rate My Prof Account Password Reset Code")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := t.TempDir()
+		if err := os.WriteFile(filepath.Join(invalid, "reset.txt"), []byte("no subject {{.ResetUrl}}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTemplates(invalid, ""); err == nil {
+			t.Error("got nil, want an error for the template without a subject")
+		}
+
+		if err := os.WriteFile(filepath.Join(invalid, "reset.txt"), []byte(`{{define "subject"}}{{.Unknown}}{{end}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTemplates(invalid, ""); err == nil {
+			t.Error("got nil, want an error for the template with an unknown variable")
+		}
+	})
+}
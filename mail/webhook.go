@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookTimeout is the maximum duration of a request to a webhook.
+const webhookTimeout = 10 * time.Second
+
+// WebhookNotifier sends the confirmation codes by posting them as JSON to a webhook,
+// for example an SMS gateway.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// webhookPayload is the JSON body posted to a webhook.
+type webhookPayload struct {
+	To   string `json:"to"`   // Recipient of the confirmation code
+	Code string `json:"code"` // Confirmation code
+}
+
+// NewWebhookNotifier returns a notifier posting the confirmation codes to the absolute http(s) URL.
+func NewWebhookNotifier(webhookUrl string) (*WebhookNotifier, error) {
+	u, err := url.Parse(webhookUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url: %s", webhookUrl)
+	}
+
+	return &WebhookNotifier{url: webhookUrl, client: &http.Client{Timeout: webhookTimeout}}, nil
+}
+
+// SendConfirmation posts the confirmation code and its recipient to the webhook.
+// A response with a non 2xx status code is an error.
+func (n *WebhookNotifier) SendConfirmation(to, code string) error {
+	body, err := json.Marshal(&webhookPayload{To: to, Code: code})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewWebhookNotifier(t *testing.T) {
+	for _, u := range []string{"", "example.com/hook", "ftp://example.com/hook", "https://"} {
+		if _, err := NewWebhookNotifier(u); err == nil {
+			t.Errorf("expected error for url %q", u)
+		}
+	}
+
+	if _, err := NewWebhookNotifier("https://example.com/hook"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhookSendConfirmation(t *testing.T) {
+	var got webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("got %s, want %s", r.Method, http.MethodPost)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("got %s, want %s", ct, "application/json")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	n, err := NewWebhookNotifier(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.SendConfirmation("+15550100", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.To != "+15550100" || got.Code != "123456" {
+		t.Errorf("got %+v, want to %s and code %s", got, "+15550100", "123456")
+	}
+}
+
+func TestWebhookSendConfirmationStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	n, err := NewWebhookNotifier(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = n.SendConfirmation("+15550100", "123456"); err == nil {
+		t.Error("expected error")
+	}
+}
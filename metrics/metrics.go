@@ -0,0 +1,94 @@
+// Package metrics records counters and histograms with the Prometheus client, and exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the histogram buckets.
+var DefaultBuckets = prometheus.DefBuckets
+
+// Metrics recorded by the server.
+var (
+	HttpRequests        = NewCounterVec("itpg_http_requests_total", "Number of HTTP requests by handler, method, and status code.", "handler", "method", "code")
+	HttpRequestDuration = NewHistogramVec("itpg_http_request_duration_seconds", "Duration of HTTP requests by handler.", "handler")
+	GradesSubmitted     = NewCounterVec("itpg_grades_submitted_total", "Number of grades successfully submitted.")
+	CacheRequests       = NewCounterVec("itpg_cache_requests_total", "Number of cache reads by result (hit, miss, or error).", "result")
+	CacheSets           = NewCounterVec("itpg_cache_sets_total", "Number of cache writes by result (ok or error).", "result")
+	DbQueryDuration     = NewHistogramVec("itpg_db_query_duration_seconds", "Duration of database operations by method.", "method")
+)
+
+// enabled is whether metrics are recorded.
+var enabled atomic.Bool
+
+// Enable enables or disables the recording of metrics.
+func Enable(enable bool) {
+	enabled.Store(enable)
+}
+
+// Enabled returns whether metrics are recorded.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Handler returns an HTTP handler writing all the registered metrics, along with the Go runtime and process metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	vec *prometheus.CounterVec
+}
+
+// NewCounterVec returns a new registered counter with the specified labels.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)}
+	prometheus.MustRegister(c.vec)
+	return c
+}
+
+// Inc increments the counter matching the label values, if metrics are enabled.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds n to the counter matching the label values, if metrics are enabled.
+func (c *CounterVec) Add(n uint64, labelValues ...string) {
+	if !Enabled() {
+		return
+	}
+
+	c.vec.WithLabelValues(labelValues...).Add(float64(n))
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	vec *prometheus.HistogramVec
+}
+
+// NewHistogramVec returns a new registered histogram with the default buckets and the specified labels.
+func NewHistogramVec(name, help string, labels ...string) *HistogramVec {
+	h := &HistogramVec{prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: DefaultBuckets}, labels)}
+	prometheus.MustRegister(h.vec)
+	return h
+}
+
+// Observe adds an observation to the histogram matching the label values, if metrics are enabled.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if !Enabled() {
+		return
+	}
+
+	h.vec.WithLabelValues(labelValues...).Observe(value)
+}
+
+// ObserveSince adds the duration in seconds since start to the histogram matching the label values, if metrics are enabled.
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	Enable(true)
+	defer Enable(false)
+
+	counter := NewCounterVec("test_requests_total", "Number of test requests.", "path")
+	counter.Inc("/b")
+	counter.Inc("/a")
+	counter.Add(2, "/a")
+	counter.Inc(`/"quoted"`)
+
+	histogram := NewHistogramVec("test_duration_seconds", "Duration of test requests.")
+	histogram.Observe(0.01)
+	histogram.Observe(0.3)
+	histogram.Observe(20)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("got %s, want text/plain", ct)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		"# HELP test_requests_total Number of test requests.\n# TYPE test_requests_total counter\n" +
+			`test_requests_total{path="/\"quoted\""} 1` + "\n" +
+			`test_requests_total{path="/a"} 3` + "\n" +
+			`test_requests_total{path="/b"} 1` + "\n",
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{le="0.005"} 0` + "\n",
+		`test_duration_seconds_bucket{le="0.01"} 1` + "\n",
+		`test_duration_seconds_bucket{le="0.5"} 2` + "\n",
+		`test_duration_seconds_bucket{le="10"} 2` + "\n",
+		`test_duration_seconds_bucket{le="+Inf"} 3` + "\n",
+		"test_duration_seconds_sum 20.31\n",
+		"test_duration_seconds_count 3\n",
+		"# TYPE go_goroutines gauge\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	counter := NewCounterVec("test_disabled_total", "Number of ignored test requests.")
+	counter.Inc()
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if strings.Contains(rr.Body.String(), "\ntest_disabled_total ") {
+		t.Errorf("expected no series when metrics are disabled, got:\n%s", rr.Body.String())
+	}
+}
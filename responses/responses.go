@@ -95,6 +95,48 @@ var (
 	ErrNotAdmin = NewResponse(4023, "not admin")
 	// ErrNotSuperAdmin indicates that the user is not a super admin.
 	ErrNotSuperAdmin = NewResponse(4024, "not admin")
+	// ErrGradeOutOfRange indicates that a grade is outside of the accepted range.
+	ErrGradeOutOfRange = NewResponse(4025, "grade out of range")
+	// ErrProfessorExists indicates that a professor with the same name already exists.
+	ErrProfessorExists = NewResponse(4026, "professor already exists")
+	// ErrCourseNotFound indicates that the course does not exist.
+	ErrCourseNotFound = NewResponse(4027, "course not found")
+	// ErrProfessorNotFound indicates that the professor does not exist.
+	ErrProfessorNotFound = NewResponse(4028, "professor not found")
+	// ErrNotFound indicates that the requested resource does not exist.
+	ErrNotFound = NewResponse(4029, "not found")
+	// ErrDuplicate indicates that the resource already exists.
+	ErrDuplicate = NewResponse(4030, "already exists")
+	// ErrReferenced indicates that the resource is still referenced by other resources.
+	ErrReferenced = NewResponse(4031, "still referenced")
+	// ErrInvalidValue indicates that a value is not accepted.
+	ErrInvalidValue = NewResponse(4032, "invalid value")
+	// ErrCommentTooLong indicates that the comment of a grade is too long.
+	ErrCommentTooLong = NewResponse(4033, "comment too long")
+	// ErrNotGraded indicates that the course is not graded.
+	ErrNotGraded = NewResponse(4034, "course not graded")
+	// ErrCourseProfessorNotFound indicates that the professor does not teach the course.
+	ErrCourseProfessorNotFound = NewResponse(4035, "professor does not teach course")
+	// ErrInvalidOrigin indicates that the origin of the request is missing or not allowed.
+	ErrInvalidOrigin = NewResponse(4036, "invalid origin")
+	// ErrTooManyLoginAttempts indicates that the user is locked out after too many failed login attempts.
+	ErrTooManyLoginAttempts = NewResponse(4037, "too many login attempts")
+	// ErrMethodNotAllowed indicates that the method of the request is not allowed for the requested resource.
+	ErrMethodNotAllowed = NewResponse(4038, "method not allowed")
+	// ErrSelfModification indicates that the user tried to demote or delete themselves.
+	ErrSelfModification = NewResponse(4039, "cannot modify own account")
+	// ErrProfessorNoDepartment indicates that the professor teaches no course with a department.
+	ErrProfessorNoDepartment = NewResponse(4040, "professor has no department")
+	// ErrSearchTooShort indicates that the search query is shorter than the minimum length.
+	ErrSearchTooShort = NewResponse(4041, "search query too short")
+	// ErrLikelyDuplicate indicates that the professor likely already exists under a slightly different name.
+	ErrLikelyDuplicate = NewResponse(4042, "likely duplicate")
+	// ErrTenantNotFound indicates that the tenant of the request does not exist.
+	ErrTenantNotFound = NewResponse(4043, "tenant not found")
+	// ErrIdempotencyKeyReused indicates that the idempotency key was already used to grade another course or professor.
+	ErrIdempotencyKeyReused = NewResponse(4044, "idempotency key reused")
+	// ErrCodeResentTooSoon indicates that a confirmation code or a password reset link was sent to the user too recently to send another one.
+	ErrCodeResentTooSoon = NewResponse(4045, "code resent too soon")
 )
 
 // Server-side Errors
@@ -105,4 +147,8 @@ var (
 	ErrSendMail = NewResponse(5001, "error mailing confirmation code")
 	// ErrInternal indicates an internal Error.
 	ErrInternal = NewResponse(5002, "internal error")
+	// ErrNotReady indicates that a dependency of the server is unavailable.
+	ErrNotReady = NewResponse(5003, "not ready")
+	// ErrMaintenance indicates that the server is in maintenance mode, and only accepts read requests.
+	ErrMaintenance = NewResponse(5004, "in maintenance")
 )
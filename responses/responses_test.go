@@ -81,6 +81,7 @@ func TestErrorCodes(t *testing.T) {
 		{ErrConfirmationCodeExpired, 4022},
 		{ErrNotAdmin, 4023},
 		{ErrNotSuperAdmin, 4024},
+		{ErrGradeOutOfRange, 4025},
 	})
 
 	// Test server-side errors
@@ -91,6 +92,8 @@ func TestErrorCodes(t *testing.T) {
 		{ErrGenCode, 5000},
 		{ErrSendMail, 5001},
 		{ErrInternal, 5002},
+		{ErrNotReady, 5003},
+		{ErrMaintenance, 5004},
 	})
 }
 
@@ -3,162 +3,450 @@ package server
 import (
 	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
-	"github.com/rs/zerolog/log"
 	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/metrics"
 	"github.com/vanillaiice/itpg/responses"
 )
 
 // GradeData contains data needed to grade a course.
 type GradeData struct {
-	CourseCode      string  `json:"code"`
-	ProfUUID        string  `json:"uuid"`
-	GradeTeaching   float32 `json:"teaching"`
-	GradeCoursework float32 `json:"coursework"`
-	GradeLearning   float32 `json:"learning"`
+	CourseCode      string             `json:"code"`
+	ProfUUID        string             `json:"uuid"`
+	GradeTeaching   float32            `json:"teaching"`
+	GradeCoursework float32            `json:"coursework"`
+	GradeLearning   float32            `json:"learning"`
+	Grades          map[string]float32 `json:"grades,omitempty"`  // Grades of the extra score dimensions, by dimension
+	Comment         string             `json:"comment,omitempty"` // Optional written review of the course and its professor
 }
 
+// ImportResult represents the outcome of adding an item in a bulk import.
+type ImportResult struct {
+	Item     string `json:"item"`            // Code of the course, or name of the professor
+	Inserted bool   `json:"inserted"`        // Whether the item was inserted
+	Error    string `json:"error,omitempty"` // Reason why the item was not inserted
+}
+
+// GradeResult represents the outcome of a grade in a batch of grades.
+type GradeResult struct {
+	CourseCode string `json:"code"`            // Code of the course
+	ProfUUID   string `json:"uuid"`            // UUID of the professor
+	Status     string `json:"status"`          // Outcome of the grade, one of graded, already-graded, or error
+	Error      string `json:"error,omitempty"` // Reason why the grade was not added
+}
+
+// CatalogSync represents the courses and professors added to the catalog since a version token.
+type CatalogSync struct {
+	Courses    []*db.Course    `json:"courses"`    // Courses added since the version token, in the order they were added
+	Professors []*db.Professor `json:"professors"` // Professors added since the version token, in the order they were added
+	Version    string          `json:"version"`    // Version token to pass as since in the next sync
+}
+
+// Enum for the outcomes of a grade in a batch of grades
+const (
+	gradeStatusGraded        = "graded"         // gradeStatusGraded indicates that the grade was added.
+	gradeStatusAlreadyGraded = "already-graded" // gradeStatusAlreadyGraded indicates that the user already graded.
+	gradeStatusError         = "error"          // gradeStatusError indicates that the grade was not added because of an error.
+)
+
+// professorSorts are the allowed sort orders when getting professors.
+var professorSorts = []db.ProfessorSort{db.ProfessorSortRecent, db.ProfessorSortName, db.ProfessorSortRating}
+
+// rankingScores are the allowed scores by which professors are ranked.
+var rankingScores = []db.RankingScore{db.RankingScoreAverage, db.RankingScoreTeaching, db.RankingScoreCoursework, db.RankingScoreLearning}
+
+// matchModes are the allowed match modes when searching by a partial name.
+var matchModes = []db.MatchMode{db.MatchSubstring, db.MatchPrefix}
+
+// trendBuckets are the allowed buckets when getting score trends.
+var trendBuckets = []db.TrendBucket{db.TrendBucketDay, db.TrendBucketWeek, db.TrendBucketMonth, db.TrendBucketYear}
+
+// maxGradedBatchSize is the maximum number of courses and professors checked in one graded batch request.
+const maxGradedBatchSize = 100
+
+// maxGradeBatchSize is the maximum number of grades submitted in one grade batch request.
+const maxGradeBatchSize = 50
+
+// maxScheduleCourses is the maximum number of courses in one request for the professors teaching them.
+const maxScheduleCourses = 20
+
+// maxCommentLength is the maximum number of characters in the comment of a grade.
+const maxCommentLength = 2000
+
+// minSearchLength is the minimum number of characters of a search query.
+const minSearchLength = 2
+
 // addCourse handles the HTTP request to add a new course.
+// The optional department query parameter sets the department of the course,
+// and the optional credits query parameter its credit hours, between 0 and 30.
 func addCourse(w http.ResponseWriter, r *http.Request) {
 	courseCode, courseName := r.FormValue("code"), r.FormValue("name")
 	if err := isEmptyStr(w, courseCode, courseName); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := dataDb.AddCourse(&db.Course{Code: courseCode, Name: courseName}); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	course := &db.Course{Code: courseCode, Name: courseName, Department: r.FormValue("department")}
+	if c := r.FormValue("credits"); c != "" {
+		credits, err := strconv.Atoi(c)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrInvalidValue.WriteJSON(w)
+			return
+		}
+		course.Credits = &credits
+	}
+
+	if err := requestDb(r).AddCourse(course); err != nil {
+		writeDbError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	writeSuccess(w)
+}
+
+// updateCourse handles the HTTP request to rename a course.
+func updateCourse(w http.ResponseWriter, r *http.Request) {
+	courseCode, courseName := r.FormValue("code"), r.FormValue("name")
+	if err := isEmptyStr(w, courseCode, courseName); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err := requestDb(r).UpdateCourseName(courseCode, courseName); err != nil {
+		if errors.Is(err, responses.ErrCourseNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrCourseNotFound.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
 }
 
 // addProfessor handles the HTTP request to add a new professor.
+// If the professor is a likely duplicate of an existing professor, the existing professor is sent in the response.
 func addProfessor(w http.ResponseWriter, r *http.Request) {
 	fullName := r.FormValue("fullname")
 	if err := isEmptyStr(w, fullName); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := dataDb.AddProfessor(fullName); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	if err := requestDb(r).AddProfessor(fullName); err != nil {
+		var likelyDuplicate *db.LikelyDuplicateError
+		if errors.As(err, &likelyDuplicate) {
+			w.WriteHeader(http.StatusConflict)
+			(&responses.Response{Code: responses.ErrLikelyDuplicate.Code, Message: likelyDuplicate.Professor}).WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	writeSuccess(w)
+}
+
+// addCourseMany handles the HTTP request to add many courses at once.
+// Either all courses are added, or none are, and each course is reported in the order of the request body.
+func addCourseMany(w http.ResponseWriter, r *http.Request) {
+	courses, err := decodeCourses(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if len(courses) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	results, valid := make([]*ImportResult, len(courses)), true
+	for i, course := range courses {
+		results[i] = &ImportResult{}
+		if course == nil || course.Code == "" || course.Name == "" {
+			results[i].Error, valid = "empty code or name", false
+		}
+		if course != nil {
+			results[i].Item = course.Code
+		}
+	}
+
+	if !valid {
+		writeImportResults(w, r, results, nil, db.ErrBatchFailed)
+		return
+	}
+
+	errs, err := requestDb(r).AddCourseMany(courses)
+	writeImportResults(w, r, results, errs, err)
+}
+
+// addProfessorMany handles the HTTP request to add many professors at once.
+// Either all professors are added, or none are, and each professor is reported in the order of the request body.
+func addProfessorMany(w http.ResponseWriter, r *http.Request) {
+	names, err := decodeProfessorNames(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if len(names) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	results, valid := make([]*ImportResult, len(names)), true
+	for i, name := range names {
+		results[i] = &ImportResult{Item: name}
+		if name == "" {
+			results[i].Error, valid = "empty name", false
+		}
+	}
+
+	if !valid {
+		writeImportResults(w, r, results, nil, db.ErrBatchFailed)
+		return
+	}
+
+	errs, err := requestDb(r).AddProfessorMany(names)
+	writeImportResults(w, r, results, errs, err)
 }
 
 // removeCourse handles the HTTP request to remove a course.
 func removeCourse(w http.ResponseWriter, r *http.Request) {
 	courseCode := r.FormValue("code")
 	if err := isEmptyStr(w, courseCode); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := dataDb.RemoveCourse(courseCode, false); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	if err := requestDb(r).RemoveCourse(courseCode, false); err != nil {
+		writeDbError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	writeSuccess(w)
 }
 
-// removeCourseForce handles the HTTP request to forcefully remove a course.
+// removeCourseForce handles the HTTP request to forcefully remove a course, along with its scores.
+// As the scores cannot be recovered, only super admins are allowed to do it.
 func removeCourseForce(w http.ResponseWriter, r *http.Request) {
 	courseCode := r.FormValue("code")
 	if err := isEmptyStr(w, courseCode); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := dataDb.RemoveCourse(courseCode, true); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	if err := requestDb(r).RemoveCourse(courseCode, true); err != nil {
+		writeDbError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	writeSuccess(w)
+}
+
+// softRemoveCourse handles the HTTP request to soft-delete a course, which can be restored later.
+func softRemoveCourse(w http.ResponseWriter, r *http.Request) {
+	setCourseDeleted(w, r, requestDb(r).SoftRemoveCourse)
+}
+
+// restoreCourse handles the HTTP request to restore a soft-deleted course.
+func restoreCourse(w http.ResponseWriter, r *http.Request) {
+	setCourseDeleted(w, r, requestDb(r).RestoreCourse)
+}
+
+// setCourseDeleted soft-deletes or restores the course with the code of the request with fn.
+func setCourseDeleted(w http.ResponseWriter, r *http.Request, fn func(string) error) {
+	courseCode := r.FormValue("code")
+	if err := isEmptyStr(w, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err := fn(courseCode); err != nil {
+		if errors.Is(err, responses.ErrCourseNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrCourseNotFound.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
+}
+
+// renameProfessor handles the HTTP request to rename a professor.
+func renameProfessor(w http.ResponseWriter, r *http.Request) {
+	writeProfessorRename(w, r, requestDb(r).RenameProfessor)
+}
+
+// updateProfessor handles the HTTP request to update the name of a professor.
+func updateProfessor(w http.ResponseWriter, r *http.Request) {
+	writeProfessorRename(w, r, requestDb(r).UpdateProfessorName)
+}
+
+// writeProfessorRename renames the professor of the request with rename, and writes the response.
+func writeProfessorRename(w http.ResponseWriter, r *http.Request, rename func(professorUUID, newName string) error) {
+	professorUUID, fullName := r.FormValue("uuid"), r.FormValue("fullname")
+	if err := isEmptyStr(w, professorUUID, fullName); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err := rename(professorUUID, fullName); err != nil {
+		if errors.Is(err, responses.ErrProfessorNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrProfessorNotFound.WriteJSON(w)
+			return
+		} else if errors.Is(err, responses.ErrProfessorExists) {
+			w.WriteHeader(http.StatusConflict)
+			responses.ErrProfessorExists.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
 }
 
 // removeProfessor handles the HTTP request to remove a professor.
 func removeProfessor(w http.ResponseWriter, r *http.Request) {
 	professorUUID := r.FormValue("uuid")
 	if err := isEmptyStr(w, professorUUID); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := dataDb.RemoveProfessor(professorUUID, false); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	if err := requestDb(r).RemoveProfessor(professorUUID, false); err != nil {
+		writeDbError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	writeSuccess(w)
 }
 
-// removeProfessorForce handles the HTTP request to forcefully remove a professor.
+// removeProfessorForce handles the HTTP request to forcefully remove a professor, along with their scores.
+// As the scores cannot be recovered, only super admins are allowed to do it.
 func removeProfessorForce(w http.ResponseWriter, r *http.Request) {
 	professorUUID := r.FormValue("uuid")
 	if err := isEmptyStr(w, professorUUID); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := dataDb.RemoveProfessor(professorUUID, true); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	if err := requestDb(r).RemoveProfessor(professorUUID, true); err != nil {
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
+}
+
+// softRemoveProfessor handles the HTTP request to soft-delete a professor, who can be restored later.
+func softRemoveProfessor(w http.ResponseWriter, r *http.Request) {
+	setProfessorDeleted(w, r, requestDb(r).SoftRemoveProfessor)
+}
+
+// restoreProfessor handles the HTTP request to restore a soft-deleted professor.
+func restoreProfessor(w http.ResponseWriter, r *http.Request) {
+	setProfessorDeleted(w, r, requestDb(r).RestoreProfessor)
+}
+
+// setProfessorDeleted soft-deletes or restores the professor with the uuid of the request with fn.
+func setProfessorDeleted(w http.ResponseWriter, r *http.Request, fn func(string) error) {
+	professorUUID := r.FormValue("uuid")
+	if err := isEmptyStr(w, professorUUID); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err := fn(professorUUID); err != nil {
+		if errors.Is(err, responses.ErrProfessorNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrProfessorNotFound.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
+}
+
+// getTrash handles the HTTP request to get the soft-deleted courses and professors, with the time they were removed.
+func getTrash(w http.ResponseWriter, r *http.Request) {
+	trash, err := requestDb(r).GetTrash()
+	if err != nil {
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: trash}).WriteJSON(w)
 }
 
 // addCourseProfessor handles the HTTP request to associate a course with a professor.
 func addCourseProfessor(w http.ResponseWriter, r *http.Request) {
 	professorUUID, courseCode := r.FormValue("uuid"), r.FormValue("code")
 	if err := isEmptyStr(w, professorUUID, courseCode); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := dataDb.AddCourseProfessor(professorUUID, courseCode); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	if err := requestDb(r).AddCourseProfessor(professorUUID, courseCode); err != nil {
+		if errors.Is(err, db.ErrForeignKey) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrNotFound.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	writeSuccess(w)
+}
+
+// removeCourseProfessor handles the HTTP request to dissociate a course from a professor.
+func removeCourseProfessor(w http.ResponseWriter, r *http.Request) {
+	professorUUID, courseCode := r.FormValue("uuid"), r.FormValue("code")
+	if err := isEmptyStr(w, professorUUID, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err := requestDb(r).RemoveCourseProfessor(professorUUID, courseCode); err != nil {
+		if errors.Is(err, responses.ErrCourseProfessorNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrCourseProfessorNotFound.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
 }
 
 // getLastCourses handles the HTTP request to get all courses.
+// The optional limit and offset query parameters paginate the courses.
 func getLastCourses(w http.ResponseWriter, r *http.Request) {
-	courses, err := dataDb.GetLastCourses()
+	limit, offset := parsePagination(r)
+
+	courses, err := requestDb(r).GetLastCourses(limit, offset)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
@@ -167,126 +455,141 @@ func getLastCourses(w http.ResponseWriter, r *http.Request) {
 }
 
 // getLastProfessors handles the HTTP request to get all professors.
+// The optional sort query parameter can be one of recent (default), name, or rating,
+// and the optional limit and offset query parameters paginate the professors.
 func getLastProfessors(w http.ResponseWriter, r *http.Request) {
-	professors, err := dataDb.GetLastProfessors()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	sort := db.ProfessorSortRecent
+	if s := r.FormValue("sort"); s != "" {
+		sort = db.ProfessorSort(s)
+	}
+
+	if !slices.Contains(professorSorts, sort) {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: professors}).WriteJSON(w)
-}
+	limit, offset := parsePagination(r)
 
-// getLastScores handles the HTTP request to get all scores.
-func getLastScores(w http.ResponseWriter, r *http.Request) {
-	scores, err := dataDb.GetLastScores()
+	professors, err := requestDb(r).GetLastProfessors(sort, limit, offset)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: professors}).WriteJSON(w)
 }
 
-// getCoursesByProfessor handles the HTTP request to get courses associated with a professor.
-func getCoursesByProfessorUUID(w http.ResponseWriter, r *http.Request) {
-	professorUUID := mux.Vars(r)["uuid"]
-	if err := isEmptyStr(w, professorUUID); err != nil {
-		log.Error().Msg(err.Error())
+// getCoursesBetween handles the HTTP request to get the courses added within a time range.
+func getCoursesBetween(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseTimeRange(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	courses, err := dataDb.GetCoursesByProfessorUUID(professorUUID)
+	courses, err := requestDb(r).GetCoursesBetween(from, to)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: courses}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: capResults(r, courses)}).WriteJSON(w)
 }
 
-// getProfessorsByCourse handles the HTTP request to get professors associated with a course.
-func getProfessorsByCourseCode(w http.ResponseWriter, r *http.Request) {
-	courseCode := mux.Vars(r)["code"]
-	if err := isEmptyStr(w, courseCode); err != nil {
-		log.Error().Msg(err.Error())
-		return
+// getRandomCourses handles the HTTP request to get a random selection of courses.
+func getRandomCourses(w http.ResponseWriter, r *http.Request) {
+	n := 5
+	if v := r.FormValue("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
 	}
 
-	professors, err := dataDb.GetProfessorsByCourseCode(courseCode)
+	courses, err := requestDb(r).GetRandomCourses(n)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: professors}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: courses}).WriteJSON(w)
 }
 
-// getScoresByProfessorUUID handles the HTTP request to get scores associated with a professor.
-func getScoresByProfessorUUID(w http.ResponseWriter, r *http.Request) {
-	professorUUID := mux.Vars(r)["uuid"]
-	if err := isEmptyStr(w, professorUUID); err != nil {
-		log.Error().Msg(err.Error())
+// getProfessorsBetween handles the HTTP request to get the professors added within a time range.
+func getProfessorsBetween(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseTimeRange(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	scores, err := dataDb.GetScoresByProfessorUUID(professorUUID)
+	professors, err := requestDb(r).GetProfessorsBetween(from, to)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: capResults(r, professors)}).WriteJSON(w)
 }
 
-// getScoresByProfessorName handles the HTTP request to get scores associated with a professor's name.
-func getScoresByProfessorName(w http.ResponseWriter, r *http.Request) {
-	professorName := mux.Vars(r)["name"]
-	if err := isEmptyStr(w, professorName); err != nil {
-		log.Error().Msg(err.Error())
-		return
+// getCatalogSync handles the HTTP request to get the courses and professors added since a version token.
+// The optional since query parameter is the version token returned by the previous sync, all the catalog being returned without it.
+func getCatalogSync(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.FormValue("since"); s != "" {
+		version, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || version <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+		since = time.Unix(0, version)
 	}
 
-	scores, err := dataDb.GetScoresByProfessorName(professorName)
+	changes, err := requestDb(r).GetCatalogSince(since)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
+	// the version token is the insertion time of the last course or professor in unix nanoseconds,
+	// which is kept as a string since it overflows the integers of javascript clients.
+	var version string
+	if !changes.Version.IsZero() {
+		version = strconv.FormatInt(changes.Version.UnixNano(), 10)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: &CatalogSync{Courses: changes.Courses, Professors: changes.Professors, Version: version}}).WriteJSON(w)
 }
 
-// getScoresByProfessorNameLike handles the HTTP request to get scores associated with a professor's name.
-func getScoresByProfessorNameLike(w http.ResponseWriter, r *http.Request) {
-	professorName := mux.Vars(r)["name"]
-	if err := isEmptyStr(w, professorName); err != nil {
-		log.Error().Msg(err.Error())
+// getUnratedProfessors handles the HTTP request to get the professors without any grades.
+func getUnratedProfessors(w http.ResponseWriter, r *http.Request) {
+	professors, err := requestDb(r).GetUnratedProfessors()
+	if err != nil {
+		writeInternalError(w, r, err)
 		return
 	}
 
-	scores, err := dataDb.GetScoresByProfessorNameLike(professorName)
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: professors}).WriteJSON(w)
+}
+
+// getLastScores handles the HTTP request to get all scores.
+// The optional limit and offset query parameters paginate the scores.
+func getLastScores(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+
+	scores, err := requestDb(r).GetLastScores(limit, offset)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
@@ -294,79 +597,556 @@ func getScoresByProfessorNameLike(w http.ResponseWriter, r *http.Request) {
 	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
 }
 
-// getScoresByCourseName handles the HTTP request to get scores associated with a course.
-func getScoresByCourseName(w http.ResponseWriter, r *http.Request) {
-	courseName := mux.Vars(r)["name"]
-	if err := isEmptyStr(w, courseName); err != nil {
-		log.Error().Msg(err.Error())
+// getCoursesByProfessor handles the HTTP request to get courses associated with a professor.
+func getCoursesByProfessorUUID(w http.ResponseWriter, r *http.Request) {
+	professorUUID := mux.Vars(r)["uuid"]
+	if err := isEmptyStr(w, professorUUID); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	scores, err := dataDb.GetScoresByCourseName(courseName)
+	courses, err := requestDb(r).GetCoursesByProfessorUUID(professorUUID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: courses}).WriteJSON(w)
 }
 
-// getScoresByCourseNameLike handles the HTTP request to get scores associated with a course.
-func getScoresByCourseNameLike(w http.ResponseWriter, r *http.Request) {
-	courseName := mux.Vars(r)["name"]
-	if err := isEmptyStr(w, courseName); err != nil {
-		log.Error().Msg(err.Error())
+// getUngradedCoursesByProfessorUUID handles the HTTP request to get courses associated with a professor,
+// in which the professor has not been graded yet.
+func getUngradedCoursesByProfessorUUID(w http.ResponseWriter, r *http.Request) {
+	professorUUID := mux.Vars(r)["uuid"]
+	if err := isEmptyStr(w, professorUUID); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	scores, err := dataDb.GetScoresByCourseNameLike(courseName)
+	courses, err := requestDb(r).GetUngradedCoursesByProfessorUUID(professorUUID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: courses}).WriteJSON(w)
 }
 
-// getScoresByCourseCode handles the HTTP request to get scores associated with a course.
-func getScoresByCourseCode(w http.ResponseWriter, r *http.Request) {
+// getProfessorsByCourse handles the HTTP request to get professors associated with a course.
+func getProfessorsByCourseCode(w http.ResponseWriter, r *http.Request) {
 	courseCode := mux.Vars(r)["code"]
 	if err := isEmptyStr(w, courseCode); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	scores, err := dataDb.GetScoresByCourseCode(courseCode)
+	professors, err := requestDb(r).GetProfessorsByCourseCode(courseCode)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: professors}).WriteJSON(w)
 }
 
-// getScoresByCourseCodeLike handles the HTTP request to get scores associated with a course.
-func getScoresByCourseCodeLike(w http.ResponseWriter, r *http.Request) {
-	courseCode := mux.Vars(r)["code"]
+// getScoresByProfessorUUID handles the HTTP request to get scores associated with a professor.
+// If the from and to query parameters are set, the scores are computed only from the grades given within that time range.
+func getScoresByProfessorUUID(w http.ResponseWriter, r *http.Request) {
+	professorUUID := mux.Vars(r)["uuid"]
+	if err := isEmptyStr(w, professorUUID); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if hasTimeRange(r) {
+		writeScoresBetween(w, r, professorUUID, "")
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresByProfessorUUID(professorUUID)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+}
+
+// getProfessorsForCourses handles the HTTP request to get the professors teaching any of the courses,
+// with their scores, grouped by course code.
+// The codes query parameter is a comma separated list of course codes.
+func getProfessorsForCourses(w http.ResponseWriter, r *http.Request) {
+	courseCodes := []string{}
+	for _, code := range strings.Split(r.FormValue("codes"), ",") {
+		if code = strings.TrimSpace(code); code != "" && !slices.Contains(courseCodes, code) {
+			courseCodes = append(courseCodes, code)
+		}
+	}
+
+	if len(courseCodes) == 0 || len(courseCodes) > maxScheduleCourses {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	scores, err := requestDb(r).GetProfessorsForCourses(courseCodes)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+}
+
+// getProfessorDetail handles the HTTP request to get a professor with the courses they teach, their average scores in each course, and across all courses.
+func getProfessorDetail(w http.ResponseWriter, r *http.Request) {
+	professorUUID := mux.Vars(r)["uuid"]
+	if err := isEmptyStr(w, professorUUID); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	detail, err := requestDb(r).GetProfessorDetail(professorUUID)
+	if err != nil {
+		if errors.Is(err, responses.ErrProfessorNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrProfessorNotFound.WriteJSON(w)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: detail}).WriteJSON(w)
+}
+
+// getProfessorRank handles the HTTP request to get the rank of a professor by average score within their department.
+func getProfessorRank(w http.ResponseWriter, r *http.Request) {
+	professorUUID := mux.Vars(r)["uuid"]
+	if err := isEmptyStr(w, professorUUID); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	rank, err := requestDb(r).GetProfessorRank(professorUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, responses.ErrProfessorNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrProfessorNotFound.WriteJSON(w)
+		case errors.Is(err, responses.ErrProfessorNoDepartment):
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrProfessorNoDepartment.WriteJSON(w)
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: rank}).WriteJSON(w)
+}
+
+// getCourseDetail handles the HTTP request to get a course with the professors teaching it, the average scores of each professor, and across all professors.
+func getCourseDetail(w http.ResponseWriter, r *http.Request) {
+	courseCode := mux.Vars(r)["code"]
 	if err := isEmptyStr(w, courseCode); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	scores, err := dataDb.GetScoresByCourseCodeLike(courseCode)
+	detail, err := requestDb(r).GetCourseDetail(courseCode)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		if errors.Is(err, responses.ErrCourseNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrCourseNotFound.WriteJSON(w)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: detail}).WriteJSON(w)
+}
+
+// getScoresByProfessorName handles the HTTP request to get scores associated with a professor's name.
+func getScoresByProfessorName(w http.ResponseWriter, r *http.Request) {
+	professorName := mux.Vars(r)["name"]
+	if err := isEmptyStr(w, professorName); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresByProfessorName(professorName)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+}
+
+// getScoresByProfessorNameLike handles the HTTP request to get scores associated with a professor's name.
+// The optional mode query parameter can be one of substring (default) or prefix.
+func getScoresByProfessorNameLike(w http.ResponseWriter, r *http.Request) {
+	professorName := mux.Vars(r)["name"]
+	if err := isEmptyStr(w, professorName); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	mode, err := parseMatchMode(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresByProfessorNameLike(professorName, mode)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: capResults(r, scores)}).WriteJSON(w)
+}
+
+// getScoresByCourseName handles the HTTP request to get scores associated with a course.
+func getScoresByCourseName(w http.ResponseWriter, r *http.Request) {
+	courseName := mux.Vars(r)["name"]
+	if err := isEmptyStr(w, courseName); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresByCourseName(courseName)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+}
+
+// getScoresByCourseNameLike handles the HTTP request to get scores associated with a course.
+// The optional mode query parameter can be one of substring (default) or prefix.
+func getScoresByCourseNameLike(w http.ResponseWriter, r *http.Request) {
+	courseName := mux.Vars(r)["name"]
+	if err := isEmptyStr(w, courseName); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	mode, err := parseMatchMode(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresByCourseNameLike(courseName, mode)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: capResults(r, scores)}).WriteJSON(w)
+}
+
+// getScoresByCourseCode handles the HTTP request to get scores associated with a course.
+// If the from and to query parameters are set, the scores are computed only from the grades given within that time range.
+func getScoresByCourseCode(w http.ResponseWriter, r *http.Request) {
+	courseCode := mux.Vars(r)["code"]
+	if err := isEmptyStr(w, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if hasTimeRange(r) {
+		writeScoresBetween(w, r, "", courseCode)
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresByCourseCode(courseCode)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+}
+
+// writeScoresBetween writes the scores of a professor or a course computed only from the grades given within the time range of the request.
+func writeScoresBetween(w http.ResponseWriter, r *http.Request, professorUUID, courseCode string) {
+	from, to, err := parseTimeRange(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresBetween(professorUUID, courseCode, from, to)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: scores}).WriteJSON(w)
+}
+
+// getScoresByCourseCodeLike handles the HTTP request to get scores associated with a course.
+func getScoresByCourseCodeLike(w http.ResponseWriter, r *http.Request) {
+	courseCode := mux.Vars(r)["code"]
+	if err := isEmptyStr(w, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresByCourseCodeLike(courseCode)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: capResults(r, scores)}).WriteJSON(w)
+}
+
+// getScoresBySearch handles the HTTP request to get scores whose professor name, course name, or course code matches a search query.
+func getScoresBySearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	if err := isEmptyStr(w, query); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoresBySearch(query)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: capResults(r, scores)}).WriteJSON(w)
+}
+
+// search handles the HTTP request to get the professors and courses matching a search query, with their average scores, ordered by relevance.
+func search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	if err := isEmptyStr(w, query); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if utf8.RuneCountInString(query) < minSearchLength {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrSearchTooShort.WriteJSON(w)
+		return
+	}
+
+	results, err := requestDb(r).Search(query)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: capResults(r, results)}).WriteJSON(w)
+}
+
+// getScoreTrend handles the HTTP request to get the score trend of a course and its professor.
+// The optional bucket query parameter can be one of day, week, month (default), or year.
+func getScoreTrend(w http.ResponseWriter, r *http.Request) {
+	professorUUID, courseCode := r.FormValue("uuid"), r.FormValue("code")
+	if err := isEmptyStr(w, professorUUID, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	bucket := db.TrendBucketMonth
+	if b := r.FormValue("bucket"); b != "" {
+		bucket = db.TrendBucket(b)
+	}
+
+	if !slices.Contains(trendBuckets, bucket) {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	trend, err := requestDb(r).GetScoreTrend(professorUUID, courseCode, bucket)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: trend}).WriteJSON(w)
+}
+
+// getScoreHistoryByCourseCode handles the HTTP request to get the monthly average scores of a course.
+func getScoreHistoryByCourseCode(w http.ResponseWriter, r *http.Request) {
+	courseCode := mux.Vars(r)["code"]
+	if err := isEmptyStr(w, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	history, err := requestDb(r).GetScoreHistoryByCourseCode(courseCode)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: history}).WriteJSON(w)
+}
+
+// getScoreDistributionByProfessorUUID handles the HTTP request to get the number of grades of a professor in each score range.
+func getScoreDistributionByProfessorUUID(w http.ResponseWriter, r *http.Request) {
+	professorUUID := mux.Vars(r)["uuid"]
+	if err := isEmptyStr(w, professorUUID); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	distribution, err := requestDb(r).GetScoreDistributionByProfessorUUID(professorUUID)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: distribution}).WriteJSON(w)
+}
+
+// getBottomRatedProfessors handles the HTTP request to get the lowest rated professors.
+// The optional limit query parameter sets the number of professors returned (default 10).
+func getBottomRatedProfessors(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if l := r.FormValue("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+	}
+
+	ratings, err := requestDb(r).GetBottomRatedProfessors(limit)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: ratings}).WriteJSON(w)
+}
+
+// getTopProfessors handles the HTTP request to get the highest rated professors.
+// The optional limit query parameter sets the number of professors returned (default 10),
+// and the optional by query parameter sets the score by which they are ranked,
+// one of average (default), teaching, coursework, or learning.
+func getTopProfessors(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if l := r.FormValue("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+	}
+
+	by := db.RankingScoreAverage
+	if b := r.FormValue("by"); b != "" {
+		by = db.RankingScore(b)
+	}
+
+	if !slices.Contains(rankingScores, by) {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	ratings, err := requestDb(r).GetTopProfessors(limit, by)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: ratings}).WriteJSON(w)
+}
+
+// getMostRatedCourses handles the HTTP request to get the courses with the most grades.
+// The optional limit query parameter sets the number of courses returned (default 10).
+func getMostRatedCourses(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if l := r.FormValue("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+	}
+
+	courses, err := requestDb(r).GetMostRatedCourses(limit)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: courses}).WriteJSON(w)
+}
+
+// getDepartmentStats handles the HTTP request to get the number of courses and professors, and the average score of each department.
+func getDepartmentStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := requestDb(r).GetDepartmentStats()
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: stats}).WriteJSON(w)
+}
+
+// getComponentAverages handles the HTTP request to get the average of the teaching, coursework, and learning scores across all the grades.
+func getComponentAverages(w http.ResponseWriter, r *http.Request) {
+	averages, err := requestDb(r).GetComponentAverages()
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: averages}).WriteJSON(w)
+}
+
+// getScoreDimensions handles the HTTP request to get the average score of a professor in a course for each score dimension.
+func getScoreDimensions(w http.ResponseWriter, r *http.Request) {
+	professorUUID, courseCode := r.FormValue("uuid"), r.FormValue("code")
+	if err := isEmptyStr(w, professorUUID, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	scores, err := requestDb(r).GetScoreDimensions(courseCode, professorUUID)
+	if err != nil {
+		writeInternalError(w, r, err)
 		return
 	}
 
@@ -375,6 +1155,8 @@ func getScoresByCourseCodeLike(w http.ResponseWriter, r *http.Request) {
 }
 
 // gradeCourseProfessor handles the HTTP request to grade a professor for a specific course.
+// If the request has an Idempotency-Key header, retrying it with the same key gets the result of the first submission,
+// instead of an already graded error.
 func gradeCourseProfessor(w http.ResponseWriter, r *http.Request) {
 	username, ok := r.Context().Value(usernameContextKey).(string)
 	if !ok || username == "" {
@@ -383,26 +1165,333 @@ func gradeCourseProfessor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idempotencyKey, ok := requestIdempotencyKey(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrInvalidValue.WriteJSON(w)
+		return
+	}
+
 	gradeData, err := decodeGradeData(w, r)
 	if err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if utf8.RuneCountInString(gradeData.Comment) > maxCommentLength {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrCommentTooLong.WriteJSON(w)
+		return
+	}
+
+	if idempotencyKey != "" && replayIdempotentGrade(w, username, idempotencyKey, gradeData) {
 		return
 	}
 
 	grades := [3]float32{gradeData.GradeTeaching, gradeData.GradeCoursework, gradeData.GradeLearning}
-	if err := dataDb.GradeCourseProfessor(gradeData.ProfUUID, gradeData.CourseCode, username, grades); err != nil {
+	details := &db.GradeDetails{Weight: gradeWeight(username), Comment: strings.TrimSpace(gradeData.Comment), Grades: gradeData.Grades}
+	if err := requestDb(r).GradeCourseProfessorWithDetails(gradeData.ProfUUID, gradeData.CourseCode, username, grades, details); err != nil {
 		if errors.Is(err, responses.ErrCourseGraded) {
+			// a concurrent retry may have submitted the grade since the key was checked.
+			if idempotencyKey != "" && replayIdempotentGrade(w, username, idempotencyKey, gradeData) {
+				return
+			}
 			w.WriteHeader(http.StatusForbidden)
 			responses.ErrCourseGraded.WriteJSON(w)
 			return
+		} else if errors.Is(err, responses.ErrGradeOutOfRange) {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrGradeOutOfRange.WriteJSON(w)
+			return
+		} else if errors.Is(err, db.ErrForeignKey) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrNotFound.WriteJSON(w)
+			return
 		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-			responses.ErrInternal.WriteJSON(w)
-			log.Error().Msg(err.Error())
+			writeDbError(w, r, err)
+			return
+		}
+	}
+
+	metrics.GradesSubmitted.Inc()
+
+	if idempotencyKey != "" {
+		// the grade is submitted, so failing to record the key only makes retries get an already graded error.
+		if err := storeIdempotentGrade(username, idempotencyKey, gradeData); err != nil {
+			requestLogger(r).Error().Err(err).Msg("storing idempotency key failed")
+		}
+	}
+
+	writeSuccess(w)
+}
+
+// gradeCourseProfessorBatch handles the HTTP request to grade many courses and their professors at once, for example in a survey.
+// The grades are added in a single transaction, and the response is a list of results, parallel to the list of grades in the request body.
+func gradeCourseProfessorBatch(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	gradeDataMany, err := decodeGradeDataMany(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if len(gradeDataMany) == 0 || len(gradeDataMany) > maxGradeBatchSize {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	results := make([]*GradeResult, len(gradeDataMany))
+	grades, indexes := []*db.Grade{}, []int{}
+	weight := gradeWeight(username)
+	for i, gradeData := range gradeDataMany {
+		if gradeData == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+
+		results[i] = &GradeResult{CourseCode: gradeData.CourseCode, ProfUUID: gradeData.ProfUUID, Status: gradeStatusError}
+
+		if gradeData.CourseCode == "" || gradeData.ProfUUID == "" {
+			results[i].Error = responses.ErrEmptyValue.Error()
+			continue
+		}
+
+		if utf8.RuneCountInString(gradeData.Comment) > maxCommentLength {
+			results[i].Error = responses.ErrCommentTooLong.Error()
+			continue
+		}
+
+		grades = append(grades, &db.Grade{
+			ProfessorUUID: gradeData.ProfUUID,
+			CourseCode:    gradeData.CourseCode,
+			Grades:        [3]float32{gradeData.GradeTeaching, gradeData.GradeCoursework, gradeData.GradeLearning},
+			Details:       db.GradeDetails{Weight: weight, Comment: strings.TrimSpace(gradeData.Comment), Grades: gradeData.Grades},
+		})
+		indexes = append(indexes, i)
+	}
+
+	if len(grades) > 0 {
+		errs, err := requestDb(r).GradeCourseProfessorMany(username, grades)
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		for j, e := range errs {
+			result := results[indexes[j]]
+			switch {
+			case e == nil:
+				result.Status = gradeStatusGraded
+				metrics.GradesSubmitted.Inc()
+			case errors.Is(e, responses.ErrCourseGraded):
+				result.Status = gradeStatusAlreadyGraded
+			case errors.Is(e, responses.ErrGradeOutOfRange):
+				result.Error = responses.ErrGradeOutOfRange.Error()
+			case errors.Is(e, db.ErrForeignKey):
+				// the course or the professor of the grade does not exist.
+				result.Error = responses.ErrNotFound.Error()
+				requestLogger(r).Error().Msg(e.Error())
+			default:
+				_, response, _ := dbErrorResponse(e)
+				result.Error = response.Error()
+				requestLogger(r).Error().Msg(e.Error())
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: results}).WriteJSON(w)
+}
+
+// updateGrade handles the HTTP request to change the grade of the logged-in user for a course and its professor.
+func updateGrade(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	gradeData, err := decodeGradeData(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	grades := [3]float32{gradeData.GradeTeaching, gradeData.GradeCoursework, gradeData.GradeLearning}
+	if err := requestDb(r).UpdateGrade(gradeData.ProfUUID, gradeData.CourseCode, username, grades, gradeData.Grades); err != nil {
+		if errors.Is(err, responses.ErrNotGraded) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrNotGraded.WriteJSON(w)
+			return
+		} else if errors.Is(err, responses.ErrGradeOutOfRange) {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrGradeOutOfRange.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
+}
+
+// deleteGrade handles the HTTP request to retract the grade of the logged-in user for a course and its professor.
+func deleteGrade(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	professorUUID, courseCode := r.FormValue("uuid"), r.FormValue("code")
+	if err := isEmptyStr(w, professorUUID, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err := requestDb(r).DeleteGrade(professorUUID, courseCode, username); err != nil {
+		if errors.Is(err, responses.ErrNotGraded) {
+			w.WriteHeader(http.StatusNotFound)
+			responses.ErrNotGraded.WriteJSON(w)
+			return
+		}
+		writeDbError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
+}
+
+// checkGradedBatch handles the HTTP request to check if the logged-in user graded many courses and their professors.
+// The response is a list of booleans, parallel to the list of courses and professors in the request body.
+func checkGradedBatch(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	pairs, err := decodeCourseProfessors(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if len(pairs) == 0 || len(pairs) > maxGradedBatchSize {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	for _, pair := range pairs {
+		if pair == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+		if err = isEmptyStr(w, pair.ProfessorUUID, pair.CourseCode); err != nil {
+			requestLogger(r).Error().Msg(err.Error())
+			return
+		}
+	}
+
+	graded, err := requestDb(r).CheckGradedMany(username, pairs)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: graded}).WriteJSON(w)
+}
+
+// getEligibleGrades handles the HTTP request to get the courses and their professors that the user can still grade.
+// The optional limit query parameter bounds the number of returned pairs.
+func getEligibleGrades(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	limit, _ := parsePagination(r)
+
+	eligible, err := requestDb(r).GetEligibleGrades(username, limit)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: eligible}).WriteJSON(w)
+}
+
+// getRawGrades handles the HTTP request to get the individual grades of a course and its professor.
+func getRawGrades(w http.ResponseWriter, r *http.Request) {
+	professorUUID, courseCode := r.FormValue("uuid"), r.FormValue("code")
+	if err := isEmptyStr(w, professorUUID, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	grades, err := requestDb(r).GetRawGrades(professorUUID, courseCode)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: grades}).WriteJSON(w)
+}
+
+// flushCache handles the HTTP request to delete all the keys from the cache.
+func flushCache(w http.ResponseWriter, r *http.Request) {
+	if err := requestDb(r).FlushCache(); err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	writeSuccess(w)
+}
+
+// getGradeAttemptsByCourseCode handles the HTTP request to get the outcomes of grade submissions for a course.
+// The optional window query parameter (e.g. 24h) limits the count to the most recent submissions.
+func getGradeAttemptsByCourseCode(w http.ResponseWriter, r *http.Request) {
+	courseCode := mux.Vars(r)["code"]
+	if err := isEmptyStr(w, courseCode); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	var since time.Time
+	if window := r.FormValue("window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
 			return
 		}
+		since = time.Now().Add(-d)
+	}
+
+	attempts, err := requestDb(r).GetGradeAttemptsByCourseCode(courseCode, since)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	(&responses.Response{Code: responses.SuccessCode, Message: attempts}).WriteJSON(w)
 }
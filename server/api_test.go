@@ -8,8 +8,11 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/vanillaiice/itpg/db"
@@ -38,20 +41,20 @@ func initDB(path ...string) (db.DB, error) {
 		path = append(path, ":memory:")
 	}
 
-	db, err := sqlite.New(path[0], "", 0, context.Background())
+	d, err := sqlite.New(path[0], "", 0, context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	if err = db.AddCourseMany(courses); err != nil {
+	if _, err = d.AddCourseMany(courses); err != nil {
 		return nil, err
 	}
 
-	if err = db.AddProfessorMany(professorNames); err != nil {
+	if _, err = d.AddProfessorMany(professorNames); err != nil {
 		return nil, err
 	}
 
-	professors, err = db.GetLastProfessors()
+	professors, err = d.GetLastProfessors(db.ProfessorSortRecent, 0, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -60,20 +63,20 @@ func initDB(path ...string) (db.DB, error) {
 
 	for i := 0; i < len(professors); i++ {
 		profScores := [3]float32{rand.Float32() * 5, rand.Float32() * 5, rand.Float32() * 5}
-		err = db.GradeCourseProfessor(professors[i].UUID, courses[i].Code, "jim", profScores)
+		err = d.GradeCourseProfessor(professors[i].UUID, courses[i].Code, "jim", profScores)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	scores, err = db.GetLastScores()
+	scores, err = d.GetLastScores(0, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	slices.Reverse(scores)
 
-	return db, err
+	return d, err
 }
 
 func dbInit() (err error) {
@@ -81,519 +84,2563 @@ func dbInit() (err error) {
 	return
 }
 
-func TestServerAddCourse(t *testing.T) {
-	err := dbInit()
+func TestServerQueryTimeout(t *testing.T) {
+	d, err := sqlite.New(":memory:", "", 0, context.Background(), db.WithQueryTimeout(time.Nanosecond))
 	if err != nil {
 		t.Fatal(err)
 	}
+	dataDb = d
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("POST", "/course/add?code=GC8F&name=Showing%20your%20son%20whose%20the%20boss", nil)
+	r, err := http.NewRequest("GET", "/course/all", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	addCourse(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	getLastCourses(rr, r)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusServiceUnavailable)
 	}
-	if rr.Body.String() != responses.Success.Error() {
-		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+	if rr.Body.String() != responses.ErrInternal.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrInternal.Error())
 	}
 }
 
-func TestServerAddProfessor(t *testing.T) {
+func TestServerAddCourse(t *testing.T) {
 	err := dbInit()
 	if err != nil {
-		t.Fatal(err.Error())
+		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("POST", "/professor/add?fullname=Gintoki%20Sakata%20Senpai", nil)
+	r, err := http.NewRequest("POST", "/course/add?code=GC8F&name=Showing%20your%20son%20whose%20the%20boss", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	addProfessor(rr, r)
+	addCourse(rr, r)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
 	}
 	if rr.Body.String() != responses.Success.Error() {
 		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
 	}
 }
 
-func TestServerRemoveCourse(t *testing.T) {
+func TestServerAddCourseCredits(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("DELETE", "/course/remove?code=S209", nil)
+	tests := []struct {
+		credits  string
+		status   int
+		expected *responses.Response
+	}{
+		{"4", http.StatusOK, responses.Success},
+		{"four", http.StatusBadRequest, responses.ErrInvalidValue},
+		{"-1", http.StatusBadRequest, responses.ErrInvalidValue},
+		{"100", http.StatusBadRequest, responses.ErrInvalidValue},
+	}
+
+	for i, test := range tests {
+		r, err := http.NewRequest("POST", fmt.Sprintf("/course/add?code=GC8F%d&name=Rally&credits=%s", i, test.credits), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		addCourse(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("%s: got %v, want %v", test.credits, rr.Code, test.status)
+		}
+		if rr.Body.String() != test.expected.Error() {
+			t.Errorf("%s: got %s, want %s", test.credits, rr.Body.String(), test.expected.Error())
+		}
+	}
+
+	allCourses, err := dataDb.GetLastCourses(0, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	rr := httptest.NewRecorder()
-	removeCourse(rr, r)
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf("got %v, want %v", rr.Code, http.StatusInternalServerError)
-	}
-	if rr.Body.String() != responses.ErrInternal.Error() {
-		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+
+	i := slices.IndexFunc(allCourses, func(c *db.Course) bool { return c.Code == "GC8F0" })
+	if i == -1 || allCourses[i].Credits == nil || *allCourses[i].Credits != 4 {
+		t.Errorf("got %v, want course GC8F0 with 4 credits", allCourses)
 	}
 }
 
-func TestServerRemoveCourseForce(t *testing.T) {
+func TestServerGetDepartmentStats(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("DELETE", "/course/removeforce?code=S209", nil)
+	r, err := http.NewRequest("POST", "/course/add?code=MA101&name=Calculus&department=Math", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	removeCourseForce(rr, r)
+	addCourse(rr, r)
 	if rr.Code != http.StatusOK {
-		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
 	}
-	if rr.Body.String() != responses.Success.Error() {
-		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+
+	r, err = http.NewRequest("GET", "/stats/departments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	getDepartmentStats(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	stats := []*db.DepartmentStats{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &stats}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := db.DepartmentStats{Department: "Math", Courses: 1}
+	if len(stats) != 1 || *stats[0] != expected {
+		t.Errorf("got %v, want [%+v]", stats, expected)
 	}
 }
 
-func TestServerAddCourseProfessor(t *testing.T) {
+func TestServerGetComponentAverages(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("POST", fmt.Sprintf("/courses/addprof?uuid=%s&code=S209", professors[1].UUID), nil)
+	r, err := http.NewRequest("GET", "/stats/components", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	addCourseProfessor(rr, r)
+	getComponentAverages(rr, r)
 	if rr.Code != http.StatusOK {
-		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
 	}
-	if rr.Body.String() != responses.Success.Error() {
-		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+
+	averages := &db.ComponentAverages{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: averages}); err != nil {
+		t.Fatal(err)
+	}
+
+	if averages.Count != len(professors) {
+		t.Errorf("got %d, want %d", averages.Count, len(professors))
 	}
 }
 
-func TestServerRemoveProfessor(t *testing.T) {
+func TestServerAddCourseDuplicate(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("DELETE", fmt.Sprintf("/professor/remove?uuid=%s", professors[0].UUID), nil)
+	r, err := http.NewRequest("POST", "/course/add?code="+courses[0].Code+"&name=Replacing%20head%20gaskets", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	removeProfessor(rr, r)
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf("got %v, want %v", rr.Code, http.StatusInternalServerError)
+	addCourse(rr, r)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusConflict)
 	}
-	if rr.Body.String() != responses.ErrInternal.Error() {
-		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+	if rr.Body.String() != responses.ErrDuplicate.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrDuplicate.Error())
 	}
 }
 
-func TestServerRemoveProfessorForce(t *testing.T) {
+func TestServerUpdateCourse(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("DELETE", fmt.Sprintf("/professor/removeforce?uuid=%s", professors[0].UUID), nil)
+	r, err := http.NewRequest("POST", "/course/update?code="+courses[0].Code+"&name=Replacing%20head%20gaskets", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	removeProfessorForce(rr, r)
+	updateCourse(rr, r)
 	if rr.Code != http.StatusOK {
 		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
 	}
 	if rr.Body.String() != responses.Success.Error() {
 		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
 	}
-}
 
-func TestServerGetLastCourses(t *testing.T) {
-	err := dbInit()
+	r, err = http.NewRequest("POST", "/course/update?code="+courses[0].Code, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer dataDb.Close()
+	rr = httptest.NewRecorder()
+	updateCourse(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
 
-	r, err := http.NewRequest("GET", "/course/all", nil)
+	r, err = http.NewRequest("POST", "/course/update?code=GC8F&name=Rally%20Driving", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	rr := httptest.NewRecorder()
-	getLastCourses(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	rr = httptest.NewRecorder()
+	updateCourse(rr, r)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusNotFound)
 	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+}
+
+func TestServerUpdateProfessor(t *testing.T) {
+	err := dbInit()
+	if err != nil {
 		t.Fatal(err)
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp == 0 {
-		t.Errorf("got len = 0, want %s", "> 0")
+	defer dataDb.Close()
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"uuid=" + professors[0].UUID + "&fullname=Takumi%20Fujiwara", http.StatusOK},
+		{"uuid=" + professors[1].UUID + "&fullname=Takumi%20Fujiwara", http.StatusConflict},
+		{"uuid=" + professors[1].UUID, http.StatusBadRequest},
+		{"uuid=deadbeef&fullname=Bunta%20Fujiwara", http.StatusNotFound},
 	}
-	if resp.Code != responses.SuccessCode {
-		t.Errorf("got %d, want %d", resp.Code, responses.SuccessCode)
+
+	for _, test := range tests {
+		for path, handler := range map[string]http.HandlerFunc{"/professor/update": updateProfessor, "/professor/rename": renameProfessor} {
+			r, err := http.NewRequest("POST", path+"?"+test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, r)
+			if rr.Code != test.status {
+				t.Errorf("%s %s: got %v, want %v", path, test.query, rr.Code, test.status)
+			}
+		}
 	}
 }
 
-func TestServerGetLastProfessors(t *testing.T) {
+func TestServerAddCourseNoContent(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", "/professor/all", nil)
+	noContentOnSuccess = true
+	defer func() { noContentOnSuccess = false }()
+
+	r, err := http.NewRequest("POST", "/course/add?code=GC8F&name=Showing%20your%20son%20whose%20the%20boss", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	getLastProfessors(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	addCourse(rr, r)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusNoContent)
 	}
-	resp := &responses.Response{}
-	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+	if rr.Body.Len() != 0 {
+		t.Errorf("got %s, want empty body", rr.Body.String())
+	}
+
+	r, err = http.NewRequest("GET", "/course/all", nil)
+	if err != nil {
 		t.Fatal(err)
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp == 0 {
-		t.Errorf("got len = 0, want %s", "> 0")
+	rr = httptest.NewRecorder()
+	getLastCourses(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Body.Len() == 0 {
+		t.Error("got empty body, want courses")
 	}
 }
 
-func TestServerGetLastScores(t *testing.T) {
+func TestServerAddCourseMany(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", "/score/all", nil)
-	if err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		courses  []*db.Course
+		status   int
+		inserted bool
+		failing  int
+		error    string
+	}{
+		{[]*db.Course{{Code: "GC8F", Name: "Rally Driving"}, {Code: "AP1", Name: "One Hand Driving 101"}}, http.StatusOK, true, -1, ""},
+		{[]*db.Course{{Code: "EK9", Name: "Art of VTEC"}, courses[0], {Code: "FD2", Name: "Drifting 101"}}, http.StatusBadRequest, false, 1, responses.ErrDuplicate.Error()},
+		{[]*db.Course{{Code: "EK9", Name: "Art of VTEC"}, {Code: "FD2", Name: ""}}, http.StatusBadRequest, false, 1, "empty code or name"},
 	}
-	rr := httptest.NewRecorder()
-	getLastScores(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+
+	for _, test := range tests {
+		data, _ := json.Marshal(test.courses)
+		r := httptest.NewRequest("POST", "/course/addmany", bytes.NewReader(data))
+		rr := httptest.NewRecorder()
+		addCourseMany(rr, r)
+		if rr.Code != test.status {
+			t.Fatalf("got %v, want %v", rr.Code, test.status)
+		}
+
+		results := []*ImportResult{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &results}); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(test.courses) {
+			t.Fatalf("got len = %d, want %d", len(results), len(test.courses))
+		}
+		for i, result := range results {
+			if result.Inserted != test.inserted {
+				t.Errorf("%s: got inserted %t, want %t", result.Item, result.Inserted, test.inserted)
+			}
+			if failed := i == test.failing; (result.Error != "") != failed {
+				t.Errorf("%s: got error %q, want failure %t", result.Item, result.Error, failed)
+			} else if failed && result.Error != test.error {
+				t.Errorf("%s: got error %q, want %q", result.Item, result.Error, test.error)
+			}
+		}
 	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+
+	allCourses, err := dataDb.GetLastCourses(0, 0)
+	if err != nil {
 		t.Fatal(err)
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp == 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+	if len(allCourses) != len(courses)+2 {
+		t.Errorf("got len = %d, want %d", len(allCourses), len(courses)+2)
+	}
+
+	r := httptest.NewRequest("POST", "/course/addmany", bytes.NewReader([]byte("[]")))
+	rr := httptest.NewRecorder()
+	addCourseMany(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
 	}
 }
 
-func TestServerGetCoursesByProfessorUUID(t *testing.T) {
+func TestServerAddProfessorMany(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/course/%s", professors[0].UUID), nil)
-	if err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		names    []string
+		status   int
+		inserted bool
+		failing  int
+	}{
+		{[]string{"Master Roshi", "Yamcha"}, http.StatusOK, true, -1},
+		{[]string{"Krillin", professorNames[0], "Tien"}, http.StatusBadRequest, false, 1},
+		{[]string{"Krillin", ""}, http.StatusBadRequest, false, 1},
 	}
-	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/course/{uuid}", getCoursesByProfessorUUID)
-	router.ServeHTTP(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+
+	for _, test := range tests {
+		data, _ := json.Marshal(test.names)
+		r := httptest.NewRequest("POST", "/professor/addmany", bytes.NewReader(data))
+		rr := httptest.NewRecorder()
+		addProfessorMany(rr, r)
+		if rr.Code != test.status {
+			t.Fatalf("got %v, want %v", rr.Code, test.status)
+		}
+
+		results := []*ImportResult{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &results}); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(test.names) {
+			t.Fatalf("got len = %d, want %d", len(results), len(test.names))
+		}
+		for i, result := range results {
+			if result.Inserted != test.inserted {
+				t.Errorf("%q: got inserted %t, want %t", result.Item, result.Inserted, test.inserted)
+			}
+			if failed := i == test.failing; (result.Error != "") != failed {
+				t.Errorf("%q: got error %q, want failure %t", result.Item, result.Error, failed)
+			}
+		}
 	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+
+	allProfessors, err := dataDb.GetLastProfessors(db.ProfessorSortRecent, 0, 0)
+	if err != nil {
 		t.Fatal(err)
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+	if len(allProfessors) != len(professorNames)+2 {
+		t.Errorf("got len = %d, want %d", len(allProfessors), len(professorNames)+2)
 	}
 }
 
-func TestServerGetProfessorsByCourseCode(t *testing.T) {
+func TestServerAddProfessor(t *testing.T) {
 	err := dbInit()
 	if err != nil {
-		t.Fatal(err)
+		t.Fatal(err.Error())
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/professor/%s", courses[0].Code), nil)
+	r, err := http.NewRequest("POST", "/professor/add?fullname=Gintoki%20Sakata%20Senpai", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/professor/{code}", getProfessorsByCourseCode)
-	router.ServeHTTP(rr, r)
+	addProfessor(rr, r)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
 	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
-		t.Fatal(err)
-	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+	if rr.Body.String() != responses.Success.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
 	}
 }
 
-func TestServerGetScoresByProfessorUUID(t *testing.T) {
-	err := dbInit()
+func TestServerAddProfessorLikelyDuplicate(t *testing.T) {
+	d, err := sqlite.New(":memory:", "", 0, context.Background(), db.WithProfessorNameDedup(true))
 	if err != nil {
 		t.Fatal(err)
 	}
+	dataDb = d
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/score/prof/%s", professors[0].UUID), nil)
+	if err = dataDb.AddProfessor("Prof. Oak"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest("POST", "/professor/add?fullname=prof%20oak", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/score/prof/{uuid}", getScoresByProfessorUUID)
-	router.ServeHTTP(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	addProfessor(rr, r)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusConflict)
 	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+
+	professor := &db.Professor{}
+	resp := &responses.Response{Message: professor}
+	if err = json.NewDecoder(rr.Body).Decode(resp); err != nil {
 		t.Fatal(err)
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+
+	if resp.Code != responses.ErrLikelyDuplicate.Code {
+		t.Errorf("got %d, want %d", resp.Code, responses.ErrLikelyDuplicate.Code)
+	}
+
+	if professor.Name != "Prof. Oak" || professor.UUID == "" {
+		t.Errorf("got %+v, want the existing professor", *professor)
 	}
 }
 
-func TestServerGetScoresByProfessorName(t *testing.T) {
+func TestServerRemoveCourse(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/score/name/%s", professors[0].Name), nil)
+	r, err := http.NewRequest("DELETE", "/course/remove?code=S209", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/score/name/{name}", getScoresByProfessorName)
-	router.ServeHTTP(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
-	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
-		t.Fatal(err)
+	removeCourse(rr, r)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusConflict)
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+	if rr.Body.String() != responses.ErrReferenced.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrReferenced.Error())
 	}
 }
 
-func TestServerGetScoresByProfessorNameLike(t *testing.T) {
+func TestServerRemoveCourseProfessor(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/score/namelike/%s", professors[0].Name[:5]), nil)
-	if err != nil {
+	if err = dataDb.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
 		t.Fatal(err)
 	}
-	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/score/namelike/{name}", getScoresByProfessorNameLike)
-	router.ServeHTTP(rr, r)
-	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
-	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
-		t.Fatal(err)
+
+	tests := []struct {
+		query    string
+		status   int
+		expected string
+	}{
+		{"uuid=" + professors[0].UUID + "&code=" + courses[0].Code, http.StatusConflict, responses.ErrReferenced.Error()},
+		{"uuid=" + professors[0].UUID + "&code=" + courses[1].Code, http.StatusOK, responses.Success.Error()},
+		{"uuid=" + professors[0].UUID + "&code=" + courses[1].Code, http.StatusNotFound, responses.ErrCourseProfessorNotFound.Error()},
+		{"code=" + courses[0].Code, http.StatusBadRequest, responses.ErrEmptyValue.Error()},
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+
+	for _, test := range tests {
+		r, err := http.NewRequest("DELETE", "/course/removeprof?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		removeCourseProfessor(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("%s: got %v, want %v", test.query, rr.Code, test.status)
+		}
+		if rr.Body.String() != test.expected {
+			t.Errorf("%s: got %s, want %s", test.query, rr.Body.String(), test.expected)
+		}
 	}
 }
 
-func TestServerGetScoresByCourseName(t *testing.T) {
+func TestServerRemoveCourseForce(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursename/%s", courses[0].Name), nil)
+	r, err := http.NewRequest("DELETE", "/course/removeforce?code=S209", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/score/coursename/{name}", getScoresByCourseName)
-	router.ServeHTTP(rr, r)
+	removeCourseForce(rr, r)
 	if rr.Code != http.StatusOK {
-		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Body.String() != responses.Success.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+	}
+}
+
+func TestServerAddCourseProfessor(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("POST", fmt.Sprintf("/courses/addprof?uuid=%s&code=S209", professors[1].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	addCourseProfessor(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Body.String() != responses.Success.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+	}
+}
+
+func TestServerRemoveProfessor(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("DELETE", fmt.Sprintf("/professor/remove?uuid=%s", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	removeProfessor(rr, r)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusConflict)
+	}
+	if rr.Body.String() != responses.ErrReferenced.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrReferenced.Error())
+	}
+}
+
+func TestServerRemoveProfessorForce(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("DELETE", fmt.Sprintf("/professor/removeforce?uuid=%s", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	removeProfessorForce(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Body.String() != responses.Success.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+	}
+}
+
+func TestServerSoftRemoveProfessor(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	target := fmt.Sprintf("/professor/softremove?uuid=%s", professors[0].UUID)
+
+	for _, tc := range []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantCode int
+		wantBody string
+	}{
+		{"soft remove", softRemoveProfessor, http.StatusOK, responses.Success.Error()},
+		{"already removed", softRemoveProfessor, http.StatusNotFound, responses.ErrProfessorNotFound.Error()},
+		{"restore", restoreProfessor, http.StatusOK, responses.Success.Error()},
+		{"not removed", restoreProfessor, http.StatusNotFound, responses.ErrProfessorNotFound.Error()},
+	} {
+		r, err := http.NewRequest("POST", target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		tc.handler(rr, r)
+		if rr.Code != tc.wantCode {
+			t.Errorf("%s: got %v, want %v", tc.name, rr.Code, tc.wantCode)
+		}
+		if rr.Body.String() != tc.wantBody {
+			t.Errorf("%s: got %s, want %s", tc.name, rr.Body.String(), tc.wantBody)
+		}
+	}
+}
+
+func TestServerGetTrash(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.SoftRemoveCourse("S209"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest("GET", "/admin/trash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getTrash(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	trash := db.Trash{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &trash}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trash.Courses) != 1 || trash.Courses[0].Code != "S209" || trash.Courses[0].DeletedAt.IsZero() {
+		t.Errorf("got unexpected deleted courses %v", trash.Courses)
+	}
+	if len(trash.Professors) != 0 {
+		t.Errorf("got %d deleted professors, want 0", len(trash.Professors))
+	}
+}
+
+func TestServerFlushCache(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("POST", "/cache/flush", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	flushCache(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestServerGetLastCourses(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", "/course/all", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getLastCourses(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp == 0 {
+		t.Errorf("got len = 0, want %s", "> 0")
+	}
+	if resp.Code != responses.SuccessCode {
+		t.Errorf("got %d, want %d", resp.Code, responses.SuccessCode)
+	}
+}
+
+func TestServerGetLastCoursesPagination(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"limit=2", 2},
+		{"limit=2&offset=2", 1},
+		{"offset=10", 0},
+		{"limit=abc&offset=abc", len(courses)},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/course/all?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getLastCourses(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got %v, want %v", test.query, rr.Code, http.StatusOK)
+		}
+		page := []*db.Course{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &page}); err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != test.expected {
+			t.Errorf("%s: got len = %d, want %d", test.query, len(page), test.expected)
+		}
+	}
+}
+
+func TestServerPublicMaxResults(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = initTestUserState(); err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	publicMaxResults = 2
+	defer func() { publicMaxResults = 0 }()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.Confirm(creds.Email)
+
+	body, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	login(rr, httptest.NewRequest("POST", "/login", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	cookie := rr.Result().Cookies()[0]
+
+	from, to := time.Now().Add(-time.Hour).Format(time.RFC3339), time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		path          string
+		handler       http.HandlerFunc
+		authenticated bool
+		expected      int
+	}{
+		{"/course/all", getLastCourses, false, 2},
+		{"/course/all?limit=10", getLastCourses, false, 2},
+		{"/course/all?limit=1", getLastCourses, false, 1},
+		{"/course/all", getLastCourses, true, len(courses)},
+		{"/course/between?from=" + url.QueryEscape(from) + "&to=" + url.QueryEscape(to), getCoursesBetween, false, 2},
+		{"/course/between?from=" + url.QueryEscape(from) + "&to=" + url.QueryEscape(to), getCoursesBetween, true, len(courses)},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.path, nil)
+		if test.authenticated {
+			r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+		rr := httptest.NewRecorder()
+		optionalSessionMiddleware(test.handler).ServeHTTP(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got %v, want %v", test.path, rr.Code, http.StatusOK)
+		}
+		if rr.Header().Get("Vary") != "Cookie" {
+			t.Errorf("%s: got Vary %q, want Cookie", test.path, rr.Header().Get("Vary"))
+		}
+		page := []*db.Course{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &page}); err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != test.expected {
+			t.Errorf("%s (authenticated: %v): got len = %d, want %d", test.path, test.authenticated, len(page), test.expected)
+		}
+	}
+}
+
+func TestServerGetLastProfessors(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", "/professor/all", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getLastProfessors(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp == 0 {
+		t.Errorf("got len = 0, want %s", "> 0")
+	}
+}
+
+func TestServerGetLastProfessorsSort(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", "/professor/all?sort=name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getLastProfessors(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	r, err = http.NewRequest("GET", "/professor/all?sort=foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	getLastProfessors(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerGetRandomCourses(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", "/course/discover?n=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getRandomCourses(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 2 {
+		t.Errorf("got len = %d, want %d", lresp, 2)
+	}
+
+	for _, n := range []string{"abc", "0", "-1"} {
+		r, err := http.NewRequest("GET", "/course/discover?n="+n, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getRandomCourses(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", n, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerGetCoursesBetween(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	from, to := time.Now().Add(-time.Hour).Format(time.RFC3339), time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	r, err := http.NewRequest("GET", "/course/between?from="+url.QueryEscape(from)+"&to="+url.QueryEscape(to), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getCoursesBetween(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp == 0 {
+		t.Errorf("got len = 0, want %s", "> 0")
+	}
+
+	tests := []string{
+		"/course/between",
+		"/course/between?from=" + url.QueryEscape(from),
+		"/course/between?from=yesterday&to=" + url.QueryEscape(to),
+		"/course/between?from=" + url.QueryEscape(to) + "&to=" + url.QueryEscape(from),
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", test, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getCoursesBetween(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", test, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerGetCatalogSync(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	getCatalog := func(since string) *CatalogSync {
+		rr := httptest.NewRecorder()
+		getCatalogSync(rr, httptest.NewRequest("GET", "/catalog/sync?since="+url.QueryEscape(since), nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+		catalog := &CatalogSync{}
+		if err := json.NewDecoder(rr.Body).Decode(&responses.Response{Message: catalog}); err != nil {
+			t.Fatal(err)
+		}
+		return catalog
+	}
+
+	catalog := getCatalog("")
+	if len(catalog.Courses) == 0 || len(catalog.Professors) == 0 || catalog.Version == "" {
+		t.Fatalf("got %+v, want the whole catalog with a version token", *catalog)
+	}
+
+	if unchanged := getCatalog(catalog.Version); len(unchanged.Courses) != 0 || len(unchanged.Professors) != 0 || unchanged.Version != catalog.Version {
+		t.Errorf("got %+v, want no changes at version %s", *unchanged, catalog.Version)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	newCourse := &db.Course{Code: "GC8", Name: "Rally Driving"}
+	if err = dataDb.AddCourse(newCourse); err != nil {
+		t.Fatal(err)
+	}
+	if err = dataDb.AddProfessor("Master Roshi"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := getCatalog(catalog.Version)
+	if len(changes.Courses) != 1 || changes.Courses[0].Code != newCourse.Code {
+		t.Errorf("got %v, want %v", changes.Courses, []*db.Course{newCourse})
+	}
+	if len(changes.Professors) != 1 || changes.Professors[0].Name != "Master Roshi" {
+		t.Errorf("got %v, want Master Roshi", changes.Professors)
+	}
+	if changes.Version == catalog.Version {
+		t.Errorf("got version %s, want a new version", changes.Version)
+	}
+
+	for _, since := range []string{"yesterday", "-1", "0"} {
+		rr := httptest.NewRecorder()
+		getCatalogSync(rr, httptest.NewRequest("GET", "/catalog/sync?since="+since, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", since, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerGetLastScores(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", "/score/all", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getLastScores(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp == 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetCoursesByProfessorUUID(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/course/%s", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/course/{uuid}", getCoursesByProfessorUUID)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetUngradedCoursesByProfessorUUID(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/professor/%s/ungraded-courses", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/professor/{uuid}/ungraded-courses", getUngradedCoursesByProfessorUUID)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	ungradedCourses := []*db.Course{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &ungradedCourses}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ungradedCourses) != 1 || ungradedCourses[0].Code != courses[1].Code {
+		t.Errorf("got %v, want [%v]", ungradedCourses, courses[1])
+	}
+}
+
+func TestServerGetProfessorsByCourseCode(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/professor/%s", courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/professor/{code}", getProfessorsByCourseCode)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetScoresByProfessorUUID(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/prof/%s", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/prof/{uuid}", getScoresByProfessorUUID)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetScoreHistoryByCourseCode(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/history/coursecode/%s", courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/history/coursecode/{code}", getScoreHistoryByCourseCode)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	history := []*db.ScoreTrendPoint{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &history}); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Count != 1 {
+		t.Errorf("got %v, want 1 month with 1 grade", history)
+	}
+}
+
+func TestServerGetScoreDistributionByProfessorUUID(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/distribution/%s", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/distribution/{uuid}", getScoreDistributionByProfessorUUID)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	distribution := &db.ScoreDistribution{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: distribution}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, counts := range [][5]int{distribution.Teaching, distribution.CourseWork, distribution.Learning} {
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		if total != 1 {
+			t.Errorf("got %v, want 1 grade", counts)
+		}
+	}
+}
+
+func TestServerGetProfessorsForCourses(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/professors/for-courses?codes=%s,%s,%s", courses[0].Code, courses[1].Code, courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getProfessorsForCourses(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	scores := map[string][]*db.Score{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &scores}); err != nil {
+		t.Fatal(err)
+	}
+	for _, course := range courses[:2] {
+		if len(scores[course.Code]) != 1 || scores[course.Code][0].ProfessorUUID != professors[slices.Index(courses, course)].UUID {
+			t.Errorf("got %v for %s, want %s", scores[course.Code], course.Code, professors[slices.Index(courses, course)].Name)
+		}
+	}
+
+	tooMany := []string{}
+	for i := 0; i <= maxScheduleCourses; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("C%d", i))
+	}
+
+	for _, codes := range []string{"", " , ", strings.Join(tooMany, ",")} {
+		r, err := http.NewRequest("GET", "/professors/for-courses?codes="+codes, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getProfessorsForCourses(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%q: got %v, want %v", codes, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerGetProfessorDetail(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/professor/detail/{uuid}", getProfessorDetail)
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/professor/detail/%s", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	detail := &db.ProfessorDetail{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: detail}); err != nil {
+		t.Fatal(err)
+	}
+	if detail.ProfessorName != professors[0].Name || len(detail.Courses) != 1 || detail.Count != 1 {
+		t.Errorf("got %+v, want %s with 1 course and 1 grade", *detail, professors[0].Name)
+	}
+
+	r, err = http.NewRequest("GET", "/professor/detail/deadbeef", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestServerGetProfessorRank(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.AddCourse(&db.Course{Code: "MA101", Name: "Calculus", Department: "Math"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, professor := range professors[:2] {
+		if err = dataDb.AddCourseProfessor(professor.UUID, "MA101"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/professor/{uuid}/rank", getProfessorRank)
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/professor/%s/rank", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	rank := &db.ProfessorRank{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: rank}); err != nil {
+		t.Fatal(err)
+	}
+	if rank.Department != "Math" || rank.Rank < 1 || rank.Total != 2 {
+		t.Errorf("got %+v, want a rank of 2 professors in %s", *rank, "Math")
+	}
+
+	tests := []struct {
+		uuid     string
+		expected *responses.Response
+	}{
+		{professors[2].UUID, responses.ErrProfessorNoDepartment},
+		{"deadbeef", responses.ErrProfessorNotFound},
+	}
+	for _, test := range tests {
+		r, err = http.NewRequest("GET", fmt.Sprintf("/professor/%s/rank", test.uuid), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusNotFound)
+		}
+		if rr.Body.String() != test.expected.Error() {
+			t.Errorf("got %s, want %s", rr.Body.String(), test.expected.Error())
+		}
+	}
+}
+
+func TestServerGetCourseDetail(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/course/detail/{code}", getCourseDetail)
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/course/detail/%s", courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	detail := &db.CourseDetail{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: detail}); err != nil {
+		t.Fatal(err)
+	}
+	if detail.CourseName != courses[0].Name || len(detail.Professors) != 1 || detail.Count != 1 {
+		t.Errorf("got %+v, want %s with 1 professor and 1 grade", *detail, courses[0].Name)
+	}
+
+	r, err = http.NewRequest("GET", "/course/detail/XX999", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestServerGetScoresByProfessorName(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/name/%s", professors[0].Name), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/name/{name}", getScoresByProfessorName)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetScoresByProfessorNameLike(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/namelike/%s", professors[0].Name[:5]), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/namelike/{name}", getScoresByProfessorNameLike)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetScoresByProfessorNameLikeMode(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/score/namelike/{name}", getScoresByProfessorNameLike)
+
+	tests := []struct {
+		target string
+		code   int
+		count  int
+	}{
+		{"/score/namelike/Prof?mode=prefix", http.StatusOK, 1},
+		{"/score/namelike/essor?mode=prefix", http.StatusOK, 0},
+		{"/score/namelike/essor?mode=substring", http.StatusOK, 1},
+		{"/score/namelike/essor", http.StatusOK, 1},
+		{"/score/namelike/essor?mode=suffix", http.StatusBadRequest, 0},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", test.target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+		if rr.Code != test.code {
+			t.Errorf("%s: got %v, want %v", test.target, rr.Code, test.code)
+			continue
+		}
+		if test.code != http.StatusOK {
+			continue
+		}
+
+		scores := []*db.Score{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &scores}); err != nil {
+			t.Fatal(err)
+		}
+		if len(scores) != test.count {
+			t.Errorf("%s: got %d, want %d", test.target, len(scores), test.count)
+		}
+	}
+}
+
+func TestServerGetScoresByCourseName(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursename/%s", courses[0].Name), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/coursename/{name}", getScoresByCourseName)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetScoresByCourseNameLike(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursenamelike/%s", courses[0].Name[:5]), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/coursenamelike/{name}", getScoresByCourseNameLike)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 2 {
+		t.Errorf("got %d, want %d", lresp, 2)
+	}
+}
+
+func TestServerGetScoresByCourseCode(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursecode/%s", courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/coursecode/{code}", getScoresByCourseCode)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetScoresByCourseCodeBetween(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	time.Sleep(time.Millisecond)
+	from := time.Now()
+
+	if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "bob", [3]float32{1, 1, 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+	to := time.Now()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/score/coursecode/{code}", getScoresByCourseCode)
+
+	query := url.Values{"from": {from.Format(time.RFC3339Nano)}, "to": {to.Format(time.RFC3339Nano)}}
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursecode/%s?%s", courses[0].Code, query.Encode()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	windowScores := []*db.Score{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &windowScores}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windowScores) != 1 {
+		t.Fatalf("got %d scores, want 1", len(windowScores))
+	}
+
+	if windowScores[0].ScoreAverage != 1 || windowScores[0].Count != 1 {
+		t.Errorf("got %+v, want only the grade given within the time range", *windowScores[0])
+	}
+
+	// both ends of the time range are required.
+	query.Del("to")
+	r, err = http.NewRequest("GET", fmt.Sprintf("/score/coursecode/%s?%s", courses[0].Code, query.Encode()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerGetScoresByCourseCodeLike(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursecodelike/%s", courses[0].Code[:3]), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/score/coursecodelike/{code}", getScoresByCourseCodeLike)
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got %d, want %d", lresp, 1)
+	}
+}
+
+func TestServerGetScoresBySearch(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	tests := []struct {
+		query    string
+		status   int
+		expected int
+	}{
+		{"q=" + courses[0].Code, http.StatusOK, 1},
+		{"q=" + url.QueryEscape(professorNames[2]), http.StatusOK, 1},
+		{"q=", http.StatusBadRequest, 0},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/search?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getScoresBySearch(rr, r)
+		if rr.Code != test.status {
+			t.Fatalf("%s: got %v, want %v", test.query, rr.Code, test.status)
+		}
+		if test.status != http.StatusOK {
+			continue
+		}
+		scores := []*db.Score{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &scores}); err != nil {
+			t.Fatal(err)
+		}
+		if len(scores) != test.expected {
+			t.Errorf("%s: got %d, want %d", test.query, len(scores), test.expected)
+		}
+	}
+}
+
+func TestServerSearch(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	tests := []struct {
+		query    string
+		status   int
+		expected *responses.Response
+	}{
+		{"onizuka great", http.StatusOK, nil},
+		{"", http.StatusBadRequest, responses.ErrEmptyValue},
+		{" o ", http.StatusBadRequest, responses.ErrSearchTooShort},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/search/ranked?q="+url.QueryEscape(test.query), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		search(rr, r)
+		if rr.Code != test.status {
+			t.Fatalf("%q: got %v, want %v", test.query, rr.Code, test.status)
+		}
+		if test.expected != nil {
+			if rr.Body.String() != test.expected.Error() {
+				t.Errorf("%q: got %s, want %s", test.query, rr.Body.String(), test.expected.Error())
+			}
+			continue
+		}
+		results := []*db.SearchResult{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &results}); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Kind != db.SearchKindProfessor || results[0].Name != professorNames[0] {
+			t.Errorf("%q: got %v, want %s", test.query, results, professorNames[0])
+		}
+	}
+}
+
+func TestServerGradeCourseProfessor(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	data, _ := json.Marshal(&GradeData{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3})
+	r := httptest.NewRequest("POST", "/course/grade", bytes.NewReader(data))
+	rr := httptest.NewRecorder()
+	err = initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.Confirm(creds.Email)
+	if err := userState.Login(rr, creds.Email); err != nil {
+		t.Fatal(err)
+	}
+	cookie := rr.Result().Cookies()[0]
+	c := &http.Cookie{
+		Name:  cookie.Name,
+		Value: cookie.Value,
+	}
+	r.AddCookie(c)
+	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+	gradeCourseProfessor(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Body.String() != responses.Success.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+	}
+}
+
+func TestServerGradeCourseProfessorIdempotencyKey(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	err = initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	idempotencyKeyValidityTime = time.Hour
+	defer func() { idempotencyKeyValidityTime = 0 }()
+
+	grade := func(gradeData *GradeData, key string) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(gradeData)
+		r := httptest.NewRequest("POST", "/course/grade", bytes.NewReader(data))
+		if key != "" {
+			r.Header.Set(idempotencyKeyHeader, key)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+		rr := httptest.NewRecorder()
+		gradeCourseProfessor(rr, r)
+		return rr
+	}
+
+	gradeData := &GradeData{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3}
+
+	// the retries with the same key get the result of the first submission.
+	for i := 0; i < 3; i++ {
+		rr := grade(gradeData, "b7e4a1c2")
+		if rr.Code != http.StatusOK {
+			t.Errorf("attempt %d: got %v, want %v", i+1, rr.Code, http.StatusOK)
+		}
+		if rr.Body.String() != responses.Success.Error() {
+			t.Errorf("attempt %d: got %s, want %s", i+1, rr.Body.String(), responses.Success.Error())
+		}
+	}
+
+	grades, err := dataDb.GetRawGrades(professors[0].UUID, courses[0].Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the grade of jim, and the single grade submitted with the key.
+	if len(grades) != 2 {
+		t.Errorf("got %d grades, want %d", len(grades), 2)
+	}
+
+	rr := grade(gradeData, "")
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusForbidden)
+	}
+	if rr.Body.String() != responses.ErrCourseGraded.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrCourseGraded.Error())
+	}
+
+	rr = grade(&GradeData{CourseCode: courses[1].Code, ProfUUID: professors[1].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3}, "b7e4a1c2")
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if rr.Body.String() != responses.ErrIdempotencyKeyReused.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrIdempotencyKeyReused.Error())
+	}
+
+	rr = grade(&GradeData{CourseCode: courses[1].Code, ProfUUID: professors[1].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3}, strings.Repeat("a", maxIdempotencyKeyLength+1))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// the keys are ignored once they are no longer valid.
+	idempotencyKeyValidityTime = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	rr = grade(gradeData, "b7e4a1c2")
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusForbidden)
+	}
+}
+
+func TestServerGradeCourseProfessorUnknownDimension(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	data, _ := json.Marshal(&GradeData{CourseCode: courses[1].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3, Grades: map[string]float32{"availability": 4}})
+	r := httptest.NewRequest("POST", "/course/grade", bytes.NewReader(data))
+	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+	rr := httptest.NewRecorder()
+	gradeCourseProfessor(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr.Body.String() != responses.ErrInvalidValue.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrInvalidValue.Error())
+	}
+}
+
+func TestServerGetScoreDimensions(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/dimensions?uuid=%s&code=%s", professors[0].UUID, courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getScoreDimensions(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	scores := []*db.DimensionScore{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &scores}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scores) != len(db.DefaultScoreDimensions) {
+		t.Fatalf("got %d dimensions, want %d", len(scores), len(db.DefaultScoreDimensions))
+	}
+	for i, score := range scores {
+		if score.Dimension != db.DefaultScoreDimensions[i] || score.Count != 1 {
+			t.Errorf("got %+v, want dimension %s with 1 grade", *score, db.DefaultScoreDimensions[i])
+		}
+	}
+
+	r, err = http.NewRequest("GET", "/score/dimensions?uuid="+professors[0].UUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	getScoreDimensions(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerGradeCourseProfessorNotFound(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	err = initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	data, _ := json.Marshal(&GradeData{CourseCode: courses[0].Code, ProfUUID: "deadbeef", GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3})
+	r := httptest.NewRequest("POST", "/course/grade", bytes.NewReader(data))
+	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+	rr := httptest.NewRecorder()
+	gradeCourseProfessor(rr, r)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusNotFound)
+	}
+	if rr.Body.String() != responses.ErrNotFound.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrNotFound.Error())
+	}
+}
+
+func TestServerGradeCourseProfessorComment(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	err = initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	tests := []struct {
+		comment  string
+		status   int
+		expected string
+	}{
+		{strings.Repeat("a", maxCommentLength+1), http.StatusBadRequest, responses.ErrCommentTooLong.Error()},
+		{"  great course  ", http.StatusOK, responses.Success.Error()},
+	}
+
+	for _, test := range tests {
+		data, _ := json.Marshal(&GradeData{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3, Comment: test.comment})
+		r := httptest.NewRequest("POST", "/course/grade", bytes.NewReader(data))
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+		rr := httptest.NewRecorder()
+		gradeCourseProfessor(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("got %v, want %v", rr.Code, test.status)
+		}
+		if rr.Body.String() != test.expected {
+			t.Errorf("got %s, want %s", rr.Body.String(), test.expected)
+		}
+	}
+
+	scores, err := dataDb.GetScoresByProfessorUUID(professors[0].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(scores, func(s *db.Score) bool { return s.CourseCode == courses[0].Code })
+	if i == -1 {
+		t.Fatalf("got %v, want score of %s", scores, courses[0].Code)
+	}
+	if expected := []string{"great course"}; !slices.Equal(scores[i].Comments, expected) {
+		t.Errorf("got %v, want %v", scores[i].Comments, expected)
+	}
+}
+
+func TestServerUpdateGrade(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, creds.Email, [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		gradeData *GradeData
+		status    int
+		expected  string
+	}{
+		{&GradeData{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3}, http.StatusOK, responses.Success.Error()},
+		{&GradeData{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 6, GradeCoursework: 4, GradeLearning: 3}, http.StatusBadRequest, responses.ErrGradeOutOfRange.Error()},
+		{&GradeData{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3, Grades: map[string]float32{"humor": 5}}, http.StatusBadRequest, responses.ErrInvalidValue.Error()},
+		{&GradeData{CourseCode: courses[1].Code, ProfUUID: professors[1].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3}, http.StatusNotFound, responses.ErrNotGraded.Error()},
+	}
+
+	for _, test := range tests {
+		data, _ := json.Marshal(test.gradeData)
+		r := httptest.NewRequest("PUT", "/course/grade", bytes.NewReader(data))
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+		rr := httptest.NewRecorder()
+		updateGrade(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("got %v, want %v", rr.Code, test.status)
+		}
+		if rr.Body.String() != test.expected {
+			t.Errorf("got %s, want %s", rr.Body.String(), test.expected)
+		}
+	}
+}
+
+func TestServerDeleteGrade(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, creds.Email, [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    string
+		status   int
+		expected string
+	}{
+		{"uuid=" + professors[0].UUID + "&code=" + courses[0].Code, http.StatusOK, responses.Success.Error()},
+		{"uuid=" + professors[0].UUID + "&code=" + courses[0].Code, http.StatusNotFound, responses.ErrNotGraded.Error()},
+		{"uuid=" + professors[0].UUID, http.StatusBadRequest, responses.ErrEmptyValue.Error()},
 	}
-	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+
+	for _, test := range tests {
+		r := httptest.NewRequest("DELETE", "/course/grade?"+test.query, nil)
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+		rr := httptest.NewRecorder()
+		deleteGrade(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("%s: got %v, want %v", test.query, rr.Code, test.status)
+		}
+		if rr.Body.String() != test.expected {
+			t.Errorf("%s: got %s, want %s", test.query, rr.Body.String(), test.expected)
+		}
+	}
+}
+
+func TestServerGradeCourseProfessorBatch(t *testing.T) {
+	err := dbInit()
+	if err != nil {
 		t.Fatal(err)
 	}
-	lresp := len(resp.Message.([]interface{}))
-	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+	defer dataDb.Close()
+
+	err = initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, creds.Email, [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	gradeDataMany := []*GradeData{
+		{CourseCode: courses[1].Code, ProfUUID: professors[1].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3},
+		{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3},
+		{CourseCode: courses[2].Code, ProfUUID: professors[2].UUID, GradeTeaching: 2, GradeCoursework: 2, GradeLearning: 2, Comment: "great"},
+	}
+	data, _ := json.Marshal(gradeDataMany)
+	r := httptest.NewRequest("POST", "/course/grade-batch", bytes.NewReader(data))
+	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+	rr := httptest.NewRecorder()
+	gradeCourseProfessorBatch(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	results := []*GradeResult{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &results}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{gradeStatusGraded, gradeStatusAlreadyGraded, gradeStatusGraded}
+	if len(results) != len(expected) {
+		t.Fatalf("got %d results, want %d", len(results), len(expected))
+	}
+	for i, result := range results {
+		if result.Status != expected[i] {
+			t.Errorf("%d: got %s, want %s", i, result.Status, expected[i])
+		}
+		if result.CourseCode != gradeDataMany[i].CourseCode || result.ProfUUID != gradeDataMany[i].ProfUUID {
+			t.Errorf("%d: got %s %s, want %s %s", i, result.CourseCode, result.ProfUUID, gradeDataMany[i].CourseCode, gradeDataMany[i].ProfUUID)
+		}
+	}
+
+	graded, err := dataDb.CheckGradedMany(creds.Email, []*db.CourseProfessor{
+		{ProfessorUUID: professors[1].UUID, CourseCode: courses[1].Code},
+		{ProfessorUUID: professors[2].UUID, CourseCode: courses[2].Code},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(graded, []bool{true, true}) {
+		t.Errorf("got %v, want %v", graded, []bool{true, true})
+	}
+
+	for _, body := range []any{[]*GradeData{}, make([]*GradeData, maxGradeBatchSize+1), []*GradeData{nil}} {
+		data, _ := json.Marshal(body)
+		r := httptest.NewRequest("POST", "/course/grade-batch", bytes.NewReader(data))
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+		rr := httptest.NewRecorder()
+		gradeCourseProfessorBatch(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+		}
 	}
 }
 
-func TestServerGetScoresByCourseNameLike(t *testing.T) {
+func TestServerCheckGradedBatch(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursenamelike/%s", courses[0].Name[:5]), nil)
+	if err = dataDb.GradeCourseProfessor(professors[1].UUID, courses[1].Code, creds.Email, [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := []*db.CourseProfessor{
+		{ProfessorUUID: professors[0].UUID, CourseCode: courses[0].Code},
+		{ProfessorUUID: professors[1].UUID, CourseCode: courses[1].Code},
+	}
+	data, _ := json.Marshal(pairs)
+	r := httptest.NewRequest("POST", "/course/graded-batch", bytes.NewReader(data))
+	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+	rr := httptest.NewRecorder()
+	checkGradedBatch(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	graded := []bool{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &graded}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []bool{false, true}
+	if !slices.Equal(graded, expected) {
+		t.Errorf("got %v, want %v", graded, expected)
+	}
+
+	tooMany := make([]*db.CourseProfessor, maxGradedBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = pairs[0]
+	}
+	for _, body := range []any{[]*db.CourseProfessor{}, tooMany, []*db.CourseProfessor{{ProfessorUUID: professors[0].UUID}}} {
+		data, _ := json.Marshal(body)
+		r := httptest.NewRequest("POST", "/course/graded-batch", bytes.NewReader(data))
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+		rr := httptest.NewRecorder()
+		checkGradedBatch(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerGetEligibleGrades(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.AddCourseProfessor(professors[0].UUID, courses[1].Code); err != nil {
+		t.Fatal(err)
+	}
+	if err = dataDb.GradeCourseProfessor(professors[1].UUID, courses[1].Code, creds.Email, [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/account/eligible-grades", nil)
+	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+	rr := httptest.NewRecorder()
+	getEligibleGrades(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	eligible := []*db.EligibleGrade{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &eligible}); err != nil {
+		t.Fatal(err)
+	}
+
+	// of the two professors of courses[1], only professors[0] is left to grade.
+	var inCourse []string
+	for _, e := range eligible {
+		if e.CourseCode == courses[1].Code {
+			inCourse = append(inCourse, e.ProfessorUUID)
+		}
+	}
+	if !slices.Equal(inCourse, []string{professors[0].UUID}) {
+		t.Errorf("got %v, want %v", inCourse, []string{professors[0].UUID})
+	}
+
+	r = httptest.NewRequest("GET", "/account/eligible-grades?limit=1", nil)
+	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
+	rr = httptest.NewRecorder()
+	getEligibleGrades(rr, r)
+
+	eligible = []*db.EligibleGrade{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &eligible}); err != nil {
+		t.Fatal(err)
+	}
+	if len(eligible) != 1 {
+		t.Errorf("got %d eligible grades, want 1", len(eligible))
+	}
+}
+
+func TestServerGetRawGrades(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "joe", [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/admin/rawgrades?uuid=%s&code=%s", professors[0].UUID, courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getRawGrades(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	grades := []*db.RawGrade{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &grades}); err != nil {
+		t.Fatal(err)
+	}
+	if len(grades) != 2 {
+		t.Errorf("got %d, want %d", len(grades), 2)
+	}
+
+	r, err = http.NewRequest("GET", fmt.Sprintf("/admin/rawgrades?uuid=%s", professors[0].UUID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	getRawGrades(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerGetGradeAttemptsByCourseCode(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, "jim", [3]float32{5, 4, 3}); err == nil {
+		t.Error("expected failure")
+	}
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/stats/course/%s/attempts?window=1h", courses[0].Code), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
 	router := mux.NewRouter()
-	router.HandleFunc("/score/coursenamelike/{name}", getScoresByCourseNameLike)
+	router.HandleFunc("/stats/course/{code}/attempts", getGradeAttemptsByCourseCode)
 	router.ServeHTTP(rr, r)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
 	}
-	resp := &responses.Response{}
+	resp := &responses.Response{Message: &db.GradeAttempts{}}
 	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
 		t.Fatal(err)
 	}
+	attempts := resp.Message.(*db.GradeAttempts)
+	if attempts.Accepted != 1 || attempts.AlreadyGraded != 1 || attempts.OutOfRange != 0 {
+		t.Errorf("got %v, want %v", attempts, &db.GradeAttempts{CourseCode: courses[0].Code, Accepted: 1, AlreadyGraded: 1})
+	}
+
+	r, err = http.NewRequest("GET", fmt.Sprintf("/stats/course/%s/attempts?window=foo", courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerGetScoreTrend(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/score/trend?uuid=%s&code=%s&bucket=day", professors[0].UUID, courses[0].Code), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getScoreTrend(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	resp := &responses.Response{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
 	lresp := len(resp.Message.([]interface{}))
-	if lresp != 2 {
-		t.Errorf("got %d, want %d", lresp, 2)
+	if lresp != 1 {
+		t.Errorf("got len = %d, want %d", lresp, 1)
+	}
+
+	tests := []string{
+		fmt.Sprintf("/score/trend?code=%s", courses[0].Code),
+		fmt.Sprintf("/score/trend?uuid=%s&code=%s&bucket=decade", professors[0].UUID, courses[0].Code),
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", test, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getScoreTrend(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", test, rr.Code, http.StatusBadRequest)
+		}
 	}
 }
 
-func TestServerGetScoresByCourseCode(t *testing.T) {
+func TestServerGetBottomRatedProfessors(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursecode/%s", courses[0].Code), nil)
+	for _, username := range []string{"joe", "bob"} {
+		if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, username, [3]float32{1, 1, 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := http.NewRequest("GET", "/leaderboard/bottom?limit=5", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/score/coursecode/{code}", getScoresByCourseCode)
-	router.ServeHTTP(rr, r)
+	getBottomRatedProfessors(rr, r)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
 	}
 	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
 		t.Fatal(err)
 	}
 	lresp := len(resp.Message.([]interface{}))
 	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+		t.Errorf("got len = %d, want %d", lresp, 1)
+	}
+
+	for _, limit := range []string{"abc", "0", "-1"} {
+		r, err := http.NewRequest("GET", "/leaderboard/bottom?limit="+limit, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getBottomRatedProfessors(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", limit, rr.Code, http.StatusBadRequest)
+		}
 	}
 }
 
-func TestServerGetScoresByCourseCodeLike(t *testing.T) {
+func TestServerGetTopProfessors(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	r, err := http.NewRequest("GET", fmt.Sprintf("/score/coursecodelike/%s", courses[0].Code[:3]), nil)
+	for _, username := range []string{"joe", "bob"} {
+		if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, username, [3]float32{5, 5, 5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := http.NewRequest("GET", "/professor/top?limit=5", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.HandleFunc("/score/coursecodelike/{code}", getScoresByCourseCodeLike)
-	router.ServeHTTP(rr, r)
+	getTopProfessors(rr, r)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
 	}
 	resp := &responses.Response{}
-	if err = json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
 		t.Fatal(err)
 	}
 	lresp := len(resp.Message.([]interface{}))
 	if lresp != 1 {
-		t.Errorf("got %d, want %d", lresp, 1)
+		t.Errorf("got len = %d, want %d", lresp, 1)
+	}
+
+	for _, limit := range []string{"abc", "0", "-1"} {
+		r, err := http.NewRequest("GET", "/professor/top?limit="+limit, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getTopProfessors(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", limit, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	for by, want := range map[string]int{"teaching": http.StatusOK, "learning": http.StatusOK, "popularity": http.StatusBadRequest} {
+		r, err := http.NewRequest("GET", "/professor/top?by="+by, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getTopProfessors(rr, r)
+		if rr.Code != want {
+			t.Errorf("%s: got %v, want %v", by, rr.Code, want)
+		}
 	}
 }
 
-func TestServerGradeCourseProfessor(t *testing.T) {
+func TestServerGetMostRatedCourses(t *testing.T) {
 	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer dataDb.Close()
 
-	data, _ := json.Marshal(&GradeData{CourseCode: courses[0].Code, ProfUUID: professors[0].UUID, GradeTeaching: 5, GradeCoursework: 4, GradeLearning: 3})
-	r := httptest.NewRequest("POST", "/course/grade", bytes.NewReader(data))
+	for _, username := range []string{"joe", "bob"} {
+		if err = dataDb.GradeCourseProfessor(professors[1].UUID, courses[1].Code, username, [3]float32{5, 5, 5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := http.NewRequest("GET", "/course/popular?limit=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	rr := httptest.NewRecorder()
-	err = initTestUserState()
+	getMostRatedCourses(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	var popular []*db.PopularCourse
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &popular}); err != nil {
+		t.Fatal(err)
+	}
+	if len(popular) != 2 {
+		t.Fatalf("got len = %d, want %d", len(popular), 2)
+	}
+	if popular[0].CourseCode != courses[1].Code || popular[0].Count != 3 {
+		t.Errorf("got %v, want %s with 3 grades first", popular[0], courses[1].Code)
+	}
+
+	for _, limit := range []string{"abc", "0", "-1"} {
+		r, err := http.NewRequest("GET", "/course/popular?limit="+limit, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getMostRatedCourses(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", limit, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerGetUnratedProfessors(t *testing.T) {
+	err := dbInit()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer removeUserState()
+	defer dataDb.Close()
 
-	userState.AddUser(creds.Email, creds.Password, "")
-	userState.Confirm(creds.Email)
-	if err := userState.Login(rr, creds.Email); err != nil {
+	if err = dataDb.AddProfessor("Yeji Kim"); err != nil {
 		t.Fatal(err)
 	}
-	cookie := rr.Result().Cookies()[0]
-	c := &http.Cookie{
-		Name:  cookie.Name,
-		Value: cookie.Value,
+
+	r, err := http.NewRequest("GET", "/professor/unrated", nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-	r.AddCookie(c)
-	r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, creds.Email))
-	gradeCourseProfessor(rr, r)
+	rr := httptest.NewRecorder()
+	getUnratedProfessors(rr, r)
 	if rr.Code != http.StatusOK {
-		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
 	}
-	if rr.Body.String() != responses.Success.Error() {
-		t.Errorf("got %s, want %s", rr.Body.String(), responses.Success.Error())
+	resp := &responses.Response{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	lresp := len(resp.Message.([]interface{}))
+	if lresp != 1 {
+		t.Errorf("got len = %d, want %d", lresp, 1)
 	}
 }
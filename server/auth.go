@@ -1,22 +1,66 @@
 package server
 
 import (
-	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/trustelem/zxcvbn"
+	"github.com/vanillaiice/itpg/db"
 	"github.com/vanillaiice/itpg/responses"
 )
 
 // keyConfirmationCodeValidityTime is the key for geting the confirmation code validity time.
 const keyConfirmationCodeValidityTime = "cc_validity"
 
+// keyConfirmationCodeSentAt is the key for getting the time the last confirmation code of a user was sent.
+const keyConfirmationCodeSentAt = "cc_sent_at"
+
+// keyResetLinkSentAt is the key for getting the time the last password reset link of a user was sent.
+const keyResetLinkSentAt = "reset-link-sent-at"
+
+// keyVerified is the key for getting whether a user is verified.
+const keyVerified = "verified"
+
+// keyLoginFailures is the prefix of the key for getting the number of failed login attempts of a user from an ip address.
+const keyLoginFailures = "login-failures-"
+
+// keyLoginLockout is the prefix of the key for getting the time until which a user is locked out from an ip address.
+const keyLoginLockout = "login-lockout-"
+
+// keySessionToken is the key for getting the session token of a user,
+// which is rotated to revoke all the sessions of the user.
+const keySessionToken = "session-token"
+
+// keyEmailChange is the key for getting the email address a user asked to change their email address to.
+const keyEmailChange = "email-change"
+
+// keyEmailChangeCode is the key for getting the code confirming the change of email address of a user.
+const keyEmailChangeCode = "email-change-code"
+
+// keyEmailChangeValidityTime is the key for getting the validity time of the code confirming the change of email address of a user.
+const keyEmailChangeValidityTime = "email-change-validity"
+
+// maxLoginLockoutDoublings is the maximum number of times the lockout duration is doubled.
+const maxLoginLockoutDoublings = 10
+
+// maxLoginAttempts is the number of failed login attempts after which a user is locked out (0 to disable lockouts).
+var maxLoginAttempts int
+
+// loginLockout is the duration of the first lockout, doubled with each failed login attempt after it.
+var loginLockout time.Duration
+
 // confirmationCodeValidityTime is the time during which the confimatoin code is valid.
 var confirmationCodeValidityTime time.Duration
 
+// codeResendCooldown is the duration after sending a confirmation code or a password reset link to a user
+// during which another one cannot be sent to them (0 to disable the cooldown).
+var codeResendCooldown time.Duration
+
 // minPasswordScore is the minimum acceptable score of a password computed by zxcvbn.
 var minPasswordScore int
 
@@ -43,16 +87,49 @@ type CredentialsChange struct {
 	NewPassword string `json:"new"`
 }
 
+// CredentialsEmailChange represents the user credentials for confirming the change of email address.
+type CredentialsEmailChange struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// CodeValidity represents the validity of a confirmation or reset code.
+type CodeValidity struct {
+	Valid   bool `json:"valid"`
+	Expired bool `json:"expired"`
+}
+
+// Session represents the session of a user.
+type Session struct {
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// UserInfo represents the public information of a user.
+type UserInfo struct {
+	Username  string `json:"username"`
+	Confirmed bool   `json:"confirmed"`
+	Verified  bool   `json:"verified"`
+	Admin     bool   `json:"admin"`
+}
+
+// maxUsersPage is the maximum number of users returned in a page.
+const maxUsersPage = 100
+
+// keySuperAdmin is the key for getting whether a user is a super admin.
+const keySuperAdmin = "super"
+
 // allowedMailDomains are the email domains allowed to register.
 // If the first item of the slice is "*", all domains will be allowed.
 var allowedMailDomains []string
 
 // register handles user registration by validating credentials, generating a confirmation
-// code, sending an email with the code, and adding the user to the system.
+// code, queuing the code for delivery, and adding the user to the system.
+// The code is delivered in the background, so that a slow or failing mail server does not fail the registration.
 func register(w http.ResponseWriter, r *http.Request) {
 	creds, err := decodeCredentials(w, r)
 	if err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -60,13 +137,13 @@ func register(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		responses.ErrInvalidEmail.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 	if err = checkDomainAllowed(domain); err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		responses.ErrEmailDomainNotAllowed.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -81,13 +158,12 @@ func register(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusForbidden)
 			responses.ErrRegistered.WriteJSON(w)
 			return
-		} else {
+		}
+		// registering an unconfirmed user again reveals whether the password is correct,
+		// so the attempts count towards the lockouts like logins.
+		if checkPassword(w, r, creds.Email, creds.Password) {
 			w.WriteHeader(http.StatusUnauthorized)
-			if userState.CorrectPassword(creds.Email, creds.Password) {
-				responses.ErrNotConfirmed.WriteJSON(w)
-			} else {
-				responses.ErrWrongUsernamePassword.WriteJSON(w)
-			}
+			responses.ErrNotConfirmed.WriteJSON(w)
 		}
 		return
 	}
@@ -96,15 +172,15 @@ func register(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrGenCode.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 	confirmationCode := uuid.String()[:codeLength]
 
-	if err = mailer.SendMail(creds.Email, mailer.MakeConfCodeMessage(creds.Email, confirmationCode)); err != nil {
+	if err = mailQueue.SendConfirmationAsync(creds.Email, confirmationCode); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrSendMail.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -114,7 +190,14 @@ func register(w http.ResponseWriter, r *http.Request) {
 	if err = userState.Users().Set(creds.Email, keyConfirmationCodeValidityTime, time.Now().Add(confirmationCodeValidityTime).Format(time.RFC3339)); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err = recordCodeSent(creds.Email, keyConfirmationCodeSentAt); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -122,12 +205,12 @@ func register(w http.ResponseWriter, r *http.Request) {
 	responses.Success.WriteJSON(w)
 }
 
-// sendNewConfirmationCode sends a new confirmation code to a registered user's email
-// for confirmation.
+// sendNewConfirmationCode queues a new confirmation code for delivery to a registered user's email
+// for confirmation, unless a code was sent to them within the resend cooldown.
 func sendNewConfirmationCode(w http.ResponseWriter, r *http.Request) {
 	creds, err := decodeCredentials(w, r)
 	if err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -142,19 +225,23 @@ func sendNewConfirmationCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if resendTooSoon(w, creds.Email, keyConfirmationCodeSentAt) {
+		return
+	}
+
 	uuid, err := uuid.NewV4()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrGenCode.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 	confirmationCode := uuid.String()[:codeLength]
 
-	if err = mailer.SendMail(creds.Email, mailer.MakeConfCodeMessage(creds.Email, confirmationCode)); err != nil {
+	if err = mailQueue.SendConfirmationAsync(creds.Email, confirmationCode); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrSendMail.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -163,7 +250,14 @@ func sendNewConfirmationCode(w http.ResponseWriter, r *http.Request) {
 	if err = userState.Users().Set(creds.Email, keyConfirmationCodeValidityTime, time.Now().Add(confirmationCodeValidityTime).Format(time.RFC3339)); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err = recordCodeSent(creds.Email, keyConfirmationCodeSentAt); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -171,11 +265,62 @@ func sendNewConfirmationCode(w http.ResponseWriter, r *http.Request) {
 	responses.Success.WriteJSON(w)
 }
 
+// resendTooSoon writes a Too Many Requests response, with the number of seconds until the end of the cooldown in the Retry-After header,
+// and returns true if a code was sent to the user within the resend cooldown, the time it was sent being stored under the key.
+func resendTooSoon(w http.ResponseWriter, username, key string) bool {
+	if codeResendCooldown == 0 {
+		return false
+	}
+
+	// users have no sent time until a code is first sent to them.
+	sentAt, err := userState.Users().Get(username, key)
+	if err != nil {
+		return false
+	}
+
+	sentTime, err := time.Parse(time.RFC3339, sentAt)
+	if err != nil {
+		return false
+	}
+
+	until := sentTime.Add(codeResendCooldown)
+	if !time.Now().Before(until) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(until).Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	responses.ErrCodeResentTooSoon.WriteJSON(w)
+
+	return true
+}
+
+// recordCodeSent stores the current time as the time a code was sent to the user under the key.
+func recordCodeSent(username, key string) error {
+	return userState.Users().Set(username, key, time.Now().Format(time.RFC3339))
+}
+
+// codeExpired checks if the code of a user is expired, from the validity time stored in RFC3339 at key.
+// A code is valid until its validity time, and expired from it on.
+func codeExpired(username, key string) (expired bool, err error) {
+	validityTime, err := userState.Users().Get(username, key)
+	if err != nil {
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, validityTime)
+	if err != nil {
+		return
+	}
+
+	return !t.After(time.Now()), nil
+}
+
 // confirm confirms the user registration with the provided confirmation code.
 func confirm(w http.ResponseWriter, r *http.Request) {
 	confirmationCode := r.FormValue("code")
 	if err := isEmptyStr(w, confirmationCode); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -183,25 +328,18 @@ func confirm(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		responses.ErrNotRegistered.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	confirmationCodeValidityTime, err := userState.Users().Get(username, keyConfirmationCodeValidityTime)
+	expired, err := codeExpired(username, keyConfirmationCodeValidityTime)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
-	t, err := time.Parse(time.RFC3339, confirmationCodeValidityTime)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
-		return
-	}
-	if !t.After(time.Now()) {
+	if expired {
 		w.WriteHeader(http.StatusForbidden)
 		responses.ErrConfirmationCodeExpired.WriteJSON(w)
 		return
@@ -210,14 +348,14 @@ func confirm(w http.ResponseWriter, r *http.Request) {
 	if err := userState.ConfirmUserByConfirmationCode(confirmationCode); err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		responses.ErrWrongConfirmationCode.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
 	if err := userState.Users().DelKey(username, keyConfirmationCodeValidityTime); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -227,12 +365,62 @@ func confirm(w http.ResponseWriter, r *http.Request) {
 	responses.Success.WriteJSON(w)
 }
 
+// validateCode checks if a confirmation or password reset code exists and is not expired, without consuming it.
+// The type query parameter is either confirm or reset.
+func validateCode(w http.ResponseWriter, r *http.Request) {
+	code, codeType := r.FormValue("code"), r.FormValue("type")
+	if err := isEmptyStr(w, code, codeType); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	validity := &CodeValidity{}
+
+	switch codeType {
+	case "confirm":
+		username, err := userState.FindUserByConfirmationCode(code)
+		if err != nil {
+			break
+		}
+
+		if validity.Expired, err = codeExpired(username, keyConfirmationCodeValidityTime); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			responses.ErrInternal.WriteJSON(w)
+			requestLogger(r).Error().Msg(err.Error())
+			return
+		}
+		validity.Valid = !validity.Expired
+	case "reset":
+		usernames, err := userState.AllUsernames()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			responses.ErrInternal.WriteJSON(w)
+			requestLogger(r).Error().Msg(err.Error())
+			return
+		}
+
+		for _, username := range usernames {
+			if resetCode, err := userState.Users().Get(username, "reset-code"); err == nil && resetCode == code {
+				validity.Valid = true
+				break
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: validity}).WriteJSON(w)
+}
+
 // login handles user login by checking credentials, confirming registration, setting a cookie
 // with an expiry time, and logging the user in.
 func login(w http.ResponseWriter, r *http.Request) {
 	creds, err := decodeCredentials(w, r)
 	if err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -241,33 +429,159 @@ func login(w http.ResponseWriter, r *http.Request) {
 		responses.ErrNotRegistered.WriteJSON(w)
 		return
 	}
-	if !userState.CorrectPassword(creds.Email, creds.Password) {
-		w.WriteHeader(http.StatusUnauthorized)
-		responses.ErrWrongUsernamePassword.WriteJSON(w)
+
+	if !checkPassword(w, r, creds.Email, creds.Password) {
 		return
 	}
+
 	if !userState.IsConfirmed(creds.Email) {
 		w.WriteHeader(http.StatusUnauthorized)
 		responses.ErrNotConfirmed.WriteJSON(w)
 		return
 	}
 
-	if err = userState.Users().Set(creds.Email, cookieExpiryUserStateKey, time.Now().Add(cookieTimeout).Format(time.UnixDate)); err != nil {
+	if err = startSession(w, creds.Email); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err = userState.Login(w, creds.Email); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+	w.Header().Set("Content-Type", "application/json")
+	responses.Success.WriteJSON(w)
+}
+
+// startSession sets the issue and expiry times of the session cookie of a user, and logs the user in.
+// If the sessions of the user were revoked before, the current session token is also sent in a cookie.
+func startSession(w http.ResponseWriter, username string) (err error) {
+	now := time.Now()
+
+	if err = userState.Users().Set(username, cookieIssuedUserStateKey, now.Format(time.UnixDate)); err != nil {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	responses.Success.WriteJSON(w)
+	if err = userState.Users().Set(username, cookieExpiryUserStateKey, now.Add(cookieTimeout).Format(time.UnixDate)); err != nil {
+		return
+	}
+
+	if err = userState.Login(w, username); err != nil {
+		return
+	}
+
+	if token, err := userState.Users().Get(username, keySessionToken); err == nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookieName,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   int(userState.CookieTimeout(username)),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return
+}
+
+// revokeSessions invalidates all the sessions of a user by rotating their session token.
+func revokeSessions(username string) error {
+	token, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	return userState.Users().Set(username, keySessionToken, token.String())
+}
+
+// checkPassword checks the password of a user logging in or registering from the ip address of a request,
+// locking the user out from the ip address after too many failed attempts.
+// If the password is wrong or the user is locked out, the error response is written and false is returned.
+func checkPassword(w http.ResponseWriter, r *http.Request, username, password string) bool {
+	// the X-Forwarded-For header is only trusted behind a proxy, since clients could otherwise change it on each attempt.
+	ip := filteredIP(r)
+	if until := loginLockedUntil(username, ip); time.Now().Before(until) {
+		writeLoginLockout(w, until)
+		return false
+	}
+
+	if !userState.CorrectPassword(username, password) {
+		until, err := recordLoginFailure(username, ip)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			responses.ErrInternal.WriteJSON(w)
+			requestLogger(r).Error().Msg(err.Error())
+			return false
+		}
+		if time.Now().Before(until) {
+			writeLoginLockout(w, until)
+			return false
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		responses.ErrWrongUsernamePassword.WriteJSON(w)
+		return false
+	}
+	resetLoginFailures(username, ip)
+
+	return true
+}
+
+// loginLockedUntil returns the time until which a user is locked out from an ip address,
+// or the zero time if the user is not locked out.
+func loginLockedUntil(username, ip string) time.Time {
+	lockout, err := userState.Users().Get(username, keyLoginLockout+ip)
+	if err != nil {
+		return time.Time{}
+	}
+
+	until, err := time.Parse(time.RFC3339, lockout)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return until
+}
+
+// recordLoginFailure increments the number of failed login attempts of a user from an ip address.
+// If the number reaches the maximum number of login attempts, the user is locked out from the ip address,
+// for a duration doubling with each further failed attempt, and the end of the lockout is returned.
+func recordLoginFailure(username, ip string) (until time.Time, err error) {
+	if maxLoginAttempts == 0 {
+		return
+	}
+
+	failures := 0
+	if value, err := userState.Users().Get(username, keyLoginFailures+ip); err == nil {
+		failures, _ = strconv.Atoi(value)
+	}
+	failures++
+
+	if err = userState.Users().Set(username, keyLoginFailures+ip, strconv.Itoa(failures)); err != nil {
+		return
+	}
+
+	if failures < maxLoginAttempts {
+		return
+	}
+
+	until = time.Now().Add(loginLockout << min(failures-maxLoginAttempts, maxLoginLockoutDoublings))
+	err = userState.Users().Set(username, keyLoginLockout+ip, until.Format(time.RFC3339))
+
+	return
+}
+
+// resetLoginFailures removes the failed login attempts and the lockout of a user from an ip address.
+func resetLoginFailures(username, ip string) {
+	for _, key := range []string{keyLoginFailures + ip, keyLoginLockout + ip} {
+		if err := userState.Users().DelKey(username, key); err != nil {
+			log.Error().Msg(err.Error())
+		}
+	}
+}
+
+// writeLoginLockout writes a Too Many Requests response, with the number of seconds until the end of the lockout in the Retry-After header.
+func writeLoginLockout(w http.ResponseWriter, until time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(until).Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	responses.ErrTooManyLoginAttempts.WriteJSON(w)
 }
 
 // logout logs out the currently logged-in user by removing their session.
@@ -291,15 +605,32 @@ func logout(w http.ResponseWriter, r *http.Request) {
 	responses.Success.WriteJSON(w)
 }
 
-// clearCookie clears the cookie for the current user session.
-func clearCookie(w http.ResponseWriter, r *http.Request) {
+// logoutAll logs out the currently logged-in user from all of their sessions, including the current one.
+func logoutAll(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	if err := revokeSessions(username); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	userState.Logout(username)
 	userState.ClearCookie(w)
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Path: "/", MaxAge: -1})
+
 	w.Header().Set("Content-Type", "application/json")
 	responses.Success.WriteJSON(w)
 }
 
-// refreshCookie refreshes the cookie for the current user session by updating its expiry time.
-func refreshCookie(w http.ResponseWriter, r *http.Request) {
+// getSessions returns when the session cookie of the currently logged-in user was issued, and when it expires.
+func getSessions(w http.ResponseWriter, r *http.Request) {
 	username, ok := r.Context().Value(usernameContextKey).(string)
 	if !ok || username == "" {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -307,25 +638,63 @@ func refreshCookie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := userState.Users().Set(username, cookieExpiryUserStateKey, time.Now().Add(cookieTimeout).Format(time.UnixDate)); err != nil {
+	cookieExpiry, err := userState.Users().Get(username, cookieExpiryUserStateKey)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
-	if err := userState.Login(w, username); err != nil {
+	session := &Session{}
+	if session.ExpiresAt, err = time.Parse(time.UnixDate, cookieExpiry); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
+	// sessions started before the issue time was recorded are assumed to have been issued for the current cookie timeout.
+	session.IssuedAt = session.ExpiresAt.Add(-cookieTimeout)
+	if cookieIssued, err := userState.Users().Get(username, cookieIssuedUserStateKey); err == nil {
+		if issuedAt, err := time.Parse(time.UnixDate, cookieIssued); err == nil {
+			session.IssuedAt = issuedAt
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: session}).WriteJSON(w)
+}
+
+// clearCookie clears the cookie for the current user session.
+func clearCookie(w http.ResponseWriter, r *http.Request) {
+	userState.ClearCookie(w)
 	w.Header().Set("Content-Type", "application/json")
 	responses.Success.WriteJSON(w)
 }
 
-// changePassword changes the account password of a currently logged-in user.
+// refreshCookie refreshes the cookie for the current user session by updating its expiry time.
+func refreshCookie(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	if err := startSession(w, username); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.Success.WriteJSON(w)
+}
+
+// changePassword changes the account password of a currently logged-in user,
+// and logs the user out from all of their other sessions.
 func changePassword(w http.ResponseWriter, r *http.Request) {
 	username, ok := r.Context().Value(usernameContextKey).(string)
 	if !ok || username == "" {
@@ -342,11 +711,11 @@ func changePassword(w http.ResponseWriter, r *http.Request) {
 
 	credsChange, err := decodeCredentialsChange(w, r)
 	if err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 	if err = isEmptyStr(w, credsChange.OldPassword, credsChange.NewPassword); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -358,15 +727,254 @@ func changePassword(w http.ResponseWriter, r *http.Request) {
 
 	userState.SetPassword(username, credsChange.NewPassword)
 
+	if err = revokeSessions(username); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err = startSession(w, username); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	responses.Success.WriteJSON(w)
 }
 
-// resetPassword resets the account password of a user, in case it was forgotten.
+// changeEmail starts the change of the email address of a currently logged-in user,
+// by queuing a confirmation code for delivery to the new email address.
+// The account stays keyed on the current email address until the code is confirmed with confirmEmailChange,
+// so that a mistyped address does not lock the user out.
+func changeEmail(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	if !userState.IsConfirmed(username) {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrNotConfirmed.WriteJSON(w)
+		return
+	}
+
+	creds, err := decodeCredentials(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+	if err = isEmptyStr(w, creds.Email, creds.Password); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if !userState.CorrectPassword(username, creds.Password) {
+		w.WriteHeader(http.StatusUnauthorized)
+		responses.ErrWrongUsernamePassword.WriteJSON(w)
+		return
+	}
+
+	domain, err := extractDomain(creds.Email)
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrInvalidEmail.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+	if err = checkDomainAllowed(domain); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrEmailDomainNotAllowed.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if userState.HasUser(creds.Email) {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrRegistered.WriteJSON(w)
+		return
+	}
+
+	uuid, err := uuid.NewV4()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrGenCode.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+	confirmationCode := uuid.String()[:codeLength]
+
+	if err = mailQueue.SendConfirmationAsync(creds.Email, confirmationCode); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrSendMail.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	for key, value := range map[string]string{
+		keyEmailChange:             creds.Email,
+		keyEmailChangeCode:         confirmationCode,
+		keyEmailChangeValidityTime: time.Now().Add(confirmationCodeValidityTime).Format(time.RFC3339),
+	} {
+		if err = userState.Users().Set(username, key, value); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			responses.ErrInternal.WriteJSON(w)
+			requestLogger(r).Error().Msg(err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.Success.WriteJSON(w)
+}
+
+// confirmEmailChange confirms the change of the email address of a currently logged-in user with the code
+// sent to the new email address. The account and the grades of the user are moved to the new email address,
+// the user is logged out from all of their sessions, and logged in again with the new email address.
+func confirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return
+	}
+
+	creds, err := decodeCredentialsEmailChange(w, r)
+	if err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+	if err = isEmptyStr(w, creds.Code, creds.Password); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if !userState.CorrectPassword(username, creds.Password) {
+		w.WriteHeader(http.StatusUnauthorized)
+		responses.ErrWrongUsernamePassword.WriteJSON(w)
+		return
+	}
+
+	if code, err := userState.Users().Get(username, keyEmailChangeCode); err != nil || code != creds.Code {
+		w.WriteHeader(http.StatusUnauthorized)
+		responses.ErrWrongConfirmationCode.WriteJSON(w)
+		return
+	}
+
+	expired, err := codeExpired(username, keyEmailChangeValidityTime)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+	if expired {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrConfirmationCodeExpired.WriteJSON(w)
+		return
+	}
+
+	email, err := userState.Users().Get(username, keyEmailChange)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	// the new email address may have registered since the change was asked.
+	if userState.HasUser(email) {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrRegistered.WriteJSON(w)
+		return
+	}
+
+	if err = migrateGrades(username, email); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err = migrateUser(username, email, creds.Password); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err = startSession(w, email); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.Success.WriteJSON(w)
+}
+
+// migrateUser moves a confirmed user to a new username with the same password, keeping their admin, super admin,
+// and verified statuses, and removes the user with the old username along with their sessions.
+func migrateUser(oldUsername, newUsername, password string) error {
+	userState.AddUser(newUsername, password, "")
+	userState.Confirm(newUsername)
+
+	if userState.IsAdmin(oldUsername) {
+		userState.SetAdminStatus(newUsername)
+	}
+	if userState.BooleanField(oldUsername, keySuperAdmin) {
+		userState.SetBooleanField(newUsername, keySuperAdmin, true)
+	}
+	if verified, err := userState.Users().Get(oldUsername, keyVerified); err == nil {
+		if err = userState.Users().Set(newUsername, keyVerified, verified); err != nil {
+			userState.RemoveUser(newUsername)
+			return err
+		}
+	}
+
+	if err := userState.Users().DelKey(oldUsername, cookieExpiryUserStateKey); err != nil {
+		userState.RemoveUser(newUsername)
+		return err
+	}
+
+	userState.RemoveUser(oldUsername)
+
+	return nil
+}
+
+// migrateGrades moves the grades of a user to a new username in the default database and in the databases of the tenants.
+// If moving the grades fails in a database, the grades already moved in the other databases are moved back.
+func migrateGrades(oldUsername, newUsername string) error {
+	dbs := []db.DB{dataDb}
+	for _, id := range tenantIDs() {
+		dbs = append(dbs, tenantDbs[id])
+	}
+
+	for i, d := range dbs {
+		if err := d.MigrateGrades(oldUsername, newUsername); err != nil {
+			for _, migrated := range dbs[:i] {
+				if err := migrated.MigrateGrades(newUsername, oldUsername); err != nil {
+					log.Error().Err(err).Msg("moving back grades failed")
+				}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetPassword resets the account password of a user, in case it was forgotten,
+// and logs the user out from all of their sessions.
 func resetPassword(w http.ResponseWriter, r *http.Request) {
 	credsReset, err := decodeCredentialsReset(w, r)
 	if err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -374,7 +982,7 @@ func resetPassword(w http.ResponseWriter, r *http.Request) {
 	if expectedResetCode, err = userState.Users().Get(credsReset.Email, "reset-code"); err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		responses.ErrResetCodeNotSent.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -387,21 +995,29 @@ func resetPassword(w http.ResponseWriter, r *http.Request) {
 	if err = userState.Users().DelKey(credsReset.Email, "reset-code"); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
 	userState.SetPassword(credsReset.Email, credsReset.Password)
 
+	if err = revokeSessions(credsReset.Email); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	responses.Success.WriteJSON(w)
 }
 
-// sendResetLink sends a mail containing a password reset link
+// sendResetLink queues a mail containing a password reset link for delivery,
+// unless a link was sent to the user within the resend cooldown.
 func sendResetLink(w http.ResponseWriter, r *http.Request) {
 	username := r.FormValue("email")
 	if err := isEmptyStr(w, username); err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -411,10 +1027,13 @@ func sendResetLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if resendTooSoon(w, username, keyResetLinkSentAt) {
+		return
+	}
+
 	if _, err := userState.Users().Get(username, "reset-code"); err == nil {
 		w.WriteHeader(http.StatusForbidden)
 		responses.ErrResetCodeSent.WriteJSON(w)
-		log.Error().Msg(err.Error())
 		return
 	}
 
@@ -422,22 +1041,37 @@ func sendResetLink(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrGenCode.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 	resetCode := uuid.String()
 
-	if err = mailer.SendMail(username, mailer.MakeResetCodeMessage(username, fmt.Sprintf("%s?code=%s", passwordResetUrl, resetCode))); err != nil {
+	message, err := mailer.MakeResetCodeMessage(username, makePasswordResetLink(requestPasswordResetUrl(r), resetCode))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrSendMail.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err = mailQueue.SendAsync(username, message); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrSendMail.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
 	if err = userState.Users().Set(username, "reset-code", resetCode); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if err = recordCodeSent(username, keyResetLinkSentAt); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -449,7 +1083,7 @@ func sendResetLink(w http.ResponseWriter, r *http.Request) {
 func deleteAccount(w http.ResponseWriter, r *http.Request) {
 	creds, err := decodeCredentials(w, r)
 	if err != nil {
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 	if !userState.CorrectPassword(creds.Email, creds.Password) {
@@ -466,7 +1100,7 @@ func deleteAccount(w http.ResponseWriter, r *http.Request) {
 	if err := userState.Users().DelKey(creds.Email, cookieExpiryUserStateKey); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		responses.ErrInternal.WriteJSON(w)
-		log.Error().Msg(err.Error())
+		requestLogger(r).Error().Msg(err.Error())
 		return
 	}
 
@@ -476,5 +1110,196 @@ func deleteAccount(w http.ResponseWriter, r *http.Request) {
 	responses.Success.WriteJSON(w)
 }
 
+// getAllUsers returns all users, flagged as confirmed or not.
+// If the confirmed parameter is set, only the users with the matching confirmation status are returned.
+func getAllUsers(w http.ResponseWriter, r *http.Request) {
+	var filter *bool
+	if confirmed := r.FormValue("confirmed"); confirmed != "" {
+		b, err := strconv.ParseBool(confirmed)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+		filter = &b
+	}
+
+	usernames, err := userState.AllUsernames()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	users := []*UserInfo{}
+	for _, username := range usernames {
+		user := &UserInfo{Username: username, Confirmed: userState.IsConfirmed(username), Verified: isVerified(username), Admin: userState.IsAdmin(username)}
+		if filter != nil && user.Confirmed != *filter {
+			continue
+		}
+		users = append(users, user)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: users}).WriteJSON(w)
+}
+
+// getUsersPage returns a page of the users sorted by username, flagged as confirmed and admin or not.
+func getUsersPage(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+	if limit == 0 || limit > maxUsersPage {
+		limit = maxUsersPage
+	}
+
+	usernames, err := userState.AllUsernames()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+	slices.Sort(usernames)
+
+	users := []*UserInfo{}
+	for _, username := range usernames[min(offset, len(usernames)):min(offset+limit, len(usernames))] {
+		users = append(users, &UserInfo{Username: username, Confirmed: userState.IsConfirmed(username), Verified: isVerified(username), Admin: userState.IsAdmin(username)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: users}).WriteJSON(w)
+}
+
+// confirmUser confirms the registration of a user.
+func confirmUser(w http.ResponseWriter, r *http.Request) {
+	email, ok := registeredUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	userState.Confirm(email)
+
+	writeSuccess(w)
+}
+
+// promoteUser gives admin rights to a user.
+func promoteUser(w http.ResponseWriter, r *http.Request) {
+	email, ok := registeredUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	userState.SetAdminStatus(email)
+
+	writeSuccess(w)
+}
+
+// demoteUser removes the admin rights of a user.
+// Super admins cannot demote themselves.
+func demoteUser(w http.ResponseWriter, r *http.Request) {
+	email, ok := registeredUserParam(w, r)
+	if !ok || isSelf(w, r, email) {
+		return
+	}
+
+	userState.RemoveAdminStatus(email)
+	userState.SetBooleanField(email, keySuperAdmin, false)
+
+	writeSuccess(w)
+}
+
+// deleteUser deletes the account of a user.
+// Super admins cannot delete themselves.
+func deleteUser(w http.ResponseWriter, r *http.Request) {
+	email, ok := registeredUserParam(w, r)
+	if !ok || isSelf(w, r, email) {
+		return
+	}
+
+	userState.RemoveUser(email)
+
+	writeSuccess(w)
+}
+
+// registeredUserParam returns the email query parameter of a request.
+// If it is empty or not a registered user, an error is written and ok is false.
+func registeredUserParam(w http.ResponseWriter, r *http.Request) (email string, ok bool) {
+	email = r.FormValue("email")
+	if err := isEmptyStr(w, email); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	if !userState.HasUser(email) {
+		w.WriteHeader(http.StatusNotFound)
+		responses.ErrNotRegistered.WriteJSON(w)
+		return
+	}
+
+	return email, true
+}
+
+// isSelf checks if the user making a request is the specified user.
+// If so, or if the user making the request is unknown, an error is written.
+func isSelf(w http.ResponseWriter, r *http.Request, email string) bool {
+	username, ok := r.Context().Value(usernameContextKey).(string)
+	if !ok || username == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		return true
+	}
+
+	if username == email {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrSelfModification.WriteJSON(w)
+		return true
+	}
+
+	return false
+}
+
+// verifyUser sets whether a user is verified, so that their grades count with the verified grade weight.
+// The optional verified parameter defaults to true.
+func verifyUser(w http.ResponseWriter, r *http.Request) {
+	username := r.FormValue("username")
+	if err := isEmptyStr(w, username); err != nil {
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	verified := true
+	if v := r.FormValue("verified"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+		verified = b
+	}
+
+	if !userState.HasUser(username) {
+		w.WriteHeader(http.StatusNotFound)
+		responses.ErrNotRegistered.WriteJSON(w)
+		return
+	}
+
+	if err := userState.Users().Set(username, keyVerified, strconv.FormatBool(verified)); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	writeSuccess(w)
+}
+
 // ping checks that the user is logged in and that the cookie is not expired.
 func ping(w http.ResponseWriter, r *http.Request) {}
+
+// getMailQueue handles the HTTP request to get the mails and confirmation codes waiting for delivery,
+// and those whose delivery failed.
+func getMailQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: mailQueue.Status()}).WriteJSON(w)
+}
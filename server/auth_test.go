@@ -0,0 +1,932 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/mail"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+func TestValidateCode(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.AddUnconfirmed(creds.Email, "validcode")
+	if err = userState.Users().Set(creds.Email, keyConfirmationCodeValidityTime, time.Now().Add(time.Hour).Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	expiredEmail := "jim@jim.com"
+	userState.AddUser(expiredEmail, creds.Password, "")
+	userState.AddUnconfirmed(expiredEmail, "expiredcode")
+	if err = userState.Users().Set(expiredEmail, keyConfirmationCodeValidityTime, time.Now().Add(-time.Hour).Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = userState.Users().Set(creds.Email, "reset-code", credsReset.Code); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    string
+		expected CodeValidity
+	}{
+		{"code=validcode&type=confirm", CodeValidity{Valid: true, Expired: false}},
+		{"code=expiredcode&type=confirm", CodeValidity{Valid: false, Expired: true}},
+		{"code=unknowncode&type=confirm", CodeValidity{Valid: false, Expired: false}},
+		{"code=" + credsReset.Code + "&type=reset", CodeValidity{Valid: true, Expired: false}},
+		{"code=unknowncode&type=reset", CodeValidity{Valid: false, Expired: false}},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/code/validate?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		validateCode(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got %v, want %v", test.query, rr.Code, http.StatusOK)
+		}
+
+		resp := &responses.Response{Message: &CodeValidity{}}
+		if err = json.NewDecoder(rr.Body).Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+		if validity := resp.Message.(*CodeValidity); *validity != test.expected {
+			t.Errorf("%s: got %+v, want %+v", test.query, *validity, test.expected)
+		}
+	}
+
+	for _, query := range []string{"code=validcode", "code=validcode&type=foo"} {
+		r, err := http.NewRequest("GET", "/code/validate?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		validateCode(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	tests := []struct {
+		email    string
+		code     string
+		validity time.Time
+		status   int
+		response *responses.Response
+	}{
+		{"fresh@joe.com", "freshcode", time.Now().Add(confirmationCodeValidityTime + time.Minute), http.StatusOK, responses.Success},
+		{"last@joe.com", "lastcode", time.Now().Add(2 * time.Second), http.StatusOK, responses.Success},
+		// the validity time is stored to the second, so a code valid until now is already expired.
+		{"now@joe.com", "nowcode", time.Now(), http.StatusForbidden, responses.ErrConfirmationCodeExpired},
+		{"expired@joe.com", "expiredcode", time.Now().Add(-time.Second), http.StatusForbidden, responses.ErrConfirmationCodeExpired},
+	}
+
+	for _, test := range tests {
+		userState.AddUser(test.email, creds.Password, "")
+		userState.AddUnconfirmed(test.email, test.code)
+		if err = userState.Users().Set(test.email, keyConfirmationCodeValidityTime, test.validity.Format(time.RFC3339)); err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest("POST", "/confirm", strings.NewReader("code="+test.code))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		confirm(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("%s: got %v, want %v", test.code, rr.Code, test.status)
+		}
+		if rr.Body.String() != test.response.Error() {
+			t.Errorf("%s: got %s, want %s", test.code, rr.Body.String(), test.response.Error())
+		}
+		if confirmed := userState.IsConfirmed(test.email); confirmed != (test.status == http.StatusOK) {
+			t.Errorf("%s: got confirmed %v, want %v", test.code, confirmed, test.status == http.StatusOK)
+		}
+	}
+}
+
+func TestCodeExpired(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser("expiry@joe.com", creds.Password, "")
+
+	tests := []struct {
+		validity string
+		expired  bool
+		fails    bool
+	}{
+		{time.Now().Add(time.Minute).Format(time.RFC3339), false, false},
+		{time.Now().Format(time.RFC3339), true, false},
+		{time.Now().Add(-time.Minute).Format(time.RFC3339), true, false},
+		// validity times not stored in RFC3339 are rejected rather than read as valid or expired.
+		{time.Now().Add(time.Minute).String(), false, true},
+	}
+
+	for _, test := range tests {
+		if err = userState.Users().Set("expiry@joe.com", keyEmailChangeValidityTime, test.validity); err != nil {
+			t.Fatal(err)
+		}
+
+		expired, err := codeExpired("expiry@joe.com", keyEmailChangeValidityTime)
+		if (err != nil) != test.fails {
+			t.Errorf("%s: got error %v, want failure %v", test.validity, err, test.fails)
+		}
+		if expired != test.expired {
+			t.Errorf("%s: got expired %v, want %v", test.validity, expired, test.expired)
+		}
+	}
+}
+
+func TestGetAllUsers(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	confirmed := []string{"jim@jim.com", "joe@joe.com"}
+	for _, username := range confirmed {
+		userState.AddUser(username, creds.Password, "")
+		userState.Confirm(username)
+	}
+	unconfirmed := "bob@bob.com"
+	userState.AddUser(unconfirmed, creds.Password, "")
+	userState.AddUnconfirmed(unconfirmed, "somecode")
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"", 3},
+		{"confirmed=true", 2},
+		{"confirmed=false", 1},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/users/all?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getAllUsers(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: got %v, want %v", test.query, rr.Code, http.StatusOK)
+		}
+
+		users := []*UserInfo{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &users}); err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != test.expected {
+			t.Errorf("%s: got %d, want %d", test.query, len(users), test.expected)
+		}
+		for _, user := range users {
+			if test.query == "confirmed=true" && !user.Confirmed {
+				t.Errorf("%s: got unconfirmed user %s", test.query, user.Username)
+			}
+			if user.Username == unconfirmed && user.Confirmed {
+				t.Errorf("%s: got confirmed, want unconfirmed for %s", test.query, user.Username)
+			}
+		}
+	}
+
+	r, err := http.NewRequest("GET", "/users/all?confirmed=foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getAllUsers(rr, r)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestLoginCookieMaxAge(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.Confirm(creds.Email)
+
+	timeout, maxAge := 30*time.Minute, 5*time.Minute
+	setCookieTimeouts(timeout, maxAge)
+	defer setCookieTimeouts(time.Minute, 0)
+
+	body, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest("POST", "/login", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	login(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	var cookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "user" {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("expected user cookie")
+	}
+
+	lifetime := time.Duration(cookie.MaxAge) * time.Second
+	if cookie.MaxAge == 0 {
+		lifetime = time.Until(cookie.Expires)
+	}
+	if lifetime < maxAge-5*time.Second || lifetime > maxAge {
+		t.Errorf("got cookie max age %v, want %v", lifetime, maxAge)
+	}
+
+	expiry, err := userState.Users().Get(creds.Email, cookieExpiryUserStateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiryTime, err := time.Parse(time.UnixDate, expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expiryTime); d < timeout-5*time.Second || d > timeout {
+		t.Errorf("got server-side validity %v, want %v", d, timeout)
+	}
+}
+
+func TestVerifyUser(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.Confirm(creds.Email)
+
+	verifiedGradeWeight = 2
+	defer func() { verifiedGradeWeight = db.DefaultGradeWeight }()
+
+	tests := []struct {
+		query    string
+		status   int
+		expected float32
+	}{
+		{"username=" + creds.Email, http.StatusOK, 2},
+		{"username=" + creds.Email + "&verified=false", http.StatusOK, db.DefaultGradeWeight},
+		{"username=" + creds.Email + "&verified=true", http.StatusOK, 2},
+		{"username=" + creds.Email + "&verified=foo", http.StatusBadRequest, 2},
+		{"username=jim@jim.com", http.StatusNotFound, 2},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("POST", "/users/verify?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		verifyUser(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("%s: got %v, want %v", test.query, rr.Code, test.status)
+		}
+		if weight := gradeWeight(creds.Email); weight != test.expected {
+			t.Errorf("%s: got weight %v, want %v", test.query, weight, test.expected)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.Confirm(creds.Email)
+
+	maxLoginAttempts, loginLockout = 3, time.Hour
+	defer func() { maxLoginAttempts, loginLockout = 0, 0 }()
+
+	doLogin := func(password, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(&Credentials{Email: creds.Email, Password: password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.NewRequest("POST", "/login", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rr := httptest.NewRecorder()
+		login(rr, r)
+		return rr
+	}
+	retryAfter := func(rr *httptest.ResponseRecorder) int {
+		seconds, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return seconds
+	}
+
+	// without a trusted proxy, a spoofed X-Forwarded-For header does not change the ip address of the attempts.
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if rr := doLogin("wrongpassword", "192.0.2.1:1234", "198.51.100."+strconv.Itoa(i+1)); rr.Code != want {
+			t.Errorf("attempt %d: got %v, want %v", i+1, rr.Code, want)
+		}
+	}
+	if _, err = userState.Users().Get(creds.Email, keyLoginFailures+"198.51.100.1"); err == nil {
+		t.Error("got failed login attempts recorded for the spoofed ip address, want none")
+	}
+
+	rr := doLogin(creds.Password, "192.0.2.1:1234", "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusTooManyRequests)
+	}
+	if seconds := retryAfter(rr); seconds <= 3590 || seconds > 3600 {
+		t.Errorf("got retry after %d, want %d", seconds, 3600)
+	}
+
+	if rr = doLogin(creds.Password, "192.0.2.1:1234", "198.51.100.7"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusTooManyRequests)
+	}
+
+	// behind a trusted proxy, the attempts are counted per forwarded ip address.
+	trustProxy = true
+	defer func() { trustProxy = false }()
+	if rr = doLogin(creds.Password, "192.0.2.1:1234", "198.51.100.7"); rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	expire := func() {
+		if err = userState.Users().Set(creds.Email, keyLoginLockout+"192.0.2.1", time.Now().Add(-time.Second).Format(time.RFC3339)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expire()
+	rr = doLogin("wrongpassword", "192.0.2.1:1234", "")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusTooManyRequests)
+	}
+	if seconds := retryAfter(rr); seconds <= 7190 || seconds > 7200 {
+		t.Errorf("got retry after %d, want %d", seconds, 7200)
+	}
+
+	expire()
+	if rr = doLogin(creds.Password, "192.0.2.1:1234", ""); rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if _, err = userState.Users().Get(creds.Email, keyLoginFailures+"192.0.2.1"); err == nil {
+		t.Error("expected failed login attempts to be reset")
+	}
+}
+
+func TestRegisterLockout(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+
+	allowedMailDomains, maxLoginAttempts, loginLockout = []string{"*"}, 3, time.Hour
+	defer func() { allowedMailDomains, maxLoginAttempts, loginLockout = nil, 0, 0 }()
+
+	doRegister := func(password string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(&Credentials{Email: creds.Email, Password: password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.NewRequest("POST", "/register", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		register(rr, r)
+		return rr
+	}
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if rr := doRegister("wrongpassword"); rr.Code != want {
+			t.Errorf("attempt %d: got %v, want %v", i+1, rr.Code, want)
+		}
+	}
+
+	rr := doRegister(creds.Password)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusTooManyRequests)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+
+	if err = userState.Users().Set(creds.Email, keyLoginLockout+"192.0.2.1", time.Now().Add(-time.Second).Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = doRegister(creds.Password)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusUnauthorized)
+	}
+	if rr.Body.String() != responses.ErrNotConfirmed.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrNotConfirmed.Error())
+	}
+	if _, err = userState.Users().Get(creds.Email, keyLoginFailures+"192.0.2.1"); err == nil {
+		t.Error("expected failed register attempts to be reset")
+	}
+}
+
+func TestManageUsers(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	super := "super@super.com"
+	userState.AddUser(super, creds.Password, "")
+	userState.Confirm(super)
+	userState.SetAdminStatus(super)
+	userState.SetBooleanField(super, keySuperAdmin, true)
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.AddUnconfirmed(creds.Email, "somecode")
+
+	do := func(handler http.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, super))
+		rr := httptest.NewRecorder()
+		handler(rr, r)
+		return rr
+	}
+
+	tests := []struct {
+		handler http.HandlerFunc
+		method  string
+		target  string
+		code    int
+	}{
+		{confirmUser, "POST", "/admin/users/confirm?email=" + creds.Email, http.StatusOK},
+		{promoteUser, "POST", "/admin/users/promote?email=" + creds.Email, http.StatusOK},
+		{promoteUser, "POST", "/admin/users/promote?email=bob@bob.com", http.StatusNotFound},
+		{promoteUser, "POST", "/admin/users/promote", http.StatusBadRequest},
+		{demoteUser, "POST", "/admin/users/demote?email=" + super, http.StatusForbidden},
+		{deleteUser, "DELETE", "/admin/users?email=" + super, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		if rr := do(test.handler, test.method, test.target); rr.Code != test.code {
+			t.Errorf("%s %s: got %v, want %v", test.method, test.target, rr.Code, test.code)
+		}
+	}
+
+	if !userState.IsConfirmed(creds.Email) || !userState.IsAdmin(creds.Email) {
+		t.Errorf("expected %s to be confirmed and admin", creds.Email)
+	}
+	if !userState.IsAdmin(super) {
+		t.Errorf("expected %s to still be admin", super)
+	}
+
+	rr := do(getUsersPage, "GET", "/admin/users?limit=1&offset=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	users := []*UserInfo{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &users}); err != nil {
+		t.Fatal(err)
+	}
+	want := []*UserInfo{{Username: super, Confirmed: true, Admin: true}}
+	if len(users) != 1 || *users[0] != *want[0] {
+		t.Errorf("got %v, want %v", users, want)
+	}
+
+	if rr = do(demoteUser, "POST", "/admin/users/demote?email="+creds.Email); rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if userState.IsAdmin(creds.Email) {
+		t.Errorf("expected %s to be demoted", creds.Email)
+	}
+
+	if rr = do(deleteUser, "DELETE", "/admin/users?email="+creds.Email); rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if userState.HasUser(creds.Email) {
+		t.Errorf("expected %s to be deleted", creds.Email)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.Confirm(creds.Email)
+
+	doLogin := func() []*http.Cookie {
+		body, err := json.Marshal(creds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		login(rr, httptest.NewRequest("POST", "/login", bytes.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+		return rr.Result().Cookies()
+	}
+
+	do := func(handler http.HandlerFunc, method, target string, body any, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(method, target, bytes.NewReader(data))
+		for _, c := range cookies {
+			r.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+		rr := httptest.NewRecorder()
+		checkCookieExpiryMiddleware(handler).ServeHTTP(rr, r)
+		return rr
+	}
+
+	t.Run("sessions", func(t *testing.T) {
+		rr := do(getSessions, "GET", "/sessions", nil, doLogin())
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+		session := &Session{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: session}); err != nil {
+			t.Fatal(err)
+		}
+		if lifetime := session.ExpiresAt.Sub(session.IssuedAt); lifetime != cookieTimeout {
+			t.Errorf("got session lifetime %v, want %v", lifetime, cookieTimeout)
+		}
+		if time.Since(session.IssuedAt) > time.Minute {
+			t.Errorf("got session issued at %v, want now", session.IssuedAt)
+		}
+	})
+
+	t.Run("logout all", func(t *testing.T) {
+		first, second := doLogin(), doLogin()
+
+		if rr := do(logoutAll, "POST", "/logout/all", nil, first); rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		for _, cookies := range [][]*http.Cookie{first, second} {
+			rr := do(ping, "GET", "/ping", nil, cookies)
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("got %v, want %v", rr.Code, http.StatusUnauthorized)
+			}
+			if rr.Body.String() != responses.ErrInvalidCookie.Error() {
+				t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrInvalidCookie.Error())
+			}
+		}
+
+		if rr := do(ping, "GET", "/ping", nil, doLogin()); rr.Code != http.StatusOK {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		current, other := doLogin(), doLogin()
+
+		rr := do(changePassword, "POST", "/changepass", credsChange, current)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+		defer userState.SetPassword(creds.Email, creds.Password)
+
+		if rr := do(ping, "GET", "/ping", nil, rr.Result().Cookies()); rr.Code != http.StatusOK {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+		if rr := do(ping, "GET", "/ping", nil, other); rr.Code != http.StatusUnauthorized {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("reset password", func(t *testing.T) {
+		cookies := doLogin()
+
+		if err = userState.Users().Set(creds.Email, "reset-code", credsReset.Code); err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := json.Marshal(credsReset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		resetPassword(rr, httptest.NewRequest("POST", "/resetpass", bytes.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		if rr := do(ping, "GET", "/ping", nil, cookies); rr.Code != http.StatusUnauthorized {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestChangeEmail(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	if err = dbInit(); err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	store, err := userState.Creator().NewKeyValue(mailQueueStoreId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mailQueue, err = mail.NewQueue(failingSender{}, failingSender{}, store); err != nil {
+		t.Fatal(err)
+	}
+	defer mailQueue.Close()
+
+	allowedMailDomains, codeLength, confirmationCodeValidityTime = []string{"*"}, 8, time.Minute
+	defer func() { allowedMailDomains, codeLength, confirmationCodeValidityTime = nil, 0, 0 }()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+	userState.Confirm(creds.Email)
+
+	pairs := []*db.CourseProfessor{{ProfessorUUID: professors[0].UUID, CourseCode: courses[0].Code}}
+	if err = dataDb.GradeCourseProfessor(professors[0].UUID, courses[0].Code, creds.Email, [3]float32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(handler http.HandlerFunc, target string, body any, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("POST", target, bytes.NewReader(data))
+		for _, c := range cookies {
+			r.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+		rr := httptest.NewRecorder()
+		checkCookieExpiryMiddleware(handler).ServeHTTP(rr, r)
+		return rr
+	}
+
+	body, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	login(rr, httptest.NewRequest("POST", "/login", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	cookies := rr.Result().Cookies()
+
+	email := "jane@jane.com"
+
+	if rr = do(changeEmail, "/changeemail", &Credentials{Email: email, Password: "wrong"}, cookies); rr.Code != http.StatusUnauthorized {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusUnauthorized)
+	}
+
+	if rr = do(changeEmail, "/changeemail", &Credentials{Email: creds.Email, Password: creds.Password}, cookies); rr.Code != http.StatusForbidden {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusForbidden)
+	}
+
+	if rr = do(changeEmail, "/changeemail", &Credentials{Email: email, Password: creds.Password}, cookies); rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	// the old email address stays active until the change is confirmed.
+	if !userState.HasUser(creds.Email) || userState.HasUser(email) {
+		t.Errorf("expected %s to still be registered instead of %s", creds.Email, email)
+	}
+	if pending := mailQueue.Status().Pending; len(pending) != 1 || pending[0].To != email {
+		t.Errorf("got %v, want a confirmation code queued for %s", pending, email)
+	}
+
+	code, err := userState.Users().Get(creds.Email, keyEmailChangeCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = do(confirmEmailChange, "/confirmemail", &CredentialsEmailChange{Code: "wrong", Password: creds.Password}, cookies)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusUnauthorized)
+	}
+	if rr.Body.String() != responses.ErrWrongConfirmationCode.Error() {
+		t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrWrongConfirmationCode.Error())
+	}
+
+	rr = do(confirmEmailChange, "/confirmemail", &CredentialsEmailChange{Code: code, Password: creds.Password}, cookies)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	if userState.HasUser(creds.Email) || !userState.IsConfirmed(email) || !userState.CorrectPassword(email, creds.Password) {
+		t.Errorf("expected %s to be moved to %s", creds.Email, email)
+	}
+
+	if rr := do(ping, "/ping", nil, rr.Result().Cookies()); rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr := do(ping, "/ping", nil, cookies); rr.Code != http.StatusUnauthorized {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusUnauthorized)
+	}
+
+	graded, err := dataDb.CheckGradedMany(email, pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !graded[0] {
+		t.Errorf("expected the grade of %s to be moved to %s", creds.Email, email)
+	}
+}
+
+// failingSender fails to deliver every message.
+type failingSender struct{}
+
+func (failingSender) SendMail(string, []byte) error         { return errors.New("connection refused") }
+func (failingSender) SendConfirmation(string, string) error { return errors.New("connection refused") }
+
+func TestGetMailQueue(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	store, err := userState.Creator().NewKeyValue(mailQueueStoreId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mailQueue, err = mail.NewQueue(failingSender{}, failingSender{}, store); err != nil {
+		t.Fatal(err)
+	}
+	defer mailQueue.Close()
+
+	if err = mailQueue.SendConfirmationAsync(creds.Email, "12345678"); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	getMailQueue(rr, httptest.NewRequest("GET", "/admin/mailqueue", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	// the confirmation code is not exposed.
+	if strings.Contains(rr.Body.String(), "12345678") {
+		t.Errorf("got %s, want no confirmation code", rr.Body.String())
+	}
+
+	status := mail.QueueStatus{}
+	if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &status}); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Pending) != 1 || status.Pending[0].To != creds.Email || status.Pending[0].Kind != mail.MessageKindConfirmation {
+		t.Errorf("got unexpected pending messages %v", status.Pending)
+	}
+	if len(status.Failed) != 0 {
+		t.Errorf("got %d failed messages, want 0", len(status.Failed))
+	}
+}
+
+func TestResendCooldown(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	store, err := userState.Creator().NewKeyValue(mailQueueStoreId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mailQueue, err = mail.NewQueue(failingSender{}, failingSender{}, store); err != nil {
+		t.Fatal(err)
+	}
+	defer mailQueue.Close()
+
+	if mailer, err = mail.NewClient("", false); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := mail.LoadTemplates("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer.SetTemplates(templates)
+	defer func() { mailer = nil }()
+
+	if passwordResetUrl, err = parsePasswordResetUrl("https://itpg.cc/resetpass"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { passwordResetUrl = nil }()
+
+	codeLength, confirmationCodeValidityTime, codeResendCooldown = 8, time.Minute, time.Minute
+	defer func() { codeLength, confirmationCodeValidityTime, codeResendCooldown = 0, 0, 0 }()
+
+	userState.AddUser(creds.Email, creds.Password, "")
+
+	sendCode := func() *httptest.ResponseRecorder {
+		body, err := json.Marshal(creds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		sendNewConfirmationCode(rr, httptest.NewRequest("POST", "/newconfirmationcode", bytes.NewReader(body)))
+		return rr
+	}
+
+	sendLink := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/sendresetlink", strings.NewReader("email="+creds.Email))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		sendResetLink(rr, r)
+		return rr
+	}
+
+	for _, tt := range []struct {
+		name  string
+		send  func() *httptest.ResponseRecorder
+		key   string
+		reset func()
+	}{
+		{"confirmation code", sendCode, keyConfirmationCodeSentAt, func() {}},
+		{"reset link", sendLink, keyResetLinkSentAt, func() { userState.Users().DelKey(creds.Email, "reset-code") }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := tt.send(); rr.Code != http.StatusOK {
+				t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+			}
+
+			rr := tt.send()
+			if rr.Code != http.StatusTooManyRequests {
+				t.Errorf("got %v, want %v", rr.Code, http.StatusTooManyRequests)
+			}
+			if rr.Body.String() != responses.ErrCodeResentTooSoon.Error() {
+				t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrCodeResentTooSoon.Error())
+			}
+			if retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After")); err != nil || retryAfter <= 0 || retryAfter > 60 {
+				t.Errorf("got Retry-After %q, want the seconds until the end of the cooldown", rr.Header().Get("Retry-After"))
+			}
+
+			// once the cooldown is over, another code can be sent.
+			if err := userState.Users().Set(creds.Email, tt.key, time.Now().Add(-codeResendCooldown).Format(time.RFC3339)); err != nil {
+				t.Fatal(err)
+			}
+			tt.reset()
+			if rr := tt.send(); rr.Code != http.StatusOK {
+				t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+			}
+		})
+	}
+}
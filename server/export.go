@@ -0,0 +1,277 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+// ExportFormat is the format of an export of the dataset.
+type ExportFormat string
+
+// Enum for export formats
+const (
+	exportFormatJSON ExportFormat = "json" // exportFormatJSON exports the dataset as a JSON object with an array for each kind of row.
+	exportFormatCSV  ExportFormat = "csv"  // exportFormatCSV exports the dataset as a single CSV table, with the kind of each row in the first column.
+)
+
+// exportFormats are the allowed formats when exporting the dataset.
+var exportFormats = []ExportFormat{exportFormatJSON, exportFormatCSV}
+
+// exportContentTypes maps the export formats to the content type of the export.
+var exportContentTypes = map[ExportFormat]string{
+	exportFormatJSON: "application/json",
+	exportFormatCSV:  "text/csv",
+}
+
+// exportCSVHeader is the header of the CSV exports of the dataset.
+var exportCSVHeader = []string{"kind", "course_code", "course_name", "department", "credits", "prof_uuid", "prof_name", "score_teaching", "score_coursework", "score_learning", "score_average", "count"}
+
+// exportScoresCSVHeader is the header of the CSV exports of the scores.
+var exportScoresCSVHeader = []string{"course_code", "course_name", "prof_uuid", "prof_name", "score_teaching", "score_coursework", "score_learning", "score_average", "count", "inserted_at"}
+
+// ExportedScore represents the average scores of a professor in a course, as written in exports.
+type ExportedScore struct {
+	ProfessorUUID   string  `json:"profUUID"`        // UUID of the professor
+	ProfessorName   string  `json:"profName"`        // Name of the professor
+	CourseCode      string  `json:"courseCode"`      // Code of the course
+	CourseName      string  `json:"courseName"`      // Name of the course
+	ScoreTeaching   float32 `json:"scoreTeaching"`   // Average teaching score of the professor in the course
+	ScoreCourseWork float32 `json:"scoreCoursework"` // Average coursework score of the professor in the course
+	ScoreLearning   float32 `json:"scoreLearning"`   // Average learning score of the professor in the course
+	ScoreAverage    float32 `json:"scoreAverage"`    // Average of the teaching, coursework, and learning scores
+	Count           int     `json:"count"`           // Number of grades of the professor in the course
+}
+
+// exportData handles the HTTP request to download all the courses, professors, and average scores,
+// as JSON (default) or CSV depending on the format query parameter.
+// The rows are written as they are read from the database, so an error while exporting can only be logged,
+// and truncates the export.
+func exportData(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseExportFormat(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	setExportHeaders(w, "itpg", format)
+
+	var err error
+	switch format {
+	case exportFormatJSON:
+		err = writeJSONExport(w, requestDb(r))
+	case exportFormatCSV:
+		err = writeCSVExport(w, requestDb(r))
+	}
+
+	if err != nil {
+		requestLogger(r).Error().Err(err).Msg("export failed")
+	}
+}
+
+// exportScores handles the HTTP request to download the average scores of each professor in each of their graded courses,
+// as JSON (default) or CSV depending on the format query parameter.
+// If the since query parameter is set to an RFC 3339 timestamp, only the scores graded since then are exported,
+// which allows exporting the scores incrementally.
+// As with exportData, an error while exporting can only be logged, and truncates the export.
+func exportScores(w http.ResponseWriter, r *http.Request) {
+	format, ok := parseExportFormat(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	var since time.Time
+	if s := r.FormValue("since"); s != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+	}
+
+	setExportHeaders(w, "itpg-scores", format)
+
+	var err error
+	switch format {
+	case exportFormatJSON:
+		err = writeJSONScoresExport(w, requestDb(r), since)
+	case exportFormatCSV:
+		err = writeCSVScoresExport(w, requestDb(r), since)
+	}
+
+	if err != nil {
+		requestLogger(r).Error().Err(err).Msg("scores export failed")
+	}
+}
+
+// parseExportFormat returns the export format of the format query parameter, defaulting to JSON,
+// and whether it is allowed.
+func parseExportFormat(r *http.Request) (ExportFormat, bool) {
+	format := exportFormatJSON
+	if f := r.FormValue("format"); f != "" {
+		format = ExportFormat(f)
+	}
+
+	return format, slices.Contains(exportFormats, format)
+}
+
+// setExportHeaders sets the content type of an export, and its file name from name and the current time.
+func setExportHeaders(w http.ResponseWriter, name string, format ExportFormat) {
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().UTC().Format("20060102T150405Z"), format))
+}
+
+// writeJSONExport writes the courses, professors, and average scores of the database as a JSON object with an array for each.
+func writeJSONExport(w io.Writer, d db.DB) (err error) {
+	enc := json.NewEncoder(w)
+
+	var sep string
+	writeItem := func(v any) error {
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		sep = ","
+		return enc.Encode(v)
+	}
+
+	if _, err = io.WriteString(w, `{"courses":[`); err != nil {
+		return
+	}
+
+	if err = d.ExportCourses(func(c *db.Course) error { return writeItem(c) }); err != nil {
+		return
+	}
+
+	if _, err = io.WriteString(w, `],"professors":[`); err != nil {
+		return
+	}
+
+	sep = ""
+	if err = d.ExportProfessors(func(p *db.Professor) error { return writeItem(p) }); err != nil {
+		return
+	}
+
+	if _, err = io.WriteString(w, `],"scores":[`); err != nil {
+		return
+	}
+
+	sep = ""
+	if err = d.ExportScores(func(s *db.Score) error { return writeItem(newExportedScore(s)) }); err != nil {
+		return
+	}
+
+	_, err = io.WriteString(w, "]}")
+
+	return
+}
+
+// writeCSVExport writes the courses, professors, and average scores of the database as a CSV table,
+// leaving empty the columns not applying to the kind of a row.
+func writeCSVExport(w io.Writer, d db.DB) (err error) {
+	cw := csv.NewWriter(w)
+
+	if err = cw.Write(exportCSVHeader); err != nil {
+		return
+	}
+
+	if err = d.ExportCourses(func(c *db.Course) error {
+		var credits string
+		if c.Credits != nil {
+			credits = strconv.Itoa(*c.Credits)
+		}
+		return cw.Write([]string{"course", c.Code, c.Name, c.Department, credits, "", "", "", "", "", "", ""})
+	}); err != nil {
+		return
+	}
+
+	if err = d.ExportProfessors(func(p *db.Professor) error {
+		return cw.Write([]string{"professor", "", "", "", "", p.UUID, p.Name, "", "", "", "", ""})
+	}); err != nil {
+		return
+	}
+
+	if err = d.ExportScores(func(s *db.Score) error {
+		return cw.Write([]string{"score", s.CourseCode, s.CourseName, "", "", s.ProfessorUUID, s.ProfessorName, formatScore(s.ScoreTeaching), formatScore(s.ScoreCourseWork), formatScore(s.ScoreLearning), formatScore(s.ScoreAverage), strconv.Itoa(s.Count)})
+	}); err != nil {
+		return
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// writeJSONScoresExport writes the average scores of the database graded since the specified time as a JSON array.
+func writeJSONScoresExport(w io.Writer, d db.DB, since time.Time) (err error) {
+	enc := json.NewEncoder(w)
+
+	if _, err = io.WriteString(w, "["); err != nil {
+		return
+	}
+
+	var sep string
+	if err = d.GetAllScoresStream(since, func(s *db.StreamedScore) error {
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		sep = ","
+		return enc.Encode(s)
+	}); err != nil {
+		return
+	}
+
+	_, err = io.WriteString(w, "]")
+
+	return
+}
+
+// writeCSVScoresExport writes the average scores of the database graded since the specified time as a CSV table.
+func writeCSVScoresExport(w io.Writer, d db.DB, since time.Time) (err error) {
+	cw := csv.NewWriter(w)
+
+	if err = cw.Write(exportScoresCSVHeader); err != nil {
+		return
+	}
+
+	if err = d.GetAllScoresStream(since, func(s *db.StreamedScore) error {
+		return cw.Write([]string{s.CourseCode, s.CourseName, s.ProfessorUUID, s.ProfessorName, formatScore(s.ScoreTeaching), formatScore(s.ScoreCourseWork), formatScore(s.ScoreLearning), formatScore(s.ScoreAverage), strconv.Itoa(s.Count), s.InsertedAt.Format(time.RFC3339Nano)})
+	}); err != nil {
+		return
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// newExportedScore returns the exported score of a score.
+func newExportedScore(s *db.Score) *ExportedScore {
+	return &ExportedScore{
+		ProfessorUUID:   s.ProfessorUUID,
+		ProfessorName:   s.ProfessorName,
+		CourseCode:      s.CourseCode,
+		CourseName:      s.CourseName,
+		ScoreTeaching:   s.ScoreTeaching,
+		ScoreCourseWork: s.ScoreCourseWork,
+		ScoreLearning:   s.ScoreLearning,
+		ScoreAverage:    s.ScoreAverage,
+		Count:           s.Count,
+	}
+}
+
+// formatScore formats a score for the CSV exports.
+func formatScore(score float32) string {
+	return strconv.FormatFloat(float64(score), 'f', -1, 32)
+}
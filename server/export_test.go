@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+func TestExportData(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	t.Run("json", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/export", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		exportData(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("got %s, want %s", ct, "application/json")
+		}
+
+		if cd := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="itpg-`) || !strings.HasSuffix(cd, `.json"`) {
+			t.Errorf("got unexpected content disposition %s", cd)
+		}
+
+		var export struct {
+			Courses    []*db.Course     `json:"courses"`
+			Professors []*db.Professor  `json:"professors"`
+			Scores     []*ExportedScore `json:"scores"`
+		}
+		if err = json.NewDecoder(rr.Body).Decode(&export); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(export.Courses) != len(courses) {
+			t.Errorf("got %d courses, want %d", len(export.Courses), len(courses))
+		}
+		if len(export.Professors) != len(professors) {
+			t.Errorf("got %d professors, want %d", len(export.Professors), len(professors))
+		}
+		if len(export.Scores) != len(scores) {
+			t.Errorf("got %d scores, want %d", len(export.Scores), len(scores))
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/export?format=csv", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		exportData(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		if ct := rr.Header().Get("Content-Type"); ct != "text/csv" {
+			t.Errorf("got %s, want %s", ct, "text/csv")
+		}
+
+		if cd := rr.Header().Get("Content-Disposition"); !strings.HasSuffix(cd, `.csv"`) {
+			t.Errorf("got unexpected content disposition %s", cd)
+		}
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := 1 + len(courses) + len(professors) + len(scores); len(records) != want {
+			t.Fatalf("got %d records, want %d", len(records), want)
+		}
+
+		if strings.Join(records[0], ",") != strings.Join(exportCSVHeader, ",") {
+			t.Errorf("got %v, want %v", records[0], exportCSVHeader)
+		}
+
+		kinds := map[string]int{}
+		for _, record := range records[1:] {
+			kinds[record[0]]++
+		}
+		if kinds["course"] != len(courses) || kinds["professor"] != len(professors) || kinds["score"] != len(scores) {
+			t.Errorf("got unexpected row kinds %v", kinds)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/export?format=xml", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		exportData(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+		}
+		if rr.Body.String() != responses.ErrBadRequest.Error() {
+			t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrBadRequest.Error())
+		}
+	})
+}
+
+func TestExportScores(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	t.Run("json", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/score/export", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		exportScores(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		if cd := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="itpg-scores-`) || !strings.HasSuffix(cd, `.json"`) {
+			t.Errorf("got unexpected content disposition %s", cd)
+		}
+
+		var streamed []*db.StreamedScore
+		if err = json.NewDecoder(rr.Body).Decode(&streamed); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(streamed) != len(scores) {
+			t.Errorf("got %d scores, want %d", len(streamed), len(scores))
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/score/export?format=csv", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		exportScores(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		if ct := rr.Header().Get("Content-Type"); ct != "text/csv" {
+			t.Errorf("got %s, want %s", ct, "text/csv")
+		}
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := 1 + len(scores); len(records) != want {
+			t.Fatalf("got %d records, want %d", len(records), want)
+		}
+
+		if strings.Join(records[0], ",") != strings.Join(exportScoresCSVHeader, ",") {
+			t.Errorf("got %v, want %v", records[0], exportScoresCSVHeader)
+		}
+	})
+
+	t.Run("since", func(t *testing.T) {
+		since := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		r, err := http.NewRequest("GET", "/score/export?format=csv&since="+url.QueryEscape(since), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		exportScores(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 1 {
+			t.Errorf("got %d records, want only the header", len(records))
+		}
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/score/export?since=yesterday", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		exportScores(rr, r)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusBadRequest)
+		}
+		if rr.Body.String() != responses.ErrBadRequest.Error() {
+			t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrBadRequest.Error())
+		}
+	})
+}
@@ -2,33 +2,71 @@ package server
 
 import (
 	"bytes"
+	_ "embed"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-chi/httprate"
 	"github.com/vanillaiice/itpg/responses"
 )
 
+// defaultHandlers is the default handler config, used when no handler config file is specified.
+//
+//go:embed handlers.json
+var defaultHandlers []byte
+
 // Handler holds data for a handler.
 type Handler struct {
-	Handlers []struct {
+	DefaultLimit *Limit `json:"defaultLimit"`
+	Handlers     []struct {
 		Path     string `json:"path"`
 		PathType string `json:"pathType"`
 		Handler  string `json:"handler"`
 		Limiter  string `json:"limiter"`
+		Limit    *Limit `json:"limit"`
 		Method   string `json:"method"`
+		Cache    bool   `json:"cache"`
 	} `json:"handlers"`
 }
 
+// Limit is a limit of the number of requests per IP address within a window of time.
+// Like the named limiters, the requests are counted in memory by the go-chi/httprate limiter, with a sliding window.
+type Limit struct {
+	Requests int    `json:"requests"` // Requests is the number of requests allowed per IP address within the window.
+	Window   string `json:"window"`   // Window is the duration of the window, such as 1s, 1m, or 1h.
+}
+
+// newLimiter returns a limiter allowing the number of requests of the limit per IP address within its window.
+func (l *Limit) newLimiter() (func(http.Handler) http.Handler, error) {
+	window, err := time.ParseDuration(l.Window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid limit window: %w", err)
+	}
+
+	if l.Requests <= 0 || window <= 0 {
+		return nil, fmt.Errorf("invalid limit: %d requests per %s (both should be greater than 0)", l.Requests, l.Window)
+	}
+
+	return httprate.Limit(
+		l.Requests,
+		window,
+		httprate.WithKeyFuncs(httprate.KeyByIP),
+		limitHandlerFunc,
+	), nil
+}
+
 // HandlerInfo represents a struct containing information about an HTTP handler.
 type HandlerInfo struct {
+	name     string                                   // Name is the name of the handler, used to label its metrics.
 	path     string                                   // Path specifies the URL pattern for which the handler is responsible.
 	handler  func(http.ResponseWriter, *http.Request) // Handler is the function that will be called to handle HTTP requests.
 	method   string                                   // Method specifies the HTTP method associated with the handler.
 	pathType PathType                                 // PathType is the type of the path (admin, user, public).
 	limiter  func(http.Handler) http.Handler          // Limiter is the limiter used to limit requests.
+	cache    bool                                     // Cache is whether clients and proxies can cache the successful responses of the handler.
 }
 
 // PathType is the type of the path (admin, user, public).
@@ -106,41 +144,118 @@ var methodMap = map[string]string{
 
 // handlerFuncMap is a map of handler functions to their names.
 var handlerFuncMap = map[string]func(http.ResponseWriter, *http.Request){
-	"gradeCourseProfessor":         gradeCourseProfessor,
-	"refreshCookie":                refreshCookie,
-	"logout":                       logout,
-	"clearCookie":                  clearCookie,
-	"changePassword":               changePassword,
-	"deleteAccount":                deleteAccount,
-	"ping":                         ping,
-	"getLastCourses":               getLastCourses,
-	"getLastProfessors":            getLastProfessors,
-	"getLastScores":                getLastScores,
-	"getCoursesByProfessorUUID":    getCoursesByProfessorUUID,
-	"getProfessorsByCourseCode":    getProfessorsByCourseCode,
-	"getScoresByProfessorUUID":     getScoresByProfessorUUID,
-	"getScoresByProfessorName":     getScoresByProfessorName,
-	"getScoresByProfessorNameLike": getScoresByProfessorNameLike,
-	"getScoresByCourseName":        getScoresByCourseName,
-	"getScoresByCourseNameLike":    getScoresByCourseNameLike,
-	"getScoresByCourseCode":        getScoresByCourseCode,
-	"getScoresByCourseCodeLike":    getScoresByCourseCodeLike,
-	"login":                        login,
-	"register":                     register,
-	"confirm":                      confirm,
-	"sendNewConfirmationCode":      sendNewConfirmationCode,
-	"sendResetLink":                sendResetLink,
-	"resetPassword":                resetPassword,
-	"addCourse":                    addCourse,
-	"removeCourse":                 removeCourse,
-	"removeCourseForce":            removeCourseForce,
-	"addCourseProfessor":           addCourseProfessor,
-	"addProfessor":                 addProfessor,
-	"removeProfessor":              removeProfessor,
-	"removeProfessorForce":         removeProfessorForce,
+	"gradeCourseProfessor":                gradeCourseProfessor,
+	"updateGrade":                         updateGrade,
+	"deleteGrade":                         deleteGrade,
+	"checkGradedBatch":                    checkGradedBatch,
+	"getEligibleGrades":                   getEligibleGrades,
+	"gradeCourseProfessorBatch":           gradeCourseProfessorBatch,
+	"refreshCookie":                       refreshCookie,
+	"logout":                              logout,
+	"logoutAll":                           logoutAll,
+	"getSessions":                         getSessions,
+	"clearCookie":                         clearCookie,
+	"changePassword":                      changePassword,
+	"changeEmail":                         changeEmail,
+	"confirmEmailChange":                  confirmEmailChange,
+	"deleteAccount":                       deleteAccount,
+	"getAllUsers":                         getAllUsers,
+	"getMailQueue":                        getMailQueue,
+	"verifyUser":                          verifyUser,
+	"ping":                                ping,
+	"getLastCourses":                      getLastCourses,
+	"getLastProfessors":                   getLastProfessors,
+	"getLastScores":                       getLastScores,
+	"getCoursesBetween":                   getCoursesBetween,
+	"getProfessorsBetween":                getProfessorsBetween,
+	"getRandomCourses":                    getRandomCourses,
+	"getCatalogSync":                      getCatalogSync,
+	"getUnratedProfessors":                getUnratedProfessors,
+	"getCoursesByProfessorUUID":           getCoursesByProfessorUUID,
+	"getUngradedCoursesByProfessorUUID":   getUngradedCoursesByProfessorUUID,
+	"getProfessorsByCourseCode":           getProfessorsByCourseCode,
+	"getProfessorsForCourses":             getProfessorsForCourses,
+	"getScoresByProfessorUUID":            getScoresByProfessorUUID,
+	"getScoresByProfessorName":            getScoresByProfessorName,
+	"getScoresByProfessorNameLike":        getScoresByProfessorNameLike,
+	"getScoresByCourseName":               getScoresByCourseName,
+	"getScoresByCourseNameLike":           getScoresByCourseNameLike,
+	"getScoresByCourseCode":               getScoresByCourseCode,
+	"getScoresByCourseCodeLike":           getScoresByCourseCodeLike,
+	"getScoresBySearch":                   getScoresBySearch,
+	"search":                              search,
+	"exportData":                          exportData,
+	"exportScores":                        exportScores,
+	"getScoreDimensions":                  getScoreDimensions,
+	"getProfessorDetail":                  getProfessorDetail,
+	"getProfessorRank":                    getProfessorRank,
+	"getCourseDetail":                     getCourseDetail,
+	"getHealth":                           getHealth,
+	"getReadiness":                        getReadiness,
+	"getScoreTrend":                       getScoreTrend,
+	"getScoreHistoryByCourseCode":         getScoreHistoryByCourseCode,
+	"getScoreDistributionByProfessorUUID": getScoreDistributionByProfessorUUID,
+	"getDepartmentStats":                  getDepartmentStats,
+	"getComponentAverages":                getComponentAverages,
+	"getPostmanCollection":                getPostmanCollection,
+	"getOpenAPI":                          getOpenAPI,
+	"getStats":                            getStats,
+	"getBottomRatedProfessors":            getBottomRatedProfessors,
+	"getTopProfessors":                    getTopProfessors,
+	"getMostRatedCourses":                 getMostRatedCourses,
+	"login":                               login,
+	"register":                            register,
+	"confirm":                             confirm,
+	"validateCode":                        validateCode,
+	"sendNewConfirmationCode":             sendNewConfirmationCode,
+	"sendResetLink":                       sendResetLink,
+	"resetPassword":                       resetPassword,
+	"addCourse":                           addCourse,
+	"addCourseMany":                       addCourseMany,
+	"updateCourse":                        updateCourse,
+	"removeCourse":                        removeCourse,
+	"removeCourseForce":                   removeCourseForce,
+	"softRemoveCourse":                    softRemoveCourse,
+	"restoreCourse":                       restoreCourse,
+	"removeCourseProfessor":               removeCourseProfessor,
+	"addCourseProfessor":                  addCourseProfessor,
+	"addProfessor":                        addProfessor,
+	"addProfessorMany":                    addProfessorMany,
+	"renameProfessor":                     renameProfessor,
+	"updateProfessor":                     updateProfessor,
+	"removeProfessor":                     removeProfessor,
+	"removeProfessorForce":                removeProfessorForce,
+	"softRemoveProfessor":                 softRemoveProfessor,
+	"restoreProfessor":                    restoreProfessor,
+	"getTrash":                            getTrash,
+	"getRawGrades":                        getRawGrades,
+	"getGradeAttemptsByCourseCode":        getGradeAttemptsByCourseCode,
+	"flushCache":                          flushCache,
+	"getUsersPage":                        getUsersPage,
+	"confirmUser":                         confirmUser,
+	"promoteUser":                         promoteUser,
+	"demoteUser":                          demoteUser,
+	"deleteUser":                          deleteUser,
+}
+
+// loadHandlers parses the handler config json file at the specified path,
+// or the embedded default handler config if the path is empty.
+func loadHandlers(path string) ([]*HandlerInfo, error) {
+	handlerCfg := defaultHandlers
+	if path != "" {
+		var err error
+		if handlerCfg, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return parseHandlers(bytes.NewReader(handlerCfg))
 }
 
 // parseHandlers parses a handlers.json file and returns a slice of HandlerInfo.
+// The requests to a handler are limited by its named limiter, or by its own limit, or else by the default limit.
+// The handlers with the same named limiter, or without a limiter or a limit, share their request counts,
+// while each handler with its own limit counts its requests separately.
 func parseHandlers(reader *bytes.Reader) ([]*HandlerInfo, error) {
 	var handlers Handler
 	var handlersInfo []*HandlerInfo
@@ -149,6 +264,14 @@ func parseHandlers(reader *bytes.Reader) ([]*HandlerInfo, error) {
 		return nil, err
 	}
 
+	var defaultLimiter func(http.Handler) http.Handler
+	if handlers.DefaultLimit != nil {
+		var err error
+		if defaultLimiter, err = handlers.DefaultLimit.newLimiter(); err != nil {
+			return nil, fmt.Errorf("default limit: %w", err)
+		}
+	}
+
 	for _, h := range handlers.Handlers {
 		handlerFunc, ok := handlerFuncMap[h.Handler]
 		if !ok {
@@ -165,17 +288,35 @@ func parseHandlers(reader *bytes.Reader) ([]*HandlerInfo, error) {
 			return nil, fmt.Errorf("path type %s not found", h.PathType)
 		}
 
-		limiter, ok := limiterMap[h.Limiter]
-		if !ok {
-			return nil, fmt.Errorf("limiter %s not found", h.Limiter)
+		var limiter func(http.Handler) http.Handler
+		switch {
+		case h.Limiter != "" && h.Limit != nil:
+			return nil, fmt.Errorf("handler %s cannot have both a limiter and a limit", h.Handler)
+		case h.Limit != nil:
+			var err error
+			if limiter, err = h.Limit.newLimiter(); err != nil {
+				return nil, fmt.Errorf("handler %s: %w", h.Handler, err)
+			}
+		case h.Limiter == "" && defaultLimiter != nil:
+			limiter = defaultLimiter
+		default:
+			if limiter, ok = limiterMap[h.Limiter]; !ok {
+				return nil, fmt.Errorf("limiter %s not found", h.Limiter)
+			}
+		}
+
+		if h.Cache && (pathType != publicPath || method != http.MethodGet) {
+			return nil, fmt.Errorf("handler %s cannot be cached (only public GET handlers can be)", h.Handler)
 		}
 
 		handlersInfo = append(handlersInfo, &HandlerInfo{
+			name:     h.Handler,
 			path:     h.Path,
 			handler:  handlerFunc,
 			method:   method,
 			pathType: pathType,
 			limiter:  limiter,
+			cache:    h.Cache,
 		})
 	}
 
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+// DependencyStatus represents the status of a dependency of the server.
+type DependencyStatus struct {
+	Name  string `json:"name"`            // Name of the dependency
+	Ok    bool   `json:"ok"`              // Whether the dependency is available
+	Error string `json:"error,omitempty"` // Reason why the dependency is unavailable
+}
+
+// newDependencyStatus returns the status of a dependency from the error returned when checking it.
+func newDependencyStatus(name string, err error) *DependencyStatus {
+	status := &DependencyStatus{Name: name, Ok: err == nil}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// getHealth handles the HTTP request to check if the server is alive.
+func getHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	responses.Success.WriteJSON(w)
+}
+
+// getReadiness handles the HTTP request to check if the server is ready to handle requests.
+// It checks the data database, the databases of the tenants, the cache if configured, and the users database,
+// and responds with 503 Service Unavailable if any of them is unavailable.
+func getReadiness(w http.ResponseWriter, r *http.Request) {
+	statuses := []*DependencyStatus{newDependencyStatus("database", dataDb.Ping())}
+
+	for _, id := range tenantIDs() {
+		statuses = append(statuses, newDependencyStatus("database:"+id, tenantDbs[id].Ping()))
+	}
+
+	if err := dataDb.PingCache(); !errors.Is(err, db.ErrNoCache) {
+		statuses = append(statuses, newDependencyStatus("cache", err))
+	}
+
+	_, err := userState.AllUsernames()
+	statuses = append(statuses, newDependencyStatus("users", err))
+
+	code := responses.SuccessCode
+	for _, status := range statuses {
+		if !status.Ok {
+			code = responses.ErrNotReady.Code
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if code != responses.SuccessCode {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	(&responses.Response{Code: code, Message: statuses}).WriteJSON(w)
+}
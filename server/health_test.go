@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vanillaiice/itpg/responses"
+)
+
+func TestGetHealth(t *testing.T) {
+	r, err := http.NewRequest("GET", "/healthz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	getHealth(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestGetReadiness(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	tests := []struct {
+		status int
+		code   int
+	}{
+		{http.StatusOK, responses.SuccessCode},
+		{http.StatusServiceUnavailable, responses.ErrNotReady.Code},
+	}
+
+	for i, test := range tests {
+		if i == 1 {
+			dataDb.Close()
+		}
+
+		r, err := http.NewRequest("GET", "/readyz", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getReadiness(rr, r)
+		if rr.Code != test.status {
+			t.Errorf("got %v, want %v", rr.Code, test.status)
+		}
+
+		statuses := []*DependencyStatus{}
+		resp := &responses.Response{Message: &statuses}
+		if err = json.NewDecoder(rr.Body).Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != test.code {
+			t.Errorf("got code %d, want %d", resp.Code, test.code)
+		}
+		if len(statuses) != 2 {
+			t.Fatalf("got %d statuses, want %d", len(statuses), 2)
+		}
+		if statuses[0].Name != "database" || statuses[0].Ok != (i == 0) {
+			t.Errorf("got %+v, want database ok %v", statuses[0], i == 0)
+		}
+		if statuses[1].Name != "users" || !statuses[1].Ok {
+			t.Errorf("got %+v, want users ok", statuses[1])
+		}
+	}
+}
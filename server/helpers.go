@@ -2,11 +2,18 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/vanillaiice/itpg/db"
 	"github.com/vanillaiice/itpg/responses"
 )
 
@@ -22,6 +29,17 @@ func isEmptyStr(w http.ResponseWriter, str ...string) (err error) {
 	return
 }
 
+// writeSuccess writes a success response after a mutation,
+// or only the 204 No Content status code if noContentOnSuccess is set.
+func writeSuccess(w http.ResponseWriter) {
+	if noContentOnSuccess {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	responses.Success.WriteJSON(w)
+}
+
 // decodeCredentials decodes JSON data from the request body into a Credentials struct.
 func decodeCredentials(w http.ResponseWriter, r *http.Request) (*Credentials, error) {
 	var credentials Credentials
@@ -55,6 +73,17 @@ func decodeCredentialsChange(w http.ResponseWriter, r *http.Request) (*Credentia
 	return &credentialsChange, nil
 }
 
+// decodeCredentialsEmailChange decodes JSON data from the request body into a Credentials Email Change struct.
+func decodeCredentialsEmailChange(w http.ResponseWriter, r *http.Request) (*CredentialsEmailChange, error) {
+	var credentialsEmailChange CredentialsEmailChange
+	if err := json.NewDecoder(r.Body).Decode(&credentialsEmailChange); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return nil, err
+	}
+	return &credentialsEmailChange, nil
+}
+
 // decodeGradeData decodes JSON data from the request body into a Grade Data struct.
 func decodeGradeData(w http.ResponseWriter, r *http.Request) (*GradeData, error) {
 	var gradeData GradeData
@@ -67,8 +96,179 @@ func decodeGradeData(w http.ResponseWriter, r *http.Request) (*GradeData, error)
 	return &gradeData, nil
 }
 
+// decodeGradeDataMany decodes JSON data from the request body into a list of Grade Data structs.
+func decodeGradeDataMany(w http.ResponseWriter, r *http.Request) ([]*GradeData, error) {
+	var gradeDataMany []*GradeData
+	if err := json.NewDecoder(r.Body).Decode(&gradeDataMany); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return nil, err
+	}
+	return gradeDataMany, nil
+}
+
+// decodeCourses decodes JSON data from the request body into a list of courses.
+func decodeCourses(w http.ResponseWriter, r *http.Request) ([]*db.Course, error) {
+	var courses []*db.Course
+	if err := json.NewDecoder(r.Body).Decode(&courses); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return nil, err
+	}
+	return courses, nil
+}
+
+// decodeProfessorNames decodes JSON data from the request body into a list of professor names.
+func decodeProfessorNames(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	var names []string
+	if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return nil, err
+	}
+	return names, nil
+}
+
+// decodeCourseProfessors decodes JSON data from the request body into a list of courses and their professors.
+func decodeCourseProfessors(w http.ResponseWriter, r *http.Request) ([]*db.CourseProfessor, error) {
+	var pairs []*db.CourseProfessor
+	if err := json.NewDecoder(r.Body).Decode(&pairs); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// writeImportResults writes the results of a bulk import, given the errors of each item and of the batch returned by the database.
+// If the batch failed, no item is reported as inserted, and a bad request is written.
+func writeImportResults(w http.ResponseWriter, r *http.Request, results []*ImportResult, errs []error, err error) {
+	if err != nil && !errors.Is(err, db.ErrBatchFailed) {
+		w.WriteHeader(http.StatusInternalServerError)
+		responses.ErrInternal.WriteJSON(w)
+		requestLogger(r).Error().Msg(err.Error())
+		return
+	}
+
+	// the errors of the items are classified like writeDbError, so that the database details are only logged.
+	for i, e := range errs {
+		if e != nil {
+			_, response, _ := dbErrorResponse(e)
+			results[i].Error = response.Error()
+			requestLogger(r).Error().Msg(e.Error())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		(&responses.Response{Code: responses.ErrBadRequest.Code, Message: results}).WriteJSON(w)
+		return
+	}
+
+	for _, result := range results {
+		result.Inserted = true
+	}
+
+	(&responses.Response{Code: responses.SuccessCode, Message: results}).WriteJSON(w)
+}
+
+// parseTimeRange parses the from and to RFC 3339 timestamps from the request.
+func parseTimeRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, err error) {
+	fromStr, toStr := r.FormValue("from"), r.FormValue("to")
+	if err = isEmptyStr(w, fromStr, toStr); err != nil {
+		return
+	}
+
+	if from, err = time.Parse(time.RFC3339, fromStr); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return
+	}
+
+	if from.After(to) {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return from, to, fmt.Errorf("invalid time range: %s is after %s", fromStr, toStr)
+	}
+
+	return
+}
+
+// hasTimeRange returns true if the request sets the from or to timestamp of a time range.
+func hasTimeRange(r *http.Request) bool {
+	return r.FormValue("from") != "" || r.FormValue("to") != ""
+}
+
+// parseMatchMode parses the optional mode query parameter of the request, which can be substring (default) or prefix.
+func parseMatchMode(w http.ResponseWriter, r *http.Request) (mode db.MatchMode, err error) {
+	mode = db.MatchSubstring
+	if m := r.FormValue("mode"); m != "" {
+		mode = db.MatchMode(m)
+	}
+
+	if !slices.Contains(matchModes, mode) {
+		w.WriteHeader(http.StatusBadRequest)
+		responses.ErrBadRequest.WriteJSON(w)
+		return mode, fmt.Errorf("invalid match mode: %s", mode)
+	}
+
+	return
+}
+
+// setCookieTimeouts sets the server-side validity of session cookies, and the max age of
+// the session cookies sent to browsers. If maxAge is 0, the server-side validity is used.
+func setCookieTimeouts(timeout, maxAge time.Duration) {
+	cookieTimeout = timeout
+	if maxAge == 0 {
+		maxAge = timeout
+	}
+	userState.SetCookieTimeout(int64(maxAge.Seconds()))
+}
+
+// parsePagination parses the limit and offset query parameters of a request.
+// Absent or invalid values are returned as 0, letting the database apply its defaults,
+// except for the limit of unauthenticated requests, which is at most publicMaxResults.
+func parsePagination(r *http.Request) (limit, offset int) {
+	if l, err := strconv.Atoi(r.FormValue("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.FormValue("offset")); err == nil && o > 0 {
+		offset = o
+	}
+	if n := maxResults(r); n > 0 && (limit == 0 || limit > n) {
+		limit = n
+	}
+	return
+}
+
+// maxResults returns the maximum number of results of the list and search endpoints for the request,
+// which is publicMaxResults for unauthenticated requests, and 0 for no limit otherwise.
+func maxResults(r *http.Request) int {
+	if username, ok := r.Context().Value(usernameContextKey).(string); ok && username != "" {
+		return 0
+	}
+	return publicMaxResults
+}
+
+// capResults returns the first results, up to the maximum number of results for the request.
+func capResults[T any](r *http.Request, results []T) []T {
+	if n := maxResults(r); n > 0 && len(results) > n {
+		return results[:n]
+	}
+	return results
+}
+
 // extractDomain extracts the domain part from an email address.
 // It takes an email address string as input and returns the domain part.
+// The domain part is lowercased, since domains are case-insensitive.
 // If the email address is in an invalid format (e.g., missing "@" symbol),
 // it returns an empty string.
 func extractDomain(email string) (domain string, err error) {
@@ -76,7 +276,16 @@ func extractDomain(email string) (domain string, err error) {
 	if len(parts) != 2 {
 		return domain, fmt.Errorf("invalid email format")
 	}
-	return parts[1], err
+	return strings.ToLower(parts[1]), err
+}
+
+// lowerDomains returns a copy of the list of mail domains, lowercased.
+func lowerDomains(domains []string) []string {
+	lowered := make([]string, len(domains))
+	for i, domain := range domains {
+		lowered[i] = strings.ToLower(domain)
+	}
+	return lowered
 }
 
 // validAllowedDomains checks if the list of allowed mail domains is empty.
@@ -87,6 +296,19 @@ func validAllowedDomains(domains []string) (err error) {
 	return
 }
 
+// validScoreWeights checks if the score weights are greater than or equal to 0, and not all 0.
+func validScoreWeights(weights [3]float32) (err error) {
+	for _, weight := range weights {
+		if weight < 0 {
+			return fmt.Errorf("invalid score weights: %v (should be greater than or equal to 0)", weights)
+		}
+	}
+	if weights == [3]float32{} {
+		return fmt.Errorf("invalid score weights: %v (should not all be 0)", weights)
+	}
+	return
+}
+
 // checkDomainAllowed checks if the given domain is allowed based on the list of allowed mail domains.
 func checkDomainAllowed(domain string) (err error) {
 	if len(allowedMailDomains) == 0 {
@@ -95,8 +317,206 @@ func checkDomainAllowed(domain string) (err error) {
 	if allowedMailDomains[0] == "*" {
 		return
 	}
-	if !slices.Contains(allowedMailDomains, domain) {
+	if !slices.Contains(allowedMailDomains, strings.ToLower(domain)) {
 		return responses.ErrEmailDomainNotAllowed
 	}
 	return
 }
+
+// parsePasswordResetUrl parses the URL of the password reset web page,
+// and checks that it is an absolute http or https URL.
+func parsePasswordResetUrl(rawUrl string) (*url.URL, error) {
+	resetUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password reset url: %w", err)
+	}
+	if (resetUrl.Scheme != "http" && resetUrl.Scheme != "https") || resetUrl.Host == "" {
+		return nil, fmt.Errorf("invalid password reset url: %s (should be an absolute http or https url)", rawUrl)
+	}
+	return resetUrl, nil
+}
+
+// parsePasswordResetUrls parses the password reset URLs of the frontends in the origin=url form,
+// and returns them by origin. Each URL is checked as the default one.
+func parsePasswordResetUrls(entries []string) (map[string]*url.URL, error) {
+	urls := make(map[string]*url.URL, len(entries))
+
+	for _, entry := range entries {
+		rawOrigin, rawUrl, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid password reset url: %s (should be in the origin=url form)", entry)
+		}
+
+		origin, ok := normalizeOrigin(rawOrigin)
+		if !ok {
+			return nil, fmt.Errorf("invalid password reset url origin: %s (should be an http or https origin)", rawOrigin)
+		}
+
+		resetUrl, err := parsePasswordResetUrl(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		urls[origin] = resetUrl
+	}
+
+	return urls, nil
+}
+
+// normalizeOrigin returns the lowercased scheme and host of an http or https URL or origin,
+// and whether it is one.
+func normalizeOrigin(rawOrigin string) (string, bool) {
+	u, err := url.Parse(rawOrigin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), true
+}
+
+// requestPasswordResetUrl returns the password reset URL of the frontend a request comes from,
+// identified by its Origin header, or its Referer header if it has no origin.
+// The default password reset URL is returned if the frontend has no URL of its own.
+func requestPasswordResetUrl(r *http.Request) *url.URL {
+	rawOrigin := r.Header.Get("Origin")
+	if rawOrigin == "" {
+		rawOrigin = r.Referer()
+	}
+
+	if origin, ok := normalizeOrigin(rawOrigin); ok {
+		if resetUrl, ok := passwordResetUrls[origin]; ok {
+			return resetUrl
+		}
+	}
+
+	return passwordResetUrl
+}
+
+// makePasswordResetLink returns the password reset link sent to users,
+// with the reset code set as a query parameter of the password reset URL.
+func makePasswordResetLink(resetUrl *url.URL, code string) string {
+	link := *resetUrl
+	query := link.Query()
+	query.Set("code", code)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// isVerified checks if a user is verified.
+func isVerified(username string) bool {
+	verified, err := userState.Users().Get(username, keyVerified)
+	return err == nil && verified == "true"
+}
+
+// gradeWeight returns the weight of the grades of a user in the averages.
+func gradeWeight(username string) float32 {
+	if isVerified(username) {
+		return verifiedGradeWeight
+	}
+	return db.DefaultGradeWeight
+}
+
+// dbErrorResponse returns the status code and the response matching a database error,
+// or ok false if the error is not a known database error.
+func dbErrorResponse(err error) (status int, response *responses.Response, ok bool) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound, responses.ErrNotFound, true
+	case errors.Is(err, db.ErrDuplicate):
+		return http.StatusConflict, responses.ErrDuplicate, true
+	case errors.Is(err, db.ErrForeignKey):
+		return http.StatusConflict, responses.ErrReferenced, true
+	case errors.Is(err, db.ErrInvalid):
+		return http.StatusBadRequest, responses.ErrInvalidValue, true
+	default:
+		return http.StatusInternalServerError, responses.ErrInternal, false
+	}
+}
+
+// writeDbError writes the response matching a database error,
+// falling back to an internal error if the error is not a known database error.
+func writeDbError(w http.ResponseWriter, r *http.Request, err error) {
+	status, response, ok := dbErrorResponse(err)
+	if !ok {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(status)
+	response.WriteJSON(w)
+}
+
+// writeInternalError writes a Service Unavailable response if the database did not answer in time,
+// or an Internal Server Error response otherwise, and logs the error.
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, db.ErrTimeout) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	responses.ErrInternal.WriteJSON(w)
+	requestLogger(r).Error().Msg(err.Error())
+}
+
+// clientIP returns the ip address of the client of a request, from the first address of the X-Forwarded-For header,
+// or from the remote address of the request if the header is not set.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		if ip, _, _ := strings.Cut(forwarded, ","); strings.TrimSpace(ip) != "" {
+			return strings.TrimSpace(ip)
+		}
+	}
+
+	return remoteIP(r)
+}
+
+// remoteIP returns the ip address of the remote address of a request.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// filteredIP returns the ip address of the client of a request checked against the ip ranges and counted in the login lockouts,
+// which is only read from the X-Forwarded-For header if the server is behind a trusted proxy.
+func filteredIP(r *http.Request) string {
+	if trustProxy {
+		return clientIP(r)
+	}
+
+	return remoteIP(r)
+}
+
+// parseIPRanges parses ip ranges in CIDR notation, or single ip addresses.
+func parseIPRanges(ranges []string) (prefixes []netip.Prefix, err error) {
+	for _, s := range ranges {
+		var prefix netip.Prefix
+		if strings.Contains(s, "/") {
+			if prefix, err = netip.ParsePrefix(s); err != nil {
+				return nil, fmt.Errorf("invalid ip range %s: %w", s, err)
+			}
+		} else {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ip address %s: %w", s, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return
+}
+
+// inIPRanges returns whether an ip address is in one of the ip ranges.
+// Invalid ip addresses are not in any range.
+func inIPRanges(ranges []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	return slices.ContainsFunc(ranges, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
@@ -113,6 +113,22 @@ func TestExtractDomain(t *testing.T) {
 	if domain != "bar.com" {
 		t.Errorf("got %s, want %s", domain, "bar.com")
 	}
+	email = "User@GMAIL.com"
+	domain, err = extractDomain(email)
+	if err != nil {
+		t.Error(err)
+	}
+	if domain != "gmail.com" {
+		t.Errorf("got %s, want %s", domain, "gmail.com")
+	}
+}
+
+func TestLowerDomains(t *testing.T) {
+	domains := lowerDomains([]string{"Foo.com", "BAR.xyz", "*"})
+	expected := []string{"foo.com", "bar.xyz", "*"}
+	if !cmp.Equal(domains, expected) {
+		t.Errorf("got %v, want %v", domains, expected)
+	}
 }
 
 func TestValidAllowedDomains(t *testing.T) {
@@ -143,8 +159,184 @@ func TestCheckDomainAllowed(t *testing.T) {
 	if err = checkDomainAllowed("buzz.cc"); err == nil {
 		t.Error("expected failure")
 	}
+	allowedMailDomains = lowerDomains([]string{"GMail.com"})
+	domain, err := extractDomain("User@GMAIL.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = checkDomainAllowed(domain); err != nil {
+		t.Error(err)
+	}
 	allowedMailDomains = []string{"*"}
 	if err = checkDomainAllowed("fizz.cc"); err != nil {
 		t.Error(err)
 	}
 }
+
+func TestParsePasswordResetUrl(t *testing.T) {
+	for _, rawUrl := range []string{"https://demo.itpg.cc/changepass", "http://localhost:8080/changepass?lang=en"} {
+		if _, err := parsePasswordResetUrl(rawUrl); err != nil {
+			t.Errorf("%s: %v", rawUrl, err)
+		}
+	}
+
+	for _, rawUrl := range []string{"", "/changepass", "demo.itpg.cc/changepass", "ftp://demo.itpg.cc/changepass", "https:///changepass"} {
+		if _, err := parsePasswordResetUrl(rawUrl); err == nil {
+			t.Errorf("%s: expected failure", rawUrl)
+		}
+	}
+}
+
+func TestMakePasswordResetLink(t *testing.T) {
+	tests := []struct {
+		rawUrl   string
+		expected string
+	}{
+		{"https://demo.itpg.cc/changepass", "https://demo.itpg.cc/changepass?code=foobarbaz"},
+		{"https://demo.itpg.cc/changepass?lang=en", "https://demo.itpg.cc/changepass?code=foobarbaz&lang=en"},
+		{"https://demo.itpg.cc/changepass?code=stale", "https://demo.itpg.cc/changepass?code=foobarbaz"},
+	}
+
+	for _, test := range tests {
+		resetUrl, err := parsePasswordResetUrl(test.rawUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link := makePasswordResetLink(resetUrl, "foobarbaz"); link != test.expected {
+			t.Errorf("%s: got %s, want %s", test.rawUrl, link, test.expected)
+		}
+		if resetUrl.String() != test.rawUrl {
+			t.Errorf("got modified url %s, want %s", resetUrl, test.rawUrl)
+		}
+	}
+}
+
+func TestParsePasswordResetUrls(t *testing.T) {
+	urls, err := parsePasswordResetUrls([]string{"https://Admin.itpg.cc=https://admin.itpg.cc/resetpass", "http://localhost:3000=http://localhost:3000/resetpass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 2 || urls["https://admin.itpg.cc"].String() != "https://admin.itpg.cc/resetpass" || urls["http://localhost:3000"].String() != "http://localhost:3000/resetpass" {
+		t.Errorf("got unexpected urls %v", urls)
+	}
+
+	for _, entries := range [][]string{{"https://admin.itpg.cc"}, {"admin.itpg.cc=https://admin.itpg.cc/resetpass"}, {"https://admin.itpg.cc=/resetpass"}, {"=https://admin.itpg.cc/resetpass"}} {
+		if _, err = parsePasswordResetUrls(entries); err == nil {
+			t.Errorf("%v: expected failure", entries)
+		}
+	}
+}
+
+func TestRequestPasswordResetUrl(t *testing.T) {
+	var err error
+	if passwordResetUrl, err = parsePasswordResetUrl("https://itpg.cc/resetpass"); err != nil {
+		t.Fatal(err)
+	}
+	if passwordResetUrls, err = parsePasswordResetUrls([]string{"https://admin.itpg.cc=https://admin.itpg.cc/resetpass"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { passwordResetUrl, passwordResetUrls = nil, nil }()
+
+	tests := []struct {
+		header   string
+		value    string
+		expected string
+	}{
+		{"Origin", "https://admin.itpg.cc", "https://admin.itpg.cc/resetpass"},
+		{"Origin", "https://ADMIN.itpg.cc", "https://admin.itpg.cc/resetpass"},
+		{"Referer", "https://admin.itpg.cc/login?next=/users", "https://admin.itpg.cc/resetpass"},
+		{"Origin", "https://itpg.cc", "https://itpg.cc/resetpass"},
+		{"Origin", "http://admin.itpg.cc", "https://itpg.cc/resetpass"},
+		{"Origin", "null", "https://itpg.cc/resetpass"},
+		{"", "", "https://itpg.cc/resetpass"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/sendresetlink", nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		if got := requestPasswordResetUrl(r).String(); got != test.expected {
+			t.Errorf("%s %s: got %s, want %s", test.header, test.value, got, test.expected)
+		}
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query         string
+		limit, offset int
+	}{
+		{"", 0, 0},
+		{"limit=10&offset=20", 10, 20},
+		{"limit=abc&offset=-5", 0, 0},
+		{"limit=-1&offset=xyz", 0, 0},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/course/all?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		limit, offset := parsePagination(r)
+		if limit != test.limit || offset != test.offset {
+			t.Errorf("%s: got %d, %d, want %d, %d", test.query, limit, offset, test.limit, test.offset)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr, forwardedFor, want string
+	}{
+		{"192.0.2.1:1234", "", "192.0.2.1"},
+		{"192.0.2.1:1234", "198.51.100.7, 203.0.113.9", "198.51.100.7"},
+		{"192.0.2.1", "", "192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		if test.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+		if got := clientIP(r); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestParseIPRanges(t *testing.T) {
+	ranges, err := parseIPRanges([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"192.0.2.1", true},
+		{"192.0.2.2", false},
+		{"::ffff:10.1.2.3", true},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"not an ip", false},
+	}
+
+	for _, test := range tests {
+		if got := inIPRanges(ranges, test.ip); got != test.want {
+			t.Errorf("got %v for %s, want %v", got, test.ip, test.want)
+		}
+	}
+
+	for _, invalid := range []string{"10.0.0.0/33", "10.0.0", "campus"} {
+		if _, err = parseIPRanges([]string{invalid}); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
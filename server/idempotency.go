@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vanillaiice/itpg/responses"
+)
+
+// idempotencyKeyHeader is the header of the key chosen by clients to safely retry a grade submission.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the maximum length of an idempotency key.
+const maxIdempotencyKeyLength = 255
+
+// keyIdempotentGrades is the key for getting the grades submitted by a user with an idempotency key.
+const keyIdempotentGrades = "idempotent-grades"
+
+// idempotencyKeyValidityTime is the duration during which a grade submission can be retried with the same idempotency key,
+// idempotency keys being ignored if it is 0.
+var idempotencyKeyValidityTime time.Duration
+
+// idempotentGradesMu guards the read-modify-write of the grades submitted with an idempotency key.
+var idempotentGradesMu sync.Mutex
+
+// idempotentGrade is a grade submitted with an idempotency key.
+type idempotentGrade struct {
+	CourseCode  string    `json:"code"`        // Code of the graded course
+	ProfUUID    string    `json:"uuid"`        // UUID of the graded professor
+	SubmittedAt time.Time `json:"submittedAt"` // Time at which the grade was submitted
+}
+
+// requestIdempotencyKey returns the idempotency key of the request, empty if it has none or if idempotency keys are disabled,
+// and whether the key is valid.
+func requestIdempotencyKey(r *http.Request) (string, bool) {
+	if idempotencyKeyValidityTime == 0 {
+		return "", true
+	}
+
+	key := r.Header.Get(idempotencyKeyHeader)
+
+	return key, len(key) <= maxIdempotencyKeyLength
+}
+
+// getIdempotentGrades returns the grades submitted by the user with an idempotency key, by key.
+func getIdempotentGrades(username string) map[string]*idempotentGrade {
+	grades := map[string]*idempotentGrade{}
+
+	// users have no grades submitted with an idempotency key until their first one.
+	if value, err := userState.Users().Get(username, keyIdempotentGrades); err == nil && value != "" {
+		if err = json.Unmarshal([]byte(value), &grades); err != nil {
+			return map[string]*idempotentGrade{}
+		}
+	}
+
+	return grades
+}
+
+// replayIdempotentGrade writes the result of the grade submitted by the user with the idempotency key, if any,
+// and returns whether it was written.
+// Retrying a grade submission gets the result of the original submission,
+// and reusing the key for another course or professor is rejected.
+func replayIdempotentGrade(w http.ResponseWriter, username, key string, gradeData *GradeData) bool {
+	idempotentGradesMu.Lock()
+	grade, ok := getIdempotentGrades(username)[key]
+	idempotentGradesMu.Unlock()
+
+	if !ok || time.Since(grade.SubmittedAt) > idempotencyKeyValidityTime {
+		return false
+	}
+
+	if grade.CourseCode != gradeData.CourseCode || grade.ProfUUID != gradeData.ProfUUID {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		responses.ErrIdempotencyKeyReused.WriteJSON(w)
+		return true
+	}
+
+	writeSuccess(w)
+
+	return true
+}
+
+// storeIdempotentGrade records the grade submitted by the user with the idempotency key,
+// removing the grades whose key is no longer valid.
+func storeIdempotentGrade(username, key string, gradeData *GradeData) error {
+	idempotentGradesMu.Lock()
+	defer idempotentGradesMu.Unlock()
+
+	grades := getIdempotentGrades(username)
+	for k, grade := range grades {
+		if time.Since(grade.SubmittedAt) > idempotencyKeyValidityTime {
+			delete(grades, k)
+		}
+	}
+
+	grades[key] = &idempotentGrade{CourseCode: gradeData.CourseCode, ProfUUID: gradeData.ProfUUID, SubmittedAt: time.Now()}
+
+	data, err := json.Marshal(grades)
+	if err != nil {
+		return err
+	}
+
+	return userState.Users().Set(username, keyIdempotentGrades, string(data))
+}
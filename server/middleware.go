@@ -2,9 +2,19 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
+	"fmt"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/vanillaiice/itpg/metrics"
 	"github.com/vanillaiice/itpg/responses"
 )
 
@@ -15,10 +25,24 @@ type contextKey string
 // the username for use in subsequent middleware.
 const usernameContextKey contextKey = "username"
 
+// requestIDContextKey is the key in the request's context to set
+// the id of the request.
+const requestIDContextKey contextKey = "request-id"
+
+// requestIDHeader is the response header holding the id of the request.
+const requestIDHeader = "X-Request-ID"
+
 // cookieExpiryuserStateKey is the key in the Userstate database
 // use to retrieve the expiry time of a session cookie.
 const cookieExpiryUserStateKey = "cookie-expiry"
 
+// cookieIssuedUserStateKey is the key in the Userstate database
+// used to retrieve the time a session cookie was issued.
+const cookieIssuedUserStateKey = "cookie-issued"
+
+// sessionCookieName is the name of the cookie holding the session token of a user.
+const sessionCookieName = "itpg-session"
+
 // checkCookieExpiry checks if the user's session cookie has expired.
 // If the cookie has expired, it logs out the user, writes an Unauthorized response, and returns an error.
 // It returns nil if the cookie is valid and has not expired.
@@ -43,6 +67,273 @@ func checkCookieExpiry(username string) error {
 	return nil
 }
 
+// checkSessionToken checks if the request carries the current session token of the user.
+// Users get a session token once their sessions are revoked for the first time,
+// so the sessions of users without a session token are always valid.
+func checkSessionToken(r *http.Request, username string) error {
+	token, err := userState.Users().Get(username, keySessionToken)
+	if err != nil {
+		return nil
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) != 1 {
+		return responses.ErrInvalidCookie
+	}
+
+	return nil
+}
+
+// serverHeaders are the response headers revealing the identity of the server.
+var serverHeaders = []string{"Server", "X-Powered-By"}
+
+// hideServerHeaderWriter is a response writer removing the server headers before writing them.
+type hideServerHeaderWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader removes the server headers, and writes the header with the status code.
+func (h *hideServerHeaderWriter) WriteHeader(statusCode int) {
+	if !h.wroteHeader {
+		for _, header := range serverHeaders {
+			h.Header().Del(header)
+		}
+		h.wroteHeader = true
+	}
+	h.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the header with the 200 status code if not yet written, and writes the data.
+func (h *hideServerHeaderWriter) Write(b []byte) (int, error) {
+	if !h.wroteHeader {
+		h.WriteHeader(http.StatusOK)
+	}
+	return h.ResponseWriter.Write(b)
+}
+
+// hideServerHeaderMiddleware is a negroni middleware removing the headers revealing the identity of the server from responses.
+func hideServerHeaderMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	next(&hideServerHeaderWriter{ResponseWriter: w}, r)
+}
+
+// publicCacheWriter is a response writer allowing clients and proxies to cache successful responses.
+type publicCacheWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader sets the Cache-Control header if the status code is successful, and writes the header with the status code.
+func (p *publicCacheWriter) WriteHeader(statusCode int) {
+	if !p.wroteHeader {
+		if statusCode >= 200 && statusCode < 300 {
+			p.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", publicCacheMaxAge))
+		} else {
+			p.Header().Set("Cache-Control", "no-store")
+		}
+		p.wroteHeader = true
+	}
+	p.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the header with the 200 status code if not yet written, and writes the data.
+func (p *publicCacheWriter) Write(b []byte) (int, error) {
+	if !p.wroteHeader {
+		p.WriteHeader(http.StatusOK)
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+// publicCacheMiddleware is a middleware allowing clients and proxies to cache the successful responses
+// of a public read for publicCacheMaxAge seconds. It does nothing if publicCacheMaxAge is 0.
+func publicCacheMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if publicCacheMaxAge == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&publicCacheWriter{ResponseWriter: w}, r)
+	}
+}
+
+// optionalSessionMiddleware is a middleware setting the username in the request's context on public paths
+// if the request carries a valid session cookie, so that authenticated users are not limited to publicMaxResults results.
+// It does nothing if publicMaxResults is 0.
+func optionalSessionMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if publicMaxResults == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the response depends on the session cookie, so shared caches must not serve it to other users.
+		w.Header().Add("Vary", "Cookie")
+
+		if username, err := userState.UsernameCookie(r); err == nil && checkCookieExpiry(username) == nil && checkSessionToken(r, username) == nil {
+			r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, username))
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// statusWriter is a response writer recording the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader records the status code, and writes the header with the status code.
+func (s *statusWriter) WriteHeader(statusCode int) {
+	if s.statusCode == 0 {
+		s.statusCode = statusCode
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the 200 status code if the header was not yet written, and writes the data.
+func (s *statusWriter) Write(b []byte) (int, error) {
+	if s.statusCode == 0 {
+		s.statusCode = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped response writer.
+func (s *statusWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// requestLogMiddleware is a negroni middleware assigning a uuid to each request, returned in the X-Request-ID header.
+// The id is set in the request's context, along with a logger including it in every entry,
+// which logs the method, path, status, and latency of the request once it is handled.
+func requestLogMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		log.Error().Msg(err.Error())
+		next(w, r)
+		return
+	}
+
+	logger := log.With().Str("request_id", id.String()).Logger()
+	ctx := context.WithValue(logger.WithContext(r.Context()), requestIDContextKey, id.String())
+
+	w.Header().Set(requestIDHeader, id.String())
+	sw := &statusWriter{ResponseWriter: w}
+	next(sw, r.WithContext(ctx))
+
+	if sw.statusCode == 0 {
+		sw.statusCode = http.StatusOK
+	}
+	logger.Info().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Int("status", sw.statusCode).
+		Dur("latency", time.Since(start)).
+		Msg("request")
+}
+
+// requestLogger returns the logger of a request, set by requestLogMiddleware,
+// or the global logger if the request has none.
+func requestLogger(r *http.Request) *zerolog.Logger {
+	if logger := zerolog.Ctx(r.Context()); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &log.Logger
+}
+
+// metricsMiddleware is a middleware recording the number and duration of the requests to a handler, if metrics are enabled.
+func metricsMiddleware(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !metrics.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if sw.statusCode == 0 {
+			sw.statusCode = http.StatusOK
+		}
+		metrics.HttpRequests.Inc(name, r.Method, strconv.Itoa(sw.statusCode))
+		metrics.HttpRequestDuration.ObserveSince(start, name)
+	})
+}
+
+// noStoreMiddleware is a middleware preventing clients and proxies from storing the responses of authenticated requests.
+func noStoreMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	}
+}
+
+// safeMethods are the HTTP methods that do not change the state of the server.
+var safeMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// requireOriginMiddleware returns a negroni middleware rejecting state-changing requests
+// without an Origin header matching one of the allowed origins, with a Forbidden response.
+// The "*" origin allows any origin, but the Origin header must still be present.
+func requireOriginMiddleware(allowedOrigins []string) func(http.ResponseWriter, *http.Request, http.HandlerFunc) {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if slices.Contains(safeMethods, r.Method) {
+			next(w, r)
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		if origin == "" || !slices.ContainsFunc(allowedOrigins, func(o string) bool { return o == "*" || strings.EqualFold(o, origin) }) {
+			w.WriteHeader(http.StatusForbidden)
+			responses.ErrInvalidOrigin.WriteJSON(w)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// trustProxy makes the ip filters read the ip address of clients from the X-Forwarded-For header.
+// It should only be set when the server is behind a reverse proxy setting the header.
+var trustProxy bool
+
+// deniedIPs are the ip ranges denied access to the server.
+var deniedIPs []netip.Prefix
+
+// adminAllowedIPs are the only ip ranges allowed to access the admin routes (empty to allow all ip addresses).
+var adminAllowedIPs []netip.Prefix
+
+// denyIPMiddleware is a negroni middleware rejecting requests from the denied ip ranges with a Forbidden response.
+func denyIPMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if inIPRanges(deniedIPs, filteredIP(r)) {
+		w.WriteHeader(http.StatusForbidden)
+		responses.ErrPermissionDenied.WriteJSON(w)
+		return
+	}
+
+	next(w, r)
+}
+
+// maintenanceHeader is the header set on all responses in maintenance mode.
+const maintenanceHeader = "X-ITPG-Maintenance"
+
+// maintenanceMiddleware is a negroni middleware putting the server in read-only maintenance mode.
+// It sets the maintenance header on all responses, and rejects state-changing requests with a Service Unavailable response.
+func maintenanceMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	w.Header().Set(maintenanceHeader, "true")
+
+	if !slices.Contains(safeMethods, r.Method) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		responses.ErrMaintenance.WriteJSON(w)
+		return
+	}
+
+	next(w, r)
+}
+
 // DummyMiddleware is middleware that does nothing.
 // It is used to wrap the go-chi/httprate limiter around a handler.
 func DummyMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -51,8 +342,8 @@ func DummyMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// checkCookieExpiryMiddleware is a middleware that checks if the user's session cookie has expired.
-// If the cookie has expired, it writes an Unauthorized response and returns.
+// checkCookieExpiryMiddleware is a middleware that checks if the user's session cookie has expired or was revoked.
+// If the cookie has expired or was revoked, it writes an error response and returns.
 // It calls the next handler if the cookie is valid and has not expired.
 func checkCookieExpiryMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -63,7 +354,11 @@ func checkCookieExpiryMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		if expired := checkCookieExpiry(username); expired == nil {
+		if err = checkCookieExpiry(username); err == nil {
+			err = checkSessionToken(r, username)
+		}
+
+		if err == nil {
 			r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, username))
 			next.ServeHTTP(w, r)
 		} else {
@@ -104,6 +399,21 @@ func checkConfirmedMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// checkAdminIPMiddleware is a middleware that checks if the client ip address is allowed to access the admin routes.
+// If it is not, it writes a Forbidden response.
+// It calls the next handler if the ip address is allowed.
+func checkAdminIPMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(adminAllowedIPs) != 0 && !inIPRanges(adminAllowedIPs, filteredIP(r)) {
+			w.WriteHeader(http.StatusForbidden)
+			responses.ErrPermissionDenied.WriteJSON(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
 func checkAdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		username, ok := r.Context().Value(usernameContextKey).(string)
@@ -138,7 +448,7 @@ func checkSuperAdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		if !userState.BooleanField(username, "super") {
+		if !userState.BooleanField(username, keySuperAdmin) {
 			w.WriteHeader(http.StatusUnauthorized)
 			responses.ErrNotSuperAdmin.WriteJSON(w)
 			return
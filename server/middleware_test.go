@@ -7,9 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/vanillaiice/itpg/metrics"
 	"github.com/vanillaiice/itpg/responses"
 	"github.com/xyproto/permissionbolt/v2"
 )
@@ -393,3 +398,336 @@ func TestCheckSuperAdminMiddleware_SuperAdmin(t *testing.T) {
 		t.Errorf("got %v, want %v", w.Code, http.StatusOK)
 	}
 }
+
+func TestCheckSuperAdminMiddleware_ManageUsers(t *testing.T) {
+	err := initTestUserState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeUserState()
+
+	admin, super := "admin@admin.com", "super@super.com"
+	for _, username := range []string{creds.Email, admin, super} {
+		userState.AddUser(username, creds.Password, "")
+		userState.Confirm(username)
+	}
+	userState.SetAdminStatus(admin)
+	userState.SetAdminStatus(super)
+	userState.SetBooleanField(super, "super", true)
+
+	tests := []struct {
+		handler  http.HandlerFunc
+		username string
+		target   string
+		code     int
+	}{
+		{promoteUser, admin, "/admin/users/promote?email=" + creds.Email, http.StatusUnauthorized},
+		{deleteUser, admin, "/admin/users?email=" + super, http.StatusUnauthorized},
+		{deleteUser, super, "/admin/users?email=" + super, http.StatusForbidden},
+		{demoteUser, super, "/admin/users/demote?email=" + super, http.StatusForbidden},
+		{promoteUser, super, "/admin/users/promote?email=" + creds.Email, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, test.target, nil)
+		r = r.WithContext(context.WithValue(r.Context(), usernameContextKey, test.username))
+		w := httptest.NewRecorder()
+		checkSuperAdminMiddleware(test.handler).ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s %s: got %v, want %v", test.username, test.target, w.Code, test.code)
+		}
+	}
+
+	if !userState.HasUser(super) || !userState.IsAdmin(super) {
+		t.Errorf("expected %s to still be a registered admin", super)
+	}
+	if !userState.IsAdmin(creds.Email) {
+		t.Errorf("expected %s to be promoted", creds.Email)
+	}
+}
+
+func TestHideServerHeaderMiddleware(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{
+		"write": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", "itpg/1.0")
+			w.Header().Set("X-Powered-By", "go")
+			w.Write([]byte("ok")) //nolint:errcheck
+		},
+		"writeHeader": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", "itpg/1.0")
+			w.Header().Set("X-Powered-By", "go")
+			w.WriteHeader(http.StatusTeapot)
+		},
+	}
+
+	for name, handler := range handlers {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/ping", nil)
+		hideServerHeaderMiddleware(w, r, handler)
+
+		for _, header := range serverHeaders {
+			if v := w.Result().Header.Get(header); v != "" {
+				t.Errorf("%s: got %s header %q, want none", name, header, v)
+			}
+		}
+	}
+}
+
+func TestRequireOriginMiddleware(t *testing.T) {
+	middleware := requireOriginMiddleware([]string{"https://itpg.cc"})
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		method string
+		origin string
+		status int
+	}{
+		{"POST", "https://itpg.cc", http.StatusOK},
+		{"DELETE", "https://ITPG.cc", http.StatusOK},
+		{"POST", "https://evil.com", http.StatusForbidden},
+		{"POST", "", http.StatusForbidden},
+		{"GET", "", http.StatusOK},
+		{"GET", "https://evil.com", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "/course/grade", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		middleware(w, r, next)
+		if w.Code != test.status {
+			t.Errorf("%s %q: got %v, want %v", test.method, test.origin, w.Code, test.status)
+		}
+		if test.status == http.StatusForbidden && w.Body.String() != responses.ErrInvalidOrigin.Error() {
+			t.Errorf("%s %q: got %s, want %s", test.method, test.origin, w.Body.String(), responses.ErrInvalidOrigin.Error())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/course/grade", nil)
+	r.Header.Set("Origin", "https://any.com")
+	requireOriginMiddleware([]string{"*"})(w, r, next)
+	if w.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		method string
+		status int
+	}{
+		{"GET", http.StatusOK},
+		{"OPTIONS", http.StatusOK},
+		{"POST", http.StatusServiceUnavailable},
+		{"DELETE", http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "/course/grade", nil)
+		maintenanceMiddleware(w, r, next)
+		if w.Code != test.status {
+			t.Errorf("%s: got %v, want %v", test.method, w.Code, test.status)
+		}
+		if header := w.Header().Get(maintenanceHeader); header != "true" {
+			t.Errorf("%s: got %s header %q, want %q", test.method, maintenanceHeader, header, "true")
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/course/grade", nil)
+	next(w, r)
+	if header := w.Header().Get(maintenanceHeader); header != "" {
+		t.Errorf("got %s header %q, want none", maintenanceHeader, header)
+	}
+}
+
+func TestPublicCacheMiddleware(t *testing.T) {
+	publicCacheMaxAge = 60
+	defer func() { publicCacheMaxAge = 0 }()
+
+	tests := []struct {
+		handler  http.HandlerFunc
+		expected string
+	}{
+		{func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }, "public, max-age=60"}, //nolint:errcheck
+		{func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }, "public, max-age=60"},
+		{func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }, "no-store"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/course/all", nil)
+		publicCacheMiddleware(test.handler)(w, r)
+		if v := w.Result().Header.Get("Cache-Control"); v != test.expected {
+			t.Errorf("got %q, want %q", v, test.expected)
+		}
+	}
+
+	publicCacheMaxAge = 0
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/course/all", nil)
+	publicCacheMiddleware(tests[0].handler)(w, r)
+	if v := w.Result().Header.Get("Cache-Control"); v != "" {
+		t.Errorf("got %q, want none", v)
+	}
+}
+
+func TestNoStoreMiddleware(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/ping", nil)
+	noStoreMiddleware(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })(w, r) //nolint:errcheck
+	if v := w.Result().Header.Get("Cache-Control"); v != "no-store" {
+		t.Errorf("got %q, want %q", v, "no-store")
+	}
+}
+
+func TestIPMiddlewares(t *testing.T) {
+	var err error
+	if deniedIPs, err = parseIPRanges([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	if adminAllowedIPs, err = parseIPRanges([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { deniedIPs, adminAllowedIPs, trustProxy = nil, nil, false }()
+
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		name                    string
+		remoteAddr, forwarded   string
+		trustProxy              bool
+		wantDeny, wantAdminDeny bool
+	}{
+		{"allowed", "10.0.0.1:1234", "", false, false, false},
+		{"not admin", "192.0.2.1:1234", "", false, false, true},
+		{"denied", "203.0.113.7:1234", "", false, true, true},
+		{"untrusted forwarded", "10.0.0.1:1234", "203.0.113.7", false, false, false},
+		{"trusted forwarded", "10.0.0.1:1234", "203.0.113.7", true, true, true},
+		{"trusted forwarded admin", "192.0.2.1:1234", "10.0.0.1", true, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trustProxy = test.trustProxy
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+			if test.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", test.forwarded)
+			}
+
+			for _, c := range []struct {
+				serve    func(w http.ResponseWriter, r *http.Request)
+				wantDeny bool
+			}{
+				{func(w http.ResponseWriter, r *http.Request) { denyIPMiddleware(w, r, next) }, test.wantDeny},
+				{checkAdminIPMiddleware(next), test.wantAdminDeny},
+			} {
+				w := httptest.NewRecorder()
+				c.serve(w, r)
+
+				want := http.StatusOK
+				if c.wantDeny {
+					want = http.StatusForbidden
+				}
+				if w.Code != want {
+					t.Errorf("got %v, want %v", w.Code, want)
+				}
+				if c.wantDeny && w.Body.String() != responses.ErrPermissionDenied.Error() {
+					t.Errorf("got %s, want %s", w.Body.String(), responses.ErrPermissionDenied.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	metrics.Enable(true)
+	defer metrics.Enable(false)
+
+	handler := metricsMiddleware("testHandler", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fail") {
+			w.WriteHeader(http.StatusBadRequest)
+			responses.ErrBadRequest.WriteJSON(w)
+			return
+		}
+		responses.Success.WriteJSON(w)
+	}))
+
+	for _, target := range []string{"/test", "/test", "/test?fail"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+
+	for _, want := range []string{
+		`itpg_http_requests_total{code="200",handler="testHandler",method="GET"} 2`,
+		`itpg_http_requests_total{code="400",handler="testHandler",method="GET"} 1`,
+		`itpg_http_request_duration_seconds_count{handler="testHandler"} 3`,
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, rr.Body.String())
+		}
+	}
+}
+
+func TestRequestLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = defaultLogger }()
+
+	var contextID string
+	next := func(w http.ResponseWriter, r *http.Request) {
+		contextID, _ = r.Context().Value(requestIDContextKey).(string)
+		requestLogger(r).Error().Msg("handler failed")
+		w.WriteHeader(http.StatusTeapot)
+	}
+
+	w := httptest.NewRecorder()
+	requestLogMiddleware(w, httptest.NewRequest(http.MethodGet, "/course/grade", nil), next)
+
+	id := w.Header().Get(requestIDHeader)
+	if _, err := uuid.FromString(id); err != nil {
+		t.Fatalf("got request id %q, want a uuid", id)
+	}
+	if contextID != id {
+		t.Errorf("got request id %q in the context, want %q", contextID, id)
+	}
+
+	var entries []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		entry := map[string]any{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		if entry["request_id"] != id {
+			t.Errorf("got request id %v in %v, want %s", entry["request_id"], entry, id)
+		}
+	}
+
+	access := entries[1]
+	if access["method"] != http.MethodGet || access["path"] != "/course/grade" || access["status"] != float64(http.StatusTeapot) {
+		t.Errorf("got unexpected request log entry %v", access)
+	}
+	if _, ok := access["latency"]; !ok {
+		t.Errorf("expected the latency in the request log entry %v", access)
+	}
+}
@@ -0,0 +1,465 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/mail"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+// openAPIVersion is the version of the OpenAPI specification of the OpenAPI document.
+const openAPIVersion = "3.0.3"
+
+// openAPIDocumentVersion is the version of the API described by the OpenAPI document.
+const openAPIDocumentVersion = "1"
+
+// openAPICookieAuth is the name of the security scheme of the paths needing a session cookie.
+const openAPICookieAuth = "cookieAuth"
+
+// openAPIDocument is the OpenAPI document of the registered handlers, generated at startup.
+var openAPIDocument []byte
+
+// handlerDoc describes a handler in the OpenAPI document.
+type handlerDoc struct {
+	summary  string   // Summary of the operation.
+	request  any      // Value of the type of the JSON request body, if any.
+	response any      // Value of the type of the message of the successful responses, if any, the message being a string otherwise.
+	params   []string // Names of the query parameters, or of the form fields of the request body for the POST and PUT handlers.
+	raw      bool     // Whether the handler writes its own body, instead of a JSON response.
+}
+
+// handlerDocs are the descriptions of the handlers in the OpenAPI document, by handler name.
+var handlerDocs = map[string]*handlerDoc{
+	"gradeCourseProfessor":                {summary: "Grade a professor in a course", request: GradeData{}},
+	"updateGrade":                         {summary: "Update the grade of a professor in a course", request: GradeData{}},
+	"deleteGrade":                         {summary: "Delete the grade of a professor in a course", params: []string{"uuid", "code"}},
+	"gradeCourseProfessorBatch":           {summary: "Grade many professors in their courses at once", request: []*GradeData{}, response: []*GradeResult{}},
+	"checkGradedBatch":                    {summary: "Check whether professors were graded in their courses", request: []*db.CourseProfessor{}, response: []bool{}},
+	"getEligibleGrades":                   {summary: "Get the courses and professors the user can still grade", params: []string{"limit"}, response: []*db.EligibleGrade{}},
+	"refreshCookie":                       {summary: "Refresh the session cookie"},
+	"logout":                              {summary: "Log out"},
+	"logoutAll":                           {summary: "Log out of all sessions"},
+	"getSessions":                         {summary: "Get the current session", response: Session{}},
+	"clearCookie":                         {summary: "Clear the session cookie"},
+	"changePassword":                      {summary: "Change the password", request: CredentialsChange{}},
+	"changeEmail":                         {summary: "Request a change of email address", request: Credentials{}},
+	"confirmEmailChange":                  {summary: "Confirm a change of email address", request: CredentialsEmailChange{}},
+	"deleteAccount":                       {summary: "Delete the account", request: Credentials{}},
+	"ping":                                {summary: "Check whether the session is valid", raw: true},
+	"getLastCourses":                      {summary: "Get the courses", params: []string{"limit", "offset"}, response: []*db.Course{}},
+	"getLastProfessors":                   {summary: "Get the professors", params: []string{"sort", "limit", "offset"}, response: []*db.Professor{}},
+	"getLastScores":                       {summary: "Get the scores", params: []string{"limit", "offset"}, response: []*db.Score{}},
+	"getCoursesBetween":                   {summary: "Get the courses added within a time range", params: []string{"from", "to"}, response: []*db.Course{}},
+	"getProfessorsBetween":                {summary: "Get the professors added within a time range", params: []string{"from", "to"}, response: []*db.Professor{}},
+	"getUnratedProfessors":                {summary: "Get the professors without grades", response: []*db.Professor{}},
+	"getTopProfessors":                    {summary: "Get the best rated professors", params: []string{"limit", "by"}, response: []*db.ProfessorRating{}},
+	"getBottomRatedProfessors":            {summary: "Get the worst rated professors", params: []string{"limit"}, response: []*db.ProfessorRating{}},
+	"getMostRatedCourses":                 {summary: "Get the most rated courses", params: []string{"limit"}, response: []*db.PopularCourse{}},
+	"getRandomCourses":                    {summary: "Get random courses", params: []string{"n"}, response: []*db.Course{}},
+	"getCatalogSync":                      {summary: "Get the courses and professors added since a version token", params: []string{"since"}, response: &CatalogSync{}},
+	"getCoursesByProfessorUUID":           {summary: "Get the courses of a professor", response: []*db.Course{}},
+	"getUngradedCoursesByProfessorUUID":   {summary: "Get the courses of a professor without grades", response: []*db.Course{}},
+	"getProfessorsForCourses":             {summary: "Get the professors of courses with their scores", params: []string{"codes"}, response: map[string][]*db.Score{}},
+	"getProfessorsByCourseCode":           {summary: "Get the professors of a course", response: []*db.Professor{}},
+	"getProfessorDetail":                  {summary: "Get a professor with their courses and scores", response: db.ProfessorDetail{}},
+	"getProfessorRank":                    {summary: "Get the rank of a professor in their department", response: db.ProfessorRank{}},
+	"getCourseDetail":                     {summary: "Get a course with its professors and scores", response: db.CourseDetail{}},
+	"getScoresByProfessorUUID":            {summary: "Get the scores of a professor", params: []string{"from", "to"}, response: []*db.Score{}},
+	"getScoresByProfessorName":            {summary: "Get the scores of a professor by name", response: []*db.Score{}},
+	"getScoresByProfessorNameLike":        {summary: "Get the scores of the professors matching a name", params: []string{"mode"}, response: []*db.Score{}},
+	"getScoresByCourseName":               {summary: "Get the scores of a course by name", response: []*db.Score{}},
+	"getScoresByCourseNameLike":           {summary: "Get the scores of the courses matching a name", params: []string{"mode"}, response: []*db.Score{}},
+	"getScoresByCourseCode":               {summary: "Get the scores of a course", params: []string{"from", "to"}, response: []*db.Score{}},
+	"getScoresByCourseCodeLike":           {summary: "Get the scores of the courses matching a code", response: []*db.Score{}},
+	"getScoresBySearch":                   {summary: "Search the scores", params: []string{"q"}, response: []*db.Score{}},
+	"search":                              {summary: "Search the courses and professors", params: []string{"q"}, response: []*db.SearchResult{}},
+	"getHealth":                           {summary: "Check whether the server is alive"},
+	"getReadiness":                        {summary: "Check whether the server is ready", response: []*DependencyStatus{}},
+	"getScoreTrend":                       {summary: "Get the scores of a professor in a course over time", params: []string{"uuid", "code", "bucket"}, response: []*db.ScoreTrendPoint{}},
+	"getScoreHistoryByCourseCode":         {summary: "Get the scores of a course over time", response: []*db.ScoreTrendPoint{}},
+	"getScoreDimensions":                  {summary: "Get the extra score dimensions of a professor in a course", params: []string{"uuid", "code"}, response: []*db.DimensionScore{}},
+	"getScoreDistributionByProfessorUUID": {summary: "Get the distribution of the grades of a professor", response: db.ScoreDistribution{}},
+	"login":                               {summary: "Log in", request: Credentials{}},
+	"register":                            {summary: "Register", request: Credentials{}},
+	"confirm":                             {summary: "Confirm the account", params: []string{"code"}},
+	"validateCode":                        {summary: "Check whether a confirmation or reset code is valid", params: []string{"code", "type"}, response: CodeValidity{}},
+	"sendNewConfirmationCode":             {summary: "Send a new confirmation code", request: Credentials{}},
+	"sendResetLink":                       {summary: "Send a password reset link", params: []string{"email"}},
+	"resetPassword":                       {summary: "Reset the password", request: CredentialsReset{}},
+	"addCourse":                           {summary: "Add a course", params: []string{"code", "name", "department", "credits"}},
+	"addCourseMany":                       {summary: "Add many courses", request: []*db.Course{}, response: []*ImportResult{}},
+	"updateCourse":                        {summary: "Rename a course", params: []string{"code", "name"}},
+	"removeCourse":                        {summary: "Remove a course", params: []string{"code"}},
+	"removeCourseForce":                   {summary: "Remove a course with its scores", params: []string{"code"}},
+	"softRemoveCourse":                    {summary: "Move a course to the trash", params: []string{"code"}},
+	"restoreCourse":                       {summary: "Restore a course from the trash", params: []string{"code"}},
+	"addCourseProfessor":                  {summary: "Add a professor to a course", params: []string{"uuid", "code"}},
+	"removeCourseProfessor":               {summary: "Remove a professor from a course", params: []string{"uuid", "code"}},
+	"addProfessor":                        {summary: "Add a professor", params: []string{"fullname"}},
+	"addProfessorMany":                    {summary: "Add many professors", request: []string{}, response: []*ImportResult{}},
+	"renameProfessor":                     {summary: "Rename a professor", params: []string{"uuid", "fullname"}},
+	"updateProfessor":                     {summary: "Update the name of a professor", params: []string{"uuid", "fullname"}},
+	"removeProfessor":                     {summary: "Remove a professor", params: []string{"uuid"}},
+	"removeProfessorForce":                {summary: "Remove a professor with their scores", params: []string{"uuid"}},
+	"softRemoveProfessor":                 {summary: "Move a professor to the trash", params: []string{"uuid"}},
+	"restoreProfessor":                    {summary: "Restore a professor from the trash", params: []string{"uuid"}},
+	"getTrash":                            {summary: "Get the courses and professors in the trash", response: db.Trash{}},
+	"getAllUsers":                         {summary: "Get the users", params: []string{"confirmed"}, response: []*UserInfo{}},
+	"verifyUser":                          {summary: "Verify a user", params: []string{"username", "verified"}},
+	"getRawGrades":                        {summary: "Get the grades of a professor in a course", params: []string{"uuid", "code"}, response: []*db.RawGrade{}},
+	"flushCache":                          {summary: "Flush the cache"},
+	"exportData":                          {summary: "Export the courses, professors, and scores", params: []string{"format"}, raw: true},
+	"exportScores":                        {summary: "Export the scores", params: []string{"format", "since"}, raw: true},
+	"getUsersPage":                        {summary: "Get a page of the users", params: []string{"limit", "offset"}, response: []*UserInfo{}},
+	"confirmUser":                         {summary: "Confirm a user", params: []string{"email"}},
+	"promoteUser":                         {summary: "Make a user an admin", params: []string{"email"}},
+	"demoteUser":                          {summary: "Make an admin a user", params: []string{"email"}},
+	"deleteUser":                          {summary: "Delete a user", params: []string{"email"}},
+	"getMailQueue":                        {summary: "Get the pending and failed mails", response: mail.QueueStatus{}},
+	"getDepartmentStats":                  {summary: "Get the statistics of the departments", response: []*db.DepartmentStats{}},
+	"getComponentAverages":                {summary: "Get the average of each score", response: db.ComponentAverages{}},
+	"getStats":                            {summary: "Get the number of courses, professors, grades, and users", response: db.Stats{}},
+	"getPostmanCollection":                {summary: "Download a Postman collection of the API", raw: true},
+	"getOpenAPI":                          {summary: "Get the OpenAPI document of the API", raw: true},
+	"getGradeAttemptsByCourseCode":        {summary: "Get the accepted and rejected grades of a course", params: []string{"window"}, response: db.GradeAttempts{}},
+}
+
+// OpenAPIDocument represents an OpenAPI document, in the 3.0 format.
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Tags       []*OpenAPITag                           `json:"tags"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+// OpenAPIInfo represents the information of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPITag represents a tag grouping the operations of an OpenAPI document.
+type OpenAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OpenAPIOperation represents an operation of an OpenAPI document.
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Tags        []string                    `json:"tags"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter represents a path or query parameter of an operation.
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIRequestBody represents the request body of an operation.
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse represents a response of an operation.
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType represents the content of a request or response body.
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIComponents represents the reusable schemas and security schemes of an OpenAPI document.
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema         `json:"schemas"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes"`
+}
+
+// OpenAPISecurityScheme represents a security scheme of an OpenAPI document.
+type OpenAPISecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+// OpenAPISchema represents a schema of an OpenAPI document, or a reference to one.
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	AllOf                []*OpenAPISchema          `json:"allOf,omitempty"`
+}
+
+// getOpenAPI handles the HTTP request to get the OpenAPI document of the registered handlers.
+func getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// newOpenAPIDocument returns the OpenAPI document of the handlers, with a tag for each path type.
+// The request and response schemas of the handlers come from their description in handlerDocs,
+// and are derived from the json tags of the types of the request bodies and of the response messages.
+func newOpenAPIDocument(handlers []*HandlerInfo) (*OpenAPIDocument, error) {
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    OpenAPIInfo{Title: "itpg", Version: openAPIDocumentVersion},
+		Paths:   map[string]map[string]*OpenAPIOperation{},
+		Components: OpenAPIComponents{
+			Schemas:         map[string]*OpenAPISchema{},
+			SecuritySchemes: map[string]*OpenAPISecurityScheme{openAPICookieAuth: {Type: "apiKey", In: "cookie", Name: "user"}},
+		},
+	}
+
+	for _, folder := range postmanFolders {
+		doc.Tags = append(doc.Tags, &OpenAPITag{Name: folder, Description: postmanFolderDescription(pathTypeMap[folder])})
+	}
+
+	schemas := &openAPISchemas{schemas: doc.Components.Schemas, types: map[string]reflect.Type{}}
+	responseRef := schemas.schemaOf(reflect.TypeOf(responses.Response{}))
+
+	operationIDs := map[string]int{}
+	for _, h := range handlers {
+		hd, ok := handlerDocs[h.name]
+		if !ok {
+			hd = &handlerDoc{}
+		}
+
+		path, params := openAPIPath(h.path)
+
+		// handlers can be registered on many paths, but operation ids are unique.
+		operationIDs[h.name]++
+		operation := &OpenAPIOperation{
+			OperationID: h.name,
+			Summary:     hd.summary,
+			Tags:        []string{pathTypeName(h.pathType)},
+			Parameters:  params,
+			Responses:   map[string]*OpenAPIResponse{},
+		}
+		if n := operationIDs[h.name]; n > 1 {
+			operation.OperationID = fmt.Sprintf("%s%d", h.name, n)
+		}
+
+		if h.pathType != publicPath {
+			operation.Security = []map[string][]string{{openAPICookieAuth: {}}}
+		}
+
+		switch {
+		case hd.request != nil:
+			operation.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  map[string]*OpenAPIMediaType{"application/json": {Schema: schemas.schemaOf(reflect.TypeOf(hd.request))}},
+			}
+		case len(hd.params) > 0 && hasFormBody(h.method):
+			form := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+			for _, p := range hd.params {
+				form.Properties[p] = &OpenAPISchema{Type: "string"}
+			}
+			operation.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  map[string]*OpenAPIMediaType{"application/x-www-form-urlencoded": {Schema: form}},
+			}
+		}
+
+		if !hasFormBody(h.method) {
+			for _, p := range hd.params {
+				operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: p, In: "query", Schema: &OpenAPISchema{Type: "string"}})
+			}
+		}
+
+		if hd.raw {
+			operation.Responses["200"] = &OpenAPIResponse{Description: "Success"}
+		} else {
+			message := &OpenAPISchema{Type: "string"}
+			if hd.response != nil {
+				message = schemas.schemaOf(reflect.TypeOf(hd.response))
+			}
+			operation.Responses["200"] = &OpenAPIResponse{
+				Description: "Success",
+				Content: map[string]*OpenAPIMediaType{"application/json": {Schema: &OpenAPISchema{AllOf: []*OpenAPISchema{
+					responseRef,
+					{Type: "object", Properties: map[string]*OpenAPISchema{"message": message}},
+				}}}},
+			}
+		}
+		operation.Responses["default"] = &OpenAPIResponse{
+			Description: "Error",
+			Content:     map[string]*OpenAPIMediaType{"application/json": {Schema: responseRef}},
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*OpenAPIOperation{}
+		}
+		// as with the router, the first handler registered for a path and a method handles its requests.
+		method := strings.ToLower(h.method)
+		if _, ok := doc.Paths[path][method]; !ok {
+			doc.Paths[path][method] = operation
+		}
+	}
+
+	return doc, nil
+}
+
+// openAPIPath returns the path of a handler in an OpenAPI document, and its path parameters.
+// The route variables of the path, such as {uuid} or {uuid:[0-9a-f-]+}, are written without their pattern, such as {uuid}.
+func openAPIPath(path string) (string, []*OpenAPIParameter) {
+	var params []*OpenAPIParameter
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name, _, _ := strings.Cut(strings.Trim(segment, "{}"), ":")
+			params = append(params, &OpenAPIParameter{Name: name, In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"}})
+			segments[i] = "{" + name + "}"
+		}
+	}
+
+	return "/" + strings.Join(segments, "/"), params
+}
+
+// hasFormBody returns whether the form values of the requests with the method are read from their body, instead of their query.
+func hasFormBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut
+}
+
+// pathTypeName returns the name of a path type, as written in the handler config.
+func pathTypeName(pathType PathType) string {
+	for name, t := range pathTypeMap {
+		if t == pathType {
+			return name
+		}
+	}
+	return ""
+}
+
+// openAPISchemas derives the schemas of Go types, adding the schemas of the structs to the components of an OpenAPI document.
+type openAPISchemas struct {
+	schemas map[string]*OpenAPISchema // schemas are the schemas of the components, by name.
+	types   map[string]reflect.Type   // types are the types of the schemas of the components, by name.
+}
+
+// timeType is the type of time.Time, written as a date-time string.
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the schema of the type, which is a reference to a component for structs.
+func (s *openAPISchemas) schemaOf(t reflect.Type) *OpenAPISchema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schemaOf(t.Elem())
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice:
+		// byte slices are written as base64 strings.
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &OpenAPISchema{Type: "array", Items: s.schemaOf(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &OpenAPISchema{Type: "string", Format: "date-time"}
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + s.component(t)}
+	default:
+		// interfaces can hold any value.
+		return &OpenAPISchema{}
+	}
+}
+
+// component adds the schema of the struct to the components if not yet added, and returns its name.
+// The name of the schema is the name of the struct, prefixed by the name of its package if another struct has the same name.
+func (s *openAPISchemas) component(t reflect.Type) string {
+	name := t.Name()
+	if other, ok := s.types[name]; ok && other != t {
+		name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + name
+	}
+	if _, ok := s.types[name]; ok {
+		return name
+	}
+
+	schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+	s.types[name] = t
+	s.schemas[name] = schema
+	s.addFields(schema, t)
+
+	return name
+}
+
+// addFields adds the properties of the fields of the struct to the schema, following the encoding/json rules:
+// the fields of embedded structs are promoted, and the fields without omitempty are required.
+func (s *openAPISchemas) addFields(schema *OpenAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.addFields(schema, f.Type)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		schema.Properties[name] = s.schemaOf(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// buildOpenAPIDocument generates the OpenAPI document of the handlers, served by getOpenAPI.
+func buildOpenAPIDocument(handlers []*HandlerInfo) (err error) {
+	doc, err := newOpenAPIDocument(handlers)
+	if err != nil {
+		return
+	}
+
+	openAPIDocument, err = json.Marshal(doc)
+
+	return
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestGetOpenAPI(t *testing.T) {
+	handlers, err := loadHandlers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = buildOpenAPIDocument(handlers); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { openAPIDocument = nil }()
+
+	r := httptest.NewRequest("GET", "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	getOpenAPI(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(rr.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		t.Fatalf("got invalid OpenAPI document: %v", err)
+	}
+
+	var count int
+	for _, path := range doc.Paths {
+		count += len(path.Operations())
+	}
+	// each registered handler is an operation.
+	if count != len(handlers) {
+		t.Errorf("got %d operations, want %d", count, len(handlers))
+	}
+
+	login := doc.Paths.Find("/login")
+	if login == nil || login.Post == nil {
+		t.Fatal("expected the login operation")
+	}
+	if ref := login.Post.RequestBody.Value.Content.Get("application/json").Schema.Ref; ref != "#/components/schemas/Credentials" {
+		t.Errorf("got login request body %s, want the credentials", ref)
+	}
+	if login.Post.Security != nil {
+		t.Error("got security requirements for a public path, want none")
+	}
+
+	grade := doc.Paths.Find("/course/grade")
+	if grade == nil || grade.Post == nil {
+		t.Fatal("expected the grade operation")
+	}
+	if grade.Post.Security == nil || len(*grade.Post.Security) != 1 {
+		t.Errorf("got security requirements %v, want the session cookie", grade.Post.Security)
+	}
+	gradeData := doc.Components.Schemas["GradeData"].Value
+	for _, property := range []string{"code", "uuid", "teaching", "coursework", "learning"} {
+		if _, ok := gradeData.Properties[property]; !ok || !slices.Contains(gradeData.Required, property) {
+			t.Errorf("expected the required %s property in the grade data schema", property)
+		}
+	}
+	if slices.Contains(gradeData.Required, "comment") {
+		t.Error("got the comment of the grade data as required, want optional")
+	}
+
+	courses := doc.Paths.Find("/course/all")
+	if courses == nil || courses.Get == nil {
+		t.Fatal("expected the courses operation")
+	}
+	message := courses.Get.Responses.Get(http.StatusOK).Value.Content.Get("application/json").Schema.Value.AllOf[1].Value.Properties["message"].Value
+	if message.Type != "array" || message.Items.Ref != "#/components/schemas/Course" {
+		t.Errorf("got courses message %s of %v, want an array of courses", message.Type, message.Items)
+	}
+
+	response := doc.Components.Schemas["Response"].Value
+	if _, ok := response.Properties["code"]; !ok {
+		t.Error("expected the code property in the response schema")
+	}
+
+	professor := doc.Paths.Find("/professor/detail/{uuid}")
+	if professor == nil || professor.Get == nil || len(professor.Get.Parameters) != 1 || professor.Get.Parameters[0].Value.In != "path" {
+		t.Error("expected the professor detail operation with the uuid path parameter")
+	}
+
+	if doc.Paths.Find("/course/add") == nil {
+		t.Error("expected the path of the add course operation to start with a slash")
+	}
+}
+
+func TestHandlerDocs(t *testing.T) {
+	for name := range handlerFuncMap {
+		if _, ok := handlerDocs[name]; !ok {
+			t.Errorf("handler %s is not described in the OpenAPI document", name)
+		}
+	}
+	for name := range handlerDocs {
+		if _, ok := handlerFuncMap[name]; !ok {
+			t.Errorf("described handler %s does not exist", name)
+		}
+	}
+}
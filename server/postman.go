@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// postmanSchema is the schema of the Postman collections.
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// registeredHandlers are the handlers registered on the router, described by the Postman collection.
+var registeredHandlers []*HandlerInfo
+
+// postmanFolders are the names of the path types, in the order of the folders of the Postman collection.
+var postmanFolders = []string{"public", "user", "admin", "super"}
+
+// postmanExamples are the example request bodies of the handlers in the Postman collection.
+var postmanExamples = map[string]any{
+	"login": &Credentials{Email: "joe@example.com", Password: "password"},
+	"gradeCourseProfessor": &GradeData{
+		CourseCode:      "S209",
+		ProfUUID:        "00000000-0000-0000-0000-000000000000",
+		GradeTeaching:   4,
+		GradeCoursework: 3.5,
+		GradeLearning:   5,
+		Comment:         "Great course.",
+	},
+}
+
+// PostmanCollection represents a Postman collection, in the v2.1 format.
+type PostmanCollection struct {
+	Info     PostmanInfo        `json:"info"`
+	Variable []*PostmanVariable `json:"variable"`
+	Item     []*PostmanItem     `json:"item"`
+}
+
+// PostmanInfo represents the information of a Postman collection.
+type PostmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// PostmanVariable represents a variable of a Postman collection, or of a request url.
+type PostmanVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PostmanItem represents a request of a Postman collection, or a folder of requests.
+type PostmanItem struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Item        []*PostmanItem  `json:"item,omitempty"`
+	Request     *PostmanRequest `json:"request,omitempty"`
+}
+
+// PostmanRequest represents a request of a Postman collection.
+type PostmanRequest struct {
+	Method string             `json:"method"`
+	Header []*PostmanVariable `json:"header"`
+	Url    *PostmanUrl        `json:"url"`
+	Body   *PostmanBody       `json:"body,omitempty"`
+}
+
+// PostmanUrl represents the url of a request of a Postman collection.
+type PostmanUrl struct {
+	Raw      string             `json:"raw"`
+	Host     []string           `json:"host"`
+	Path     []string           `json:"path"`
+	Variable []*PostmanVariable `json:"variable,omitempty"`
+}
+
+// PostmanBody represents the body of a request of a Postman collection.
+type PostmanBody struct {
+	Mode string `json:"mode"`
+	Raw  string `json:"raw"`
+}
+
+// getPostmanCollection handles the HTTP request to download a Postman collection of the registered handlers,
+// with the base url of the requests set to the url of the server.
+func getPostmanCollection(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	collection, err := newPostmanCollection(registeredHandlers, scheme+"://"+r.Host)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(collection)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="itpg.postman_collection.json"`)
+	w.Write(data)
+}
+
+// newPostmanCollection returns a Postman collection of the handlers, with a folder for each path type.
+func newPostmanCollection(handlers []*HandlerInfo, baseUrl string) (*PostmanCollection, error) {
+	collection := &PostmanCollection{
+		Info:     PostmanInfo{Name: "itpg", Schema: postmanSchema},
+		Variable: []*PostmanVariable{{Key: "baseUrl", Value: baseUrl}},
+	}
+
+	for _, folder := range postmanFolders {
+		item := &PostmanItem{Name: folder, Description: postmanFolderDescription(pathTypeMap[folder])}
+
+		for _, h := range handlers {
+			if h.pathType != pathTypeMap[folder] {
+				continue
+			}
+
+			request, err := newPostmanRequest(h)
+			if err != nil {
+				return nil, err
+			}
+			item.Item = append(item.Item, &PostmanItem{Name: h.name, Request: request})
+		}
+
+		if len(item.Item) > 0 {
+			collection.Item = append(collection.Item, item)
+		}
+	}
+
+	return collection, nil
+}
+
+// newPostmanRequest returns the request of a handler in a Postman collection.
+// The route variables of the path of the handler, such as {uuid}, are written as Postman path variables, such as :uuid.
+func newPostmanRequest(h *HandlerInfo) (*PostmanRequest, error) {
+	url := &PostmanUrl{Host: []string{"{{baseUrl}}"}}
+	for _, segment := range strings.Split(strings.Trim(h.path, "/"), "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name, _, _ := strings.Cut(strings.Trim(segment, "{}"), ":")
+			url.Variable = append(url.Variable, &PostmanVariable{Key: name})
+			segment = ":" + name
+		}
+		url.Path = append(url.Path, segment)
+	}
+	url.Raw = "{{baseUrl}}/" + strings.Join(url.Path, "/")
+
+	request := &PostmanRequest{Method: h.method, Header: []*PostmanVariable{}, Url: url}
+
+	if example, ok := postmanExamples[h.name]; ok {
+		raw, err := json.MarshalIndent(example, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		request.Header = append(request.Header, &PostmanVariable{Key: "Content-Type", Value: "application/json"})
+		request.Body = &PostmanBody{Mode: "raw", Raw: string(raw)}
+	}
+
+	return request, nil
+}
+
+// postmanFolderDescription returns the description of the folder of the requests of a path type.
+func postmanFolderDescription(pathType PathType) string {
+	switch pathType {
+	case userPath:
+		return "Requests needing the session cookie of a confirmed user, set by the login request."
+	case adminPath:
+		return "Requests needing the session cookie of an admin."
+	case superPath:
+		return "Requests needing the session cookie of a super admin."
+	default:
+		return "Requests accessible by anyone."
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetPostmanCollection(t *testing.T) {
+	handlers, err := loadHandlers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registeredHandlers = handlers
+	defer func() { registeredHandlers = nil }()
+
+	r := httptest.NewRequest("GET", "http://api.itpg.cc/postman.json", nil)
+	rr := httptest.NewRecorder()
+	getPostmanCollection(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	collection := &PostmanCollection{}
+	if err = json.NewDecoder(rr.Body).Decode(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	if collection.Info.Schema != postmanSchema {
+		t.Errorf("got schema %s, want %s", collection.Info.Schema, postmanSchema)
+	}
+	if len(collection.Variable) != 1 || collection.Variable[0].Value != "http://api.itpg.cc" {
+		t.Errorf("got variables %v, want the base url", collection.Variable)
+	}
+
+	count, requests := 0, map[string]*PostmanRequest{}
+	for _, folder := range collection.Item {
+		for _, item := range folder.Item {
+			requests[item.Name] = item.Request
+			count++
+		}
+	}
+
+	if count != len(handlers) {
+		t.Errorf("got %d requests, want %d", count, len(handlers))
+	}
+
+	grade, ok := requests["gradeCourseProfessor"]
+	if !ok {
+		t.Fatal("expected the grade request")
+	}
+	if grade.Method != http.MethodPost || grade.Url.Raw != "{{baseUrl}}/course/grade" {
+		t.Errorf("got %s %s, want POST {{baseUrl}}/course/grade", grade.Method, grade.Url.Raw)
+	}
+	if grade.Body == nil {
+		t.Fatal("expected an example body for the grade request")
+	}
+	gradeData := &GradeData{}
+	if err = json.Unmarshal([]byte(grade.Body.Raw), gradeData); err != nil {
+		t.Fatal(err)
+	}
+	if gradeData.CourseCode == "" || gradeData.ProfUUID == "" {
+		t.Errorf("got example grade %v, want a course code and professor uuid", gradeData)
+	}
+
+	if login, ok := requests["login"]; !ok || login.Body == nil {
+		t.Error("expected the login request with an example body")
+	}
+
+	course, ok := requests["getCoursesByProfessorUUID"]
+	if !ok {
+		t.Fatal("expected the courses by professor uuid request")
+	}
+	if course.Url.Raw != "{{baseUrl}}/course/:uuid" || len(course.Url.Variable) != 1 || course.Url.Variable[0].Key != "uuid" {
+		t.Errorf("got %s %v, want the uuid path variable", course.Url.Raw, course.Url.Variable)
+	}
+}
@@ -1,13 +1,14 @@
 package server
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -18,9 +19,11 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/negroni"
 	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/db/mysql"
 	"github.com/vanillaiice/itpg/db/postgres"
 	"github.com/vanillaiice/itpg/db/sqlite"
 	"github.com/vanillaiice/itpg/mail"
+	"github.com/vanillaiice/itpg/metrics"
 	"github.com/vanillaiice/itpg/responses"
 	"github.com/xyproto/permissionbolt/v2"
 	"github.com/xyproto/pinterface"
@@ -34,10 +37,21 @@ const (
 	sqliteBackend   DatabaseBackend = "sqlite"
 	postgresBackend DatabaseBackend = "postgres"
 	pgBackend       DatabaseBackend = "pg"
+	mysqlBackend    DatabaseBackend = "mysql"
+	mariadbBackend  DatabaseBackend = "mariadb"
 )
 
 type LogLevel string
 
+// NotifierKind is the kind of notifier used to send confirmation codes.
+type NotifierKind string
+
+// Enum for notifier kinds
+const (
+	smtpNotifier    NotifierKind = "smtp"    // smtpNotifier sends confirmation codes by mail.
+	webhookNotifier NotifierKind = "webhook" // webhookNotifier posts confirmation codes to a webhook, for example an SMS gateway.
+)
+
 // logLevelMap is the map of log levels.
 var logLevelMap = map[string]zerolog.Level{
 	"disabled": zerolog.Disabled,
@@ -51,6 +65,12 @@ var logLevelMap = map[string]zerolog.Level{
 // mailer is the client used to send mail.
 var mailer *mail.SmtpClient
 
+// mailQueue delivers the mails and confirmation codes in the background.
+var mailQueue *mail.Queue
+
+// mailQueueStoreId is the id of the key-value store persisting the mail queue in the users database.
+const mailQueueStoreId = "mailqueue"
+
 // dataDb represents a database connection,
 // storing professor names, course codes and names,
 // and professor scores.
@@ -61,38 +81,135 @@ var userState pinterface.IUserState
 
 // passwordResetUrl is the URL of the password reset web page.
 // An example URL would be: https://demo.itpg.cc/changepass.
-// The backend server will then append the following to the previous URL:
+// The backend server will then set the following query parameter on the previous URL:
 // ?code=foobarbaz, and send it to the user's email.
 // Then, the website should get the email and new password of the user,
 // and make the following example POST request to the api server:
 // curl https://api.itpg.cc/resetpass -d '{"code": "foobarbaz", "email": "foo@bar.com", "password": "fizzbuzz"}'
-var passwordResetUrl string
+var passwordResetUrl *url.URL
+
+// passwordResetUrls are the URLs of the password reset web pages of the frontends, by origin.
+// The password reset link of a request from one of the origins is made from its URL instead of passwordResetUrl.
+var passwordResetUrls map[string]*url.URL
 
 // cookieTimeout represents the duration after which a session cookie expires.
 var cookieTimeout time.Duration
 
+// verifiedGradeWeight is the weight of the grades of verified users in the averages.
+var verifiedGradeWeight = db.DefaultGradeWeight
+
+// noContentOnSuccess makes mutation handlers respond with 204 No Content instead of a success body.
+var noContentOnSuccess bool
+
+// publicCacheMaxAge is the duration in seconds during which clients and proxies can cache the responses of the cacheable public reads.
+var publicCacheMaxAge int
+
+// publicMaxResults is the maximum number of results of the list and search endpoints returned to unauthenticated users (0 for no limit).
+var publicMaxResults int
+
 // RunCfg defines the server's configuration.
 type RunCfg struct {
-	Port               string          // Port on which the server will run.
-	DbUrl              string          // Path to the SQLite database file.
-	DbBackend          DatabaseBackend // Database backend type.
-	CacheDbUrl         string          // URL to the redis cache database.
-	CacheTtl           int             // Time-to-live of the cache in seconds.
-	UsersDbPath        string          // Path to the users BOLT database file.
-	AllowedOrigins     []string        // List of allowed origins for CORS.
-	AllowedMailDomains []string        // List of allowed mail domains for registering with the service.
-	PasswordResetUrl   string          // URL to the password reset website page.
-	SmtpEnvPath        string          // Path to the .env file containing SMTP cfguration.
-	UseSmtp            bool            // Whether to use SMTP (false for SMTPS).
-	UseHttp            bool            // Whether to use HTTP (false for HTTPS).
-	HandlersFilePath   string          // Handler config json file.
-	CertFilePath       string          // Path to the certificate file (required for HTTPS).
-	KeyFilePath        string          // Path to the key file (required for HTTPS).
-	CookieTimeout      int             // Duration in minute after which a session cookie expires.
-	CodeValidityMinute int             // Duration in minute after which a code is invalid.
-	CodeLength         int             // Length of generated codes.
-	MinPasswordScore   int             // Minimum acceptable score of a password scores computed by zxcvbn.
-	LogLevel           LogLevel        // Log level.
+	Port                   string           // Port on which the server will run.
+	DbUrl                  string           // Path to the SQLite database file.
+	DbBackend              DatabaseBackend  // Database backend type.
+	ReadReplicaUrl         string           // URL to the read replica database (postgres and mysql only).
+	Tenants                []string         // Tenants served from their own database with the same backend, in the tenant=url form (empty to disable multi-tenant mode).
+	CacheDbUrl             string           // URL to the redis cache database.
+	CacheTtl               int              // Time-to-live of the cache in seconds.
+	DedupScope             db.DedupScope    // Scope within which a user can only grade once.
+	MinGradesForRanking    int              // Minimum number of grades for a professor to be ranked (0 to use the default of 3).
+	MinGradesForVerified   int              // Minimum number of grades for a score to be verified (0 to use the default of 5).
+	HashAlgorithm          db.HashAlgorithm // Algorithm used to hash grade deduplication inputs.
+	HashKey                string           // Secret key used by keyed hash algorithms.
+	MaxRowReturn           int              // Maximum number of rows returned by a query (0 to use the default of 100).
+	QueryTimeout           int              // Timeout in seconds of each database operation (0 to disable the timeout).
+	SlowQueryMs            int              // Duration in milliseconds above which a database operation is logged as slow (0 to disable the logging).
+	UsersDbPath            string           // Path to the users BOLT database file.
+	AllowedOrigins         []string         // List of allowed origins for CORS.
+	AllowedMailDomains     []string         // List of allowed mail domains for registering with the service.
+	PasswordResetUrl       string           // URL to the password reset website page.
+	PasswordResetUrls      []string         // URLs to the password reset website pages of other frontends, in the origin=url form.
+	SmtpEnvPath            string           // Path to the .env file containing SMTP cfguration.
+	UseSmtp                bool             // Whether to use SMTP (false for SMTPS).
+	SmtpIdleTimeout        int              // Duration in seconds after which an idle connection to the SMTP server is closed (0 to disable connection reuse).
+	Notifier               NotifierKind     // Kind of notifier used to send confirmation codes (empty to use smtp).
+	NotifierWebhookUrl     string           // URL of the webhook receiving the confirmation codes (required by the webhook notifier).
+	MailTemplateDir        string           // Directory of the confirmation and password reset mail templates (empty to use the embedded ones).
+	SiteName               string           // Name of the site used in the mails (empty to use ITPG).
+	UseHttp                bool             // Whether to use HTTP (false for HTTPS).
+	HandlersFilePath       string           // Handler config json file (empty to use the embedded default).
+	CertFilePath           string           // Path to the certificate file (required for HTTPS).
+	KeyFilePath            string           // Path to the key file (required for HTTPS).
+	CookieTimeout          int              // Duration in minute after which a session cookie expires.
+	CookieMaxAge           int              // Max age in minute of the session cookie sent to browsers (0 to use CookieTimeout).
+	CodeValidityMinute     int              // Duration in minute after which a code is invalid.
+	CodeResendCooldown     int              // Duration in seconds after sending a confirmation code or a password reset link to a user during which another one cannot be sent (0 to disable the cooldown).
+	CodeLength             int              // Length of generated codes.
+	MinPasswordScore       int              // Minimum acceptable score of a password scores computed by zxcvbn.
+	LogLevel               LogLevel         // Log level.
+	NoContentOnSuccess     bool             // Whether successful mutations return 204 No Content instead of a body.
+	HideServerHeader       bool             // Whether to remove the headers revealing the identity of the server from responses.
+	RequireOrigin          bool             // Whether state-changing requests must have an Origin header matching AllowedOrigins.
+	Maintenance            bool             // Whether the server is in read-only maintenance mode.
+	VerifiedGradeWeight    float64          // Weight of the grades of verified users in the averages (0 to use the default of 1).
+	ScoreWeights           *[3]float32      // Weights of the teaching, coursework, and learning scores in the average score (nil to use equal weights).
+	ScoreDimensions        []string         // Names of the graded score dimensions, starting with teaching, coursework, and learning (empty to only grade those).
+	ProfessorNameDedup     bool             // Whether adding a professor whose normalized name matches the name of an existing professor is rejected.
+	ShutdownTimeoutSeconds int              // Duration in seconds to wait for in-flight requests on shutdown (0 to use the default of 10).
+	MaxLoginAttempts       int              // Number of failed login attempts from an ip address after which a user is locked out (0 to disable lockouts).
+	LockoutMinutes         int              // Duration in minutes of the first lockout, doubled with each further failed attempt (0 to use the default of 15).
+	IdempotencyKeyHours    int              // Duration in hours during which a grade submission can be retried with the same idempotency key (0 to ignore idempotency keys).
+	PublicCacheMaxAge      int              // Duration in seconds during which clients and proxies can cache the responses of the cacheable public reads (0 to disable caching).
+	PublicMaxResults       int              // Maximum number of results of the list and search endpoints returned to unauthenticated users (0 for no limit).
+	TrustProxy             bool             // Whether the ip address of clients is read from the X-Forwarded-For header set by a reverse proxy.
+	DeniedIPs              []string         // IP ranges in CIDR notation, or ip addresses, denied access to the server.
+	AdminAllowedIPs        []string         // IP ranges in CIDR notation, or ip addresses, only allowed to access the admin routes (empty to allow all).
+	MetricsEnabled         bool             // Whether metrics are recorded and exposed in the Prometheus text format at /metrics.
+}
+
+// metricsPath is the path of the metrics endpoint.
+const metricsPath = "/metrics"
+
+// defaultShutdownTimeout is the default duration to wait for in-flight requests on shutdown.
+const defaultShutdownTimeout = 10 * time.Second
+
+// defaultLoginLockout is the default duration of the first lockout after too many failed login attempts.
+const defaultLoginLockout = 15 * time.Minute
+
+// openDataDb opens the data database of the backend at dbUrl, reading from the replica at replicaUrl if not empty.
+// The replica is opened read-only, so that it does not set up the schema, which is set up by the primary.
+func openDataDb(backend DatabaseBackend, dbUrl, replicaUrl, cacheUrl string, cacheTtl time.Duration, ctx context.Context, opts ...db.Option) (d db.DB, err error) {
+	replicaOpts := append(slices.Clip(opts), db.WithReadOnly())
+
+	switch backend {
+	case sqliteBackend:
+		if replicaUrl != "" {
+			log.Warn().Msg("read replica is not supported by the sqlite backend, ignoring")
+		}
+		return sqlite.New(dbUrl, cacheUrl, cacheTtl, ctx, opts...)
+	case postgresBackend, pgBackend:
+		if d, err = postgres.New(dbUrl, cacheUrl, cacheTtl, ctx, opts...); err != nil || replicaUrl == "" {
+			return
+		}
+		var replicaDb *postgres.DB
+		if replicaDb, err = postgres.New(replicaUrl, cacheUrl, cacheTtl, ctx, replicaOpts...); err != nil {
+			d.Close()
+			return nil, err
+		}
+		return db.NewReplicaDB(d, replicaDb), nil
+	case mysqlBackend, mariadbBackend:
+		if d, err = mysql.New(dbUrl, cacheUrl, cacheTtl, ctx, opts...); err != nil || replicaUrl == "" {
+			return
+		}
+		var replicaDb *mysql.DB
+		if replicaDb, err = mysql.New(replicaUrl, cacheUrl, cacheTtl, ctx, replicaOpts...); err != nil {
+			d.Close()
+			return nil, err
+		}
+		return db.NewReplicaDB(d, replicaDb), nil
+	default:
+		return nil, fmt.Errorf("invalid database backend: %s", backend)
+	}
 }
 
 // Run starts the HTTP server on the specified port and connects to the specified database.
@@ -100,12 +217,49 @@ func Run(cfg *RunCfg) (err error) {
 	if err = validAllowedDomains(cfg.AllowedMailDomains); err != nil {
 		return
 	}
-	allowedMailDomains = cfg.AllowedMailDomains
+	allowedMailDomains = lowerDomains(cfg.AllowedMailDomains)
+
+	if cfg.ScoreWeights != nil {
+		if err = validScoreWeights(*cfg.ScoreWeights); err != nil {
+			return
+		}
+	}
+
+	if cfg.MinGradesForRanking < 0 {
+		return fmt.Errorf("invalid min grades for ranking: %d (should be greater than or equal to 0)", cfg.MinGradesForRanking)
+	}
+	if cfg.MinGradesForVerified < 0 {
+		return fmt.Errorf("invalid min grades for verified: %d (should be greater than or equal to 0)", cfg.MinGradesForVerified)
+	}
 
 	mailer, err = mail.NewClient(cfg.SmtpEnvPath, !cfg.UseSmtp)
 	if err != nil {
 		return
 	}
+	defer mailer.Close()
+
+	if cfg.SmtpIdleTimeout < 0 {
+		return fmt.Errorf("invalid smtp idle timeout: %d (should be greater than or equal to 0)", cfg.SmtpIdleTimeout)
+	}
+	mailer.SetIdleTimeout(time.Second * time.Duration(cfg.SmtpIdleTimeout))
+
+	mailTemplates, err := mail.LoadTemplates(cfg.MailTemplateDir, cfg.SiteName)
+	if err != nil {
+		return
+	}
+	mailer.SetTemplates(mailTemplates)
+
+	var notifier mail.Notifier
+	switch cfg.Notifier {
+	case "", smtpNotifier:
+		notifier = mailer
+	case webhookNotifier:
+		if notifier, err = mail.NewWebhookNotifier(cfg.NotifierWebhookUrl); err != nil {
+			return
+		}
+	default:
+		return fmt.Errorf("invalid notifier: %s", cfg.Notifier)
+	}
 
 	logLevel, ok := logLevelMap[string(cfg.LogLevel)]
 	if !ok {
@@ -117,21 +271,56 @@ func Run(cfg *RunCfg) (err error) {
 
 	cacheTtl := time.Duration(cfg.CacheTtl) * time.Second
 
-	switch cfg.DbBackend {
-	case sqliteBackend:
-		dataDb, err = sqlite.New(cfg.DbUrl, cfg.CacheDbUrl, cacheTtl, ctx)
-	case postgresBackend, pgBackend:
-		dataDb, err = postgres.New(cfg.DbUrl, cfg.CacheDbUrl, cacheTtl, ctx)
-	default:
-		return fmt.Errorf("invalid database backend: %s", cfg.DbBackend)
+	var dbOpts []db.Option
+	if cfg.MinGradesForRanking != 0 {
+		dbOpts = append(dbOpts, db.WithMinGradesForRanking(cfg.MinGradesForRanking))
+	}
+	if cfg.MinGradesForVerified != 0 {
+		dbOpts = append(dbOpts, db.WithMinGradesForVerified(cfg.MinGradesForVerified))
+	}
+	if cfg.DedupScope != "" {
+		dbOpts = append(dbOpts, db.WithDedupScope(cfg.DedupScope))
+	}
+	if cfg.HashAlgorithm != "" {
+		dbOpts = append(dbOpts, db.WithHashAlgorithm(cfg.HashAlgorithm, cfg.HashKey))
+	}
+	if cfg.MaxRowReturn != 0 {
+		dbOpts = append(dbOpts, db.WithMaxRowReturn(cfg.MaxRowReturn))
+	}
+	if cfg.ScoreWeights != nil {
+		dbOpts = append(dbOpts, db.WithScoreWeights(*cfg.ScoreWeights))
+	}
+	if len(cfg.ScoreDimensions) != 0 {
+		dbOpts = append(dbOpts, db.WithScoreDimensions(cfg.ScoreDimensions...))
+	}
+	if cfg.ProfessorNameDedup {
+		dbOpts = append(dbOpts, db.WithProfessorNameDedup(true))
+	}
+	if cfg.QueryTimeout != 0 {
+		dbOpts = append(dbOpts, db.WithQueryTimeout(time.Duration(cfg.QueryTimeout)*time.Second))
+	}
+	if cfg.SlowQueryMs != 0 {
+		dbOpts = append(dbOpts, db.WithSlowQueryThreshold(time.Duration(cfg.SlowQueryMs)*time.Millisecond))
 	}
 
+	tenantUrls, err := parseTenants(cfg.Tenants)
 	if err != nil {
 		return
 	}
 
+	if dataDb, err = openDataDb(cfg.DbBackend, cfg.DbUrl, cfg.ReadReplicaUrl, cfg.CacheDbUrl, cacheTtl, ctx, dbOpts...); err != nil {
+		return
+	}
 	defer dataDb.Close()
 
+	tenantDbs = make(map[string]db.DB, len(tenantUrls))
+	defer closeTenantDbs()
+	for id, tenantUrl := range tenantUrls {
+		if tenantDbs[id], err = openDataDb(cfg.DbBackend, tenantUrl, "", cfg.CacheDbUrl, cacheTtl, ctx, append(slices.Clip(dbOpts), db.WithCacheNamespace(tenantCacheNamespace(id)))...); err != nil {
+			return
+		}
+	}
+
 	var initUsersDbAdmin bool
 	if _, err := os.Stat(cfg.UsersDbPath); errors.Is(err, os.ErrNotExist) {
 		initUsersDbAdmin = true
@@ -148,6 +337,20 @@ func Run(cfg *RunCfg) (err error) {
 	})
 
 	userState = perm.UserState()
+	defer func() {
+		if closer, ok := userState.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}()
+
+	mailQueueStore, err := userState.Creator().NewKeyValue(mailQueueStoreId)
+	if err != nil {
+		return
+	}
+	if mailQueue, err = mail.NewQueue(mailer, notifier, mailQueueStore); err != nil {
+		return
+	}
+	defer mailQueue.Close()
 
 	if initUsersDbAdmin {
 		log.Info().Msgf("Initializing users database %s", cfg.UsersDbPath)
@@ -200,14 +403,21 @@ func Run(cfg *RunCfg) (err error) {
 
 		userState.SetAdminStatus(adminUsername)
 
-		userState.SetBooleanField(adminUsername, "super", true)
+		userState.SetBooleanField(adminUsername, keySuperAdmin, true)
 
 		log.Info().Msgf("Initialized users database %s with super admin %s", cfg.UsersDbPath, adminUsername)
 	}
 
-	cookieTimeout = time.Minute * time.Duration(cfg.CookieTimeout)
+	if cfg.CookieMaxAge < 0 {
+		return fmt.Errorf("invalid cookie max age: %d (should be greater than or equal to 0)", cfg.CookieMaxAge)
+	}
+	setCookieTimeouts(time.Minute*time.Duration(cfg.CookieTimeout), time.Minute*time.Duration(cfg.CookieMaxAge))
 
-	userState.SetCookieTimeout(int64(cookieTimeout.Seconds()))
+	if cfg.VerifiedGradeWeight < 0 {
+		return fmt.Errorf("invalid verified grade weight: %v (should be greater than or equal to 0)", cfg.VerifiedGradeWeight)
+	} else if cfg.VerifiedGradeWeight > 0 {
+		verifiedGradeWeight = float32(cfg.VerifiedGradeWeight)
+	}
 
 	if cfg.CodeLength > 32 || cfg.CodeLength < 8 {
 		return fmt.Errorf("invalid code length: %d (should be between 8 and 32)", cfg.CodeLength)
@@ -224,60 +434,131 @@ func Run(cfg *RunCfg) (err error) {
 	}
 	confirmationCodeValidityTime = time.Minute * time.Duration(cfg.CodeValidityMinute)
 
-	router := mux.NewRouter()
+	if cfg.CodeResendCooldown < 0 {
+		return fmt.Errorf("invalid code resend cooldown: %d (should be greater than or equal to 0)", cfg.CodeResendCooldown)
+	}
+	codeResendCooldown = time.Second * time.Duration(cfg.CodeResendCooldown)
 
-	handlerCfg, err := os.ReadFile(cfg.HandlersFilePath)
-	if err != nil {
+	if cfg.ShutdownTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid shutdown timeout: %d (should be greater than or equal to 0)", cfg.ShutdownTimeoutSeconds)
+	}
+	shutdownTimeout := defaultShutdownTimeout
+	if cfg.ShutdownTimeoutSeconds > 0 {
+		shutdownTimeout = time.Second * time.Duration(cfg.ShutdownTimeoutSeconds)
+	}
+
+	if cfg.MaxLoginAttempts < 0 {
+		return fmt.Errorf("invalid max login attempts: %d (should be greater than or equal to 0)", cfg.MaxLoginAttempts)
+	}
+	maxLoginAttempts = cfg.MaxLoginAttempts
+
+	if cfg.LockoutMinutes < 0 {
+		return fmt.Errorf("invalid lockout: %d (should be greater than or equal to 0)", cfg.LockoutMinutes)
+	}
+	loginLockout = defaultLoginLockout
+	if cfg.LockoutMinutes > 0 {
+		loginLockout = time.Minute * time.Duration(cfg.LockoutMinutes)
+	}
+
+	if cfg.IdempotencyKeyHours < 0 {
+		return fmt.Errorf("invalid idempotency key validity: %d (should be greater than or equal to 0)", cfg.IdempotencyKeyHours)
+	}
+	idempotencyKeyValidityTime = time.Hour * time.Duration(cfg.IdempotencyKeyHours)
+
+	if passwordResetUrl, err = parsePasswordResetUrl(cfg.PasswordResetUrl); err != nil {
+		return
+	}
+	if passwordResetUrls, err = parsePasswordResetUrls(cfg.PasswordResetUrls); err != nil {
 		return
 	}
 
-	handlers, err := parseHandlers(bytes.NewReader(handlerCfg))
+	trustProxy = cfg.TrustProxy
+	if deniedIPs, err = parseIPRanges(cfg.DeniedIPs); err != nil {
+		return
+	}
+	if adminAllowedIPs, err = parseIPRanges(cfg.AdminAllowedIPs); err != nil {
+		return
+	}
+
+	router := newRouter()
+
+	handlers, err := loadHandlers(cfg.HandlersFilePath)
 	if err != nil {
 		return
 	}
 
-	for _, h := range handlers {
-		switch h.pathType {
-		case superPath:
-			router.Handle(h.path, h.limiter(checkCookieExpiryMiddleware(checkSuperAdminMiddleware(h.handler)))).Methods(h.method)
-			perm.AddAdminPath(h.path)
-		case adminPath:
-			router.Handle(h.path, h.limiter(checkCookieExpiryMiddleware(checkAdminMiddleware(h.handler)))).Methods(h.method)
-			perm.AddAdminPath(h.path)
-		case userPath:
-			router.Handle(h.path, h.limiter(checkCookieExpiryMiddleware(checkConfirmedMiddleware(h.handler)))).Methods(h.method)
-			perm.AddUserPath(h.path)
-		case publicPath:
-			router.Handle(h.path, h.limiter(DummyMiddleware(h.handler))).Methods(h.method)
-			perm.AddPublicPath(h.path)
-		default:
-			return fmt.Errorf("invalid path type: %d", h.pathType)
-		}
+	if cfg.PublicCacheMaxAge < 0 {
+		return fmt.Errorf("invalid public cache max age: %d (should be greater than or equal to 0)", cfg.PublicCacheMaxAge)
+	}
+	publicCacheMaxAge = cfg.PublicCacheMaxAge
+
+	if cfg.PublicMaxResults < 0 {
+		return fmt.Errorf("invalid public max results: %d (should be greater than or equal to 0)", cfg.PublicMaxResults)
+	}
+	publicMaxResults = cfg.PublicMaxResults
+
+	if err = registerHandlers(router, perm, handlers); err != nil {
+		return
+	}
+
+	if err = buildOpenAPIDocument(handlers); err != nil {
+		return
 	}
 
-	passwordResetUrl = cfg.PasswordResetUrl
+	metrics.Enable(cfg.MetricsEnabled)
+	if cfg.MetricsEnabled {
+		// the metrics are restricted to the ip addresses allowed to access the admin routes.
+		router.Handle(metricsPath, checkAdminIPMiddleware(metrics.Handler().ServeHTTP)).Methods(http.MethodGet)
+		perm.AddPublicPath(metricsPath)
+	}
 
+	noContentOnSuccess = cfg.NoContentOnSuccess
+
+	// the allowed headers are the default ones, along with the idempotency key of grade submissions.
 	c := cors.New(cors.Options{
 		AllowedOrigins:   cfg.AllowedOrigins,
-		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders:   []string{"Origin", "Accept", "Content-Type", "X-Requested-With", idempotencyKeyHeader},
 		AllowCredentials: true,
 	})
 
-	n := negroni.Classic()
+	// the negroni access logger is replaced by the structured request logs.
+	n := negroni.New(negroni.NewRecovery(), negroni.HandlerFunc(requestLogMiddleware), negroni.NewStatic(http.Dir("public")))
+
+	if len(tenantDbs) != 0 {
+		n.Use(negroni.HandlerFunc(tenantMiddleware))
+	}
+
+	if cfg.HideServerHeader {
+		n.Use(negroni.HandlerFunc(hideServerHeaderMiddleware))
+	}
+
+	if cfg.Maintenance {
+		log.Warn().Msg("maintenance mode enabled, only read requests are accepted")
+		n.Use(negroni.HandlerFunc(maintenanceMiddleware))
+	}
+
+	if len(deniedIPs) != 0 {
+		n.Use(negroni.HandlerFunc(denyIPMiddleware))
+	}
 
 	n.Use(c)
+
+	if cfg.RequireOrigin {
+		if len(cfg.AllowedOrigins) == 0 {
+			return fmt.Errorf("requiring the origin of requests needs at least one allowed origin")
+		}
+		n.Use(negroni.HandlerFunc(requireOriginMiddleware(cfg.AllowedOrigins)))
+	}
+
 	n.Use(perm)
 	n.UseHandler(router)
 
 	sigChan := make(chan os.Signal, 1)
-	errChan := make(chan error)
-
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
-	go func() {
-		sig := <-sigChan
-		errChan <- fmt.Errorf("%v signal received, shutting down", sig)
-	}()
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: n}
 
 	s := fmt.Sprintf("itpg-backend (%s) listening on port %s", cfg.DbBackend, cfg.Port)
 	if !cfg.UseSmtp {
@@ -286,17 +567,99 @@ func Run(cfg *RunCfg) (err error) {
 		s += " with SMTP,"
 	}
 
-	go func() {
-		if !cfg.UseHttp {
+	serve := func() error {
+		log.Info().Msgf("%s with HTTP", s)
+		return srv.ListenAndServe()
+	}
+	if !cfg.UseHttp {
+		serve = func() error {
 			log.Info().Msgf("%s with HTTPS", s)
-			errChan <- http.ListenAndServeTLS(":"+cfg.Port, cfg.CertFilePath, cfg.KeyFilePath, n)
-		} else {
-			log.Info().Msgf("%s with HTTP", s)
-			errChan <- http.ListenAndServe(":"+cfg.Port, n)
+			return srv.ListenAndServeTLS(cfg.CertFilePath, cfg.KeyFilePath)
+		}
+	}
+
+	// the databases and the cache are closed by the deferred calls,
+	// only after the server has stopped accepting connections.
+	return serveUntilSignal(srv, serve, sigChan, shutdownTimeout)
+}
+
+// registerHandlers registers the handlers on the router, and their paths in the permissions.
+// The responses of the authenticated paths are never stored by clients and proxies,
+// and the responses of the cacheable public paths can be cached by them.
+func registerHandlers(router *mux.Router, perm *permissionbolt.Permissions, handlers []*HandlerInfo) error {
+	registeredHandlers = handlers
+
+	for _, h := range handlers {
+		switch h.pathType {
+		case superPath:
+			router.Handle(h.path, metricsMiddleware(h.name, h.limiter(checkAdminIPMiddleware(noStoreMiddleware(checkCookieExpiryMiddleware(checkSuperAdminMiddleware(h.handler))))))).Methods(h.method)
+			perm.AddAdminPath(h.path)
+		case adminPath:
+			router.Handle(h.path, metricsMiddleware(h.name, h.limiter(checkAdminIPMiddleware(noStoreMiddleware(checkCookieExpiryMiddleware(checkAdminMiddleware(h.handler))))))).Methods(h.method)
+			perm.AddAdminPath(h.path)
+		case userPath:
+			router.Handle(h.path, metricsMiddleware(h.name, h.limiter(noStoreMiddleware(checkCookieExpiryMiddleware(checkConfirmedMiddleware(h.handler)))))).Methods(h.method)
+			perm.AddUserPath(h.path)
+		case publicPath:
+			handler := h.handler
+			if h.cache {
+				handler = publicCacheMiddleware(handler)
+			}
+			handler = optionalSessionMiddleware(handler)
+			router.Handle(h.path, metricsMiddleware(h.name, h.limiter(DummyMiddleware(handler)))).Methods(h.method)
+			perm.AddPublicPath(h.path)
+		default:
+			return fmt.Errorf("invalid path type: %d", h.pathType)
 		}
+	}
+
+	return nil
+}
+
+// newRouter returns a router writing JSON errors for unmatched routes and methods.
+func newRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		responses.ErrNotFound.WriteJSON(w)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		responses.ErrMethodNotAllowed.WriteJSON(w)
+	})
+
+	return router
+}
+
+// serveUntilSignal serves HTTP requests until a signal is received,
+// then stops accepting connections and waits at most timeout for in-flight requests to complete.
+func serveUntilSignal(srv *http.Server, serve func() error, sigChan <-chan os.Signal, timeout time.Duration) (err error) {
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- serve()
 	}()
 
-	return <-errChan
+	select {
+	case err = <-errChan:
+		return
+	case sig := <-sigChan:
+		log.Info().Msgf("%v signal received, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err = srv.Shutdown(ctx); err != nil {
+		return
+	}
+
+	if err = <-errChan; errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	return
 }
 
 func removeUsersDb(path string) {
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/vanillaiice/itpg/responses"
+	"github.com/xyproto/permissionbolt/v2"
+)
+
+func TestServeUntilSignal(t *testing.T) {
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- serveUntilSignal(srv, func() error { return srv.Serve(ln) }, sigChan, time.Second)
+	}()
+
+	bodyChan := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			bodyChan <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		bodyChan <- string(body)
+	}()
+
+	<-started
+	sigChan <- syscall.SIGTERM
+
+	if err = <-errChan; err != nil {
+		t.Fatal(err)
+	}
+
+	if body := <-bodyChan; body != "done" {
+		t.Errorf("got %s, want %s", body, "done")
+	}
+
+	if _, err = http.Get("http://" + ln.Addr().String()); err == nil {
+		t.Error("expected failure after shutdown")
+	}
+}
+
+func TestNewRouter(t *testing.T) {
+	router := newRouter()
+	router.HandleFunc("/ping", ping).Methods(http.MethodGet)
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/ping", http.StatusOK, ""},
+		{http.MethodGet, "/pong", http.StatusNotFound, responses.ErrNotFound.Error()},
+		{http.MethodPost, "/ping", http.StatusMethodNotAllowed, responses.ErrMethodNotAllowed.Error()},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest(test.method, test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+		if rr.Code != test.code {
+			t.Errorf("%s %s: got %v, want %v", test.method, test.path, rr.Code, test.code)
+		}
+		if rr.Body.String() != test.body {
+			t.Errorf("%s %s: got %s, want %s", test.method, test.path, rr.Body.String(), test.body)
+		}
+	}
+}
+
+func TestRegisterDefaultHandlers(t *testing.T) {
+	perm, err := permissionbolt.NewWithConf(filepath.Join(t.TempDir(), "userstate-test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handlers, err := loadHandlers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handlers) == 0 {
+		t.Fatal("expected default handlers")
+	}
+
+	router := newRouter()
+	if err = registerHandlers(router, perm, handlers); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/login"},
+		{http.MethodGet, "/ping"},
+		{http.MethodGet, "/professor/top"},
+	} {
+		r, err := http.NewRequest(route.method, route.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var match mux.RouteMatch
+		if !router.Match(r, &match) || match.MatchErr != nil {
+			t.Errorf("%s %s: expected a registered route", route.method, route.path)
+		}
+	}
+}
+
+func TestRegisterHandlersCacheControl(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	perm, err := permissionbolt.NewWithConf(filepath.Join(t.TempDir(), "userstate-test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userState = perm.UserState()
+
+	handlers, err := loadHandlers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicCacheMaxAge = 300
+	defer func() { publicCacheMaxAge = 0 }()
+
+	router := newRouter()
+	if err = registerHandlers(router, perm, handlers); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		code     int
+		expected string
+	}{
+		{"/course/all", http.StatusOK, "public, max-age=300"},
+		{"/professor/top", http.StatusOK, ""},
+		{"/ping", http.StatusUnauthorized, "no-store"},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest(http.MethodGet, test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+		if rr.Code != test.code {
+			t.Errorf("%s: got %v, want %v", test.path, rr.Code, test.code)
+		}
+		if v := rr.Header().Get("Cache-Control"); v != test.expected {
+			t.Errorf("%s: got %q, want %q", test.path, v, test.expected)
+		}
+	}
+}
+
+func TestParseHandlersCache(t *testing.T) {
+	for _, pathType := range []string{"user", "admin", "super"} {
+		handlerCfg := `{"handlers": [{"path": "/ping", "pathType": "` + pathType + `", "handler": "ping", "limiter": "lenient", "method": "GET", "cache": true}]}`
+		if _, err := parseHandlers(bytes.NewReader([]byte(handlerCfg))); err == nil {
+			t.Errorf("%s: expected failure", pathType)
+		}
+	}
+}
+
+func TestParseHandlersLimit(t *testing.T) {
+	handlerCfg := `{
+		"defaultLimit": {"requests": 3, "window": "1m"},
+		"handlers": [
+			{"path": "/ping", "pathType": "public", "handler": "ping", "limit": {"requests": 1, "window": "1m"}, "method": "GET"},
+			{"path": "/pong", "pathType": "public", "handler": "ping", "method": "GET"}
+		]
+	}`
+	handlers, err := parseHandlers(bytes.NewReader([]byte(handlerCfg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		handler *HandlerInfo
+		codes   []int
+	}{
+		{handlers[0], []int{http.StatusOK, http.StatusTooManyRequests}},
+		{handlers[1], []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+	}
+
+	for _, test := range tests {
+		handler := test.handler.limiter(http.HandlerFunc(test.handler.handler))
+		for i, want := range test.codes {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(test.handler.method, test.handler.path, nil))
+			if rr.Code != want {
+				t.Errorf("%s: request %d: got %v, want %v", test.handler.path, i, rr.Code, want)
+			}
+			if want == http.StatusTooManyRequests && rr.Body.String() != responses.ErrRequestLimitReached.Error() {
+				t.Errorf("%s: got %s, want %s", test.handler.path, rr.Body.String(), responses.ErrRequestLimitReached.Error())
+			}
+		}
+	}
+
+	invalidCfgs := []string{
+		`{"handlers": [{"path": "/ping", "pathType": "public", "handler": "ping", "limiter": "lenient", "limit": {"requests": 1, "window": "1m"}, "method": "GET"}]}`,
+		`{"handlers": [{"path": "/ping", "pathType": "public", "handler": "ping", "limit": {"requests": 0, "window": "1m"}, "method": "GET"}]}`,
+		`{"handlers": [{"path": "/ping", "pathType": "public", "handler": "ping", "limit": {"requests": 1, "window": "soon"}, "method": "GET"}]}`,
+		`{"defaultLimit": {"requests": 1, "window": "-1m"}, "handlers": [{"path": "/ping", "pathType": "public", "handler": "ping", "method": "GET"}]}`,
+		`{"handlers": [{"path": "/ping", "pathType": "public", "handler": "ping", "method": "GET"}]}`,
+	}
+
+	for _, handlerCfg := range invalidCfgs {
+		if _, err := parseHandlers(bytes.NewReader([]byte(handlerCfg))); err == nil {
+			t.Errorf("%s: expected failure", handlerCfg)
+		}
+	}
+}
+
+func TestRunInvalidScoreWeights(t *testing.T) {
+	defer func() { allowedMailDomains = nil }()
+
+	for _, weights := range [][3]float32{{0, 0, 0}, {1, -1, 1}} {
+		err := Run(&RunCfg{AllowedMailDomains: []string{"*"}, ScoreWeights: &weights})
+		if err == nil || !strings.Contains(err.Error(), "invalid score weights") {
+			t.Errorf("%v: got %v, want invalid score weights error", weights, err)
+		}
+	}
+}
+
+func TestRunInvalidMinGrades(t *testing.T) {
+	defer func() { allowedMailDomains = nil }()
+
+	for _, cfg := range []*RunCfg{{MinGradesForRanking: -1}, {MinGradesForVerified: -1}} {
+		cfg.AllowedMailDomains = []string{"*"}
+		if err := Run(cfg); err == nil || !strings.Contains(err.Error(), "invalid min grades") {
+			t.Errorf("got %v, want invalid min grades error", err)
+		}
+	}
+}
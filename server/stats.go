@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+// statsCacheTtl is the duration during which the totals of the instance are served without being counted again.
+const statsCacheTtl = 10 * time.Second
+
+// statsCache holds the totals of the instance last counted, and the time after which they are counted again.
+var statsCache struct {
+	sync.Mutex
+	stats   *db.Stats
+	expires time.Time
+}
+
+// getStats handles the HTTP request to get the number of courses, professors, grades, and registered users.
+// The totals are counted at most once every statsCacheTtl, since counting them scans whole tables.
+func getStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := cachedStats()
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	(&responses.Response{Code: responses.SuccessCode, Message: stats}).WriteJSON(w)
+}
+
+// cachedStats returns the totals of the instance, counting them again if they expired.
+// Concurrent requests wait for the totals being counted instead of counting them too.
+func cachedStats() (*db.Stats, error) {
+	statsCache.Lock()
+	defer statsCache.Unlock()
+
+	if statsCache.stats != nil && time.Now().Before(statsCache.expires) {
+		return statsCache.stats, nil
+	}
+
+	stats, err := dataDb.Stats()
+	if err != nil {
+		return nil, err
+	}
+
+	usernames, err := userState.AllUsernames()
+	if err != nil {
+		return nil, err
+	}
+	stats.Users = len(usernames)
+
+	statsCache.stats, statsCache.expires = stats, time.Now().Add(statsCacheTtl)
+
+	return stats, nil
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/responses"
+	"github.com/xyproto/permissionbolt/v2"
+)
+
+func TestServerGetStats(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	perm, err := permissionbolt.NewWithConf(filepath.Join(t.TempDir(), "userstate-test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userState = perm.UserState()
+	userState.AddUser(creds.Email, creds.Password, "")
+
+	statsCache.stats = nil
+	defer func() { statsCache.stats = nil }()
+
+	getStatsResponse := func() *db.Stats {
+		r, err := http.NewRequest("GET", "/stats", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		getStats(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		stats := &db.Stats{}
+		if err = json.NewDecoder(rr.Body).Decode(&responses.Response{Message: stats}); err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+
+	stats := getStatsResponse()
+	expected := db.Stats{Courses: len(courses), Professors: len(professors), Grades: len(scores), Users: 1}
+	if *stats != expected {
+		t.Errorf("got %+v, want %+v", *stats, expected)
+	}
+
+	// the totals are served from the cache until they expire.
+	if err = dataDb.AddCourse(&db.Course{Code: "GC8F", Name: "Showing your son whose the boss"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats = getStatsResponse(); stats.Courses != len(courses) {
+		t.Errorf("got %d courses, want the cached %d", stats.Courses, len(courses))
+	}
+
+	statsCache.stats = nil
+	if stats = getStatsResponse(); stats.Courses != len(courses)+1 {
+		t.Errorf("got %d courses, want %d", stats.Courses, len(courses)+1)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+// tenantPathPrefix is the prefix of the paths of the requests to a tenant, followed by the identifier of the tenant.
+// For example, /t/foo/course/all gets the courses of the tenant foo.
+const tenantPathPrefix = "/t/"
+
+// tenantHeader is the request header holding the identifier of the tenant of a request without tenant path prefix.
+const tenantHeader = "X-ITPG-Tenant"
+
+// tenantContextKey is the key in the request's context to set the database of the tenant of the request.
+const tenantContextKey contextKey = "tenant"
+
+// tenantIDRegex matches the valid identifiers of tenants.
+var tenantIDRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// tenantDbs are the databases of the tenants by identifier, each holding its own courses, professors, and scores.
+// It is empty when multi-tenant mode is disabled.
+var tenantDbs map[string]db.DB
+
+// parseTenants parses tenants in the tenant=url form into a map of the identifiers of the tenants to the URLs of their databases.
+func parseTenants(tenants []string) (map[string]string, error) {
+	urls := make(map[string]string, len(tenants))
+
+	for _, tenant := range tenants {
+		id, url, ok := strings.Cut(tenant, "=")
+		if !ok || url == "" || !tenantIDRegex.MatchString(id) {
+			return nil, fmt.Errorf("invalid tenant: %s (should be in the tenant=url form, with a lowercase alphanumeric tenant)", tenant)
+		}
+
+		if _, ok := urls[id]; ok {
+			return nil, fmt.Errorf("duplicate tenant: %s", id)
+		}
+
+		urls[id] = url
+	}
+
+	return urls, nil
+}
+
+// tenantCacheNamespace returns the namespace of the keys of the database of a tenant in the cache.
+func tenantCacheNamespace(id string) string {
+	return "tenant:" + id + ":"
+}
+
+// tenantIDs returns the sorted identifiers of the tenants.
+func tenantIDs() []string {
+	ids := make([]string, 0, len(tenantDbs))
+	for id := range tenantDbs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// closeTenantDbs closes the databases of the tenants.
+func closeTenantDbs() {
+	for _, d := range tenantDbs {
+		d.Close()
+	}
+}
+
+// tenantMiddleware is a negroni middleware resolving the tenant of a request, in multi-tenant mode.
+// The tenant is read from the tenant path prefix, which is then stripped from the path,
+// or from the tenant header. The database of the tenant is set in the request's context,
+// and requests to an unknown tenant are rejected with a Not Found response.
+// Requests without tenant use the default database.
+func tenantMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// the responses of cacheable reads depend on the tenant header.
+	w.Header().Add("Vary", tenantHeader)
+
+	id := r.Header.Get(tenantHeader)
+	if rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix); ok {
+		id, rest, _ = strings.Cut(rest, "/")
+
+		u := *r.URL
+		u.Path = "/" + rest
+		u.RawPath = ""
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = &u
+		r = r2
+	}
+
+	if id == "" {
+		next(w, r)
+		return
+	}
+
+	d, ok := tenantDbs[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		responses.ErrTenantNotFound.WriteJSON(w)
+		return
+	}
+
+	next(w, r.WithContext(context.WithValue(r.Context(), tenantContextKey, d)))
+}
+
+// requestDb returns the database of the tenant of a request, set by tenantMiddleware,
+// or the default database if the request has no tenant.
+func requestDb(r *http.Request) db.DB {
+	if d, ok := r.Context().Value(tenantContextKey).(db.DB); ok {
+		return d
+	}
+	return dataDb
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vanillaiice/itpg/db"
+	"github.com/vanillaiice/itpg/db/sqlite"
+	"github.com/vanillaiice/itpg/responses"
+)
+
+func TestParseTenants(t *testing.T) {
+	urls, err := parseTenants([]string{"foo=foo.db", "bar-2=postgres://user@localhost:5432/bar?sslmode=disable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls["foo"] != "foo.db" || urls["bar-2"] != "postgres://user@localhost:5432/bar?sslmode=disable" {
+		t.Errorf("got unexpected tenants %v", urls)
+	}
+
+	for _, tenants := range [][]string{{"foo"}, {"foo="}, {"=foo.db"}, {"Foo=foo.db"}, {"foo/bar=foo.db"}, {"foo=foo.db", "foo=bar.db"}} {
+		if _, err = parseTenants(tenants); err == nil {
+			t.Errorf("expected error for tenants %v", tenants)
+		}
+	}
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	err := dbInit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataDb.Close()
+
+	tenantDbs = map[string]db.DB{}
+	defer func() {
+		closeTenantDbs()
+		tenantDbs = nil
+	}()
+
+	// both tenants have a course with the same code.
+	for id, name := range map[string]string{"foo": "Algorithms", "bar": "Biology"} {
+		d, err := sqlite.New(":memory:", "", 0, context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tenantDbs[id] = d
+
+		if err = d.AddCourse(&db.Course{Code: "CS101", Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var path string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		tenantMiddleware(w, r, func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			getLastCourses(w, r)
+		})
+	}
+
+	getCourses := func(t *testing.T, r *http.Request) []*db.Course {
+		rr := httptest.NewRecorder()
+		handler(rr, r)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rr.Code, http.StatusOK)
+		}
+
+		if vary := rr.Header().Get("Vary"); vary != tenantHeader {
+			t.Errorf("got %s, want %s", vary, tenantHeader)
+		}
+
+		var courses []*db.Course
+		if err := json.NewDecoder(rr.Body).Decode(&responses.Response{Message: &courses}); err != nil {
+			t.Fatal(err)
+		}
+		return courses
+	}
+
+	t.Run("path prefix", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/t/foo/course/all", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		courses := getCourses(t, r)
+		if len(courses) != 1 || courses[0].Code != "CS101" || courses[0].Name != "Algorithms" {
+			t.Errorf("got unexpected courses %v", courses)
+		}
+
+		if path != "/course/all" {
+			t.Errorf("got %s, want %s", path, "/course/all")
+		}
+	})
+
+	t.Run("header", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/course/all", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set(tenantHeader, "bar")
+
+		courses := getCourses(t, r)
+		if len(courses) != 1 || courses[0].Code != "CS101" || courses[0].Name != "Biology" {
+			t.Errorf("got unexpected courses %v", courses)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/course/all", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := getCourses(t, r); len(got) != len(courses) {
+			t.Errorf("got %d courses, want %d", len(got), len(courses))
+		}
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/t/baz/course/all", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, r)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("got %v, want %v", rr.Code, http.StatusNotFound)
+		}
+		if rr.Body.String() != responses.ErrTenantNotFound.Error() {
+			t.Errorf("got %s, want %s", rr.Body.String(), responses.ErrTenantNotFound.Error())
+		}
+	})
+}